@@ -0,0 +1,141 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+
+	"github.com/Fantom-foundation/go-evm/src/service"
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+// accountStoragePage is the number of storage slots fetched per request
+const accountStoragePage = 1024
+
+var (
+	accountNode    string
+	accountLocal   bool
+	accountStorage bool
+)
+
+//AddAccountFlags adds flags to the account inspect command
+func AddAccountFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&accountNode, "node", "localhost:8080", "Address of the HTTP API of the node to query")
+	cmd.Flags().BoolVar(&accountLocal, "local", false, "Read the account from the local database (eth.db) of the stopped node instead of a node")
+	cmd.Flags().BoolVar(&accountStorage, "storage", false, "Print the whole storage of the account")
+}
+
+//NewAccountCmd returns the command that inspects accounts
+func NewAccountCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "account",
+		Short: "Inspect accounts of the state",
+	}
+
+	inspectCmd := &cobra.Command{
+		Use:   "inspect [address]",
+		Short: "Print the balance, nonce, code size and optionally the storage of an account",
+		Long: `Print the balance, nonce, code size and optionally the storage of an account.
+
+The account is read in the state of the last committed block, from a running
+node, or from the database of the stopped node with --local. --storage prints
+every slot of the storage, in trie order.`,
+		Args: cobra.ExactArgs(1),
+		RunE: inspectAccount,
+	}
+	AddAccountFlags(inspectCmd)
+
+	cmd.AddCommand(inspectCmd)
+	return cmd
+}
+
+func inspectAccount(cmd *cobra.Command, args []string) error {
+	if !common.IsHexAddress(args[0]) {
+		return fmt.Errorf("invalid address %q", args[0])
+	}
+	address := common.HexToAddress(args[0])
+
+	fetch := func(start common.Hash, limit int) (*service.JsonAccountState, error) {
+		return remoteAccountState(accountNode, address, start, limit)
+	}
+	if accountLocal {
+		st, err := state.NewState(logger,
+			config.Eth.DbFile,
+			config.Eth.Cache,
+			nil)
+		if err != nil {
+			return fmt.Errorf("opening database: %s", err)
+		}
+		defer st.Close()
+		fetch = func(start common.Hash, limit int) (*service.JsonAccountState, error) {
+			return service.GetJsonAccountState(st, address, start, limit)
+		}
+	}
+
+	limit := 0
+	if accountStorage {
+		limit = accountStoragePage
+	}
+	account, err := fetch(common.Hash{}, limit)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Address:    %s\n", account.Address.Hex())
+	fmt.Printf("Block:      %d\n", uint64(account.BlockNumber))
+	fmt.Printf("State root: %s\n", account.StateRoot.Hex())
+	if !account.Exists {
+		fmt.Println("The account does not exist")
+		return nil
+	}
+	fmt.Printf("Balance:    %s\n", account.Balance.ToInt())
+	fmt.Printf("Nonce:      %d\n", uint64(account.Nonce))
+	fmt.Printf("Code size:  %d\n", uint64(account.CodeSize))
+	fmt.Printf("Code hash:  %s\n", account.CodeHash.Hex())
+	if !accountStorage {
+		return nil
+	}
+
+	// Each page is read at the last committed block, which must not change
+	root := account.StateRoot
+	slots := 0
+	fmt.Println("Storage:")
+	for {
+		for _, slot := range account.Storage {
+			fmt.Printf("  %s: %s\n", slot.Key.Hex(), slot.Value.Hex())
+		}
+		slots += len(account.Storage)
+		if account.Next == nil {
+			break
+		}
+		if account, err = fetch(*account.Next, limit); err != nil {
+			return err
+		}
+		if account.StateRoot != root {
+			return fmt.Errorf("a block was committed while reading the storage, try again")
+		}
+	}
+	fmt.Printf("Slots:      %d\n", slots)
+	return nil
+}
+
+func remoteAccountState(node string, address common.Address, start common.Hash, limit int) (*service.JsonAccountState, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/account/%s/state?start=%s&limit=%d",
+		nodeURL(node), address.Hex(), start.Hex(), limit), nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := doNodeRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	account := new(service.JsonAccountState)
+	if err := json.Unmarshal(body, account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/Fantom-foundation/go-evm/src/engine"
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+var (
+	exportFrom int64
+	exportTo   int64
+)
+
+//AddExportFlags adds flags to the export command
+func AddExportFlags(cmd *cobra.Command) {
+	cmd.Flags().Int64Var(&exportFrom, "from", -1, "First block exported (the first committed block if negative)")
+	cmd.Flags().Int64Var(&exportTo, "to", -1, "Last block exported (the last committed block if negative)")
+}
+
+//NewExportCmd returns the command that exports the chain to a file
+func NewExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export [file]",
+		Short: "Export the blocks, transactions and receipts of the chain to a file",
+		Long: `Export the blocks, transactions and receipts of the chain to a file.
+
+The file is a stream of RLP encoded blocks with their transactions and
+receipts, gzipped if its name ends with .gz. It is a backup of the chain, which
+"evm import" replays into a fresh node, e.g. to migrate to another database
+backend. The node must be stopped.`,
+		Args: cobra.ExactArgs(1),
+		RunE: exportChain,
+	}
+	AddExportFlags(cmd)
+	return cmd
+}
+
+//NewImportCmd returns the command that imports the chain from a file
+func NewImportCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "import [file]",
+		Short: "Replay the blocks of a chain export",
+		Long: `Replay the blocks of a chain export.
+
+The database must hold the state the first exported block was applied to, e.g.
+a fresh node initialized with the same genesis by "evm init". Each block is
+applied again, and must result in the exported header: the state, transactions
+and receipts are then those of the exporting node. Blocks already applied are
+skipped, so an interrupted import can be run again. The node must be stopped.`,
+		Args: cobra.ExactArgs(1),
+		RunE: importChain,
+	}
+}
+
+func exportChain(cmd *cobra.Command, args []string) error {
+	st, err := state.NewState(logger,
+		config.Eth.DbFile,
+		config.Eth.Cache,
+		nil)
+	if err != nil {
+		return fmt.Errorf("opening database: %s", err)
+	}
+	defer st.Close()
+
+	f, err := os.Create(args[0])
+	if err != nil {
+		return err
+	}
+	var w io.Writer = f
+	var gz *gzip.Writer
+	if strings.HasSuffix(args[0], ".gz") {
+		gz = gzip.NewWriter(f)
+		w = gz
+	}
+
+	blocks, err := st.ExportChain(w, exportFrom, exportTo)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	// The end of the gzip stream is only written on Close
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	logger.WithFields(logrus.Fields{
+		"file":   args[0],
+		"blocks": blocks,
+	}).Info("Exported chain")
+	return nil
+}
+
+func importChain(cmd *cobra.Command, args []string) error {
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(args[0], ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	st, err := engine.NewState(*config, logger)
+	if err != nil {
+		return fmt.Errorf("opening database: %s", err)
+	}
+	defer st.Close()
+
+	blocks, err := st.ImportChain(r, func(block *state.Block) {
+		if block.Number%1000 == 0 {
+			logger.WithField("block", block.Number).Info("Importing chain")
+		}
+	})
+	if err != nil {
+		return err
+	}
+	logger.WithFields(logrus.Fields{
+		"file":   args[0],
+		"blocks": blocks,
+		"head":   st.GetBlockIndex(),
+	}).Info("Imported chain")
+	return nil
+}
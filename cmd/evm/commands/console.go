@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/console"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/spf13/cobra"
+)
+
+var (
+	consoleExec    string
+	consolePreload []string
+	consoleJSPath  string
+)
+
+//AddConsoleFlags adds flags to the attach command
+func AddConsoleFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&consoleExec, "exec", "", "JavaScript statement to execute before exiting, instead of the interactive console")
+	cmd.Flags().StringSliceVar(&consolePreload, "preload", nil, "JavaScript files to load into the console")
+	cmd.Flags().StringVar(&consoleJSPath, "jspath", ".", "Directory of the scripts loaded with loadScript and --preload")
+	cmd.Flags().StringVar(&nodeAPIKey, "api-key", "", "API key of the node, if its JSON-RPC namespaces are protected")
+}
+
+//NewAttachCmd returns the command that starts an interactive JavaScript console
+//attached to a node
+func NewAttachCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "attach [endpoint]",
+		Aliases: []string{"console"},
+		Short:   "Start an interactive JavaScript console attached to a node",
+		Long: `Start an interactive JavaScript console attached to a node.
+
+The console exposes the web3.js bindings (eth, net, personal, txpool, debug,
+admin...) of the JSON-RPC APIs of the node, like geth attach. The endpoint is
+the IPC socket (eth.ipc by default), or an http://, https://, ws:// or wss://
+URL of the JSON-RPC endpoint.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: attach,
+	}
+	AddConsoleFlags(cmd)
+	return cmd
+}
+
+func attach(cmd *cobra.Command, args []string) error {
+	endpoint := config.Eth.IPCPath
+	if len(args) > 0 {
+		endpoint = args[0]
+	}
+	client, err := dialNode(endpoint)
+	if err != nil {
+		return fmt.Errorf("attaching to %s: %s", endpoint, err)
+	}
+
+	c, err := console.New(console.Config{
+		DataDir: filepath.Dir(config.Eth.IPCPath),
+		DocRoot: consoleJSPath,
+		Client:  client,
+		Preload: consolePreload,
+	})
+	if err != nil {
+		return err
+	}
+	defer c.Stop(false)
+
+	if consoleExec != "" {
+		c.Evaluate(consoleExec)
+		return nil
+	}
+	c.Welcome()
+	c.Interactive()
+	return nil
+}
+
+// dialNode connects to the JSON-RPC endpoint of a node, sending the API key
+// with the HTTP requests
+func dialNode(endpoint string) (*rpc.Client, error) {
+	if strings.HasPrefix(endpoint, "http://") || strings.HasPrefix(endpoint, "https://") {
+		return rpc.DialHTTPWithClient(endpoint, &http.Client{Transport: apiKeyTransport{http.DefaultTransport}})
+	}
+	if _, err := os.Stat(endpoint); err != nil && !strings.Contains(endpoint, "://") {
+		return nil, err
+	}
+	return rpc.Dial(endpoint)
+}
+
+// apiKeyTransport adds the API key of the node to HTTP requests
+type apiKeyTransport struct {
+	next http.RoundTripper
+}
+
+func (t apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if nodeAPIKey != "" {
+		clone := *req
+		clone.Header = cloneHeader(req.Header)
+		clone.Header.Set("Authorization", "Bearer "+nodeAPIKey)
+		req = &clone
+	}
+	return t.next.RoundTrip(req)
+}
+
+func cloneHeader(h http.Header) http.Header {
+	res := make(http.Header, len(h))
+	for k, v := range h {
+		res[k] = append([]string(nil), v...)
+	}
+	return res
+}
@@ -0,0 +1,160 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+var migrateDryRun bool
+
+//NewDBCmd returns the command that maintains the database of a stopped node
+func NewDBCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Maintain the database of a stopped node",
+	}
+
+	compactCmd := &cobra.Command{
+		Use:   "compact",
+		Short: "Compact the database",
+		Long: `Compact the database (eth.db).
+
+LevelDB keeps the deleted and overwritten entries of a long-running node until
+they are compacted, which slows down reads. The keys are compacted one range
+at a time, reporting the progress. The node must be stopped.`,
+		Args: cobra.NoArgs,
+		RunE: compactDB,
+	}
+
+	inspectCmd := &cobra.Command{
+		Use:   "inspect",
+		Short: "Report the size of the database per family of keys",
+		Long: `Report the size of the database (eth.db) per family of keys.
+
+Every entry of the database is read, and counted in its family: trie nodes,
+code, transactions, receipts, tx metadata, block headers, indexes... The node
+must be stopped.`,
+		Args: cobra.NoArgs,
+		RunE: inspectDB,
+	}
+
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Upgrade the layout of the database to the version of this node",
+		Long: `Upgrade the layout of the database (eth.db) to the version of this node.
+
+The database records the version of its layout (schema version). When a new
+version of the node changes how blocks, receipts or indexes are stored, it
+adds a migration upgrading the existing entries, instead of requiring a resync
+from the genesis. The pending migrations are applied in order, recording the
+version reached after each one. The node must be stopped.`,
+		Args: cobra.NoArgs,
+		RunE: migrateDB,
+	}
+	migrateCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "List the pending migrations without applying them")
+
+	cmd.AddCommand(compactCmd, inspectCmd, migrateCmd)
+	return cmd
+}
+
+func compactDB(cmd *cobra.Command, args []string) error {
+	if _, err := os.Stat(config.Eth.DbFile); err != nil {
+		return fmt.Errorf("opening database: %s", err)
+	}
+	before, err := state.DBSize(config.Eth.DbFile)
+	if err != nil {
+		return err
+	}
+
+	err = state.CompactDB(config.Eth.DbFile, config.Eth.Cache, func(done, total int) {
+		if done%16 == 0 || done == total {
+			logger.WithField("progress", fmt.Sprintf("%d%%", done*100/total)).Info("Compacting database")
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	after, err := state.DBSize(config.Eth.DbFile)
+	if err != nil {
+		return err
+	}
+	logger.WithFields(logrus.Fields{
+		"before": common.StorageSize(before).String(),
+		"after":  common.StorageSize(after).String(),
+	}).Info("Compacted database")
+	return nil
+}
+
+func inspectDB(cmd *cobra.Command, args []string) error {
+	if _, err := os.Stat(config.Eth.DbFile); err != nil {
+		return fmt.Errorf("opening database: %s", err)
+	}
+
+	families, err := state.InspectDB(config.Eth.DbFile, config.Eth.Cache, func(entries int64) {
+		logger.WithField("entries", entries).Info("Inspecting database")
+	})
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "FAMILY\tENTRIES\tSIZE")
+	var entries, size int64
+	for _, f := range families {
+		fmt.Fprintf(w, "%s\t%d\t%s\n", f.Family, f.Entries, common.StorageSize(f.Size))
+		entries += f.Entries
+		size += f.Size
+	}
+	fmt.Fprintf(w, "total\t%d\t%s\n", entries, common.StorageSize(size))
+	return w.Flush()
+}
+
+func migrateDB(cmd *cobra.Command, args []string) error {
+	if _, err := os.Stat(config.Eth.DbFile); err != nil {
+		return fmt.Errorf("opening database: %s", err)
+	}
+	st, err := state.NewState(logger,
+		config.Eth.DbFile,
+		config.Eth.Cache,
+		nil)
+	if err != nil {
+		return fmt.Errorf("opening database: %s", err)
+	}
+	defer st.Close()
+	// Migrations may recover the senders of transactions
+	if err := setChainID(st); err != nil {
+		return err
+	}
+
+	pending, err := st.PendingMigrations()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Schema version: %d (this node: %d)\n", st.GetSchemaVersion(), state.SchemaVersion)
+	for _, m := range pending {
+		fmt.Printf("Pending:        %d: %s\n", m.Version, m.Description)
+	}
+	if len(pending) == 0 || migrateDryRun {
+		return nil
+	}
+
+	err = st.Migrate(func(m state.Migration, done int64) {
+		logger.WithFields(logrus.Fields{
+			"version": m.Version,
+			"entries": done,
+		}).Info("Migrating database")
+	})
+	if err != nil {
+		return err
+	}
+	logger.WithField("version", st.GetSchemaVersion()).Info("Migrated database")
+	return nil
+}
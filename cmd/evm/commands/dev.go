@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	_config "github.com/Fantom-foundation/go-evm/src/config"
+)
+
+// devBalance is the balance of the pre-funded accounts of a development node,
+// 1,000,000 ether
+const devBalance = "1000000000000000000000000"
+
+// setupDevNode prepares a throwaway development node in a temporary directory,
+// removed by the returned function: a keystore of pre-funded accounts unlocked
+// with an empty password and a genesis funding them. The node runs with Solo
+// consensus committing every transaction at once, and serves any origin.
+func setupDevNode() (func(), error) {
+	if config.DevAccounts < 1 {
+		return nil, fmt.Errorf("dev-accounts must be at least 1")
+	}
+	dir, err := ioutil.TempDir("", "evm-dev")
+	if err != nil {
+		return nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	// The paths derived from the data directory, the others are kept
+	eth := _config.DefaultEthConfig()
+	eth.SetDataDir(filepath.Join(dir, "eth"))
+	config.Eth.Genesis = eth.Genesis
+	config.Eth.Keystore = eth.Keystore
+	config.Eth.PwdFile = eth.PwdFile
+	config.Eth.DbFile = eth.DbFile
+	config.Eth.NodeKey = eth.NodeKey
+	config.Eth.IPCPath = eth.IPCPath
+	config.Eth.RPCCors = []string{"*"}
+	config.Consensus = "solo"
+	config.Solo.Interval = 0
+
+	if err := writeDevAccounts(); err != nil {
+		cleanup()
+		return nil, err
+	}
+	return cleanup, nil
+}
+
+// writeDevAccounts generates the pre-funded accounts, prints their keys and
+// writes the keystore, the password file and the genesis
+func writeDevAccounts() error {
+	if err := os.MkdirAll(config.Eth.Keystore, 0700); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(config.Eth.PwdFile, []byte("\n"), 0600); err != nil {
+		return err
+	}
+
+	ks := keystore.NewKeyStore(config.Eth.Keystore, keystore.LightScryptN, keystore.LightScryptP)
+	alloc := make(map[string]map[string]string)
+	fmt.Println("Development accounts:")
+	for i := 0; i < config.DevAccounts; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			return err
+		}
+		account, err := ks.ImportECDSA(key, "")
+		if err != nil {
+			return err
+		}
+		alloc[account.Address.Hex()] = map[string]string{"balance": devBalance}
+		fmt.Printf("(%d) %s  %s\n", i, account.Address.Hex(), common.ToHex(crypto.FromECDSA(key)))
+	}
+	fmt.Println()
+
+	genesis, err := json.MarshalIndent(map[string]interface{}{"alloc": alloc}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(config.Eth.Genesis, genesis, 0600)
+}
@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/spf13/cobra"
+
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+//NewGenesisCmd returns the command that checks genesis files
+func NewGenesisCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "genesis",
+		Short: "Check a genesis file",
+	}
+
+	validateCmd := &cobra.Command{
+		Use:   "validate [file]",
+		Short: "Validate a genesis file and print its state root",
+		Long: `Validate a genesis file and print its state root.
+
+The parameters of the network (config), the addresses, balances, code and
+storage of the accounts are checked, as well as the fee policy: hard forks out
+of order, an account listed twice, a balance or a total of balances overflowing
+256 bits is an error. The constructors of the contracts are run, and one which
+fails is an error. The chain id is the one of the config section, or
+eth.chain-id. The state root is computed in memory: the data
+directory is not touched. The file is eth.genesis if none is given.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: validateGenesis,
+	}
+
+	cmd.AddCommand(validateCmd)
+	return cmd
+}
+
+func validateGenesis(cmd *cobra.Command, args []string) error {
+	file := config.Eth.Genesis
+	if len(args) > 0 {
+		file = args[0]
+	}
+	genesis, err := state.ReadGenesis(file)
+	if err != nil {
+		return err
+	}
+
+	chainConfig := state.DefaultChainConfig()
+	chainConfig.ChainID = new(big.Int).SetUint64(config.Eth.ChainID)
+	gasLimit, minGasPrice := uint64(state.DefaultGasLimit), "none"
+	if c := genesis.Config; c != nil {
+		chainConfig = c.ChainConfig(chainConfig.ChainID)
+		if c.GasLimit != 0 {
+			gasLimit = c.GasLimit
+		}
+		if c.MinGasPrice != nil {
+			minGasPrice = (*big.Int)(c.MinGasPrice).String()
+		}
+	}
+	if chainConfig.ChainID.Sign() == 0 {
+		return fmt.Errorf("the chain id (config.chainId or eth.chain-id) must not be 0")
+	}
+
+	root, logs, err := genesis.StateRoot()
+	if err != nil {
+		return err
+	}
+	total := new(big.Int)
+	constructors := 0
+	for _, account := range genesis.Alloc {
+		total.Add(total, math.MustParseBig256(account.Balance))
+		if account.Constructor != "" {
+			constructors++
+		}
+	}
+
+	fmt.Printf("Genesis:      %s\n", file)
+	fmt.Printf("Genesis hash: %s\n", genesis.Hash().Hex())
+	fmt.Printf("State root:   %s\n", root.Hex())
+	fmt.Printf("Accounts:     %d\n", len(genesis.Alloc))
+	fmt.Printf("Total supply: %s\n", total)
+	fmt.Printf("Constructors: %d (%d logs)\n", constructors, len(logs))
+	fmt.Printf("Fee policy:   %t\n", genesis.FeePolicy != nil)
+	fmt.Printf("Chain id:     %s\n", chainConfig.ChainID)
+	fmt.Printf("Fork:         %s\n", state.ForkName(&chainConfig, big.NewInt(0)))
+	fmt.Printf("Gas limit:    %d\n", gasLimit)
+	fmt.Printf("Min price:    %s\n", minGasPrice)
+	return nil
+}
@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+var initGenesis string
+
+//AddInitFlags adds flags to the init command
+func AddInitFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&initGenesis, "genesis", "", "Genesis file to initialize the node with (eth.genesis if empty)")
+}
+
+//NewInitCmd returns the command that initializes the data directory and the
+//database of a node from a genesis file
+func NewInitCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Initialize the data directory and the database from a genesis file",
+		Long: `Initialize the data directory and the database from a genesis file.
+
+The genesis file is validated and copied to eth.genesis, the keystore directory
+is created, and the accounts of the genesis are written to the empty database,
+which records the hash of the genesis. The node then refuses to run with
+another genesis.`,
+		Args: cobra.NoArgs,
+		RunE: initNode,
+	}
+	AddInitFlags(cmd)
+	return cmd
+}
+
+func initNode(cmd *cobra.Command, args []string) error {
+	source := initGenesis
+	if source == "" {
+		source = config.Eth.Genesis
+	}
+	genesis, err := state.ReadGenesis(source)
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range []string{filepath.Dir(config.Eth.Genesis), config.Eth.Keystore, filepath.Dir(config.Eth.DbFile)} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+	}
+	if err := installGenesis(source, genesis); err != nil {
+		return err
+	}
+
+	st, err := state.NewState(logger,
+		config.Eth.DbFile,
+		config.Eth.Cache,
+		nil)
+	if err != nil {
+		return fmt.Errorf("opening database: %s", err)
+	}
+	defer st.Close()
+	info, err := st.InitGenesis(genesis)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Genesis:      %s\n", config.Eth.Genesis)
+	fmt.Printf("Genesis hash: %s\n", info.Hash.Hex())
+	fmt.Printf("State root:   %s\n", info.Root.Hex())
+	fmt.Printf("Accounts:     %d\n", len(genesis.Alloc))
+	return nil
+}
+
+// installGenesis copies the genesis file to eth.genesis, unless it is there
+// already. A different genesis found there is not overwritten.
+func installGenesis(source string, genesis *state.Genesis) error {
+	if source == config.Eth.Genesis {
+		return nil
+	}
+	if existing, err := state.ReadGenesis(config.Eth.Genesis); err == nil {
+		if existing.Hash() != genesis.Hash() {
+			return fmt.Errorf("%s holds another genesis", config.Eth.Genesis)
+		}
+		return nil
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(source)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(config.Eth.Genesis, data, 0600)
+}
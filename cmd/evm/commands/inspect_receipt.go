@@ -0,0 +1,233 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/spf13/cobra"
+
+	"github.com/Fantom-foundation/go-evm/src/service"
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+var (
+	inspectNode  string
+	inspectLocal bool
+	inspectABIs  []string
+)
+
+//AddInspectReceiptFlags adds flags to the inspect-receipt command
+func AddInspectReceiptFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&inspectNode, "node", "localhost:8080", "Address of the HTTP API of the node to fetch the receipt from")
+	cmd.Flags().BoolVar(&inspectLocal, "local", false, "Read the receipt from the local database (eth.db) instead of a node")
+	cmd.Flags().StringSliceVar(&inspectABIs, "abi", nil, "ABI files, or directories of ABI files, used to decode the logs")
+}
+
+//NewInspectReceiptCmd returns the command that prints a human-readable report of
+//a transaction receipt
+func NewInspectReceiptCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "inspect-receipt [tx_hash]",
+		Short: "Print a human-readable report of a transaction receipt",
+		Args:  cobra.ExactArgs(1),
+		RunE:  inspectReceipt,
+	}
+	AddInspectReceiptFlags(cmd)
+	return cmd
+}
+
+func inspectReceipt(cmd *cobra.Command, args []string) error {
+	txHash := common.HexToHash(args[0])
+
+	events, err := loadEvents(inspectABIs)
+	if err != nil {
+		return err
+	}
+
+	var receipt *service.JsonReceipt
+	if inspectLocal {
+		receipt, err = localReceipt(txHash)
+	} else {
+		receipt, err = remoteReceipt(inspectNode, txHash)
+	}
+	if err != nil {
+		return err
+	}
+
+	printReceipt(os.Stdout, receipt, events)
+	return nil
+}
+
+func localReceipt(txHash common.Hash) (*service.JsonReceipt, error) {
+	st, err := state.NewState(logger,
+		config.Eth.DbFile,
+		config.Eth.Cache,
+		config.Eth.ArchiveDirs)
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %s", err)
+	}
+	defer st.Close()
+	return service.GetJsonReceipt(st, txHash, logger)
+}
+
+func remoteReceipt(node string, txHash common.Hash) (*service.JsonReceipt, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/tx/%s", nodeURL(node), txHash.Hex()), nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := doNodeRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := new(service.JsonReceipt)
+	if err := json.Unmarshal(body, receipt); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// loadEvents reads the events of the ABIs in the given files and directories,
+// indexed by their topic. Files may contain a bare ABI or a contract artifact
+// with an "abi" field (eg. truffle).
+func loadEvents(paths []string) (map[common.Hash]abi.Event, error) {
+	events := make(map[common.Hash]abi.Event)
+
+	files := []string{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		abiMatches, err := filepath.Glob(filepath.Join(path, "*.abi"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, append(matches, abiMatches...)...)
+	}
+
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		var artifact struct {
+			ABI json.RawMessage `json:"abi"`
+		}
+		if err := json.Unmarshal(data, &artifact); err == nil && len(artifact.ABI) > 0 {
+			data = artifact.ABI
+		}
+
+		parsed, err := abi.JSON(strings.NewReader(string(data)))
+		if err != nil {
+			return nil, fmt.Errorf("parsing ABI %s: %s", file, err)
+		}
+		for _, event := range parsed.Events {
+			events[event.Id()] = event
+		}
+	}
+
+	return events, nil
+}
+
+func printReceipt(w io.Writer, r *service.JsonReceipt, events map[common.Hash]abi.Event) {
+	status := "success"
+	if r.Failed {
+		status = "failed (not applied)"
+	} else if r.GasCapped {
+		status = "gas capped"
+	} else if uint64(r.Status) == ethTypes.ReceiptStatusFailed {
+		status = "reverted"
+	}
+
+	fmt.Fprintf(w, "Transaction:      %s\n", r.TransactionHash.Hex())
+	fmt.Fprintf(w, "Status:           %s\n", status)
+	if r.Error != "" {
+		fmt.Fprintf(w, "Error:            %s\n", r.Error)
+	}
+	if r.RevertReason != "" {
+		fmt.Fprintf(w, "Revert reason:    %s\n", r.RevertReason)
+	} else if len(r.RevertData) > 0 {
+		fmt.Fprintf(w, "Revert data:      %s\n", r.RevertData)
+	}
+	if !r.Failed {
+		fmt.Fprintf(w, "Block:            %d (%s)\n", uint64(r.BlockNumber), r.BlockHash.Hex())
+		fmt.Fprintf(w, "Index in block:   %d\n", uint64(r.TransactionIndex))
+	}
+	fmt.Fprintf(w, "From:             %s\n", r.From.Hex())
+	if r.To != nil {
+		fmt.Fprintf(w, "To:               %s\n", r.To.Hex())
+	} else {
+		fmt.Fprintf(w, "Contract created: %s\n", r.ContractAddress.Hex())
+	}
+	fmt.Fprintf(w, "Value:            %s\n", r.Value.ToInt())
+	fmt.Fprintf(w, "Gas price:        %s\n", r.GasPrice.ToInt())
+	fmt.Fprintf(w, "Gas limit:        %d\n", uint64(r.Gas))
+	if !r.Failed {
+		fmt.Fprintf(w, "Gas used:         %d\n", uint64(r.GasUsed))
+		fmt.Fprintf(w, "Cumulative gas:   %d\n", uint64(r.CumulativeGasUsed))
+	}
+
+	fmt.Fprintf(w, "Logs:             %d\n", len(r.Logs))
+	for _, log := range r.Logs {
+		fmt.Fprintf(w, "  [%d] %s\n", log.Index, log.Address.Hex())
+		fmt.Fprintf(w, "      %s\n", formatLog(log, events))
+	}
+}
+
+// formatLog decodes a log with the matching event, or prints its raw topics and
+// data when the event is unknown
+func formatLog(log *ethTypes.Log, events map[common.Hash]abi.Event) string {
+	raw := fmt.Sprintf("topics=%v data=0x%x", log.Topics, log.Data)
+	if len(log.Topics) == 0 {
+		return raw
+	}
+	event, ok := events[log.Topics[0]]
+	if !ok {
+		return raw
+	}
+
+	values, err := event.Inputs.NonIndexed().UnpackValues(log.Data)
+	if err != nil {
+		return fmt.Sprintf("%s (undecodable: %s) %s", event.Name, err, raw)
+	}
+
+	args := []string{}
+	topic := 1
+	for _, input := range event.Inputs {
+		var value interface{}
+		if input.Indexed {
+			if topic >= len(log.Topics) {
+				return fmt.Sprintf("%s (missing topics) %s", event.Name, raw)
+			}
+			value = log.Topics[topic].Hex()
+			if input.Type.T == abi.AddressTy {
+				value = common.BytesToAddress(log.Topics[topic].Bytes()).Hex()
+			}
+			topic++
+		} else {
+			value, values = values[0], values[1:]
+		}
+		args = append(args, fmt.Sprintf("%s=%v", input.Name, value))
+	}
+
+	return fmt.Sprintf("%s(%s)", event.Name, strings.Join(args, ", "))
+}
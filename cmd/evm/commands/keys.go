@@ -0,0 +1,260 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+var (
+	keysPasswordFile string
+	keysPrivate      bool
+)
+
+//AddKeysFlags adds flags to the keys commands
+func AddKeysFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&keysPasswordFile, "password", "", "File holding the password of the keys (prompted if empty)")
+}
+
+//NewKeysCmd returns the command that manages the account keys of the keystore
+func NewKeysCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "keys",
+		Short: "Manage the account keys of the keystore",
+		Long: `Manage the account keys of the keystore (eth.keystore).
+
+The keys are stored as web3 keystore v3 files, encrypted with a password, as
+geth and the web3 libraries do. The node unlocks them at startup with the
+password of eth.pwd, so the keys it uses must be encrypted with that password.`,
+	}
+	AddKeysFlags(cmd)
+
+	newCmd := &cobra.Command{
+		Use:   "new",
+		Short: "Create a new key",
+		Args:  cobra.NoArgs,
+		RunE:  newKey,
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the keys of the keystore",
+		Args:  cobra.NoArgs,
+		RunE:  listKeys,
+	}
+
+	importCmd := &cobra.Command{
+		Use:   "import [file]",
+		Short: "Import a keystore file, or a file holding a hex private key",
+		Long: `Import a keystore file, or a file holding a hex private key.
+
+A keystore file keeps its password, which must be given; a private key is
+encrypted with the given password.`,
+		Args: cobra.ExactArgs(1),
+		RunE: importKey,
+	}
+
+	exportCmd := &cobra.Command{
+		Use:   "export [address]",
+		Short: "Print the keystore file of a key",
+		Args:  cobra.ExactArgs(1),
+		RunE:  exportKey,
+	}
+
+	inspectCmd := &cobra.Command{
+		Use:   "inspect [address or file]",
+		Short: "Decrypt a key and print its address and public key",
+		Args:  cobra.ExactArgs(1),
+		RunE:  inspectKey,
+	}
+	inspectCmd.Flags().BoolVar(&keysPrivate, "private", false, "Also print the private key")
+
+	cmd.AddCommand(newCmd, listCmd, importCmd, exportCmd, inspectCmd)
+	return cmd
+}
+
+func openKeyStore() (*keystore.KeyStore, error) {
+	if err := os.MkdirAll(config.Eth.Keystore, 0700); err != nil {
+		return nil, err
+	}
+	return keystore.NewKeyStore(config.Eth.Keystore, keystore.StandardScryptN, keystore.StandardScryptP), nil
+}
+
+// readPassword reads the password from the password file, or prompts for it,
+// twice if it is a new one
+func readPassword(confirm bool) (string, error) {
+	if keysPasswordFile != "" {
+		text, err := ioutil.ReadFile(keysPasswordFile)
+		if err != nil {
+			return "", err
+		}
+		lines := strings.Split(string(text), "\n")
+		return strings.TrimRight(lines[0], "\r"), nil
+	}
+
+	if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("no password file and stdin is not a terminal")
+	}
+	pwd, err := promptPassword("Password: ")
+	if err != nil {
+		return "", err
+	}
+	if confirm {
+		again, err := promptPassword("Repeat password: ")
+		if err != nil {
+			return "", err
+		}
+		if pwd != again {
+			return "", fmt.Errorf("passwords do not match")
+		}
+	}
+	return pwd, nil
+}
+
+func promptPassword(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	pwd, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	return string(pwd), err
+}
+
+// findKey returns the keystore account with the given address
+func findKey(ks *keystore.KeyStore, param string) (accounts.Account, error) {
+	if !common.IsHexAddress(param) {
+		return accounts.Account{}, fmt.Errorf("invalid address %q", param)
+	}
+	return ks.Find(accounts.Account{Address: common.HexToAddress(param)})
+}
+
+func newKey(cmd *cobra.Command, args []string) error {
+	ks, err := openKeyStore()
+	if err != nil {
+		return err
+	}
+	pwd, err := readPassword(true)
+	if err != nil {
+		return err
+	}
+
+	account, err := ks.NewAccount(pwd)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Address: %s\n", account.Address.Hex())
+	fmt.Printf("File:    %s\n", account.URL.Path)
+	return nil
+}
+
+func listKeys(cmd *cobra.Command, args []string) error {
+	ks, err := openKeyStore()
+	if err != nil {
+		return err
+	}
+	for _, account := range ks.Accounts() {
+		fmt.Printf("%s %s\n", account.Address.Hex(), account.URL.Path)
+	}
+	return nil
+}
+
+func importKey(cmd *cobra.Command, args []string) error {
+	data, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	ks, err := openKeyStore()
+	if err != nil {
+		return err
+	}
+
+	var account accounts.Account
+	if json.Valid(data) {
+		pwd, err := readPassword(false)
+		if err != nil {
+			return err
+		}
+		account, err = ks.Import(data, pwd, pwd)
+		if err != nil {
+			return err
+		}
+	} else {
+		key, err := crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+		if err != nil {
+			return fmt.Errorf("%s is neither a keystore file nor a hex private key: %s", args[0], err)
+		}
+		pwd, err := readPassword(true)
+		if err != nil {
+			return err
+		}
+		account, err = ks.ImportECDSA(key, pwd)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Address: %s\n", account.Address.Hex())
+	fmt.Printf("File:    %s\n", account.URL.Path)
+	return nil
+}
+
+func exportKey(cmd *cobra.Command, args []string) error {
+	ks, err := openKeyStore()
+	if err != nil {
+		return err
+	}
+	account, err := findKey(ks, args[0])
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(account.URL.Path)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func inspectKey(cmd *cobra.Command, args []string) error {
+	file := args[0]
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		ks, err := openKeyStore()
+		if err != nil {
+			return err
+		}
+		account, err := findKey(ks, args[0])
+		if err != nil {
+			return err
+		}
+		file = account.URL.Path
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	pwd, err := readPassword(false)
+	if err != nil {
+		return err
+	}
+	key, err := keystore.DecryptKey(data, pwd)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Address:     %s\n", key.Address.Hex())
+	fmt.Printf("Public key:  %s\n", hexutil.Encode(crypto.FromECDSAPub(&key.PrivateKey.PublicKey)))
+	if keysPrivate {
+		fmt.Printf("Private key: %s\n", hexutil.Encode(crypto.FromECDSA(key.PrivateKey)))
+	}
+	return nil
+}
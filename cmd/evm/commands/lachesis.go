@@ -1,14 +1,9 @@
 package commands
 
 import (
-	"fmt"
-
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-
-	"github.com/Fantom-foundation/go-evm/src/consensus/lachesis"
-	"github.com/Fantom-foundation/go-evm/src/engine"
 )
 
 //AddLachesisFlags adds flags to the Lachesis command
@@ -51,15 +46,5 @@ func NewLachesisCmd() *cobra.Command {
 }
 
 func runLachesis(cmd *cobra.Command, args []string) error {
-	lachesisConsensus := lachesis.NewInmemLachesis(config.Lachesis, logger)
-	consensusEngine, err := engine.NewConsensusEngine(*config, lachesisConsensus, logger)
-	if err != nil {
-		return fmt.Errorf("error building Engine: %s", err)
-	}
-
-	if err := consensusEngine.Run(); err != nil {
-		return fmt.Errorf("error running Engine: %s", err)
-	}
-
-	return nil
+	return runEngine("lachesis")
 }
@@ -1,14 +1,9 @@
 package commands
 
 import (
-	"fmt"
-
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-
-	"github.com/Fantom-foundation/go-evm/src/consensus/raft"
-	"github.com/Fantom-foundation/go-evm/src/engine"
 )
 
 //AddRaftFlags adds flags to the Raft command
@@ -48,15 +43,5 @@ func NewRaftCmd() *cobra.Command {
 }
 
 func runRaft(cmd *cobra.Command, args []string) error {
-	raftConsensus := raft.NewRaft(*config.Raft, logger)
-	consensusEngine, err := engine.NewConsensusEngine(*config, raftConsensus, logger)
-	if err != nil {
-		return fmt.Errorf("error building Engine: %s", err)
-	}
-
-	if err := consensusEngine.Run(); err != nil {
-		return fmt.Errorf("error running Engine: %s", err)
-	}
-
-	return nil
+	return runEngine("raft")
 }
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+var (
+	reindexIndexes []string
+	reindexForce   bool
+)
+
+//AddReindexFlags adds flags to the reindex command
+func AddReindexFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&reindexIndexes, "index", nil,
+		fmt.Sprintf("Indexes to rebuild (%s). All by default", strings.Join(state.IndexNames(), ", ")))
+	cmd.Flags().BoolVar(&reindexForce, "force", false, "Rebuild indexes from the first block even if they are complete")
+}
+
+//NewReindexCmd returns the command that rebuilds the missing indexes of the
+//local database
+func NewReindexCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reindex",
+		Short: "Rebuild missing indexes from the stored blocks, transactions and receipts",
+		Long: `Rebuild missing indexes from the stored blocks, transactions and receipts.
+
+Indexes introduced by a newer version are only maintained for the blocks
+committed after the upgrade. reindex backfills them for the older blocks, so
+the node gains the new query features without resyncing. The node must be
+stopped.`,
+		RunE: reindex,
+	}
+	AddReindexFlags(cmd)
+	return cmd
+}
+
+func reindex(cmd *cobra.Command, args []string) error {
+	st, err := state.NewState(logger,
+		config.Eth.DbFile,
+		config.Eth.Cache,
+		nil)
+	if err != nil {
+		return fmt.Errorf("opening database: %s", err)
+	}
+	defer st.Close()
+	if err := setChainID(st); err != nil {
+		return err
+	}
+
+	progress := func(index string, number int64, head int64) {
+		if number%1000 == 0 || number == head {
+			logger.WithField("index", index).Infof("Indexed block %d/%d", number, head)
+		}
+	}
+
+	return st.Reindex(reindexIndexes, reindexForce, progress)
+}
+
+// setChainID sets the chain id of a State opened offline: the one of the config
+// section of the genesis, or eth.chain-id
+func setChainID(st *state.State) error {
+	chainID := config.Eth.ChainID
+	genesisConfig, err := state.LoadGenesisConfig(config.Eth.Genesis)
+	if err != nil {
+		return err
+	}
+	if genesisConfig != nil && genesisConfig.ChainID != 0 {
+		chainID = genesisConfig.ChainID
+	}
+	st.SetChainID(new(big.Int).SetUint64(chainID))
+	return nil
+}
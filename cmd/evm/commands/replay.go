@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/Fantom-foundation/go-evm/src/engine"
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+var (
+	replayFrom int64
+	replayTo   int64
+	replayKeep bool
+)
+
+//AddReplayFlags adds flags to the replay command
+func AddReplayFlags(cmd *cobra.Command) {
+	cmd.Flags().Int64Var(&replayFrom, "from", 0, "First block replayed: 0 replays from the genesis, a later block from the state of the block before")
+	cmd.Flags().Int64Var(&replayTo, "to", -1, "Last block replayed (the last committed block if negative)")
+	cmd.Flags().BoolVar(&replayKeep, "keep", false, "Keep the database of the replay instead of removing it")
+}
+
+//NewReplayCmd returns the command that re-executes the committed blocks into a
+//fresh database to verify the determinism of the execution
+func NewReplayCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Re-execute the committed blocks into a fresh state and compare the results",
+		Long: `Re-execute the committed blocks into a fresh state and compare the results.
+
+The transactions of the blocks of the database (eth.db) are applied again, with
+the configuration of the node, to a fresh database in a temporary directory:
+from the genesis (eth.genesis), or from a copy of the state of the block before
+--from. Each block must result in the committed header, state root included.
+The first block which diverges is reported: a code regression or a corrupted
+database. The node must be stopped.`,
+		Args: cobra.NoArgs,
+		RunE: replay,
+	}
+	AddReplayFlags(cmd)
+	return cmd
+}
+
+func replay(cmd *cobra.Command, args []string) error {
+	source, err := state.NewState(logger,
+		config.Eth.DbFile,
+		config.Eth.Cache,
+		nil)
+	if err != nil {
+		return fmt.Errorf("opening database: %s", err)
+	}
+	defer source.Close()
+
+	if replayFrom < 0 {
+		replayFrom = 0
+	}
+	var genesis *state.Genesis
+	if replayFrom == 0 {
+		if genesis, err = state.ReadGenesis(config.Eth.Genesis); err != nil {
+			return err
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "evm-replay")
+	if err != nil {
+		return err
+	}
+	// Registered first, so that it runs once the replay database is closed
+	if !replayKeep {
+		defer os.RemoveAll(dir)
+	}
+
+	// The replay applies the blocks with the policies of the node, but doesn't
+	// sync, halt or export traces
+	replayConfig := *config
+	eth := *config.Eth
+	eth.DbFile = filepath.Join(dir, "chaindata")
+	eth.ArchiveDirs = nil
+	eth.SyncPeer = ""
+	eth.FastSyncSnapshot = ""
+	eth.HaltHeight = 0
+	eth.TraceEndpoint = ""
+	replayConfig.Eth = &eth
+	replayConfig.Resume = false
+	replayConfig.SafeMode = false
+
+	target, err := engine.NewState(replayConfig, logger)
+	if err != nil {
+		return fmt.Errorf("creating replay database: %s", err)
+	}
+	defer target.Close()
+	root, err := target.StartReplay(source, genesis, replayFrom)
+	if err != nil {
+		return err
+	}
+	logger.WithFields(logrus.Fields{
+		"from": replayFrom,
+		"root": root.Hex(),
+	}).Info("Replaying blocks")
+
+	replayed, err := target.ReplayFrom(source, replayFrom, replayTo, func(block *state.Block) {
+		if block.Number%1000 == 0 {
+			logger.WithField("block", block.Number).Info("Replaying blocks")
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("after %d blocks: %s", replayed, err)
+	}
+
+	fields := logrus.Fields{
+		"blocks": replayed,
+		"head":   target.GetBlockIndex(),
+	}
+	if replayKeep {
+		fields["db"] = eth.DbFile
+	}
+	logger.WithFields(fields).Info("Replayed blocks: the results match")
+	return nil
+}
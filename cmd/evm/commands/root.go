@@ -1,7 +1,9 @@
 package commands
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/sirupsen/logrus"
@@ -19,15 +21,64 @@ var (
 func init() {
 	//Base
 	RootCmd.PersistentFlags().StringP("datadir", "d", config.BaseConfig.DataDir, "Top-level directory for configuration and data")
+	RootCmd.PersistentFlags().String("config", "", "Config file (TOML, YAML or JSON) of the flags (config.toml... of the datadir if empty)")
 	RootCmd.PersistentFlags().String("log", config.BaseConfig.LogLevel, "debug, info, warn, error, fatal, panic")
+	RootCmd.PersistentFlags().String("pprof", config.BaseConfig.PprofAddr, "Address of the pprof profiling endpoints, e.g. 127.0.0.1:6060 (disabled if empty)")
+	RootCmd.PersistentFlags().Bool("resume", config.Resume, "Resume a node halted for an upgrade or in safe mode")
+	RootCmd.PersistentFlags().Bool("safe-mode", config.SafeMode, "Serve reads but refuse transactions and commits until resumed")
 
 	//Eth
 	RootCmd.PersistentFlags().String("eth.genesis", config.Eth.Genesis, "Location of genesis file")
+	RootCmd.PersistentFlags().Uint64("eth.chain-id", config.Eth.ChainID, "EIP-155 chain id of the transactions, which must be identical on all validators")
+	RootCmd.PersistentFlags().String("eth.coinbase", config.Eth.Coinbase, "Address credited the transaction fees of the blocks without a proposer, which must be identical on all validators")
 	RootCmd.PersistentFlags().String("eth.keystore", config.Eth.Keystore, "Location of Ethereum account keys")
 	RootCmd.PersistentFlags().String("eth.pwd", config.Eth.PwdFile, "Password file to unlock accounts")
 	RootCmd.PersistentFlags().String("eth.db", config.Eth.DbFile, "Eth database file")
 	RootCmd.PersistentFlags().String("eth.listen", config.Eth.EthAPIAddr, "Address of HTTP API service")
+	RootCmd.PersistentFlags().String("eth.rpc-tls-cert", config.Eth.TLSCert, "Certificate file (PEM) to serve the HTTP API over HTTPS")
+	RootCmd.PersistentFlags().String("eth.rpc-tls-key", config.Eth.TLSKey, "Key file (PEM) of the HTTPS certificate")
+	RootCmd.PersistentFlags().String("eth.api-keys", config.Eth.APIKeys, "JSON file of the API keys required by the protected namespaces (all public if empty)")
+	RootCmd.PersistentFlags().StringSlice("eth.auth-namespaces", config.Eth.AuthNamespaces, "Namespaces requiring an API key (tx covers transaction submission)")
+	RootCmd.PersistentFlags().Float64("eth.rate-limit", config.Eth.RateLimit, "Requests per second allowed to a single client of the HTTP API and JSON-RPC (0 for no limit)")
+	RootCmd.PersistentFlags().Int("eth.rate-burst", config.Eth.RateBurst, "Requests a single client may send in a burst above the rate limit (defaults to the rate)")
+	RootCmd.PersistentFlags().Int("eth.max-concurrent", config.Eth.MaxConcurrent, "Requests of a single client served at the same time (0 for no limit)")
+	RootCmd.PersistentFlags().StringSlice("eth.rpc-cors", config.Eth.RPCCors, "Origins allowed to call the HTTP API and JSON-RPC from a browser (* for any)")
+	RootCmd.PersistentFlags().String("eth.ipc", config.Eth.IPCPath, "Path of the IPC socket of the JSON-RPC APIs (disabled if empty)")
+	RootCmd.PersistentFlags().String("eth.admin-listen", config.Eth.AdminAddr, "Address of the operational dashboard (disabled if empty)")
+	RootCmd.PersistentFlags().String("eth.grpc-listen", config.Eth.GRPCAddr, "Address of the gRPC API (disabled if empty)")
 	RootCmd.PersistentFlags().Int("eth.cache", config.Eth.Cache, "Megabytes of memory allocated to internal caching (min 16MB / database forced)")
+	RootCmd.PersistentFlags().String("eth.node-key", config.Eth.NodeKey, "File of the key identifying this node to its peers (generated if missing)")
+	RootCmd.PersistentFlags().StringSlice("eth.node-peers", config.Eth.NodePeers, "Node key addresses of the peers allowed to call the inter-node endpoints (disabled if empty)")
+	RootCmd.PersistentFlags().String("eth.sync-peer", config.Eth.SyncPeer, "API address of a peer to download the state from when the database is empty")
+	RootCmd.PersistentFlags().String("eth.sync-peer-key", config.Eth.SyncPeerKey, "Node key address of the sync peer, which must sign its responses")
+	RootCmd.PersistentFlags().String("eth.fast-sync-snapshot", config.Eth.FastSyncSnapshot, "Snapshot directory or URL to import when the database is empty, before replaying the next blocks from the sync peer")
+	RootCmd.PersistentFlags().StringSlice("eth.archive", config.Eth.ArchiveDirs, "Read-only archive databases to federate historical queries over")
+	RootCmd.PersistentFlags().Bool("eth.check-invariants", config.Eth.CheckInvariants, "Verify supply conservation and nonce monotonicity after every commit, halting on violation")
+	RootCmd.PersistentFlags().String("eth.gas-cap-policy", config.Eth.GasCapPolicy, "JSON file of per-contract gas caps, which must be identical on all validators")
+	RootCmd.PersistentFlags().Int64("eth.halt-height", config.Eth.HaltHeight, "Halt after committing the block at this height, to coordinate an upgrade (0 to disable)")
+	RootCmd.PersistentFlags().Uint64("eth.price-bump", config.Eth.PriceBump, "Minimum gas price increase (%) to replace a pending transaction")
+	RootCmd.PersistentFlags().Int("eth.txpool-global-slots", config.Eth.TxPoolGlobalSlots, "Maximum number of transactions held by the txpool (0 for no limit)")
+	RootCmd.PersistentFlags().Int("eth.txpool-account-slots", config.Eth.TxPoolAccountSlots, "Maximum number of transactions held by the txpool for a single sender (0 for no limit)")
+	RootCmd.PersistentFlags().Int("eth.txpool-account-pending", config.Eth.TxPoolAccountPending, "Maximum number of pending transactions of a single sender per block, further ones are queued (0 for no limit)")
+	RootCmd.PersistentFlags().StringSlice("eth.txpool-locals", config.Eth.TxPoolLocals, "Addresses exempt from the txpool limits and eviction, in addition to the keystore accounts")
+	RootCmd.PersistentFlags().Duration("eth.tx-ttl", config.Eth.TxTTL, "How long a queued transaction may wait in the txpool to be submitted to consensus before it is dropped (0 to disable)")
+	RootCmd.PersistentFlags().Int("eth.max-code-size", config.Eth.MaxCodeSize, "Maximum size of the code of a deployed contract, which must be identical on all validators (0 for no limit)")
+	RootCmd.PersistentFlags().Int("eth.max-init-code-size", config.Eth.MaxInitCodeSize, "Maximum size of the init code of a contract creation, which must be identical on all validators (0 for no limit)")
+	RootCmd.PersistentFlags().Int64("eth.blob-retention", config.Eth.BlobRetention, "Number of blocks the blob data of transactions is kept (0 to keep it forever)")
+	RootCmd.PersistentFlags().String("eth.watch-calls", config.Eth.WatchCalls, "JSON file of read-only calls to execute periodically and serve on /watches")
+	RootCmd.PersistentFlags().Duration("eth.watch-interval", config.Eth.WatchInterval, "Interval between executions of the watched calls")
+	RootCmd.PersistentFlags().String("eth.event-metrics", config.Eth.EventMetrics, "JSON file mapping contract events to metrics served on /metrics")
+	RootCmd.PersistentFlags().Int("eth.gpo-blocks", config.Eth.GasPriceBlocks, "Number of recent blocks with transactions the suggested gas price is computed from")
+	RootCmd.PersistentFlags().Int("eth.gpo-percentile", config.Eth.GasPricePercentile, "Percentile of the lowest gas prices of the recent blocks suggested by eth_gasPrice")
+	RootCmd.PersistentFlags().Int("eth.max-logs", config.Eth.MaxLogs, "Maximum number of logs of a log query, or of a page of GET /logs (0 for no limit)")
+	RootCmd.PersistentFlags().Int64("eth.max-log-range", config.Eth.MaxLogBlockRange, "Maximum number of blocks of a log query, or of a page of GET /logs (0 for no limit)")
+	RootCmd.PersistentFlags().Duration("eth.commit-slo-p50", config.Eth.CommitSLOP50, "Threshold of the median commit latency, from block reception to commit (0 to disable)")
+	RootCmd.PersistentFlags().Duration("eth.commit-slo-p95", config.Eth.CommitSLOP95, "Threshold of the 95th percentile of the commit latency (0 to disable)")
+	RootCmd.PersistentFlags().Duration("eth.commit-slo-p99", config.Eth.CommitSLOP99, "Threshold of the 99th percentile of the commit latency (0 to disable)")
+	RootCmd.PersistentFlags().Int("eth.commit-slo-window", config.Eth.CommitSLOWindow, "Number of recent blocks the commit latency percentiles are computed over")
+	RootCmd.PersistentFlags().String("eth.commit-slo-webhook", config.Eth.CommitSLOWebhook, "URL notified of the breaches of the commit latency SLO")
+	RootCmd.PersistentFlags().String("eth.trace-endpoint", config.Eth.TraceEndpoint, "URL of the OTLP/HTTP collector the trace spans are exported to (disabled if empty)")
+	RootCmd.PersistentFlags().Float64("eth.trace-sample-ratio", config.Eth.TraceSampleRatio, "Ratio of the traces sampled, unless the caller of a request decided already")
 
 }
 
@@ -58,6 +109,10 @@ var RootCmd = &cobra.Command{
 
 		config.SetDataDir(config.BaseConfig.DataDir)
 
+		// The config file or the environment may set another log level
+		logger.Level = logLevel(config.BaseConfig.LogLevel)
+		setEthereumLogLevel(config.BaseConfig.LogLevel)
+
 		logger.WithFields(logrus.Fields{
 			"Base":     config.BaseConfig,
 			"Eth":      config.Eth,
@@ -79,7 +134,9 @@ func ParseConfig() (*_config.Config, error) {
 	return conf, err
 }
 
-// Bind all flags and read the config into viper
+// Bind all flags and read the config into viper. A flag set on the command
+// line overrides the environment (EVM_ETH_CACHE for eth.cache...), which
+// overrides the config file.
 func bindFlagsLoadViper(cmd *cobra.Command) error {
 	// cmd.Flags() includes flags from this command and all persistent flags from the parent
 	if err := viper.BindPFlags(cmd.Flags()); err != nil {
@@ -87,17 +144,28 @@ func bindFlagsLoadViper(cmd *cobra.Command) error {
 		return err
 	}
 
-	viper.SetConfigName("config")                  // name of config file (without extension)
-	viper.AddConfigPath(config.BaseConfig.DataDir) // search root directory
+	viper.SetEnvPrefix("evm")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
+	viper.AutomaticEnv()
+
+	configFile := viper.GetString("config")
+	if configFile != "" {
+		viper.SetConfigFile(configFile)
+	} else {
+		viper.SetConfigName("config")                   // name of config file (without extension)
+		viper.AddConfigPath(viper.GetString("datadir")) // search root directory
+	}
 
 	err := viper.ReadInConfig()
 
 	// If a config file is found, read it in.
 	if err == nil {
 		// stderr, so if we redirect output to json file, this doesn't appear
-		logger.Debugf("Using config file: ", viper.ConfigFileUsed())
-	} else if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-		logger.Debugf("No config file found in %s", config.DataDir)
+		logger.Debugf("Using config file: %s", viper.ConfigFileUsed())
+	} else if _, ok := err.(viper.ConfigFileNotFoundError); ok {
+		logger.Debugf("No config file found in %s", viper.GetString("datadir"))
+	} else {
+		return fmt.Errorf("reading config file: %s", err)
 	}
 
 	return nil
@@ -3,21 +3,35 @@ package commands
 import (
 	"fmt"
 	"runtime"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/Fantom-foundation/go-evm/src/consensus"
 	"github.com/Fantom-foundation/go-evm/src/engine"
 	"github.com/Fantom-foundation/go-lachesis/src/utils"
 )
 
 //AddRunFlags adds flags to the Run command
 func AddRunFlags(cmd *cobra.Command) {
+	cmd.Flags().String("consensus", config.Consensus, fmt.Sprintf("Consensus system, one of %s: socket relays to a separate Lachesis process through its proxy, inmem embeds Lachesis in the process",
+		strings.Join(consensus.Names(), ", ")))
 	//Lachesis Socket
-	cmd.Flags().String("proxy", config.ProxyAddr, "IP:PORT of Lachesis proxy")
+	cmd.Flags().String("proxy", config.ProxyAddr, "IP:PORT of Lachesis proxy, or a comma-separated list of proxies to fail over to")
+	cmd.Flags().Duration("proxy-max-backoff", config.ProxyMaxBackoff, "Longest delay between two attempts to reconnect to the Lachesis proxy")
+	cmd.Flags().Int("proxy-buffer", config.ProxyBuffer, "Number of transactions buffered while the Lachesis proxy is unreachable, before they are moved to the dead-letter queue")
+	cmd.Flags().Int64("max-block-lag", config.MaxBlockLag, "Number of blocks from the Lachesis proxy waiting to be committed before the node stops pulling blocks (0 to disable)")
 	if runtime.GOOS != "windows" {
 		cmd.Flags().String("pidfile", config.Pidfile, "pidfile location; /tmp/go-evm.pid by default")
 	}
+	//Development
+	cmd.Flags().Bool("dev", config.Dev, "Run a throwaway development node: Solo consensus, instant blocks, pre-funded accounts, any CORS origin and the dev JSON-RPC namespace")
+	cmd.Flags().Int("dev-accounts", config.DevAccounts, "Number of pre-funded accounts of the development node")
+	//Lachesis Inmem
+	AddLachesisFlags(cmd)
+	//Tendermint
+	AddTendermintFlags(cmd)
 	if err := viper.BindPFlags(cmd.Flags()); err != nil {
 		panic("Unable to bind viper flags")
 	}
@@ -43,13 +57,25 @@ func run(cmd *cobra.Command, args []string) error {
 			return err
 		}
 	}
-	socketEngine, err := engine.NewSocketEngine(*config, logger)
-	//socketEngine, err := socketEngine.NewInmemEngine(*config, logger)
+	if config.Dev {
+		cleanup, err := setupDevNode()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+	}
+	return runEngine(config.Consensus)
+}
+
+// runEngine builds and runs the engine of the consensus system registered
+// under name
+func runEngine(name string) error {
+	consensusEngine, err := engine.NewEngine(name, *config, logger)
 	if err != nil {
 		return fmt.Errorf("error building Engine: %s", err)
 	}
 
-	if err := socketEngine.Run(); err != nil {
+	if err := consensusEngine.Run(); err != nil {
 		return fmt.Errorf("error running Engine: %s", err)
 	}
 
@@ -0,0 +1,199 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+var (
+	snapshotShards  int
+	snapshotWorkers int
+	snapshotNode    string
+)
+
+//AddSnapshotFlags adds flags to the snapshot commands
+func AddSnapshotFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().IntVar(&snapshotWorkers, "workers", runtime.NumCPU(), "Number of shards exported or imported in parallel")
+}
+
+//NewSnapshotCmd returns the command that exports and imports snapshots of the
+//state
+func NewSnapshotCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Export or import a snapshot of the state",
+		Long: `Export or import a snapshot of the state.
+
+A snapshot is a directory holding the accounts of the state, with their code
+and storage, split by range of the account trie into shard files, and a
+manifest. The shards are exported and imported by parallel workers. The node
+must be stopped, except for "snapshot create --node" which asks a running node
+to write the snapshot. "create" and "restore" verify the checksums of the
+shard files against the manifest.`,
+	}
+	AddSnapshotFlags(cmd)
+
+	exportCmd := &cobra.Command{
+		Use:   "export [dir]",
+		Short: "Export the state of the last committed block to a directory",
+		Args:  cobra.ExactArgs(1),
+		RunE:  exportSnapshot,
+	}
+	exportCmd.Flags().IntVar(&snapshotShards, "shards", state.DefaultSnapshotShards, "Number of ranges the account trie is split into (1-256)")
+
+	importCmd := &cobra.Command{
+		Use:   "import [dir]",
+		Short: "Restore a snapshot into an empty database",
+		Args:  cobra.ExactArgs(1),
+		RunE:  importSnapshot,
+	}
+
+	createCmd := &cobra.Command{
+		Use:   "create [dir]",
+		Short: "Export the state and verify the written snapshot",
+		Long: `Export the state of the last committed block and verify the written snapshot.
+
+Without --node, the snapshot is exported from the database of the stopped node.
+With --node, the running node is asked through its admin dashboard
+(eth.admin-listen) to export the snapshot while it keeps committing blocks; the
+directory is then on the node host.`,
+		Args: cobra.ExactArgs(1),
+		RunE: createSnapshot,
+	}
+	createCmd.Flags().IntVar(&snapshotShards, "shards", state.DefaultSnapshotShards, "Number of ranges the account trie is split into (1-256)")
+	createCmd.Flags().StringVar(&snapshotNode, "node", "", "Admin dashboard address of a running node to snapshot")
+	createCmd.Flags().StringVar(&nodeAPIKey, "api-key", "", "API key of the node, if the admin namespace is protected")
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore [dir]",
+		Short: "Verify a snapshot and restore it into an empty database",
+		Args:  cobra.ExactArgs(1),
+		RunE:  restoreSnapshot,
+	}
+
+	cmd.AddCommand(exportCmd, importCmd, createCmd, restoreCmd)
+	return cmd
+}
+
+func exportSnapshot(cmd *cobra.Command, args []string) error {
+	st, err := state.NewState(logger,
+		config.Eth.DbFile,
+		config.Eth.Cache,
+		nil)
+	if err != nil {
+		return fmt.Errorf("opening database: %s", err)
+	}
+	defer st.Close()
+
+	manifest, err := st.ExportSnapshot(args[0], snapshotShards, snapshotWorkers)
+	if err != nil {
+		return err
+	}
+	logger.WithFields(logrus.Fields{
+		"block": manifest.Block,
+		"root":  manifest.Root.Hex(),
+	}).Info("Exported snapshot")
+	return nil
+}
+
+func importSnapshot(cmd *cobra.Command, args []string) error {
+	st, err := state.NewState(logger,
+		config.Eth.DbFile,
+		config.Eth.Cache,
+		nil)
+	if err != nil {
+		return fmt.Errorf("opening database: %s", err)
+	}
+	defer st.Close()
+
+	manifest, err := st.ImportSnapshot(args[0], snapshotWorkers)
+	if err != nil {
+		return err
+	}
+	logger.WithFields(logrus.Fields{
+		"block": manifest.Block,
+		"root":  manifest.Root.Hex(),
+	}).Info("Imported snapshot")
+	return nil
+}
+
+func createSnapshot(cmd *cobra.Command, args []string) error {
+	var manifest *state.SnapshotManifest
+	if snapshotNode != "" {
+		var err error
+		if manifest, err = remoteSnapshot(args[0]); err != nil {
+			return err
+		}
+	} else {
+		if err := exportSnapshot(cmd, args); err != nil {
+			return err
+		}
+		var err error
+		if manifest, err = state.VerifySnapshot(args[0]); err != nil {
+			return err
+		}
+	}
+	printSnapshot(args[0], manifest)
+	return nil
+}
+
+// remoteSnapshot asks a running node to export a snapshot to a directory of
+// its host, which it verifies
+func remoteSnapshot(dir string) (*state.SnapshotManifest, error) {
+	data, err := json.Marshal(map[string]interface{}{
+		"dir":    dir,
+		"shards": snapshotShards,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", nodeURL(snapshotNode)+"/snapshot", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	body, err := doNodeRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("creating snapshot: %s", err)
+	}
+	manifest := new(state.SnapshotManifest)
+	if err := json.Unmarshal(body, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func restoreSnapshot(cmd *cobra.Command, args []string) error {
+	manifest, err := state.VerifySnapshot(args[0])
+	if err != nil {
+		return err
+	}
+	logger.WithField("shards", len(manifest.Shards)).Info("Verified snapshot")
+	if err := importSnapshot(cmd, args); err != nil {
+		return err
+	}
+	printSnapshot(args[0], manifest)
+	return nil
+}
+
+func printSnapshot(dir string, manifest *state.SnapshotManifest) {
+	var accounts, slots uint64
+	for _, shard := range manifest.Shards {
+		accounts += shard.Accounts
+		slots += shard.Slots
+	}
+	fmt.Printf("Snapshot:   %s\n", dir)
+	fmt.Printf("Block:      %d (%s)\n", manifest.Block, manifest.BlockHash.Hex())
+	fmt.Printf("State root: %s\n", manifest.Root.Hex())
+	fmt.Printf("Shards:     %d, checksums verified\n", len(manifest.Shards))
+	fmt.Printf("Accounts:   %d\n", accounts)
+	fmt.Printf("Slots:      %d\n", slots)
+}
@@ -1,7 +1,6 @@
 package commands
 
 import (
-	"fmt"
 	"html/template"
 	"os"
 	"path/filepath"
@@ -9,9 +8,6 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-
-	"github.com/Fantom-foundation/go-evm/src/consensus/solo"
-	"github.com/Fantom-foundation/go-evm/src/engine"
 )
 
 var genesisTemplate = `
@@ -29,6 +25,7 @@ var genesisAddress string
 //AddSoloFlags adds flags to the Solo command
 func AddSoloFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&genesisAddress, "genesis", "", "create genesis file specifying pre-funded account with given address")
+	cmd.Flags().Duration("solo.interval", config.Solo.Interval, "Interval between blocks, e.g. 500ms (0 to commit every transaction as soon as it is submitted)")
 	if err := viper.BindPFlags(cmd.Flags()); err != nil {
 		panic("Unable to bind viper flags")
 	}
@@ -45,6 +42,7 @@ func NewSoloCmd() *cobra.Command {
 
 			logger.WithFields(logrus.Fields{
 				"Eth":     config.Eth,
+				"Solo":    config.Solo,
 				"genesis": genesisAddress,
 			}).Debug("Config")
 
@@ -96,15 +94,5 @@ func createGenesis(genesisFile, genesisAddr string) error {
 }
 
 func runSolo(cmd *cobra.Command, args []string) error {
-	soloConsensus := solo.NewSolo(logger)
-	soloEngine, err := engine.NewConsensusEngine(*config, soloConsensus, logger)
-	if err != nil {
-		return fmt.Errorf("error building Engine: %s", err)
-	}
-
-	if err := soloEngine.Run(); err != nil {
-		return fmt.Errorf("error running Engine: %s", err)
-	}
-
-	return nil
+	return runEngine("solo")
 }
@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+//AddTendermintFlags adds flags to the Tendermint command
+func AddTendermintFlags(cmd *cobra.Command) {
+	cmd.Flags().String("tendermint.abci", config.Tendermint.ABCIAddr, "Address of the ABCI server, which the Tendermint node's proxy_app connects to")
+	cmd.Flags().String("tendermint.rpc", config.Tendermint.RPCAddr, "Address of the Tendermint node's RPC server, which the transactions are broadcast to")
+	if err := viper.BindPFlags(cmd.Flags()); err != nil {
+		panic("Unable to bind viper flags")
+	}
+}
+
+//NewTendermintCmd returns the command that starts EVM-Lite as the ABCI
+//application of a Tendermint node
+func NewTendermintCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tendermint",
+		Short: "Run the evm node as the ABCI application of a Tendermint node",
+		PreRunE: func(cmd *cobra.Command, args []string) (err error) {
+
+			config.SetDataDir(config.BaseConfig.DataDir)
+
+			logger.WithFields(logrus.Fields{
+				"Tendermint": config.Tendermint,
+			}).Debug("Config")
+
+			return nil
+		},
+		RunE: runTendermint,
+	}
+
+	AddTendermintFlags(cmd)
+
+	return cmd
+}
+
+func runTendermint(cmd *cobra.Command, args []string) error {
+	return runEngine("tendermint")
+}
@@ -0,0 +1,229 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/spf13/cobra"
+
+	"github.com/Fantom-foundation/go-evm/src/service"
+)
+
+var (
+	txNode     string
+	nodeAPIKey string
+	txFrom     string
+	txTo       string
+	txValue    string
+	txData     string
+	txGas      uint64
+	txGasPrice string
+	txNonce    int64
+	txWait     time.Duration
+)
+
+// AddTxFlags adds flags to the tx commands
+func AddTxFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&txNode, "node", "localhost:8080", "Address of the HTTP API of the node")
+	cmd.PersistentFlags().StringVar(&nodeAPIKey, "api-key", "", "API key of the node, if transaction submission is protected")
+}
+
+// NewTxCmd returns the command that signs transactions with the keys of the
+// keystore and submits them to a node
+func NewTxCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tx",
+		Short: "Sign and submit transactions",
+	}
+	AddTxFlags(cmd)
+
+	sendCmd := &cobra.Command{
+		Use:   "send",
+		Short: "Sign a transaction with a key of the keystore and submit it to a node",
+		Long: `Sign a transaction with a key of the keystore and submit it to a node.
+
+The transaction is signed for the chain id of eth.chain-id. Unless given, its
+nonce is the one of the sender on the node. Without --to, the transaction
+deploys the contract whose init code is --data.`,
+		Args: cobra.NoArgs,
+		RunE: sendTx,
+	}
+	sendCmd.Flags().StringVar(&txFrom, "from", "", "Address of the sender, whose key is in the keystore")
+	sendCmd.Flags().StringVar(&txTo, "to", "", "Address of the recipient (contract creation if empty)")
+	sendCmd.Flags().StringVar(&txValue, "value", "0", "Value transferred, in wei")
+	sendCmd.Flags().StringVar(&txData, "data", "", "Hex data of the transaction")
+	sendCmd.Flags().Uint64Var(&txGas, "gas", 90000, "Gas limit")
+	sendCmd.Flags().StringVar(&txGasPrice, "gas-price", "0", "Gas price, in wei")
+	sendCmd.Flags().Int64Var(&txNonce, "nonce", -1, "Nonce of the transaction (the sender's on the node if negative)")
+	sendCmd.Flags().StringVar(&keysPasswordFile, "password", "", "File holding the password of the sender's key (prompted if empty)")
+	sendCmd.Flags().DurationVar(&txWait, "wait", 0, "How long to wait for the receipt, printed once the transaction is applied (0 not to wait)")
+
+	cmd.AddCommand(sendCmd)
+	return cmd
+}
+
+func sendTx(cmd *cobra.Command, args []string) error {
+	if !common.IsHexAddress(txFrom) {
+		return fmt.Errorf("invalid sender %q", txFrom)
+	}
+	from := common.HexToAddress(txFrom)
+	value, ok := new(big.Int).SetString(txValue, 10)
+	if !ok {
+		return fmt.Errorf("invalid value %q", txValue)
+	}
+	gasPrice, ok := new(big.Int).SetString(txGasPrice, 10)
+	if !ok {
+		return fmt.Errorf("invalid gas price %q", txGasPrice)
+	}
+	var data []byte
+	if txData != "" {
+		var err error
+		if data, err = hexutil.Decode(txData); err != nil {
+			return fmt.Errorf("invalid data: %s", err)
+		}
+	}
+
+	ks, err := openKeyStore()
+	if err != nil {
+		return err
+	}
+	account, err := ks.Find(accounts.Account{Address: from})
+	if err != nil {
+		return fmt.Errorf("key of %s: %s", from.Hex(), err)
+	}
+	pwd, err := readPassword(false)
+	if err != nil {
+		return err
+	}
+
+	node := nodeURL(txNode)
+	nonce := uint64(txNonce)
+	if txNonce < 0 {
+		if nonce, err = remoteNonce(node, from); err != nil {
+			return fmt.Errorf("getting nonce: %s", err)
+		}
+	}
+
+	var tx *ethTypes.Transaction
+	if txTo == "" {
+		tx = ethTypes.NewContractCreation(nonce, value, txGas, gasPrice, data)
+	} else {
+		if !common.IsHexAddress(txTo) {
+			return fmt.Errorf("invalid recipient %q", txTo)
+		}
+		tx = ethTypes.NewTransaction(nonce, common.HexToAddress(txTo), value, txGas, gasPrice, data)
+	}
+	signed, err := ks.SignTxWithPassphrase(account, pwd, tx, new(big.Int).SetUint64(config.Eth.ChainID))
+	if err != nil {
+		return err
+	}
+
+	txHash, err := submitRawTx(node, signed)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Transaction: %s\n", txHash.Hex())
+
+	if txWait <= 0 {
+		return nil
+	}
+	receipt, err := waitReceipt(node, txHash, txWait)
+	if err != nil {
+		return err
+	}
+	printReceipt(os.Stdout, receipt, nil)
+	return nil
+}
+
+// nodeURL returns the URL of the HTTP API of a node given by its address
+func nodeURL(node string) string {
+	if !strings.HasPrefix(node, "http://") && !strings.HasPrefix(node, "https://") {
+		node = "http://" + node
+	}
+	return strings.TrimRight(node, "/")
+}
+
+// doNodeRequest sends a request to the HTTP API of a node and returns the body
+// of the response
+func doNodeRequest(req *http.Request) ([]byte, error) {
+	if nodeAPIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+nodeAPIKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+func remoteNonce(node string, address common.Address) (uint64, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/account/%s", node, address.Hex()), nil)
+	if err != nil {
+		return 0, err
+	}
+	body, err := doNodeRequest(req)
+	if err != nil {
+		return 0, err
+	}
+	var account service.JsonAccount
+	if err := json.Unmarshal(body, &account); err != nil {
+		return 0, err
+	}
+	return uint64(account.Nonce), nil
+}
+
+func submitRawTx(node string, tx *ethTypes.Transaction) (common.Hash, error) {
+	raw, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	req, err := http.NewRequest("POST", node+"/rawtx", strings.NewReader(hexutil.Encode(raw)))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	body, err := doNodeRequest(req)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	var res service.JsonTxRes
+	if err := json.Unmarshal(body, &res); err != nil {
+		return common.Hash{}, err
+	}
+	return common.HexToHash(res.TxHash), nil
+}
+
+// waitReceipt polls the node for the receipt of a transaction until it is
+// applied or the timeout expires
+func waitReceipt(node string, txHash common.Hash, timeout time.Duration) (*service.JsonReceipt, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		receipt, err := remoteReceipt(node, txHash)
+		if err == nil {
+			return receipt, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("no receipt after %s: %s", timeout, err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
@@ -3,16 +3,38 @@ package commands
 import (
 	"fmt"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/spf13/cobra"
 
+	"github.com/Fantom-foundation/go-evm/src/state"
 	"github.com/Fantom-foundation/go-evm/src/version"
 )
 
-// VersionCmd displays the version of the evm program being used
+var versionShort bool
+
+// VersionCmd displays the version of the evm program being used, with the
+// metadata of its build
 var VersionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Show version info",
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println(version.Version)
+		if versionShort {
+			fmt.Println(version.Version)
+			return
+		}
+		chainConfig := state.DefaultChainConfig()
+		commit := version.GitCommit
+		if commit == "" {
+			commit = "unknown"
+		}
+		fmt.Printf("Version:    %s\n", version.Version)
+		fmt.Printf("Git commit: %s\n", commit)
+		fmt.Printf("Go version: %s\n", version.GoVersion())
+		fmt.Printf("Platform:   %s\n", version.Platform())
+		fmt.Printf("EVM fork:   %s\n", state.ForkName(&chainConfig, common.Big0))
 	},
 }
+
+func init() {
+	VersionCmd.Flags().BoolVar(&versionShort, "short", false, "Only print the version")
+}
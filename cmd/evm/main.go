@@ -12,7 +12,21 @@ func main() {
 	rootCmd.AddCommand(
 		cmd.NewSoloCmd(),
 		cmd.NewRaftCmd(),
+		cmd.NewTendermintCmd(),
 		cmd.NewRunCmd(),
+		cmd.NewInitCmd(),
+		cmd.NewGenesisCmd(),
+		cmd.NewInspectReceiptCmd(),
+		cmd.NewReindexCmd(),
+		cmd.NewSnapshotCmd(),
+		cmd.NewExportCmd(),
+		cmd.NewImportCmd(),
+		cmd.NewReplayCmd(),
+		cmd.NewDBCmd(),
+		cmd.NewKeysCmd(),
+		cmd.NewAccountCmd(),
+		cmd.NewTxCmd(),
+		cmd.NewAttachCmd(),
 		cmd.VersionCmd)
 
 	//Do not print usage when error occurs
@@ -0,0 +1,44 @@
+#!/bin/sh
+# dist.sh cross-compiles pure-Go evm binaries (no cgo: goleveldb storage and the
+# pure-Go secp256k1 of the nocgo tag) for each platform of PLATFORMS, and
+# packages them with their checksums in build/dist.
+set -e
+
+cd "$(dirname "$0")/.."
+
+PLATFORMS=${PLATFORMS:-"linux/amd64 linux/arm64 linux/arm darwin/amd64 windows/amd64"}
+VERSION=$(go run ./cmd/evm version --short 2>/dev/null || echo dev)
+COMMIT=$(git rev-parse HEAD 2>/dev/null || true)
+DIST=build/dist
+
+rm -rf "$DIST"
+mkdir -p "$DIST"
+
+for platform in $PLATFORMS; do
+	os=${platform%/*}
+	arch=${platform#*/}
+	name="evm-$VERSION-$os-$arch"
+	bin=evm
+	if [ "$os" = "windows" ]; then
+		bin=evm.exe
+	fi
+
+	echo "==> $name"
+	mkdir -p "$DIST/$name"
+	CGO_ENABLED=0 GOOS=$os GOARCH=$arch go build \
+		-tags "nocgo $BUILD_TAGS" \
+		-ldflags "-s -w -X github.com/Fantom-foundation/go-evm/src/version.GitCommit=$COMMIT" \
+		-o "$DIST/$name/$bin" ./cmd/evm/
+	cp LICENSE README.md "$DIST/$name/"
+
+	if [ "$os" = "windows" ]; then
+		(cd "$DIST" && zip -qr "$name.zip" "$name")
+	else
+		(cd "$DIST" && tar -czf "$name.tar.gz" "$name")
+	fi
+	rm -rf "$DIST/$name"
+done
+
+(cd "$DIST" && sha256sum evm-* > SHA256SUMS)
+echo "==> $DIST"
+cat "$DIST/SHA256SUMS"
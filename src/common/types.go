@@ -1,7 +1,19 @@
 package common
 
-type AccountMap map[string]struct {
-	Code    string
-	Storage map[string]string
-	Balance string
+type AccountMap map[string]GenesisAccount
+
+// GenesisAccount is an account of the alloc of a genesis file. The code of a
+// contract is either its runtime Code, or the Constructor bytecode run at
+// genesis with the ABI-encoded Args appended, which returns the code and
+// writes the storage. Nonce is the initial nonce of the account, e.g. to
+// precompute the addresses of the contracts it will create. The fields added
+// to the format are omitted when empty, so that the hash of the existing
+// genesis files doesn't change.
+type GenesisAccount struct {
+	Code        string
+	Constructor string `json:",omitempty"`
+	Args        string `json:",omitempty"`
+	Storage     map[string]string
+	Balance     string
+	Nonce       uint64 `json:",omitempty"`
 }
@@ -6,6 +6,7 @@ import (
 	"os/user"
 	"path/filepath"
 	"runtime"
+	"time"
 )
 
 var (
@@ -28,22 +29,59 @@ type Config struct {
 	// Options for Raft consensus
 	Raft *RaftConfig `mapstructure:"raft"`
 
+	// Options for Solo consensus
+	Solo *SoloConfig `mapstructure:"solo"`
+
+	// Options for Tendermint consensus
+	Tendermint *TendermintConfig `mapstructure:"tendermint"`
+
+	// Name of the consensus system of the run command (see consensus.Register)
+	Consensus string `mapstructure:"consensus"`
+
 	ProxyAddr  string `mapstructure:"proxy"`
 	ClientAddr string `mapstructure:"client-connect"`
 	Standalone bool   `mapstructure:"standalone"`
 	Pidfile    string `mapstructure:"pidfile"`
+
+	// Longest delay between two attempts to reconnect to the Lachesis proxy,
+	// and number of transactions buffered while it is unreachable
+	ProxyMaxBackoff time.Duration `mapstructure:"proxy-max-backoff"`
+	ProxyBuffer     int           `mapstructure:"proxy-buffer"`
+
+	// Number of blocks committed by the Lachesis proxy which may wait for the
+	// State before the node stops pulling blocks. 0 disables backpressure
+	MaxBlockLag int64 `mapstructure:"max-block-lag"`
+
+	// Resume a State halted for an upgrade or in safe mode
+	Resume bool `mapstructure:"resume"`
+
+	// Start in safe mode: serve reads but refuse transactions and commits
+	// until resumed
+	SafeMode bool `mapstructure:"safe-mode"`
+
+	// Run a throwaway development node: Solo consensus committing every
+	// transaction at once, and DevAccounts pre-funded accounts
+	Dev         bool `mapstructure:"dev"`
+	DevAccounts int  `mapstructure:"dev-accounts"`
 }
 
 // DefaultConfig returns the default configuration for an EVM-Lite node
 func DefaultConfig() *Config {
 	return &Config{
-		BaseConfig: DefaultBaseConfig(),
-		Eth:        DefaultEthConfig(),
-		Lachesis:   DefaultLachesisConfig(),
-		Raft:       DefaultRaftConfig(),
-		ProxyAddr:  "127.0.0.1:1338",
-		ClientAddr: "127.0.0.1:1339",
-		Pidfile:    filepath.Join(os.TempDir(), "go-evm.pid"),
+		BaseConfig:      DefaultBaseConfig(),
+		Eth:             DefaultEthConfig(),
+		Lachesis:        DefaultLachesisConfig(),
+		Raft:            DefaultRaftConfig(),
+		Solo:            DefaultSoloConfig(),
+		Tendermint:      DefaultTendermintConfig(),
+		Consensus:       "socket",
+		ProxyAddr:       "127.0.0.1:1338",
+		ProxyMaxBackoff: 30 * time.Second,
+		ProxyBuffer:     1024,
+		MaxBlockLag:     64,
+		ClientAddr:      "127.0.0.1:1339",
+		Pidfile:         filepath.Join(os.TempDir(), "go-evm.pid"),
+		DevAccounts:     10,
 	}
 }
 
@@ -73,6 +111,9 @@ type BaseConfig struct {
 
 	// Debug, info, warn, error, fatal, panic
 	LogLevel string `mapstructure:"log"`
+
+	// Address of the net/http/pprof endpoints (disabled if empty)
+	PprofAddr string `mapstructure:"pprof"`
 }
 
 // DefaultBaseConfig returns the default top-level configuration for EVM-Lachesis
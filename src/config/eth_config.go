@@ -1,15 +1,34 @@
 package config
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 var (
 	defaultEthAPIAddr   = ":8080"
 	defaultCache        = 128
+	defaultChainID      = uint64(1)
+	defaultPriceBump    = uint64(10)
+	defaultGlobalSlots  = 4096
+	defaultAccountSlots = 64
+	defaultPendingSlots = 16
+	defaultWatchPeriod  = 15 * time.Second
+	defaultSLOWindow    = 1000
+	defaultGPOBlocks    = 20
+	defaultGPOPercent   = 60
+	defaultMaxLogs      = 10000
+	defaultTraceRatio   = 1.0
+	defaultTxTTL        = 3 * time.Hour
+	defaultMaxCodeSize  = 24576
+	defaultMaxInitCode  = 2 * defaultMaxCodeSize
 	defaultEthDir       = fmt.Sprintf("%s/eth", DefaultDataDir)
 	defaultKeystoreFile = fmt.Sprintf("%s/keystore", defaultEthDir)
 	defaultGenesisFile  = fmt.Sprintf("%s/genesis.json", defaultEthDir)
 	defaultPwdFile      = fmt.Sprintf("%s/pwd.txt", defaultEthDir)
 	defaultDbFile       = fmt.Sprintf("%s/chaindata", defaultEthDir)
+	defaultNodeKeyFile  = fmt.Sprintf("%s/nodekey", defaultEthDir)
+	defaultIPCPath      = fmt.Sprintf("%s/evm.ipc", defaultEthDir)
 )
 
 // EthConfig contains the configuration relative to the accounts, EVM, trie/db,
@@ -18,6 +37,17 @@ type EthConfig struct {
 	// Genesis file
 	Genesis string `mapstructure:"genesis"`
 
+	// EIP-155 chain id of the transactions, also returned by eth_chainId and
+	// net_version. It is part of the state transition: all validators must use
+	// the same chain id
+	ChainID uint64 `mapstructure:"chain-id"`
+
+	// Beneficiary credited the transaction fees of the blocks whose consensus
+	// system names none (e.g. Lachesis). It is part of the state transition:
+	// all validators must use the same coinbase. The fees go to the zero
+	// address when empty
+	Coinbase string `mapstructure:"coinbase"`
+
 	// Location of ethereum account keys
 	Keystore string `mapstructure:"keystore"`
 
@@ -30,19 +60,189 @@ type EthConfig struct {
 	// Address of HTTP API Service
 	EthAPIAddr string `mapstructure:"listen"`
 
+	// Certificate and key files (PEM) to serve the HTTP API over HTTPS.
+	// Plain HTTP when empty
+	TLSCert string `mapstructure:"rpc-tls-cert"`
+	TLSKey  string `mapstructure:"rpc-tls-key"`
+
+	// JSON file of the API keys granting access to the AuthNamespaces (tx,
+	// debug, admin, personal, ...), whose endpoints otherwise refuse
+	// requests. All endpoints are public when empty
+	APIKeys        string   `mapstructure:"api-keys"`
+	AuthNamespaces []string `mapstructure:"auth-namespaces"`
+
+	// Limits of the requests of a single client (API key or IP address) to
+	// the HTTP API and JSON-RPC: sustained requests per second, burst, and
+	// concurrent requests. 0 disables a limit
+	RateLimit     float64 `mapstructure:"rate-limit"`
+	RateBurst     int     `mapstructure:"rate-burst"`
+	MaxConcurrent int     `mapstructure:"max-concurrent"`
+
+	// Origins allowed to call the HTTP API and JSON-RPC from a browser ("*"
+	// for any). CORS headers are not sent when empty
+	RPCCors []string `mapstructure:"rpc-cors"`
+
+	// Path of the unix domain socket (named pipe on Windows) serving the
+	// JSON-RPC APIs to local tools and consoles. Disabled when empty
+	IPCPath string `mapstructure:"ipc"`
+
+	// Address of the operational dashboard. Disabled when empty
+	AdminAddr string `mapstructure:"admin-listen"`
+
+	// Address of the gRPC API. Disabled when empty
+	GRPCAddr string `mapstructure:"grpc-listen"`
+
 	// Megabytes of memory allocated to internal caching (min 16MB / database forced)
 	Cache int `mapstructure:"cache"`
+
+	// File of the key identifying this node to its peers, generated if
+	// missing
+	NodeKey string `mapstructure:"node-key"`
+
+	// Addresses of the node keys of the peers allowed to call the inter-node
+	// endpoints (/node/...). The endpoints are disabled when empty
+	NodePeers []string `mapstructure:"node-peers"`
+
+	// API address of a peer whose state an empty node downloads at startup,
+	// over the inter-node endpoints, instead of replaying the history. The
+	// node key must be one of the peer's node-peers
+	SyncPeer string `mapstructure:"sync-peer"`
+
+	// Address of the node key of the sync-peer, which must sign its responses.
+	// Required with sync-peer
+	SyncPeerKey string `mapstructure:"sync-peer-key"`
+
+	// Snapshot (see ExportSnapshot), as a directory or the URL it is published
+	// at, which an empty node imports at startup. The blocks after it are then
+	// replayed from the sync-peer
+	FastSyncSnapshot string `mapstructure:"fast-sync-snapshot"`
+
+	// Read-only databases holding historical chain data (e.g. yearly archives).
+	// Block, transaction and receipt lookups fall back to them, in order.
+	ArchiveDirs []string `mapstructure:"archive"`
+
+	// Verify state invariants (supply conservation, nonce monotonicity) after
+	// every commit, and halt on violation
+	CheckInvariants bool `mapstructure:"check-invariants"`
+
+	// JSON file of the policy capping the gas of transactions to specific
+	// contracts. It is part of the state transition: all validators must use
+	// the same policy. Disabled when empty
+	GasCapPolicy string `mapstructure:"gas-cap-policy"`
+
+	// Maximum size of the code, and of the init code, of the contracts
+	// deployed by transactions. They are part of the state transition: all
+	// validators must use the same limits. 0 disables a limit
+	MaxCodeSize     int `mapstructure:"max-code-size"`
+	MaxInitCodeSize int `mapstructure:"max-init-code-size"`
+
+	// Number of blocks the blob data of transactions is kept before it is
+	// pruned. The hashes committed to the state are kept. 0 keeps it forever
+	BlobRetention int64 `mapstructure:"blob-retention"`
+
+	// Halt after committing the block at this height, to coordinate a binary
+	// upgrade. 0 disables halting
+	HaltHeight int64 `mapstructure:"halt-height"`
+
+	// Minimum gas price increase, in percent, for a transaction to replace a
+	// pending one with the same nonce
+	PriceBump uint64 `mapstructure:"price-bump"`
+
+	// Maximum number of transactions held by the txpool, overall and for a
+	// single sender. 0 disables a limit
+	TxPoolGlobalSlots  int `mapstructure:"txpool-global-slots"`
+	TxPoolAccountSlots int `mapstructure:"txpool-account-slots"`
+
+	// Maximum number of pending transactions of a single sender per block.
+	// Further transactions are queued until the next block. 0 disables the
+	// limit
+	TxPoolAccountPending int `mapstructure:"txpool-account-pending"`
+
+	// Senders whose transactions are exempt from the txpool limits and from
+	// eviction, in addition to the keystore accounts
+	TxPoolLocals []string `mapstructure:"txpool-locals"`
+
+	// How long a queued transaction may wait in the txpool to be submitted to
+	// consensus before it is dropped. 0 disables expiry
+	TxTTL time.Duration `mapstructure:"tx-ttl"`
+
+	// JSON file listing read-only calls (e.g. health or oracle checks)
+	// executed every WatchInterval, whose results are served on /watches.
+	// Disabled when empty
+	WatchCalls    string        `mapstructure:"watch-calls"`
+	WatchInterval time.Duration `mapstructure:"watch-interval"`
+
+	// Gas price suggested by eth_gasPrice, and used for the transactions sent
+	// without one: the percentile of the lowest prices of the transactions
+	// of the recent blocks with transactions
+	GasPriceBlocks     int `mapstructure:"gpo-blocks"`
+	GasPricePercentile int `mapstructure:"gpo-percentile"`
+
+	// Maximum number of logs, and of blocks, of a log query. eth_getLogs and
+	// GraphQL fail above them, and GET /logs returns several pages. 0
+	// disables a limit
+	MaxLogs          int   `mapstructure:"max-logs"`
+	MaxLogBlockRange int64 `mapstructure:"max-log-range"`
+
+	// JSON file mapping contract events to metrics (counters or gauges of
+	// event fields), evaluated on every committed block and exposed on
+	// /metrics. Disabled when empty
+	EventMetrics string `mapstructure:"event-metrics"`
+
+	// Thresholds of the p50, p95 and p99 of the commit latency, from the
+	// reception of a block to the end of its commit, over the last
+	// CommitSLOWindow blocks. Breaches are logged, exposed on /metrics and
+	// posted to CommitSLOWebhook. 0 disables a threshold
+	CommitSLOP50     time.Duration `mapstructure:"commit-slo-p50"`
+	CommitSLOP95     time.Duration `mapstructure:"commit-slo-p95"`
+	CommitSLOP99     time.Duration `mapstructure:"commit-slo-p99"`
+	CommitSLOWindow  int           `mapstructure:"commit-slo-window"`
+	CommitSLOWebhook string        `mapstructure:"commit-slo-webhook"`
+
+	// OTLP/HTTP collector the trace spans of the requests and the commits are
+	// exported to, e.g. http://localhost:4318. Disabled when empty
+	TraceEndpoint string `mapstructure:"trace-endpoint"`
+
+	// Ratio of the traces sampled, unless the caller decided already
+	TraceSampleRatio float64 `mapstructure:"trace-sample-ratio"`
 }
 
 // DefaultEthConfig return the default configuration for Eth services
 func DefaultEthConfig() *EthConfig {
 	return &EthConfig{
 		Genesis:    defaultGenesisFile,
+		ChainID:    defaultChainID,
 		Keystore:   defaultKeystoreFile,
 		PwdFile:    defaultPwdFile,
 		DbFile:     defaultDbFile,
+		NodeKey:    defaultNodeKeyFile,
 		EthAPIAddr: defaultEthAPIAddr,
+		IPCPath:    defaultIPCPath,
 		Cache:      defaultCache,
+		PriceBump:  defaultPriceBump,
+
+		RPCCors:        []string{"*"},
+		AuthNamespaces: []string{"tx", "debug", "admin", "personal"},
+
+		TxPoolGlobalSlots:  defaultGlobalSlots,
+		TxPoolAccountSlots: defaultAccountSlots,
+
+		TxPoolAccountPending: defaultPendingSlots,
+		TxTTL:                defaultTxTTL,
+
+		MaxCodeSize:     defaultMaxCodeSize,
+		MaxInitCodeSize: defaultMaxInitCode,
+
+		WatchInterval: defaultWatchPeriod,
+
+		GasPriceBlocks:     defaultGPOBlocks,
+		GasPricePercentile: defaultGPOPercent,
+
+		MaxLogs: defaultMaxLogs,
+
+		CommitSLOWindow: defaultSLOWindow,
+
+		TraceSampleRatio: defaultTraceRatio,
 	}
 }
 
@@ -61,4 +261,10 @@ func (c *EthConfig) SetDataDir(datadir string) {
 	if c.DbFile == defaultDbFile {
 		c.DbFile = fmt.Sprintf("%s/chaindata", datadir)
 	}
+	if c.NodeKey == defaultNodeKeyFile {
+		c.NodeKey = fmt.Sprintf("%s/nodekey", datadir)
+	}
+	if c.IPCPath == defaultIPCPath {
+		c.IPCPath = fmt.Sprintf("%s/evm.ipc", datadir)
+	}
 }
@@ -0,0 +1,19 @@
+package config
+
+import (
+	"time"
+)
+
+// SoloConfig contains the configuration of the Solo consensus, which orders
+// the transactions of a single node, e.g. a development network
+type SoloConfig struct {
+	// Interval between blocks, which hold the transactions submitted since the
+	// previous one. Each transaction is committed in a block of its own as soon
+	// as it is submitted when 0
+	Interval time.Duration `mapstructure:"interval"`
+}
+
+// DefaultSoloConfig returns the default configuration of the Solo consensus
+func DefaultSoloConfig() *SoloConfig {
+	return &SoloConfig{}
+}
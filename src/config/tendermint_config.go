@@ -0,0 +1,28 @@
+package config
+
+// TendermintConfig contains the configuration of the Tendermint consensus, an
+// ABCI application ordered by a separate Tendermint node
+type TendermintConfig struct {
+	// Address the ABCI server listens on, which the Tendermint node's
+	// proxy_app connects to
+	ABCIAddr string `mapstructure:"abci"`
+
+	// Address of the RPC server of the Tendermint node, which the submitted
+	// transactions are broadcast to
+	RPCAddr string `mapstructure:"rpc"`
+
+	// Beneficiary credited the fees of the blocks proposed by each validator,
+	// by Tendermint validator address (hex). The blocks of the other
+	// validators go to the default coinbase. It is part of the state
+	// transition: all nodes must use the same beneficiaries
+	Beneficiaries map[string]string `mapstructure:"beneficiaries"`
+}
+
+// DefaultTendermintConfig returns the default configuration of the Tendermint
+// consensus, matching the defaults of a Tendermint node
+func DefaultTendermintConfig() *TendermintConfig {
+	return &TendermintConfig{
+		ABCIAddr: "tcp://127.0.0.1:26658",
+		RPCAddr:  "http://127.0.0.1:26657",
+	}
+}
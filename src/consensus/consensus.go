@@ -11,3 +11,10 @@ type Consensus interface {
 	Run() error
 	Info() (map[string]string, error)
 }
+
+// Stopper is implemented by the consensus systems which have a node to stop or
+// a connection to close when the engine shuts down. Stop returns once no more
+// blocks are handed over to the State.
+type Stopper interface {
+	Stop() error
+}
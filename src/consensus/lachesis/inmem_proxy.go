@@ -1,6 +1,8 @@
 package lachesis
 
 import (
+	"context"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/sirupsen/logrus"
 
@@ -50,14 +52,18 @@ func (i *InmemProxy) CommitBlock(block poset.Block) ([]byte, error) {
 	i.logger.Debug("CommitBlock")
 
 	blockHash := common.BytesToHash(block.Hash)
+	// TIMESTAMP is the time of the block given by Lachesis, so that it is the
+	// same on every node. Lachesis blocks have no proposer: the fees go to the
+	// default coinbase
+	i.state.BeginBlock(blockHash, block.GetCreatedTime(), common.Address{})
 
 	for x, tx := range block.Transactions() {
-		if err := i.state.ApplyTransaction(tx, x, blockHash); err != nil {
+		if err := i.state.ApplyTransaction(context.Background(), tx, x, blockHash); err != nil {
 			return []byte{}, err
 		}
 	}
 
-	hash, err := i.state.Commit()
+	hash, err := i.state.Commit(context.Background())
 	if err != nil {
 		return []byte{}, err
 	}
@@ -0,0 +1,52 @@
+package lachesis
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	bcommon "github.com/Fantom-foundation/go-evm/src/common"
+	"github.com/Fantom-foundation/go-evm/src/state"
+	"github.com/Fantom-foundation/go-lachesis/src/poset"
+)
+
+// newTestProxy returns an InmemProxy on a fresh State whose genesis funds the
+// account of the returned key
+func newTestProxy(t *testing.T) (*InmemProxy, *ecdsa.PrivateKey, func()) {
+	st, key, cleanup := state.NewTestState(t)
+	return NewInmemProxy(st, nil, make(chan []byte), bcommon.NewTestLogger(t)), key, cleanup
+}
+
+// TestCommitBlock checks that the blocks of Lachesis are recorded in order,
+// with their hash, and that the state root of each is returned
+func TestCommitBlock(t *testing.T) {
+	proxy, key, cleanup := newTestProxy(t)
+	defer cleanup()
+
+	for i := int64(0); i < 2; i++ {
+		block := *poset.NewBlock(i, i+1, []byte("frame"), [][]byte{state.NewTestTransfer(t, key, uint64(i))})
+		block.Hash = common.BigToHash(big.NewInt(i + 1)).Bytes()
+
+		root, err := proxy.CommitBlock(block)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		committed, err := proxy.state.GetBlockByNumber(i)
+		if err != nil {
+			t.Fatalf("block %d: %v", i, err)
+		}
+		if committed.ConsensusHash != common.BytesToHash(block.Hash) {
+			t.Fatalf("block %d: consensus hash should be %x, not %s", i, block.Hash, committed.ConsensusHash.Hex())
+		}
+		if common.BytesToHash(root) != committed.StateRoot {
+			t.Fatalf("block %d: returned root should be %s, not %x", i, committed.StateRoot.Hex(), root)
+		}
+	}
+	if nonce := proxy.state.GetNonce(crypto.PubkeyToAddress(key.PublicKey)); nonce != 2 {
+		t.Fatalf("nonce should be 2, not %d", nonce)
+	}
+}
@@ -4,11 +4,18 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/Fantom-foundation/go-evm/src/config"
+	"github.com/Fantom-foundation/go-evm/src/consensus"
 	"github.com/Fantom-foundation/go-evm/src/service"
 	"github.com/Fantom-foundation/go-evm/src/state"
 	_lachesis "github.com/Fantom-foundation/go-lachesis/src/lachesis"
 )
 
+func init() {
+	consensus.Register("lachesis", func(conf config.Config, logger *logrus.Logger) (consensus.Consensus, error) {
+		return NewInmemLachesis(conf.Lachesis, logger), nil
+	})
+}
+
 // InmemLachesis implements the Consensus interface.
 // It uses an inmemory Lachesis node.
 type InmemLachesis struct {
@@ -58,6 +65,12 @@ func (b *InmemLachesis) Run() error {
 	return nil
 }
 
+// Stop shuts the Lachesis node down
+func (b *InmemLachesis) Stop() error {
+	b.lachesis.Node.Shutdown()
+	return nil
+}
+
 // Info returns Lachesis stats
 func (b *InmemLachesis) Info() (map[string]string, error) {
 	info := b.lachesis.Node.GetStats()
@@ -1,6 +1,8 @@
 package raft
 
 import (
+	"context"
+	"encoding/binary"
 	"fmt"
 	"io"
 
@@ -13,8 +15,9 @@ import (
 
 // FSM wraps a state object and implements the Raft FSM interface
 type FSM struct {
-	state  *state.State
-	logger *logrus.Entry
+	state     *state.State
+	lastIndex uint64
+	logger    *logrus.Entry
 }
 
 // NewFSM returns a new FSM
@@ -30,7 +33,10 @@ IMPLEMENT RAFT FSM INTERFACE
 *******************************************************************************/
 
 // Apply is invoked once a log entry is committed.
-// It applies the log data to the state as a transaction.
+// It applies the transaction of the entry to the state, in a block of its own
+// with the time set by the leader, so that all the nodes compute the same
+// block. The entries replayed after a restart, which the state already applied,
+// are skipped.
 func (f *FSM) Apply(log *_raft.Log) interface{} {
 
 	f.logger.WithFields(logrus.Fields{
@@ -40,12 +46,29 @@ func (f *FSM) Apply(log *_raft.Log) interface{} {
 		"data":  log.Data,
 	}).Debug("Apply")
 
-	if err := f.state.ApplyTransaction(log.Data, int(log.Index), _ethCommon.Hash{}); err != nil {
+	f.lastIndex = log.Index
+
+	blockHash := logBlockHash(log.Index)
+	if root, ok := f.state.AppliedBlock(blockHash); ok {
+		f.logger.WithField("index", log.Index).Debug("Entry already applied. Skipping")
+		return root.Bytes()
+	}
+
+	blockTime, tx, err := decodeEntry(log.Data)
+	if err != nil {
+		f.logger.WithError(err).WithField("index", log.Index).Error("Decoding log entry")
+	}
+
+	// Raft entries have no proposer: the fees go to the default coinbase
+	f.state.BeginBlock(blockHash, blockTime, _ethCommon.Address{})
+
+	if err := f.state.ApplyTransaction(context.Background(), tx, 0, blockHash); err != nil {
 		f.logger.WithError(err).Error("Error applying transaction")
-		return nil
 	}
 
-	hash, err := f.state.Commit()
+	// The block is committed even if the transaction failed, so that the entry
+	// is recorded as applied
+	hash, err := f.state.Commit(context.Background())
 	if err != nil {
 		f.logger.WithError(err).Error("Error committing")
 		return nil
@@ -54,12 +77,75 @@ func (f *FSM) Apply(log *_raft.Log) interface{} {
 	return hash.Bytes()
 }
 
-// Snapshot is not implemented yet
+// entryVersion starts the log entries which carry the block time set by the
+// leader. The entries of older versions are a bare transaction, whose RLP
+// encoding starts with a list prefix (0xc0 or more).
+const entryVersion = 1
+
+// encodeEntry returns the data of the log entry of a transaction, with the
+// time of its block
+func encodeEntry(blockTime int64, tx []byte) []byte {
+	data := make([]byte, 9, 9+len(tx))
+	data[0] = entryVersion
+	binary.BigEndian.PutUint64(data[1:], uint64(blockTime))
+	return append(data, tx...)
+}
+
+// decodeEntry returns the block time and the transaction of a log entry. An
+// entry of an older version has no time: 0 gives its block the time of the
+// parent block, the same on all the nodes.
+func decodeEntry(data []byte) (int64, []byte, error) {
+	if len(data) == 0 || data[0] != entryVersion {
+		return 0, data, nil
+	}
+	if len(data) < 9 {
+		return 0, nil, fmt.Errorf("truncated log entry of %d bytes", len(data))
+	}
+	return int64(binary.BigEndian.Uint64(data[1:9])), data[9:], nil
+}
+
+// logBlockHash is the consensus hash of the block of a log entry
+func logBlockHash(index uint64) _ethCommon.Hash {
+	return _ethCommon.BytesToHash([]byte(fmt.Sprintf("raft %d", index)))
+}
+
+// Snapshot lets Raft truncate its log. The state persists itself on every
+// Commit, so the snapshot only holds the index of the last applied entry.
 func (f *FSM) Snapshot() (_raft.FSMSnapshot, error) {
-	return nil, fmt.Errorf("snapshot function not implemented")
+	return &fsmSnapshot{index: f.lastIndex}, nil
 }
 
-// Restore is not implemented yet
-func (f *FSM) Restore(io.ReadCloser) error {
-	return fmt.Errorf("restore function not implemented")
+// Restore is invoked on startup with the last snapshot, or when a node is too
+// far behind the leader to catch up from its log. The state can't be
+// transferred through Raft: a node which didn't apply the entries of the
+// snapshot must be restored from a state snapshot (see the snapshot command).
+func (f *FSM) Restore(rc io.ReadCloser) error {
+	defer rc.Close()
+
+	var index uint64
+	if err := binary.Read(rc, binary.BigEndian, &index); err != nil {
+		return fmt.Errorf("reading snapshot: %s", err)
+	}
+	if index > 0 {
+		if _, ok := f.state.AppliedBlock(logBlockHash(index)); !ok {
+			return fmt.Errorf("state is behind Raft snapshot at index %d: import a state snapshot", index)
+		}
+	}
+	f.lastIndex = index
+	return nil
 }
+
+// fsmSnapshot is the FSMSnapshot of the index of the last applied entry
+type fsmSnapshot struct {
+	index uint64
+}
+
+func (s *fsmSnapshot) Persist(sink _raft.SnapshotSink) error {
+	if err := binary.Write(sink, binary.BigEndian, s.index); err != nil {
+		sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+func (s *fsmSnapshot) Release() {}
@@ -0,0 +1,95 @@
+package raft
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_raft "github.com/hashicorp/raft"
+
+	bcommon "github.com/Fantom-foundation/go-evm/src/common"
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+func newTestFSM(t *testing.T) (*FSM, func()) {
+	dir, err := ioutil.TempDir("", "raft-fsm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := bcommon.NewTestLogger(t)
+	st, err := state.NewState(logger, filepath.Join(dir, "chaindata"), 16, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewFSM(st, logger.WithField("module", "raft")), func() {
+		st.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestEntryEncoding(t *testing.T) {
+	// An RLP-encoded transaction starts with a list prefix
+	legacy := []byte{0xf8, 0x6b, 0x01}
+
+	cases := []struct {
+		name string
+		data []byte
+		time int64
+		tx   []byte
+		err  bool
+	}{
+		{"entry", encodeEntry(1546300800, legacy), 1546300800, legacy, false},
+		{"empty transaction", encodeEntry(42, nil), 42, []byte{}, false},
+		{"legacy entry", legacy, 0, legacy, false},
+		{"truncated entry", []byte{entryVersion, 0, 0}, 0, nil, true},
+	}
+	for _, c := range cases {
+		blockTime, tx, err := decodeEntry(c.data)
+		if (err != nil) != c.err {
+			t.Fatalf("%s: error %v", c.name, err)
+		}
+		if blockTime != c.time {
+			t.Fatalf("%s: time should be %d, not %d", c.name, c.time, blockTime)
+		}
+		if !bytes.Equal(tx, c.tx) {
+			t.Fatalf("%s: transaction should be %x, not %x", c.name, c.tx, tx)
+		}
+	}
+}
+
+// TestApplySameBlock checks that the nodes applying an entry compute the same
+// block, whatever the time they apply it at
+func TestApplySameBlock(t *testing.T) {
+	fsm1, cleanup1 := newTestFSM(t)
+	defer cleanup1()
+	fsm2, cleanup2 := newTestFSM(t)
+	defer cleanup2()
+
+	entry := &_raft.Log{Index: 1, Data: encodeEntry(1546300800, []byte{0xc0})}
+	fsm1.Apply(entry)
+	fsm2.Apply(entry)
+
+	block1, err := fsm1.state.GetBlockByNumber(fsm1.state.GetBlockIndex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	block2, err := fsm2.state.GetBlockByNumber(fsm2.state.GetBlockIndex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block1.Timestamp != 1546300800 {
+		t.Fatalf("block time should be the leader's 1546300800, not %d", block1.Timestamp)
+	}
+	if block1.Hash() != block2.Hash() {
+		t.Fatalf("blocks differ: %s and %s", block1.Hash().Hex(), block2.Hash().Hex())
+	}
+
+	// An entry replayed after a restart is skipped
+	index := fsm1.state.GetBlockIndex()
+	fsm1.Apply(entry)
+	if fsm1.state.GetBlockIndex() != index {
+		t.Fatalf("replayed entry applied again: block %d, not %d", fsm1.state.GetBlockIndex(), index)
+	}
+}
@@ -3,17 +3,25 @@ package raft
 import (
 	"fmt"
 	"os"
-	"os/signal"
+	"path/filepath"
 	"time"
 
 	_raft "github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb"
 	"github.com/sirupsen/logrus"
 
 	"github.com/Fantom-foundation/go-evm/src/config"
+	"github.com/Fantom-foundation/go-evm/src/consensus"
 	"github.com/Fantom-foundation/go-evm/src/service"
 	"github.com/Fantom-foundation/go-evm/src/state"
 )
 
+func init() {
+	consensus.Register("raft", func(conf config.Config, logger *logrus.Logger) (consensus.Consensus, error) {
+		return NewRaft(*conf.Raft, logger), nil
+	})
+}
+
 // Raft implements the Consensus interface.
 // It uses Hashicorp Raft
 type Raft struct {
@@ -22,7 +30,7 @@ type Raft struct {
 	fsm       _raft.FSM
 	raftNode  *_raft.Raft
 	logger    *logrus.Entry
-	terminate chan os.Signal
+	terminate chan struct{}
 	txIndex   int
 }
 
@@ -31,7 +39,7 @@ func NewRaft(config config.RaftConfig, logger *logrus.Logger) *Raft {
 	return &Raft{
 		config:    config,
 		logger:    logger.WithField("module", "raft"),
-		terminate: make(chan os.Signal, 1),
+		terminate: make(chan struct{}),
 	}
 }
 
@@ -49,10 +57,7 @@ func (r *Raft) Init(state *state.State, service *service.Service) error {
 	r.fsm = NewFSM(state, r.logger)
 
 	// Initialize raft node
-
-	//TODO: Use r.config
-	config := _raft.DefaultConfig()
-	config.LocalID = r.config.LocalID
+	config := r.raftConfig()
 
 	// Setup Raft communication.
 	transport, err := _raft.NewTCPTransport(r.config.NodeAddr,
@@ -70,35 +75,63 @@ func (r *Raft) Init(state *state.State, service *service.Service) error {
 		return fmt.Errorf("file snapshot store: %s", err)
 	}
 
-	// Create the log store and stable store.
-	// TODO: Add option for persistent store
-	logStore := _raft.NewInmemStore()
-	stableStore := _raft.NewInmemStore()
+	// Create the log store and stable store. They are persisted, so that a
+	// node which crashed rejoins the cluster with its log and term
+	store, err := raftboltdb.NewBoltStore(filepath.Join(r.config.RaftDir, "raft.db"))
+	if err != nil {
+		return fmt.Errorf("bolt store: %s", err)
+	}
 
 	// Instantiate the Raft systems.
-	ra, err := _raft.NewRaft(config, r.fsm, logStore, stableStore, snapshots, transport)
+	ra, err := _raft.NewRaft(config, r.fsm, store, store, snapshots, transport)
 	if err != nil {
 		return fmt.Errorf("new raft: %s", err)
 	}
 
-	//TODO: We should be using the new dynmamic membership protocol
-	configuration, err := _raft.ReadConfigJSON(fmt.Sprintf("%s/peers.json", r.config.RaftDir))
+	// The cluster is bootstrapped from peers.json the first time only
+	hasState, err := _raft.HasExistingState(store, store, snapshots)
 	if err != nil {
-		return fmt.Errorf("unable to create cluster configuration from peers.json: %v", err)
+		return err
+	}
+	if !hasState {
+		//TODO: We should be using the new dynmamic membership protocol
+		configuration, err := _raft.ReadConfigJSON(filepath.Join(r.config.RaftDir, "peers.json"))
+		if err != nil {
+			return fmt.Errorf("unable to create cluster configuration from peers.json: %v", err)
+		}
+		if err := ra.BootstrapCluster(configuration).Error(); err != nil {
+			return fmt.Errorf("bootstrapping cluster: %s", err)
+		}
 	}
-	ra.BootstrapCluster(configuration)
 
 	r.raftNode = ra
 
 	return nil
 }
 
+// raftConfig returns the configuration of the Raft node from the RaftConfig
+func (r *Raft) raftConfig() *_raft.Config {
+	config := _raft.DefaultConfig()
+	config.ProtocolVersion = r.config.ProtocolVersion
+	config.HeartbeatTimeout = r.config.HeartbeatTimeout
+	config.ElectionTimeout = r.config.ElectionTimeout
+	config.CommitTimeout = r.config.CommitTimeout
+	config.MaxAppendEntries = r.config.MaxAppendEntries
+	config.ShutdownOnRemove = r.config.ShutdownOnRemove
+	config.TrailingLogs = r.config.TrailingLogs
+	config.SnapshotInterval = r.config.SnapshotInterval
+	config.SnapshotThreshold = r.config.SnapshotThreshold
+	config.LeaderLeaseTimeout = r.config.LeaderLeaseTimeout
+	config.StartAsLeader = r.config.StartAsLeader
+	config.LocalID = r.config.LocalID
+	return config
+}
+
 // Run starts the Raft node and service
 func (r *Raft) Run() error {
 
 	// Relay submitCh to Raft
 	submitCh := r.service.GetSubmitCh()
-	signal.Notify(r.terminate, os.Interrupt)
 	for {
 		select {
 		case t := <-submitCh:
@@ -107,19 +140,9 @@ func (r *Raft) Run() error {
 				"state": r.raftNode.State(),
 			}).Debug("Adding Transaction")
 
-			if r.raftNode.State() != _raft.Leader {
-				r.logger.Debug("NOT LEADER")
-				//TODO: Relay message to leader
-				break
+			if r.service.SubmitToConsensus(t, r.apply) {
+				r.txIndex++
 			}
-
-			f := r.raftNode.Apply(t, r.config.CommitTimeout)
-			if err := f.Error(); err != nil {
-				r.logger.WithError(err).Error("Applying Raft tx")
-				break
-			}
-
-			r.txIndex++
 		case <-r.terminate:
 			r.logger.Debug("Raft exiting")
 			return nil
@@ -127,9 +150,26 @@ func (r *Raft) Run() error {
 	}
 }
 
+// apply submits a transaction to the Raft log, with the time of its block
+func (r *Raft) apply(t []byte) error {
+	if r.raftNode.State() != _raft.Leader {
+		//TODO: Relay message to leader
+		return fmt.Errorf("not the Raft leader")
+	}
+	return r.raftNode.Apply(encodeEntry(time.Now().Unix(), t), r.config.CommitTimeout).Error()
+}
+
+// Stop stops relaying transactions and shuts the Raft node down, once the
+// entry being applied is committed
+func (r *Raft) Stop() error {
+	close(r.terminate)
+	return r.raftNode.Shutdown().Error()
+}
+
 // Info returns Raft stats
 func (r *Raft) Info() (map[string]string, error) {
 	info := r.raftNode.Stats()
 	info["type"] = "raft"
+	info["leader"] = string(r.raftNode.Leader())
 	return info, nil
 }
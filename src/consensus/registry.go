@@ -0,0 +1,59 @@
+package consensus
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Fantom-foundation/go-evm/src/config"
+)
+
+// Factory creates a consensus system from the configuration of the node
+type Factory func(config.Config, *logrus.Logger) (Consensus, error)
+
+var (
+	registryLock sync.Mutex
+	factories    = make(map[string]Factory)
+)
+
+// Register makes a consensus system available under a name, e.g. to the
+// consensus option of the configuration. It is meant to be called from the
+// init function of the package implementing it, and panics if the name is
+// taken.
+func Register(name string, factory Factory) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if _, ok := factories[name]; ok {
+		panic(fmt.Sprintf("consensus %q registered twice", name))
+	}
+	factories[name] = factory
+}
+
+// New creates the consensus system registered under name
+func New(name string, conf config.Config, logger *logrus.Logger) (Consensus, error) {
+	registryLock.Lock()
+	factory, ok := factories[name]
+	registryLock.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown consensus %q (one of %s)", name, strings.Join(Names(), ", "))
+	}
+	return factory(conf, logger)
+}
+
+// Names returns the names of the registered consensus systems, sorted
+func Names() []string {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
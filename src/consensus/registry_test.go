@@ -0,0 +1,62 @@
+package consensus
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Fantom-foundation/go-evm/src/config"
+	"github.com/Fantom-foundation/go-evm/src/service"
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+type testConsensus struct {
+	name string
+}
+
+func (c *testConsensus) Init(*state.State, *service.Service) error { return nil }
+func (c *testConsensus) Run() error                                { return nil }
+func (c *testConsensus) Info() (map[string]string, error) {
+	return map[string]string{"type": c.name}, nil
+}
+
+func TestRegistry(t *testing.T) {
+	for _, name := range []string{"test-b", "test-a"} {
+		name := name
+		Register(name, func(config.Config, *logrus.Logger) (Consensus, error) {
+			return &testConsensus{name: name}, nil
+		})
+	}
+
+	names := []string{}
+	for _, name := range Names() {
+		if name == "test-a" || name == "test-b" {
+			names = append(names, name)
+		}
+	}
+	if !reflect.DeepEqual(names, []string{"test-a", "test-b"}) {
+		t.Fatalf("names should be sorted, not %v", Names())
+	}
+
+	c, err := New("test-b", config.Config{}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := c.Info(); info["type"] != "test-b" {
+		t.Fatalf("consensus should be test-b, not %v", info["type"])
+	}
+
+	if _, err := New("unknown", config.Config{}, logrus.New()); err == nil {
+		t.Fatal("an unknown consensus should fail")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("registering a name twice should panic")
+		}
+	}()
+	Register("test-a", func(config.Config, *logrus.Logger) (Consensus, error) {
+		return nil, nil
+	})
+}
@@ -1,31 +1,51 @@
 package solo
 
 import (
+	"context"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/sirupsen/logrus"
 
+	"github.com/Fantom-foundation/go-evm/src/config"
+	"github.com/Fantom-foundation/go-evm/src/consensus"
 	"github.com/Fantom-foundation/go-evm/src/service"
 	"github.com/Fantom-foundation/go-evm/src/state"
 )
 
+func init() {
+	consensus.Register("solo", func(conf config.Config, logger *logrus.Logger) (consensus.Consensus, error) {
+		return NewSolo(conf.Solo.Interval, logger), nil
+	})
+}
+
 /*
 Solo implements the Consensus interface.
-It relays messages directly from the State to the Service.
+It relays messages directly from the State to the Service, committing each
+transaction as soon as it is submitted, or the transactions submitted during
+an interval together, for a single node development network.
 */
 type Solo struct {
-	txIndex int
-	state   *state.State
-	service *service.Service
-	logger  *logrus.Entry
+	interval time.Duration
+	txIndex  int
+	blocks   int
+	state    *state.State
+	service  *service.Service
+	stop     chan struct{}
+	done     chan struct{}
+	logger   *logrus.Entry
 }
 
-// NewSolo returns a Solo object with nil State and Service
-func NewSolo(logger *logrus.Logger) *Solo {
+// NewSolo returns a Solo object with nil State and Service. Blocks are
+// committed every interval, or for every transaction if 0.
+func NewSolo(interval time.Duration, logger *logrus.Logger) *Solo {
 	return &Solo{
-		logger: logger.WithField("module", "solo"),
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		logger:   logger.WithField("module", "solo"),
 	}
 }
 
@@ -44,39 +64,94 @@ func (s *Solo) Init(state *state.State, service *service.Service) error {
 	return nil
 }
 
-// Run pipes the Service's submitCh to the States's ProcessBlock function. It
-// wraps individual transactions into Lachesis Blocks
+// Run pipes the Service's submitCh to the State. Transactions are committed
+// right away, or every interval if set
 func (s *Solo) Run() error {
+	defer close(s.done)
+
 	submitCh := s.service.GetSubmitCh()
+	if s.interval <= 0 {
+		for {
+			select {
+			case t := <-submitCh:
+				s.commitBlock([][]byte{t})
+			case <-s.stop:
+				return nil
+			}
+		}
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	var pending [][]byte
 	for {
 		select {
 		case t := <-submitCh:
-			s.logger.WithField("tx", s.txIndex).Debug("Adding Transaction")
-
-			err := s.state.ApplyTransaction(t,
-				s.txIndex,
-				common.BytesToHash([]byte((fmt.Sprintf("block %d", s.txIndex)))))
-			if err != nil {
-				s.logger.WithField("tx", s.txIndex).WithError(err).Errorf("ApplyTransaction")
+			pending = append(pending, t)
+		case <-s.stop:
+			// The transactions of the current interval are committed
+			if len(pending) > 0 {
+				s.commitBlock(pending)
 			}
-
-			hash, err := s.state.Commit()
-			if err != nil {
-				s.logger.WithField("tx", s.txIndex).WithError(err).Errorf("Commit")
+			return nil
+		case <-ticker.C:
+			// No empty blocks are committed
+			if len(pending) > 0 {
+				s.commitBlock(pending)
+				pending = nil
 			}
+		}
+	}
+}
 
-			s.logger.WithField("tx", s.txIndex).Debugf("Result State Hash: %v", hash)
+// commitBlock applies transactions and commits them as a block. Its synthetic
+// consensus hash is named after the number the State records it at. Solo is the
+// only validator, so its clock gives the time of the block.
+func (s *Solo) commitBlock(txs [][]byte) {
+	blockIndex := s.state.NextBlockNumber()
+	blockHash := common.BytesToHash([]byte(fmt.Sprintf("block %d", blockIndex)))
+	s.state.BeginBlock(blockHash, time.Now().Unix(), common.Address{})
 
-			s.txIndex++
+	for _, t := range txs {
+		s.logger.WithField("tx", s.txIndex).Debug("Adding Transaction")
+
+		err := s.state.ApplyTransaction(context.Background(), t, s.txIndex, blockHash)
+		if err != nil {
+			s.logger.WithField("tx", s.txIndex).WithError(err).Errorf("ApplyTransaction")
 		}
+		s.txIndex++
+	}
+
+	hash, err := s.state.Commit(context.Background())
+	if err != nil {
+		s.logger.WithField("block", blockIndex).WithError(err).Errorf("Commit")
+		return
 	}
+	s.blocks++
+
+	s.logger.WithFields(logrus.Fields{
+		"block": blockIndex,
+		"txs":   len(txs),
+	}).Debugf("Result State Hash: %v", hash)
+}
+
+// Stop returns once the block being committed, and the transactions waiting
+// for the next one, are committed
+func (s *Solo) Stop() error {
+	close(s.stop)
+	<-s.done
+	return nil
 }
 
-// Info returns the current transaction index
+// Info returns the current transaction index and the number of blocks
+// committed
 func (s *Solo) Info() (map[string]string, error) {
 	info := map[string]string{
 		"type":     "solo",
 		"tx_index": strconv.Itoa(s.txIndex),
+		"blocks":   strconv.Itoa(s.blocks),
+		"interval": s.interval.String(),
 	}
 	return info, nil
 }
@@ -0,0 +1,55 @@
+package solo
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	bcommon "github.com/Fantom-foundation/go-evm/src/common"
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+// newTestSolo returns a Solo on a fresh State whose genesis funds the account
+// of the returned key
+func newTestSolo(t *testing.T) (*Solo, *ecdsa.PrivateKey, func()) {
+	st, key, cleanup := state.NewTestState(t)
+	s := NewSolo(0, bcommon.NewTestLogger(t))
+	if err := s.Init(st, nil); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	return s, key, cleanup
+}
+
+// TestCommitBlockNumbers checks that each block gets its own consensus hash,
+// named after the number it is recorded at, the first one included
+func TestCommitBlockNumbers(t *testing.T) {
+	s, key, cleanup := newTestSolo(t)
+	defer cleanup()
+
+	s.commitBlock([][]byte{state.NewTestTransfer(t, key, 0)})
+	s.commitBlock([][]byte{state.NewTestTransfer(t, key, 1)})
+
+	if index := s.state.GetBlockIndex(); index != 1 {
+		t.Fatalf("block index should be 1, not %d", index)
+	}
+	for number := int64(0); number < 2; number++ {
+		block, err := s.state.GetBlockByNumber(number)
+		if err != nil {
+			t.Fatalf("block %d: %v", number, err)
+		}
+		expected := common.BytesToHash([]byte(fmt.Sprintf("block %d", number)))
+		if block.ConsensusHash != expected {
+			t.Fatalf("block %d: consensus hash should be %s, not %s", number, expected.Hex(), block.ConsensusHash.Hex())
+		}
+		if len(block.Transactions) != 1 {
+			t.Fatalf("block %d should have 1 transaction, not %d", number, len(block.Transactions))
+		}
+	}
+	if nonce := s.state.GetNonce(crypto.PubkeyToAddress(key.PublicKey)); nonce != 2 {
+		t.Fatalf("nonce should be 2, not %d", nonce)
+	}
+}
@@ -0,0 +1,111 @@
+package tendermint
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+// codeTxRejected is the ABCI code of the transactions refused by CheckTx or
+// skipped by DeliverTx
+const codeTxRejected uint32 = 1
+
+// app is the ABCI application over the State. Tendermint calls it from a
+// single connection at a time for a block: BeginBlock, DeliverTx for each
+// transaction, EndBlock and Commit.
+type app struct {
+	abci.BaseApplication
+
+	state         *state.State
+	beneficiaries map[string]common.Address // by upper case validator address
+	blockHash     common.Hash
+	txIndex       int
+	logger        *logrus.Entry
+}
+
+func newApp(st *state.State, beneficiaries map[string]common.Address, logger *logrus.Entry) *app {
+	return &app{
+		state:         st,
+		beneficiaries: beneficiaries,
+		logger:        logger,
+	}
+}
+
+// lastBlock returns the height and the state root of the last committed block.
+// Tendermint heights start at 1, when block indexes start at 0.
+func (a *app) lastBlock() (int64, []byte) {
+	block, err := a.state.GetBlockByNumber(a.state.GetBlockIndex())
+	if err != nil {
+		return 0, nil
+	}
+	return int64(block.Number) + 1, block.StateRoot.Bytes()
+}
+
+// Info tells Tendermint the last committed block, from which it replays the
+// blocks the State missed
+func (a *app) Info(req abci.RequestInfo) abci.ResponseInfo {
+	height, appHash := a.lastBlock()
+	return abci.ResponseInfo{
+		Data:             "go-evm",
+		LastBlockHeight:  height,
+		LastBlockAppHash: appHash,
+	}
+}
+
+// CheckTx checks a transaction against the TxPool before Tendermint adds it to
+// its mempool. The transactions submitted through the Service were checked
+// when they were added to the TxPool.
+func (a *app) CheckTx(tx []byte) abci.ResponseCheckTx {
+	t, _, err := state.DecodeTx(tx)
+	if err != nil {
+		return abci.ResponseCheckTx{Code: codeTxRejected, Log: err.Error()}
+	}
+	if pooled, _ := a.state.GetPoolTransaction(t.Hash()); pooled != nil {
+		return abci.ResponseCheckTx{Code: abci.CodeTypeOK}
+	}
+	if err := a.state.CheckTx(context.Background(), t); err != nil {
+		a.logger.WithError(err).Debug("CheckTx")
+		return abci.ResponseCheckTx{Code: codeTxRejected, Log: err.Error()}
+	}
+	return abci.ResponseCheckTx{Code: abci.CodeTypeOK}
+}
+
+// BeginBlock starts a block with the hash and the time of the Tendermint
+// block, so that empty blocks are recorded too and the heights stay in line.
+// The fees of the block go to the beneficiary of its proposer.
+func (a *app) BeginBlock(req abci.RequestBeginBlock) abci.ResponseBeginBlock {
+	a.blockHash = common.BytesToHash(req.Hash)
+	a.txIndex = 0
+	coinbase := a.beneficiaries[strings.ToUpper(hex.EncodeToString(req.Header.ProposerAddress))]
+	a.state.BeginBlock(a.blockHash, req.Header.Time.Unix(), coinbase)
+	return abci.ResponseBeginBlock{}
+}
+
+// DeliverTx applies a transaction of the block. A transaction which fails is
+// skipped, like in the blocks of the other consensus systems.
+func (a *app) DeliverTx(tx []byte) abci.ResponseDeliverTx {
+	err := a.state.ApplyTransaction(context.Background(), tx, a.txIndex, a.blockHash)
+	a.txIndex++
+	if err != nil {
+		a.logger.WithError(err).WithField("class", state.ErrorClass(err)).Warn("Skipping transaction")
+		return abci.ResponseDeliverTx{Code: codeTxRejected, Log: err.Error()}
+	}
+	return abci.ResponseDeliverTx{Code: abci.CodeTypeOK}
+}
+
+// Commit commits the block and returns the state root as the app hash
+func (a *app) Commit() abci.ResponseCommit {
+	root, err := a.state.Commit(context.Background())
+	if err != nil {
+		// Tendermint can't go on without the app hash of the block
+		a.logger.WithError(err).Panic("Commit")
+	}
+	a.logger.WithField("root", root.Hex()).Debug("Committed block")
+	return abci.ResponseCommit{Data: root.Bytes()}
+}
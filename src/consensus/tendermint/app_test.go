@@ -0,0 +1,89 @@
+package tendermint
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	bcommon "github.com/Fantom-foundation/go-evm/src/common"
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+var (
+	testProposer    = []byte{0xab, 0xcd}
+	testBeneficiary = common.HexToAddress("0x0c01")
+)
+
+// newTestApp returns an app on a fresh State whose genesis funds the account
+// of the returned key. The fees of testProposer go to testBeneficiary.
+func newTestApp(t *testing.T) (*app, *ecdsa.PrivateKey, func()) {
+	st, key, cleanup := state.NewTestState(t)
+	beneficiaries := map[string]common.Address{"ABCD": testBeneficiary}
+	logger := bcommon.NewTestLogger(t).WithField("module", "tendermint")
+	return newApp(st, beneficiaries, logger), key, cleanup
+}
+
+// TestAppBlocks checks that the blocks of Tendermint are recorded with their
+// time and the beneficiary of their proposer, empty ones included, and that
+// Info reports the last one
+func TestAppBlocks(t *testing.T) {
+	a, key, cleanup := newTestApp(t)
+	defer cleanup()
+
+	if info := a.Info(abci.RequestInfo{}); info.LastBlockHeight != 0 {
+		t.Fatalf("height should be 0, not %d", info.LastBlockHeight)
+	}
+
+	tx := state.NewTestTransfer(t, key, 0)
+	if res := a.CheckTx(tx); res.Code != abci.CodeTypeOK {
+		t.Fatalf("CheckTx: %s", res.Log)
+	}
+	if res := a.CheckTx([]byte{0x01}); res.Code != codeTxRejected {
+		t.Fatal("CheckTx should reject an invalid transaction")
+	}
+
+	blockTime := time.Unix(1546300800, 0)
+	a.BeginBlock(abci.RequestBeginBlock{
+		Hash:   []byte{0x01},
+		Header: abci.Header{Time: blockTime, ProposerAddress: testProposer},
+	})
+	if res := a.DeliverTx(tx); res.Code != abci.CodeTypeOK {
+		t.Fatalf("DeliverTx: %s", res.Log)
+	}
+	if res := a.DeliverTx([]byte{0x01}); res.Code != codeTxRejected {
+		t.Fatal("DeliverTx should skip an invalid transaction")
+	}
+	commit := a.Commit()
+
+	block, err := a.state.GetBlockByNumber(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if common.BytesToHash(commit.Data) != block.StateRoot {
+		t.Fatalf("app hash should be %s, not %x", block.StateRoot.Hex(), commit.Data)
+	}
+	if block.Timestamp != uint64(blockTime.Unix()) {
+		t.Fatalf("timestamp should be %d, not %d", blockTime.Unix(), block.Timestamp)
+	}
+	if block.Coinbase != testBeneficiary {
+		t.Fatalf("beneficiary should be %s, not %s", testBeneficiary.Hex(), block.Coinbase.Hex())
+	}
+	if balance := a.state.GetBalance(testBeneficiary); balance.Cmp(big.NewInt(21000)) != 0 {
+		t.Fatalf("beneficiary should get the fees 21000, not %v", balance)
+	}
+
+	// An empty block is recorded too
+	a.BeginBlock(abci.RequestBeginBlock{
+		Hash:   []byte{0x02},
+		Header: abci.Header{Time: blockTime.Add(time.Second), ProposerAddress: testProposer},
+	})
+	a.Commit()
+	info := a.Info(abci.RequestInfo{})
+	if info.LastBlockHeight != 2 {
+		t.Fatalf("height should be 2, not %d", info.LastBlockHeight)
+	}
+}
@@ -0,0 +1,200 @@
+package tendermint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	abciserver "github.com/tendermint/tendermint/abci/server"
+	cmn "github.com/tendermint/tendermint/libs/common"
+
+	"github.com/Fantom-foundation/go-evm/src/config"
+	"github.com/Fantom-foundation/go-evm/src/consensus"
+	"github.com/Fantom-foundation/go-evm/src/service"
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+func init() {
+	consensus.Register("tendermint", func(conf config.Config, logger *logrus.Logger) (consensus.Consensus, error) {
+		t, err := NewTendermint(*conf.Tendermint, logger)
+		if err != nil {
+			return nil, err
+		}
+		return t, nil
+	})
+}
+
+// rpcTimeout bounds the broadcast of a transaction to the Tendermint node
+const rpcTimeout = 10 * time.Second
+
+/*
+Tendermint implements the Consensus interface.
+It serves the State as an ABCI application to a separate Tendermint node, which
+orders the transactions with BFT consensus: CheckTx checks them against the
+TxPool, DeliverTx applies them and Commit commits the State. The transactions
+submitted to the Service are broadcast to the node through its RPC server.
+*/
+type Tendermint struct {
+	config        config.TendermintConfig
+	beneficiaries map[string]common.Address
+	app           *app
+	service       *service.Service
+	server        cmn.Service
+	client        *http.Client
+	submitted     int
+	stop          chan struct{}
+	logger        *logrus.Entry
+}
+
+// NewTendermint returns a Tendermint object with nil State and Service
+func NewTendermint(config config.TendermintConfig, logger *logrus.Logger) (*Tendermint, error) {
+	beneficiaries := map[string]common.Address{}
+	for validator, addr := range config.Beneficiaries {
+		if !common.IsHexAddress(addr) {
+			return nil, fmt.Errorf("invalid beneficiary %q of validator %s", addr, validator)
+		}
+		beneficiaries[strings.ToUpper(strings.TrimPrefix(validator, "0x"))] = common.HexToAddress(addr)
+	}
+
+	return &Tendermint{
+		config:        config,
+		beneficiaries: beneficiaries,
+		client:        &http.Client{Timeout: rpcTimeout},
+		stop:          make(chan struct{}),
+		logger:        logger.WithField("module", "tendermint"),
+	}, nil
+}
+
+/*******************************************************************************
+IMPLEMENT CONSENSUS INTERFACE
+*******************************************************************************/
+
+// Init sets the state and service
+func (t *Tendermint) Init(st *state.State, srv *service.Service) error {
+
+	t.logger.Debug("INIT")
+
+	t.app = newApp(st, t.beneficiaries, t.logger)
+	t.service = srv
+
+	return nil
+}
+
+// Run serves the ABCI application and broadcasts the transactions of the
+// Service's submitCh to the Tendermint node
+func (t *Tendermint) Run() error {
+	server, err := abciserver.NewServer(t.config.ABCIAddr, "socket", t.app)
+	if err != nil {
+		return err
+	}
+	if err := server.Start(); err != nil {
+		return err
+	}
+	t.server = server
+
+	t.logger.WithField("abci", t.config.ABCIAddr).Info("Serving ABCI")
+
+	submitCh := t.service.GetSubmitCh()
+	for {
+		select {
+		case tx := <-submitCh:
+			if t.service.SubmitToConsensus(tx, t.broadcastTx) {
+				t.submitted++
+			}
+		case <-t.stop:
+			return nil
+		}
+	}
+}
+
+// Stop stops broadcasting transactions and closes the ABCI server. Tendermint
+// replays the blocks the State didn't commit when it reconnects.
+func (t *Tendermint) Stop() error {
+	close(t.stop)
+	if t.server == nil {
+		return nil
+	}
+	return t.server.Stop()
+}
+
+// Info returns the ABCI and RPC addresses and the last committed height
+func (t *Tendermint) Info() (map[string]string, error) {
+	height, _ := t.app.lastBlock()
+	info := map[string]string{
+		"type":      "tendermint",
+		"abci":      t.config.ABCIAddr,
+		"rpc":       t.config.RPCAddr,
+		"height":    strconv.FormatInt(height, 10),
+		"submitted": strconv.Itoa(t.submitted),
+	}
+	return info, nil
+}
+
+/*******************************************************************************
+RPC CLIENT
+*******************************************************************************/
+
+type rpcRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      string      `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data"`
+}
+
+type broadcastResult struct {
+	Code uint32 `json:"code"`
+	Log  string `json:"log"`
+	Hash string `json:"hash"`
+}
+
+// broadcastTx submits a transaction to the mempool of the Tendermint node,
+// which checks it with CheckTx before answering
+func (t *Tendermint) broadcastTx(tx []byte) error {
+	body, err := json.Marshal(rpcRequest{
+		JSONRPC: "2.0",
+		ID:      "go-evm",
+		Method:  "broadcast_tx_sync",
+		// []byte is encoded in base64, as Tendermint expects
+		Params: map[string][]byte{"tx": tx},
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := t.client.Post(t.config.RPCAddr, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var res struct {
+		Result *broadcastResult `json:"result"`
+		Error  *rpcError        `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return fmt.Errorf("decoding broadcast_tx_sync response: %s", err)
+	}
+	switch {
+	case res.Error != nil:
+		return fmt.Errorf("broadcast_tx_sync: %s %s", res.Error.Message, res.Error.Data)
+	case res.Result == nil:
+		return fmt.Errorf("broadcast_tx_sync: empty response")
+	case res.Result.Code != 0:
+		return fmt.Errorf("transaction rejected by CheckTx: %s", res.Result.Log)
+	}
+
+	t.logger.WithField("hash", res.Result.Hash).Debug("Broadcast transaction")
+	return nil
+}
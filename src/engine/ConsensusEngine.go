@@ -1,12 +1,22 @@
 package engine
 
 import (
+	"os"
+	"os/signal"
+	"syscall"
+
 	"github.com/sirupsen/logrus"
 
 	"github.com/Fantom-foundation/go-evm/src/config"
 	"github.com/Fantom-foundation/go-evm/src/consensus"
 	"github.com/Fantom-foundation/go-evm/src/service"
 	"github.com/Fantom-foundation/go-evm/src/state"
+
+	// Register the consensus systems which aren't part of this package
+	_ "github.com/Fantom-foundation/go-evm/src/consensus/lachesis"
+	_ "github.com/Fantom-foundation/go-evm/src/consensus/raft"
+	_ "github.com/Fantom-foundation/go-evm/src/consensus/solo"
+	_ "github.com/Fantom-foundation/go-evm/src/consensus/tendermint"
 )
 
 // ConsensusEngine is the actor that coordinates State, Service and Consensus
@@ -14,6 +24,17 @@ type ConsensusEngine struct {
 	state     *state.State
 	service   *service.Service
 	consensus consensus.Consensus
+	logger    *logrus.Logger
+}
+
+// NewEngine instantiates a ConsensusEngine with the consensus system
+// registered under name (see consensus.Register)
+func NewEngine(name string, config config.Config, logger *logrus.Logger) (*ConsensusEngine, error) {
+	c, err := consensus.New(name, config, logger)
+	if err != nil {
+		return nil, err
+	}
+	return NewConsensusEngine(config, c, logger)
 }
 
 // NewConsensusEngine instantiates a new ConsensusEngine with coupled State, Service, and Consensus
@@ -22,44 +43,74 @@ func NewConsensusEngine(config config.Config,
 	logger *logrus.Logger) (*ConsensusEngine, error) {
 	submitCh := make(chan []byte)
 
-	state, err := state.NewState(logger,
-		config.Eth.DbFile,
-		config.Eth.Cache)
+	st, err := NewState(config, logger)
 	if err != nil {
 		return nil, err
 	}
 
-	service := service.NewService(config.Eth.Genesis,
-		config.Eth.Keystore,
-		config.Eth.EthAPIAddr,
-		config.Eth.PwdFile,
-		state,
-		submitCh,
-		logger)
-
-	if err := consensus.Init(state, service); err != nil {
+	srv, err := newService(config, st, submitCh, logger)
+	if err != nil {
 		return nil, err
 	}
 
-	service.SetInfoCallback(consensus.Info)
+	// The sync status is derived from the info of the consensus system, unless
+	// it sets its own callback in Init
+	srv.SetInfoCallback(consensus.Info)
+	srv.SetSyncCallback(func() service.SyncStatus {
+		info, err := consensus.Info()
+		return consensusSyncStatus(st, info, err)
+	})
+
+	if err := consensus.Init(st, srv); err != nil {
+		return nil, err
+	}
 
 	engine := &ConsensusEngine{
-		state:     state,
-		service:   service,
+		state:     st,
+		service:   srv,
 		consensus: consensus,
+		logger:    logger,
 	}
 
 	return engine, nil
 }
 
-// Run starts the engine's Service asynchronously and starts the Consensus system
-// synchronously
+// Run starts the engine's Service asynchronously and runs the Consensus system
+// until it stops or the process receives SIGINT or SIGTERM, then shuts down
 func (e *ConsensusEngine) Run() error {
 	go e.service.Run()
 
-	if err := e.consensus.Run(); err != nil {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- e.consensus.Run()
+	}()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	select {
+	case err := <-errCh:
+		e.shutdown()
 		return err
+	case sig := <-sigCh:
+		e.logger.WithField("signal", sig).Info("Shutting down")
+		e.shutdown()
+		return nil
+	}
+}
+
+// shutdown stops accepting transactions, stops the Consensus system once the
+// block being processed is committed, and closes the State, so that a restart
+// never finds a half written block
+func (e *ConsensusEngine) shutdown() {
+	e.service.Stop()
+
+	if stopper, ok := e.consensus.(consensus.Stopper); ok {
+		if err := stopper.Stop(); err != nil {
+			e.logger.WithError(err).Error("Stopping consensus")
+		}
 	}
 
-	return nil
+	e.state.Close()
 }
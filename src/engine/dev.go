@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/Fantom-foundation/go-evm/src/service"
+)
+
+// loadDevAccounts decrypts the keys of the keystore of a development node,
+// whose accounts are the pre-funded ones, in the order they were created
+func loadDevAccounts(keystoreDir, pwdFile string) ([]service.DevAccount, error) {
+	data, err := ioutil.ReadFile(pwdFile)
+	if err != nil {
+		return nil, err
+	}
+	pwd := strings.TrimRight(strings.SplitN(string(data), "\n", 2)[0], "\r")
+
+	ks := keystore.NewKeyStore(keystoreDir, keystore.LightScryptN, keystore.LightScryptP)
+	accounts := []service.DevAccount{}
+	for _, account := range ks.Accounts() {
+		keyJSON, err := ioutil.ReadFile(account.URL.Path)
+		if err != nil {
+			return nil, err
+		}
+		key, err := keystore.DecryptKey(keyJSON, pwd)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, service.DevAccount{
+			Address:    key.Address,
+			PrivateKey: crypto.FromECDSA(key.PrivateKey),
+		})
+	}
+	return accounts, nil
+}
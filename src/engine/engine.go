@@ -1,5 +1,261 @@
 package engine
 
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Fantom-foundation/go-evm/src/config"
+	"github.com/Fantom-foundation/go-evm/src/service"
+	"github.com/Fantom-foundation/go-evm/src/state"
+	"github.com/Fantom-foundation/go-evm/src/tracing"
+)
+
+// Engine runs a node: its State, its Service and a consensus system
 type Engine interface {
-	Run()
+	Run() error
+}
+
+// NewState creates the State shared by all the engines from the eth config. The
+// offline commands replaying blocks use it too, so that they apply them with
+// the same chain id, coinbase and policies as the node.
+func NewState(config config.Config, logger *logrus.Logger) (*state.State, error) {
+	// Tracing is set up before the State and the Service record their spans
+	if config.Eth.TraceEndpoint != "" {
+		if config.Eth.TraceSampleRatio < 0 || config.Eth.TraceSampleRatio > 1 {
+			return nil, fmt.Errorf("trace sample ratio must be between 0 and 1")
+		}
+		if err := tracing.Setup(config.Eth.TraceEndpoint, config.Eth.TraceSampleRatio); err != nil {
+			return nil, err
+		}
+	}
+
+	st, err := state.NewState(logger,
+		config.Eth.DbFile,
+		config.Eth.Cache,
+		config.Eth.ArchiveDirs)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Resume {
+		if err := st.ExitSafeMode(); err != nil {
+			return nil, err
+		}
+		if err := st.Resume(); err != nil {
+			return nil, err
+		}
+	}
+	if config.SafeMode {
+		if err := st.EnterSafeMode("started with --safe-mode"); err != nil {
+			return nil, err
+		}
+	}
+	if err := st.Halted(); err != nil {
+		if !st.InSafeMode() {
+			return nil, fmt.Errorf("%s (start with --resume)", err)
+		}
+		logger.WithError(err).Warn("Serving reads only (start with --resume, or DELETE /safe-mode on the admin dashboard, to clear)")
+	}
+
+	// The parameters of the network in the genesis prevail over the flags
+	genesisConfig, err := state.LoadGenesisConfig(config.Eth.Genesis)
+	if err != nil {
+		return nil, err
+	}
+	chainID := config.Eth.ChainID
+	if genesisConfig != nil && genesisConfig.ChainID != 0 {
+		chainID = genesisConfig.ChainID
+	}
+	st.SetChainID(new(big.Int).SetUint64(chainID))
+	if genesisConfig != nil {
+		st.SetGenesisConfig(genesisConfig)
+	}
+
+	pending, err := st.PendingMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) > 0 {
+		logger.WithField("migrations", len(pending)).Warn("The database uses an older layout (stop the node and run evm db migrate to upgrade it)")
+	}
+
+	if err := bootstrapState(config, st, logger); err != nil {
+		return nil, err
+	}
+	if config.Eth.Coinbase != "" {
+		if !common.IsHexAddress(config.Eth.Coinbase) {
+			return nil, fmt.Errorf("invalid coinbase %q", config.Eth.Coinbase)
+		}
+		st.SetCoinbase(common.HexToAddress(config.Eth.Coinbase))
+	}
+	st.SetHaltHeight(config.Eth.HaltHeight)
+	st.SetPriceBump(config.Eth.PriceBump)
+	st.SetTxPoolLimits(config.Eth.TxPoolGlobalSlots,
+		config.Eth.TxPoolAccountSlots,
+		config.Eth.TxPoolAccountPending)
+	st.SetTxTTL(config.Eth.TxTTL)
+	st.SetBlobRetention(config.Eth.BlobRetention)
+	st.SetCodeSizeLimits(state.CodeSizeLimits{
+		MaxCodeSize:     config.Eth.MaxCodeSize,
+		MaxInitCodeSize: config.Eth.MaxInitCodeSize,
+	})
+
+	locals := []common.Address{}
+	for _, addr := range config.Eth.TxPoolLocals {
+		if !common.IsHexAddress(addr) {
+			return nil, fmt.Errorf("invalid local address %q", addr)
+		}
+		locals = append(locals, common.HexToAddress(addr))
+	}
+	st.AddLocals(locals)
+
+	if config.Eth.GasCapPolicy != "" {
+		policy, err := state.LoadGasCapPolicy(config.Eth.GasCapPolicy)
+		if err != nil {
+			return nil, err
+		}
+		st.SetGasCapPolicy(policy)
+	}
+
+	// A database initialized by `evm init` must run with the same genesis
+	if info, ok := st.GetGenesis(); ok {
+		genesis, err := state.ReadGenesis(config.Eth.Genesis)
+		if err != nil {
+			return nil, err
+		}
+		if genesis.Hash() != info.Hash {
+			return nil, fmt.Errorf("genesis %s (%s) is not the one the database was initialized with (%s)",
+				config.Eth.Genesis, genesis.Hash().Hex(), info.Hash.Hex())
+		}
+	}
+
+	feePolicy, err := state.LoadGenesisFeePolicy(config.Eth.Genesis)
+	if err != nil {
+		return nil, err
+	}
+	st.SetFeePolicy(feePolicy)
+
+	if config.Eth.CheckInvariants {
+		if err := st.EnableInvariantChecks(); err != nil {
+			return nil, err
+		}
+	}
+
+	return st, nil
+}
+
+// newService creates the Service shared by all the engines from the eth config
+func newService(config config.Config,
+	st *state.State,
+	submitCh chan []byte,
+	logger *logrus.Logger) (*service.Service, error) {
+
+	s := service.NewService(config.Eth.Genesis,
+		config.Eth.Keystore,
+		config.Eth.EthAPIAddr,
+		config.Eth.AdminAddr,
+		config.Eth.PwdFile,
+		st,
+		submitCh,
+		logger)
+
+	s.SetIPCPath(config.Eth.IPCPath)
+	s.SetGRPCAddr(config.Eth.GRPCAddr)
+	s.SetPprofAddr(config.PprofAddr)
+	s.SetCORS(config.Eth.RPCCors)
+
+	if config.Eth.TLSCert != "" || config.Eth.TLSKey != "" {
+		if config.Eth.TLSCert == "" || config.Eth.TLSKey == "" {
+			return nil, fmt.Errorf("both a TLS certificate and key are required")
+		}
+		if err := s.SetTLS(config.Eth.TLSCert, config.Eth.TLSKey); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.Eth.APIKeys != "" {
+		keys, err := service.LoadAPIKeys(config.Eth.APIKeys)
+		if err != nil {
+			return nil, err
+		}
+		s.SetAPIAuth(keys, config.Eth.AuthNamespaces)
+	}
+
+	if config.Eth.RateLimit < 0 || config.Eth.RateBurst < 0 || config.Eth.MaxConcurrent < 0 {
+		return nil, fmt.Errorf("rate limits must not be negative")
+	}
+	if config.Eth.RateLimit > 0 || config.Eth.MaxConcurrent > 0 {
+		s.SetRateLimits(service.RateLimits{
+			Rate:          config.Eth.RateLimit,
+			Burst:         config.Eth.RateBurst,
+			MaxConcurrent: config.Eth.MaxConcurrent,
+		})
+	}
+
+	if config.Eth.WatchCalls != "" {
+		if config.Eth.WatchInterval <= 0 {
+			return nil, fmt.Errorf("invalid watch interval %s", config.Eth.WatchInterval)
+		}
+		calls, err := service.LoadWatchedCalls(config.Eth.WatchCalls)
+		if err != nil {
+			return nil, err
+		}
+		s.SetWatchedCalls(calls, config.Eth.WatchInterval)
+	}
+
+	if config.Eth.EventMetrics != "" {
+		metrics, err := service.LoadEventMetrics(config.Eth.EventMetrics)
+		if err != nil {
+			return nil, err
+		}
+		s.SetEventMetrics(metrics)
+	}
+
+	s.SetGasPriceOracle(service.GasPriceOracle{
+		Blocks:     config.Eth.GasPriceBlocks,
+		Percentile: config.Eth.GasPricePercentile,
+	})
+
+	s.SetLogLimits(service.LogLimits{
+		MaxLogs:       config.Eth.MaxLogs,
+		MaxBlockRange: config.Eth.MaxLogBlockRange,
+	})
+
+	s.SetCommitSLO(service.CommitSLO{
+		P50:     config.Eth.CommitSLOP50,
+		P95:     config.Eth.CommitSLOP95,
+		P99:     config.Eth.CommitSLOP99,
+		Window:  config.Eth.CommitSLOWindow,
+		Webhook: config.Eth.CommitSLOWebhook,
+	})
+
+	if config.Dev {
+		accounts, err := loadDevAccounts(config.Eth.Keystore, config.Eth.PwdFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.SetDevAccounts(accounts); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(config.Eth.NodePeers) > 0 {
+		key, err := service.LoadNodeKey(config.Eth.NodeKey)
+		if err != nil {
+			return nil, err
+		}
+		peers := []common.Address{}
+		for _, addr := range config.Eth.NodePeers {
+			if !common.IsHexAddress(addr) {
+				return nil, fmt.Errorf("invalid peer address %q", addr)
+			}
+			peers = append(peers, common.HexToAddress(addr))
+		}
+		s.SetNodeAuth(key, peers)
+	}
+
+	return s, nil
 }
@@ -0,0 +1,163 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Fantom-foundation/go-evm/src/config"
+	"github.com/Fantom-foundation/go-evm/src/service"
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+// bootstrapState fills an empty State before the node starts: from the
+// fast-sync snapshot if set, otherwise from the state of the sync peer. The
+// blocks the peer committed after that are then replayed, so that the node
+// joins at the head of the chain.
+func bootstrapState(config config.Config, st *state.State, logger *logrus.Logger) error {
+	if !st.IsEmpty() || (config.Eth.SyncPeer == "" && config.Eth.FastSyncSnapshot == "") {
+		return nil
+	}
+
+	var peer *service.SyncPeer
+	if config.Eth.SyncPeer != "" {
+		if !common.IsHexAddress(config.Eth.SyncPeerKey) {
+			return fmt.Errorf("sync peer %s needs the address of its node key (eth.sync-peer-key), not %q", config.Eth.SyncPeer, config.Eth.SyncPeerKey)
+		}
+		key, err := service.LoadNodeKey(config.Eth.NodeKey)
+		if err != nil {
+			return err
+		}
+		peer = service.NewSyncPeer(config.Eth.SyncPeer, key, common.HexToAddress(config.Eth.SyncPeerKey))
+	}
+
+	if config.Eth.FastSyncSnapshot != "" {
+		manifest, err := importSnapshot(st, config.Eth.FastSyncSnapshot, logger)
+		if err != nil {
+			return fmt.Errorf("importing snapshot %s: %s", config.Eth.FastSyncSnapshot, err)
+		}
+		logger.WithFields(logrus.Fields{
+			"snapshot": config.Eth.FastSyncSnapshot,
+			"block":    manifest.Block,
+			"root":     manifest.Root.Hex(),
+		}).Info("Imported snapshot")
+	} else {
+		head, err := st.SyncState(peer, state.DefaultSyncRangeLimit)
+		if err != nil {
+			return fmt.Errorf("syncing state from %s: %s", config.Eth.SyncPeer, err)
+		}
+		logger.WithFields(logrus.Fields{
+			"peer":  config.Eth.SyncPeer,
+			"block": head.Block,
+			"root":  head.Root.Hex(),
+		}).Info("Synced state")
+	}
+
+	if peer == nil {
+		return nil
+	}
+	return replayTail(st, peer, logger)
+}
+
+// importSnapshot imports a snapshot directory, downloading it first if it is
+// published at a URL
+func importSnapshot(st *state.State, snapshot string, logger *logrus.Logger) (*state.SnapshotManifest, error) {
+	dir := snapshot
+	if strings.HasPrefix(snapshot, "http://") || strings.HasPrefix(snapshot, "https://") {
+		tmp, err := ioutil.TempDir("", "evm-snapshot")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(tmp)
+		if err := downloadSnapshot(strings.TrimRight(snapshot, "/"), tmp, logger); err != nil {
+			return nil, err
+		}
+		dir = tmp
+	}
+	return st.ImportSnapshot(dir, runtime.NumCPU())
+}
+
+// downloadSnapshot downloads the manifest and the shards of the snapshot
+// published at a URL to a directory. The shards are checked against the
+// manifest when they are imported.
+func downloadSnapshot(url string, dir string, logger *logrus.Logger) error {
+	if err := downloadFile(url+"/"+state.SnapshotManifestFile, dir); err != nil {
+		return err
+	}
+	manifest, err := state.ReadSnapshotManifest(dir)
+	if err != nil {
+		return err
+	}
+	for i, shard := range manifest.Shards {
+		if err := downloadFile(url+"/"+shard.File, dir); err != nil {
+			return err
+		}
+		logger.WithFields(logrus.Fields{
+			"shard":  i,
+			"shards": len(manifest.Shards),
+		}).Info("Downloaded snapshot shard")
+	}
+	return nil
+}
+
+// downloadFile downloads a file to a directory, under its name in the URL
+func downloadFile(url string, dir string) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("downloading %s: %s", url, resp.Status)
+	}
+
+	f, err := os.Create(filepath.Join(dir, filepath.Base(url)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		return fmt.Errorf("downloading %s: %s", url, err)
+	}
+	return f.Sync()
+}
+
+// replayTail replays the blocks the peer committed after the last block of the
+// State, until it has caught up with the peer
+func replayTail(st *state.State, peer *service.SyncPeer, logger *logrus.Logger) error {
+	replayed := 0
+	for {
+		next := st.GetBlockIndex() + 1
+		blocks, err := peer.Blocks(next, state.MaxSyncBlocks)
+		if err != nil {
+			return fmt.Errorf("getting blocks from %d: %s", next, err)
+		}
+		if len(blocks) == 0 {
+			break
+		}
+		for i := range blocks {
+			if _, err := st.ReplayBlock(&blocks[i]); err != nil {
+				return err
+			}
+		}
+		replayed += len(blocks)
+		logger.WithFields(logrus.Fields{
+			"replayed": replayed,
+			"block":    st.GetBlockIndex(),
+		}).Info("Replayed blocks")
+	}
+
+	logger.WithFields(logrus.Fields{
+		"blocks": replayed,
+		"head":   st.GetBlockIndex(),
+	}).Info("Caught up with the sync peer")
+	return nil
+}
@@ -3,11 +3,11 @@ package engine
 import (
 	"fmt"
 	//"os"
-	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/Fantom-foundation/go-evm/src/config"
+	"github.com/Fantom-foundation/go-evm/src/consensus"
 	"github.com/Fantom-foundation/go-evm/src/service"
 	"github.com/Fantom-foundation/go-evm/src/state"
 	"github.com/Fantom-foundation/go-lachesis/src/crypto"
@@ -18,32 +18,39 @@ import (
 	serv "github.com/Fantom-foundation/go-lachesis/src/service"
 )
 
-type InmemEngine struct {
-	ethService *service.Service
-	ethState   *state.State
-	node       *node.Node
-	service    *serv.Service
+func init() {
+	consensus.Register("inmem", func(conf config.Config, logger *logrus.Logger) (consensus.Consensus, error) {
+		return NewInmemEngine(conf, logger), nil
+	})
 }
 
-func NewInmemEngine(config config.Config, logger *logrus.Logger) (*InmemEngine, error) {
-	submitCh := make(chan []byte)
+// InmemEngine implements the Consensus interface. It runs a Lachesis node in
+// the same process, configured from the lachesis options.
+type InmemEngine struct {
+	config  config.Config
+	logger  *logrus.Logger
+	node    *node.Node
+	service *serv.Service
+}
 
-	state, err := state.NewState(logger,
-		config.Eth.DbFile,
-		config.Eth.Cache)
-	if err != nil {
-		return nil, err
+// NewInmemEngine returns an InmemEngine, whose Lachesis node is created by Init
+func NewInmemEngine(config config.Config, logger *logrus.Logger) *InmemEngine {
+	return &InmemEngine{
+		config: config,
+		logger: logger,
 	}
+}
 
-	service := service.NewService(config.Eth.Genesis,
-		config.Eth.Keystore,
-		config.Eth.EthAPIAddr,
-		config.Eth.PwdFile,
-		state,
-		submitCh,
-		logger)
+/*******************************************************************************
+Implement Consensus interface
+*******************************************************************************/
 
-	appProxy := NewInmemProxy(state, service, submitCh, logger)
+// Init creates the Lachesis node, which hands its blocks over to the State
+func (i *InmemEngine) Init(st *state.State, srv *service.Service) error {
+	config := i.config
+	logger := i.logger
+
+	appProxy := NewInmemProxy(st, srv, srv.GetSubmitCh(), logger)
 
 	//------------------------------------------------------------------------------
 
@@ -53,7 +60,7 @@ func NewInmemEngine(config config.Config, logger *logrus.Logger) (*InmemEngine,
 	// Try a read
 	key, err := pemKey.ReadKey()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// Create the peer store
@@ -61,12 +68,12 @@ func NewInmemEngine(config config.Config, logger *logrus.Logger) (*InmemEngine,
 	// Try a read
 	participants, err := peerStore.Peers()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// There should be at least two peers
 	if participants.Len() < 2 {
-		return nil, fmt.Errorf("peers.json should define at least two peers")
+		return fmt.Errorf("peers.json should define at least two peers")
 	}
 
 	pmap := participants
@@ -76,7 +83,7 @@ func NewInmemEngine(config config.Config, logger *logrus.Logger) (*InmemEngine,
 	n, ok := pmap.ByPubKey[nodePub]
 
 	if !ok {
-		return nil, fmt.Errorf("cannot find self pubkey in peers.json")
+		return fmt.Errorf("cannot find self pubkey in peers.json")
 	}
 
 	nodeID := n.ID
@@ -87,8 +94,8 @@ func NewInmemEngine(config config.Config, logger *logrus.Logger) (*InmemEngine,
 	}).Debug("Participants")
 
 	conf := node.NewConfig(
-		time.Duration(config.Lachesis.Heartbeat)*time.Millisecond,
-		time.Duration(config.Lachesis.TCPTimeout)*time.Millisecond,
+		config.Lachesis.Heartbeat,
+		config.Lachesis.TCPTimeout,
 		config.Lachesis.CacheSize,
 		config.Lachesis.SyncLimit,
 		logger)
@@ -106,7 +113,7 @@ func NewInmemEngine(config config.Config, logger *logrus.Logger) (*InmemEngine,
 			logger.Debug("loading badger store from existing database")
 			store, err = poset.LoadBadgerStore(conf.CacheSize, conf.StorePath)
 			if err != nil {
-				return nil, fmt.Errorf("failed to load BadgerStore from existing file: %s", err)
+				return fmt.Errorf("failed to load BadgerStore from existing file: %s", err)
 			}
 			needBootstrap = true
 		} else {
@@ -114,44 +121,37 @@ func NewInmemEngine(config config.Config, logger *logrus.Logger) (*InmemEngine,
 			logger.Debug("creating new badger store from fresh database")
 			store, err = poset.NewBadgerStore(pmap, conf.CacheSize, conf.StorePath)
 			if err != nil {
-				return nil, fmt.Errorf("failed to create new BadgerStore: %s", err)
+				return fmt.Errorf("failed to create new BadgerStore: %s", err)
 			}
 		}
 	default:
-		return nil, fmt.Errorf("Invalid StoreType: %s", conf.StoreType)
+		return fmt.Errorf("Invalid StoreType: %s", conf.StoreType)
 	}*/
 
 	trans, err := net.NewTCPTransport(
-		config.Lachesis.BindAddr, nil, 2, conf.TCPTimeout, logger)
+		config.Lachesis.BindAddr, nil, config.Lachesis.MaxPool, conf.TCPTimeout, logger)
 	if err != nil {
-		return nil, fmt.Errorf("creating TCP Transport: %s", err)
+		return fmt.Errorf("creating TCP Transport: %s", err)
 	}
 
 	node := node.NewNode(conf, nodeID, key, participants, store, trans, appProxy)
 	if err := node.Init(); err != nil {
-		return nil, fmt.Errorf("initializing node: %s", err)
+		return fmt.Errorf("initializing node: %s", err)
 	}
 
-	lserv := serv.NewService(config.Lachesis.ServiceAddr, node, logger)
+	srv.SetSyncCallback(func() service.SyncStatus {
+		return consensusSyncStatus(st, node.GetStats(), nil)
+	})
 
-	return &InmemEngine{
-		ethState:   state,
-		ethService: service,
-		node:       node,
-		service:    lserv,
-	}, nil
+	i.node = node
+	i.service = serv.NewService(config.Lachesis.ServiceAddr, node, logger)
 
+	return nil
 }
 
-/*******************************************************************************
-Implement Engine interface
-*******************************************************************************/
-
+// Run serves the Lachesis API and runs the node
 func (i *InmemEngine) Run() error {
 
-	//ETH API service
-	go i.ethService.Run()
-
 	//Lachesis API service
 	go i.service.Serve()
 
@@ -159,3 +159,16 @@ func (i *InmemEngine) Run() error {
 
 	return nil
 }
+
+// Stop shuts the Lachesis node down
+func (i *InmemEngine) Stop() error {
+	i.node.Shutdown()
+	return nil
+}
+
+// Info returns the stats of the Lachesis node
+func (i *InmemEngine) Info() (map[string]string, error) {
+	info := i.node.GetStats()
+	info["type"] = "inmem"
+	return info, nil
+}
@@ -1,71 +1,341 @@
 package engine
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/sirupsen/logrus"
 
 	"github.com/Fantom-foundation/go-evm/src/config"
+	"github.com/Fantom-foundation/go-evm/src/consensus"
 	"github.com/Fantom-foundation/go-evm/src/service"
 	"github.com/Fantom-foundation/go-evm/src/state"
 	"github.com/Fantom-foundation/go-lachesis/src/poset"
 	"github.com/Fantom-foundation/go-lachesis/src/proxy"
 )
 
+func init() {
+	consensus.Register("socket", func(conf config.Config, logger *logrus.Logger) (consensus.Consensus, error) {
+		engine, err := NewSocketEngine(conf, logger)
+		if err != nil {
+			return nil, err
+		}
+		return engine, nil
+	})
+}
+
+// proxyBackoff is the delay before the first attempt to reconnect to the
+// proxy, doubled after each failed attempt up to the maximum backoff
+const proxyBackoff = 500 * time.Millisecond
+
+// SocketEngine implements the Consensus interface. It relays the transactions
+// to a Lachesis node running in a separate process, through its proxy, and
+// processes the blocks committed by the proxy. When the proxy is unreachable,
+// e.g. while it restarts, the transactions are buffered and the engine
+// reconnects with exponential backoff, then resubmits them. Given several
+// proxies, the engine fails over to the next one when the current one becomes
+// unreachable. When the State falls behind the proxy, the engine stops pulling
+// blocks until it catches up.
 type SocketEngine struct {
-	service  *service.Service
-	state    *state.State
-	proxy    *proxy.GrpcLachesisProxy
-	submitCh chan []byte
-	logger   *logrus.Logger
+	service     *service.Service
+	state       *state.State
+	proxy       *proxy.GrpcLachesisProxy
+	proxyAddrs  []string
+	maxBackoff  time.Duration
+	bufferSize  int
+	maxBlockLag int64
+	submitCh    chan []byte
+	blocks      chan queuedBlock
+	processed   chan struct{}
+	stop        chan struct{}
+	done        chan struct{}
+	logger      *logrus.Logger
+
+	// the proxy in use, whether it is reachable, the number of reconnections
+	// and of failovers to another proxy, the transactions waiting for it, and
+	// the index of the last block it committed
+	syncLock   sync.Mutex
+	current    int
+	connected  bool
+	failovers  int
+	reconnects int
+	buffered   [][]byte
+	highest    int64
 }
 
+// NewSocketEngine connects to the proxy of a Lachesis node, the first
+// reachable one of a comma-separated list. If none is reachable, the engine
+// keeps trying to connect when it runs.
 func NewSocketEngine(config config.Config, logger *logrus.Logger) (*SocketEngine, error) {
-	submitCh := make(chan []byte)
+	logger.WithFields(logrus.Fields{
+		"config": config}).Debug("NewSocketEngine")
 
-	state, err := state.NewState(logger,
-		config.Eth.DbFile,
-		config.Eth.Cache)
-	if err != nil {
-		return nil, err
+	addrs := []string{}
+	for _, addr := range strings.Split(config.ProxyAddr, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no Lachesis proxy address")
 	}
 
-	service := service.NewService(config.Eth.Genesis,
-		config.Eth.Keystore,
-		config.Eth.EthAPIAddr,
-		config.Eth.PwdFile,
-		state,
-		submitCh,
-		logger)
+	queue := config.MaxBlockLag
+	if queue < 1 {
+		queue = 1
+	}
+	s := &SocketEngine{
+		proxyAddrs:  addrs,
+		maxBackoff:  config.ProxyMaxBackoff,
+		bufferSize:  config.ProxyBuffer,
+		maxBlockLag: config.MaxBlockLag,
+		blocks:      make(chan queuedBlock, queue),
+		processed:   make(chan struct{}),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+		logger:      logger,
+	}
+	if err := s.dialAny(); err != nil {
+		logger.WithError(err).Warn("No Lachesis proxy reachable")
+	}
+	return s, nil
+}
 
-	logger.WithFields(logrus.Fields{
-		"config": config}).Debug("NewSocketEngine")
+// syncStatus returns the progress of the node as seen from the proxy: the
+// proxy only reports the blocks it commits, so the node is in sync once it
+// has processed them.
+func (s *SocketEngine) syncStatus() service.SyncStatus {
+	s.syncLock.Lock()
+	defer s.syncLock.Unlock()
+
+	return service.SyncStatus{
+		Connected:    s.connected,
+		CurrentBlock: s.state.GetBlockIndex(),
+		HighestBlock: s.highest,
+	}
+}
+
+// proxyAddr returns the address of the proxy in use
+func (s *SocketEngine) proxyAddr() string {
+	s.syncLock.Lock()
+	defer s.syncLock.Unlock()
+
+	return s.proxyAddrs[s.current]
+}
 
-	lproxy, err := proxy.NewGrpcLachesisProxy(config.ProxyAddr, logger)
+// dial connects to the proxy in use
+func (s *SocketEngine) dial() error {
+	lproxy, err := proxy.NewGrpcLachesisProxy(s.proxyAddr(), s.logger)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	s.proxy = lproxy
 
-	return &SocketEngine{
-		service:  service,
-		state:    state,
-		proxy:    lproxy,
-		submitCh: submitCh,
-		logger:   logger,
-	}, nil
+	s.syncLock.Lock()
+	s.connected = true
+	s.syncLock.Unlock()
+	return nil
+}
+
+// dialAny connects to the first reachable proxy, from the one in use and in
+// the order of the list
+func (s *SocketEngine) dialAny() error {
+	var err error
+	for i := 0; i < len(s.proxyAddrs); i++ {
+		if err = s.dial(); err == nil {
+			return nil
+		}
+		s.logger.WithError(err).WithField("proxy", s.proxyAddr()).Warn("Lachesis proxy unreachable")
+		s.failover()
+	}
+	return err
+}
+
+// failover switches to the next proxy of the list, if any
+func (s *SocketEngine) failover() {
+	if len(s.proxyAddrs) < 2 {
+		return
+	}
+	s.syncLock.Lock()
+	s.current = (s.current + 1) % len(s.proxyAddrs)
+	s.failovers++
+	s.syncLock.Unlock()
+}
+
+// disconnect drops the connection to the proxy after it failed. The next
+// connection attempt is to the next proxy of the list.
+func (s *SocketEngine) disconnect(err error) {
+	s.logger.WithError(err).WithField("proxy", s.proxyAddr()).Error("Lost connection to Lachesis proxy")
+	if s.proxy != nil {
+		s.proxy.Close()
+		s.proxy = nil
+	}
+
+	s.syncLock.Lock()
+	s.connected = false
+	s.syncLock.Unlock()
+	s.failover()
+}
+
+// reconnect dials the proxy until it answers, waiting longer after each failed
+// attempt. The transactions submitted meanwhile are buffered. It returns false
+// if the engine was stopped first.
+func (s *SocketEngine) reconnect() bool {
+	backoff := proxyBackoff
+	for attempt := 1; ; attempt++ {
+		timer := time.NewTimer(backoff)
+	wait:
+		for {
+			select {
+			case tx := <-s.submitCh:
+				s.buffer(tx, fmt.Errorf("lachesis proxy unreachable"))
+			case <-timer.C:
+				break wait
+			case <-s.stop:
+				timer.Stop()
+				return false
+			}
+		}
+
+		err := s.dialAny()
+		if err == nil {
+			s.syncLock.Lock()
+			s.reconnects++
+			s.syncLock.Unlock()
+			s.logger.WithFields(logrus.Fields{
+				"proxy":    s.proxyAddr(),
+				"attempts": attempt,
+			}).Info("Reconnected to Lachesis proxy")
+			return true
+		}
+		s.logger.WithError(err).WithFields(logrus.Fields{
+			"attempt": attempt,
+			"backoff": backoff,
+		}).Warn("Reconnecting to Lachesis proxy")
+
+		if backoff *= 2; s.maxBackoff > 0 && backoff > s.maxBackoff {
+			backoff = s.maxBackoff
+		}
+	}
+}
+
+// buffer keeps a transaction to resubmit once the proxy is reachable again.
+// When the buffer is full, the transaction is moved to the dead-letter queue.
+func (s *SocketEngine) buffer(tx []byte, cause error) {
+	s.syncLock.Lock()
+	full := len(s.buffered) >= s.bufferSize
+	if !full {
+		s.buffered = append(s.buffered, tx)
+	}
+	s.syncLock.Unlock()
+
+	if full {
+		s.logger.Warn("Proxy buffer full. Moving tx to dead-letter queue")
+		if _, err := s.state.AddDeadLetter(tx, 1, cause); err != nil {
+			s.logger.WithError(err).Error("Adding tx to dead-letter queue")
+		}
+	}
+}
+
+// submit relays a transaction to the proxy. If the proxy fails, the
+// transaction is buffered and the connection dropped.
+func (s *SocketEngine) submit(tx []byte) {
+	if s.proxy == nil {
+		s.buffer(tx, fmt.Errorf("lachesis proxy unreachable"))
+		return
+	}
+	s.logger.Debug("proxy about to submit tx")
+	if err := s.proxy.SubmitTx(tx); err != nil {
+		s.buffer(tx, err)
+		s.disconnect(err)
+		return
+	}
+	s.logger.Debug("proxy submitted tx")
+}
+
+// resubmit relays the buffered transactions, in the order they were
+// submitted, after a reconnection
+func (s *SocketEngine) resubmit() {
+	s.syncLock.Lock()
+	txs := s.buffered
+	s.buffered = nil
+	s.syncLock.Unlock()
+
+	if len(txs) > 0 {
+		s.logger.WithField("txs", len(txs)).Info("Resubmitting buffered transactions")
+	}
+	for _, tx := range txs {
+		s.submit(tx)
+	}
+}
+
+// queuedBlock is a block committed by the proxy waiting to be processed, with
+// the function answering the proxy
+type queuedBlock struct {
+	block   poset.Block
+	respond func(stateHash []byte, err error)
+}
+
+// process applies the blocks pulled from the proxy, in order, and answers the
+// proxy with the resulting state hashes
+func (s *SocketEngine) process() {
+	defer close(s.processed)
+	for b := range s.blocks {
+		// A block replayed after a reconnection is not applied twice (see
+		// State.ProcessBlock)
+		stateHash, err := s.state.ProcessBlock(b.block)
+		b.respond(stateHash.Bytes(), err)
+	}
 }
 
 func (s *SocketEngine) serve() {
+	defer func() {
+		// The blocks already pulled are processed before stopping
+		close(s.blocks)
+		<-s.processed
+		close(s.done)
+	}()
 	for {
+		if s.proxy == nil {
+			if !s.reconnect() {
+				return
+			}
+			s.resubmit()
+			continue
+		}
+
+		// While the State is behind, the blocks wait in the proxy instead of
+		// piling up in memory
+		commits := s.proxy.CommitCh()
+		resume := s.state.Backpressure()
+		if resume != nil {
+			commits = nil
+		}
+
 		select {
+		case <-s.stop:
+			return
 		case tx := <-s.submitCh:
-			s.logger.Debug("proxy about to submit tx")
-			if err := s.proxy.SubmitTx(tx); err != nil {
-				s.logger.WithError(err).Error("SubmitTx")
+			s.submit(tx)
+		case <-resume:
+		case commit, ok := <-commits:
+			if !ok {
+				s.disconnect(fmt.Errorf("commit channel closed"))
+				continue
 			}
-			s.logger.Debug("proxy submitted tx")
-		case commit := <-s.proxy.CommitCh():
 			s.logger.Debug("CommitBlock")
-			stateHash, err := s.state.ProcessBlock(commit.Block)
-			commit.Respond(stateHash.Bytes(), err)
+			index := commit.Block.Index()
+			s.syncLock.Lock()
+			s.connected = true
+			if index > s.highest {
+				s.highest = index
+			}
+			s.syncLock.Unlock()
+			s.state.BlockDelivered(index)
+			s.blocks <- queuedBlock{block: commit.Block, respond: commit.Respond}
 		}
 	}
 }
@@ -109,14 +379,70 @@ func NewHandler(state *state.State) *Handler {
 }
 
 /*******************************************************************************
-Implement Engine interface
+Implement Consensus interface
 *******************************************************************************/
 
+// Init relays the transactions submitted to the Service to the proxy
+func (s *SocketEngine) Init(state *state.State, service *service.Service) error {
+	s.state = state
+	s.service = service
+	s.submitCh = service.GetSubmitCh()
+	s.highest = state.GetBlockIndex()
+	state.SetMaxBlockLag(s.maxBlockLag)
+	service.SetSyncCallback(s.syncStatus)
+	return nil
+}
+
+// Run processes the blocks committed by the proxy
 func (s *SocketEngine) Run() error {
+	go s.process()
+	s.serve()
+	return nil
+}
 
-	go s.service.Run()
+// Stop waits for the block being processed, then closes the connection to the
+// proxy. The buffered transactions are moved to the dead-letter queue, so that
+// they can be retried after a restart.
+func (s *SocketEngine) Stop() error {
+	close(s.stop)
+	<-s.done
 
-	s.serve()
+	s.syncLock.Lock()
+	txs := s.buffered
+	s.buffered = nil
+	s.syncLock.Unlock()
+	for _, tx := range txs {
+		if _, err := s.state.AddDeadLetter(tx, 0, fmt.Errorf("node shut down before the proxy was reachable")); err != nil {
+			s.logger.WithError(err).Error("Adding tx to dead-letter queue")
+		}
+	}
 
-	return nil
+	if s.proxy == nil {
+		return nil
+	}
+	return s.proxy.Close()
+}
+
+// Info returns the address of the proxy in use, whether it is reachable, how
+// many times the engine reconnected or failed over, how many transactions wait
+// for it and how many of its blocks wait to be committed
+func (s *SocketEngine) Info() (map[string]string, error) {
+	status := s.syncStatus()
+
+	s.syncLock.Lock()
+	reconnects, failovers, buffered := s.reconnects, s.failovers, len(s.buffered)
+	s.syncLock.Unlock()
+
+	return map[string]string{
+		"type":             "socket",
+		"proxy":            s.proxyAddr(),
+		"proxies":          strings.Join(s.proxyAddrs, ","),
+		"connected":        strconv.FormatBool(status.Connected),
+		"reconnects":       strconv.Itoa(reconnects),
+		"failovers":        strconv.Itoa(failovers),
+		"buffered_txs":     strconv.Itoa(buffered),
+		"queued_blocks":    strconv.Itoa(len(s.blocks)),
+		"block_lag":        strconv.FormatInt(s.state.BlockLag(), 10),
+		"last_block_index": strconv.FormatInt(status.HighestBlock, 10),
+	}, nil
 }
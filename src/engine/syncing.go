@@ -0,0 +1,26 @@
+package engine
+
+import (
+	"strconv"
+
+	"github.com/Fantom-foundation/go-evm/src/service"
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+// consensusSyncStatus returns the progress of the node from the info of the
+// consensus system. Lachesis reports the index of the last block it ordered,
+// and whether it is catching up with its peers. Other consensus systems are
+// considered in sync.
+func consensusSyncStatus(st *state.State, info map[string]string, err error) service.SyncStatus {
+	current := st.GetBlockIndex()
+	status := service.SyncStatus{
+		Connected:    err == nil,
+		CurrentBlock: current,
+		HighestBlock: current,
+	}
+	if highest, err := strconv.ParseInt(info["last_block_index"], 10, 64); err == nil && highest > current {
+		status.HighestBlock = highest
+	}
+	status.CatchingUp = info["state"] == "CatchingUp"
+	return status
+}
@@ -0,0 +1,161 @@
+// Package evmpb holds the messages and the service of the gRPC API of the evm
+// node, described in evm.proto. The messages are encoded from the protobuf
+// tags of their fields, so they must be kept in line with evm.proto.
+package evmpb
+
+import (
+	"github.com/golang/protobuf/proto"
+)
+
+type RawTx struct {
+	Tx []byte `protobuf:"bytes,1,opt,name=tx,proto3" json:"tx,omitempty"`
+}
+
+func (m *RawTx) Reset()         { *m = RawTx{} }
+func (m *RawTx) String() string { return proto.CompactTextString(m) }
+func (*RawTx) ProtoMessage()    {}
+
+type TxHash struct {
+	Hash []byte `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+}
+
+func (m *TxHash) Reset()         { *m = TxHash{} }
+func (m *TxHash) String() string { return proto.CompactTextString(m) }
+func (*TxHash) ProtoMessage()    {}
+
+type SubmitResult struct {
+	Hash  []byte `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	Error string `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (m *SubmitResult) Reset()         { *m = SubmitResult{} }
+func (m *SubmitResult) String() string { return proto.CompactTextString(m) }
+func (*SubmitResult) ProtoMessage()    {}
+
+type CallRequest struct {
+	From        []byte `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To          []byte `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	Gas         uint64 `protobuf:"varint,3,opt,name=gas,proto3" json:"gas,omitempty"`
+	GasPrice    []byte `protobuf:"bytes,4,opt,name=gas_price,json=gasPrice,proto3" json:"gas_price,omitempty"`
+	Value       []byte `protobuf:"bytes,5,opt,name=value,proto3" json:"value,omitempty"`
+	Data        []byte `protobuf:"bytes,6,opt,name=data,proto3" json:"data,omitempty"`
+	BlockNumber uint64 `protobuf:"varint,7,opt,name=block_number,json=blockNumber,proto3" json:"block_number,omitempty"`
+}
+
+func (m *CallRequest) Reset()         { *m = CallRequest{} }
+func (m *CallRequest) String() string { return proto.CompactTextString(m) }
+func (*CallRequest) ProtoMessage()    {}
+
+type CallResult struct {
+	Return       []byte `protobuf:"bytes,1,opt,name=return,proto3" json:"return,omitempty"`
+	Failed       bool   `protobuf:"varint,2,opt,name=failed,proto3" json:"failed,omitempty"`
+	GasUsed      uint64 `protobuf:"varint,3,opt,name=gas_used,json=gasUsed,proto3" json:"gas_used,omitempty"`
+	RevertReason string `protobuf:"bytes,4,opt,name=revert_reason,json=revertReason,proto3" json:"revert_reason,omitempty"`
+}
+
+func (m *CallResult) Reset()         { *m = CallResult{} }
+func (m *CallResult) String() string { return proto.CompactTextString(m) }
+func (*CallResult) ProtoMessage()    {}
+
+type Log struct {
+	Address     []byte   `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Topics      [][]byte `protobuf:"bytes,2,rep,name=topics,proto3" json:"topics,omitempty"`
+	Data        []byte   `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	BlockNumber uint64   `protobuf:"varint,4,opt,name=block_number,json=blockNumber,proto3" json:"block_number,omitempty"`
+	TxHash      []byte   `protobuf:"bytes,5,opt,name=tx_hash,json=txHash,proto3" json:"tx_hash,omitempty"`
+	TxIndex     uint32   `protobuf:"varint,6,opt,name=tx_index,json=txIndex,proto3" json:"tx_index,omitempty"`
+	BlockHash   []byte   `protobuf:"bytes,7,opt,name=block_hash,json=blockHash,proto3" json:"block_hash,omitempty"`
+	Index       uint32   `protobuf:"varint,8,opt,name=index,proto3" json:"index,omitempty"`
+}
+
+func (m *Log) Reset()         { *m = Log{} }
+func (m *Log) String() string { return proto.CompactTextString(m) }
+func (*Log) ProtoMessage()    {}
+
+type Receipt struct {
+	TxHash            []byte `protobuf:"bytes,1,opt,name=tx_hash,json=txHash,proto3" json:"tx_hash,omitempty"`
+	TxIndex           uint64 `protobuf:"varint,2,opt,name=tx_index,json=txIndex,proto3" json:"tx_index,omitempty"`
+	BlockHash         []byte `protobuf:"bytes,3,opt,name=block_hash,json=blockHash,proto3" json:"block_hash,omitempty"`
+	BlockNumber       uint64 `protobuf:"varint,4,opt,name=block_number,json=blockNumber,proto3" json:"block_number,omitempty"`
+	From              []byte `protobuf:"bytes,5,opt,name=from,proto3" json:"from,omitempty"`
+	To                []byte `protobuf:"bytes,6,opt,name=to,proto3" json:"to,omitempty"`
+	ContractAddress   []byte `protobuf:"bytes,7,opt,name=contract_address,json=contractAddress,proto3" json:"contract_address,omitempty"`
+	GasUsed           uint64 `protobuf:"varint,8,opt,name=gas_used,json=gasUsed,proto3" json:"gas_used,omitempty"`
+	CumulativeGasUsed uint64 `protobuf:"varint,9,opt,name=cumulative_gas_used,json=cumulativeGasUsed,proto3" json:"cumulative_gas_used,omitempty"`
+	Status            uint64 `protobuf:"varint,10,opt,name=status,proto3" json:"status,omitempty"`
+	Logs              []*Log `protobuf:"bytes,11,rep,name=logs,proto3" json:"logs,omitempty"`
+	LogsBloom         []byte `protobuf:"bytes,12,opt,name=logs_bloom,json=logsBloom,proto3" json:"logs_bloom,omitempty"`
+	RevertReason      string `protobuf:"bytes,13,opt,name=revert_reason,json=revertReason,proto3" json:"revert_reason,omitempty"`
+}
+
+func (m *Receipt) Reset()         { *m = Receipt{} }
+func (m *Receipt) String() string { return proto.CompactTextString(m) }
+func (*Receipt) ProtoMessage()    {}
+
+type BlockRequest struct {
+	Hash   []byte `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	Number uint64 `protobuf:"varint,2,opt,name=number,proto3" json:"number,omitempty"`
+	Latest bool   `protobuf:"varint,3,opt,name=latest,proto3" json:"latest,omitempty"`
+}
+
+func (m *BlockRequest) Reset()         { *m = BlockRequest{} }
+func (m *BlockRequest) String() string { return proto.CompactTextString(m) }
+func (*BlockRequest) ProtoMessage()    {}
+
+type Block struct {
+	Number           uint64   `protobuf:"varint,1,opt,name=number,proto3" json:"number,omitempty"`
+	Hash             []byte   `protobuf:"bytes,2,opt,name=hash,proto3" json:"hash,omitempty"`
+	ParentHash       []byte   `protobuf:"bytes,3,opt,name=parent_hash,json=parentHash,proto3" json:"parent_hash,omitempty"`
+	ConsensusHash    []byte   `protobuf:"bytes,4,opt,name=consensus_hash,json=consensusHash,proto3" json:"consensus_hash,omitempty"`
+	Timestamp        uint64   `protobuf:"varint,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	StateRoot        []byte   `protobuf:"bytes,6,opt,name=state_root,json=stateRoot,proto3" json:"state_root,omitempty"`
+	TransactionsRoot []byte   `protobuf:"bytes,7,opt,name=transactions_root,json=transactionsRoot,proto3" json:"transactions_root,omitempty"`
+	ReceiptsRoot     []byte   `protobuf:"bytes,8,opt,name=receipts_root,json=receiptsRoot,proto3" json:"receipts_root,omitempty"`
+	GasUsed          uint64   `protobuf:"varint,9,opt,name=gas_used,json=gasUsed,proto3" json:"gas_used,omitempty"`
+	Transactions     [][]byte `protobuf:"bytes,10,rep,name=transactions,proto3" json:"transactions,omitempty"`
+}
+
+func (m *Block) Reset()         { *m = Block{} }
+func (m *Block) String() string { return proto.CompactTextString(m) }
+func (*Block) ProtoMessage()    {}
+
+type SubscribeBlocksRequest struct {
+	FromNumber uint64 `protobuf:"varint,1,opt,name=from_number,json=fromNumber,proto3" json:"from_number,omitempty"`
+}
+
+func (m *SubscribeBlocksRequest) Reset()         { *m = SubscribeBlocksRequest{} }
+func (m *SubscribeBlocksRequest) String() string { return proto.CompactTextString(m) }
+func (*SubscribeBlocksRequest) ProtoMessage()    {}
+
+type Topics struct {
+	Alternatives [][]byte `protobuf:"bytes,1,rep,name=alternatives,proto3" json:"alternatives,omitempty"`
+}
+
+func (m *Topics) Reset()         { *m = Topics{} }
+func (m *Topics) String() string { return proto.CompactTextString(m) }
+func (*Topics) ProtoMessage()    {}
+
+type LogFilter struct {
+	Addresses [][]byte  `protobuf:"bytes,1,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	Topics    []*Topics `protobuf:"bytes,2,rep,name=topics,proto3" json:"topics,omitempty"`
+	FromBlock uint64    `protobuf:"varint,3,opt,name=from_block,json=fromBlock,proto3" json:"from_block,omitempty"`
+}
+
+func (m *LogFilter) Reset()         { *m = LogFilter{} }
+func (m *LogFilter) String() string { return proto.CompactTextString(m) }
+func (*LogFilter) ProtoMessage()    {}
+
+func init() {
+	proto.RegisterType((*RawTx)(nil), "evm.RawTx")
+	proto.RegisterType((*TxHash)(nil), "evm.TxHash")
+	proto.RegisterType((*SubmitResult)(nil), "evm.SubmitResult")
+	proto.RegisterType((*CallRequest)(nil), "evm.CallRequest")
+	proto.RegisterType((*CallResult)(nil), "evm.CallResult")
+	proto.RegisterType((*Log)(nil), "evm.Log")
+	proto.RegisterType((*Receipt)(nil), "evm.Receipt")
+	proto.RegisterType((*BlockRequest)(nil), "evm.BlockRequest")
+	proto.RegisterType((*Block)(nil), "evm.Block")
+	proto.RegisterType((*SubscribeBlocksRequest)(nil), "evm.SubscribeBlocksRequest")
+	proto.RegisterType((*Topics)(nil), "evm.Topics")
+	proto.RegisterType((*LogFilter)(nil), "evm.LogFilter")
+}
@@ -0,0 +1,317 @@
+package evmpb
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// serviceName is the full name of the EVM service of evm.proto
+const serviceName = "evm.EVM"
+
+// EVMServer is the server API of the EVM service
+type EVMServer interface {
+	SubmitTx(context.Context, *RawTx) (*TxHash, error)
+	SubmitTxs(EVM_SubmitTxsServer) error
+	Call(context.Context, *CallRequest) (*CallResult, error)
+	GetReceipt(context.Context, *TxHash) (*Receipt, error)
+	GetBlock(context.Context, *BlockRequest) (*Block, error)
+	SubscribeBlocks(*SubscribeBlocksRequest, EVM_SubscribeBlocksServer) error
+	SubscribeLogs(*LogFilter, EVM_SubscribeLogsServer) error
+}
+
+// RegisterEVMServer registers the EVM service on a gRPC server
+func RegisterEVMServer(s *grpc.Server, srv EVMServer) {
+	s.RegisterService(&serviceDesc, srv)
+}
+
+// EVM_SubmitTxsServer is the server side of the SubmitTxs stream
+type EVM_SubmitTxsServer interface {
+	Send(*SubmitResult) error
+	Recv() (*RawTx, error)
+	grpc.ServerStream
+}
+
+type submitTxsServer struct {
+	grpc.ServerStream
+}
+
+func (x *submitTxsServer) Send(m *SubmitResult) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *submitTxsServer) Recv() (*RawTx, error) {
+	m := new(RawTx)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// EVM_SubscribeBlocksServer is the server side of the SubscribeBlocks stream
+type EVM_SubscribeBlocksServer interface {
+	Send(*Block) error
+	grpc.ServerStream
+}
+
+type subscribeBlocksServer struct {
+	grpc.ServerStream
+}
+
+func (x *subscribeBlocksServer) Send(m *Block) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// EVM_SubscribeLogsServer is the server side of the SubscribeLogs stream
+type EVM_SubscribeLogsServer interface {
+	Send(*Log) error
+	grpc.ServerStream
+}
+
+type subscribeLogsServer struct {
+	grpc.ServerStream
+}
+
+func (x *subscribeLogsServer) Send(m *Log) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// unaryHandler returns the gRPC handler of a unary method, decoding its
+// request into a new message and calling call with it
+func unaryHandler(method string, newReq func() interface{}, call func(EVMServer, context.Context, interface{}) (interface{}, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: method,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := newReq()
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return call(srv.(EVMServer), ctx, in)
+			}
+			info := &grpc.UnaryServerInfo{
+				Server:     srv,
+				FullMethod: "/" + serviceName + "/" + method,
+			}
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return call(srv.(EVMServer), ctx, req)
+			}
+			return interceptor(ctx, in, info, handler)
+		},
+	}
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*EVMServer)(nil),
+	Methods: []grpc.MethodDesc{
+		unaryHandler("SubmitTx", func() interface{} { return new(RawTx) },
+			func(s EVMServer, ctx context.Context, in interface{}) (interface{}, error) {
+				return s.SubmitTx(ctx, in.(*RawTx))
+			}),
+		unaryHandler("Call", func() interface{} { return new(CallRequest) },
+			func(s EVMServer, ctx context.Context, in interface{}) (interface{}, error) {
+				return s.Call(ctx, in.(*CallRequest))
+			}),
+		unaryHandler("GetReceipt", func() interface{} { return new(TxHash) },
+			func(s EVMServer, ctx context.Context, in interface{}) (interface{}, error) {
+				return s.GetReceipt(ctx, in.(*TxHash))
+			}),
+		unaryHandler("GetBlock", func() interface{} { return new(BlockRequest) },
+			func(s EVMServer, ctx context.Context, in interface{}) (interface{}, error) {
+				return s.GetBlock(ctx, in.(*BlockRequest))
+			}),
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName: "SubmitTxs",
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				return srv.(EVMServer).SubmitTxs(&submitTxsServer{stream})
+			},
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName: "SubscribeBlocks",
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				m := new(SubscribeBlocksRequest)
+				if err := stream.RecvMsg(m); err != nil {
+					return err
+				}
+				return srv.(EVMServer).SubscribeBlocks(m, &subscribeBlocksServer{stream})
+			},
+			ServerStreams: true,
+		},
+		{
+			StreamName: "SubscribeLogs",
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				m := new(LogFilter)
+				if err := stream.RecvMsg(m); err != nil {
+					return err
+				}
+				return srv.(EVMServer).SubscribeLogs(m, &subscribeLogsServer{stream})
+			},
+			ServerStreams: true,
+		},
+	},
+	Metadata: "evm.proto",
+}
+
+// EVMClient is the client API of the EVM service
+type EVMClient interface {
+	SubmitTx(ctx context.Context, in *RawTx, opts ...grpc.CallOption) (*TxHash, error)
+	SubmitTxs(ctx context.Context, opts ...grpc.CallOption) (EVM_SubmitTxsClient, error)
+	Call(ctx context.Context, in *CallRequest, opts ...grpc.CallOption) (*CallResult, error)
+	GetReceipt(ctx context.Context, in *TxHash, opts ...grpc.CallOption) (*Receipt, error)
+	GetBlock(ctx context.Context, in *BlockRequest, opts ...grpc.CallOption) (*Block, error)
+	SubscribeBlocks(ctx context.Context, in *SubscribeBlocksRequest, opts ...grpc.CallOption) (EVM_SubscribeBlocksClient, error)
+	SubscribeLogs(ctx context.Context, in *LogFilter, opts ...grpc.CallOption) (EVM_SubscribeLogsClient, error)
+}
+
+type evmClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewEVMClient returns a client of the EVM service of a connection
+func NewEVMClient(cc *grpc.ClientConn) EVMClient {
+	return &evmClient{cc}
+}
+
+func (c *evmClient) invoke(ctx context.Context, method string, in, out interface{}, opts []grpc.CallOption) error {
+	return c.cc.Invoke(ctx, "/"+serviceName+"/"+method, in, out, opts...)
+}
+
+func (c *evmClient) SubmitTx(ctx context.Context, in *RawTx, opts ...grpc.CallOption) (*TxHash, error) {
+	out := new(TxHash)
+	if err := c.invoke(ctx, "SubmitTx", in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *evmClient) Call(ctx context.Context, in *CallRequest, opts ...grpc.CallOption) (*CallResult, error) {
+	out := new(CallResult)
+	if err := c.invoke(ctx, "Call", in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *evmClient) GetReceipt(ctx context.Context, in *TxHash, opts ...grpc.CallOption) (*Receipt, error) {
+	out := new(Receipt)
+	if err := c.invoke(ctx, "GetReceipt", in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *evmClient) GetBlock(ctx context.Context, in *BlockRequest, opts ...grpc.CallOption) (*Block, error) {
+	out := new(Block)
+	if err := c.invoke(ctx, "GetBlock", in, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// newStream opens the stream of a method, sending its request if it isn't a
+// client stream
+func (c *evmClient) newStream(ctx context.Context, index int, in interface{}, opts []grpc.CallOption) (grpc.ClientStream, error) {
+	desc := &serviceDesc.Streams[index]
+	stream, err := c.cc.NewStream(ctx, desc, "/"+serviceName+"/"+desc.StreamName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if in == nil {
+		return stream, nil
+	}
+	if err := stream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+// EVM_SubmitTxsClient is the client side of the SubmitTxs stream
+type EVM_SubmitTxsClient interface {
+	Send(*RawTx) error
+	Recv() (*SubmitResult, error)
+	grpc.ClientStream
+}
+
+type submitTxsClient struct {
+	grpc.ClientStream
+}
+
+func (c *evmClient) SubmitTxs(ctx context.Context, opts ...grpc.CallOption) (EVM_SubmitTxsClient, error) {
+	stream, err := c.newStream(ctx, 0, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &submitTxsClient{stream}, nil
+}
+
+func (x *submitTxsClient) Send(m *RawTx) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *submitTxsClient) Recv() (*SubmitResult, error) {
+	m := new(SubmitResult)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// EVM_SubscribeBlocksClient is the client side of the SubscribeBlocks stream
+type EVM_SubscribeBlocksClient interface {
+	Recv() (*Block, error)
+	grpc.ClientStream
+}
+
+type subscribeBlocksClient struct {
+	grpc.ClientStream
+}
+
+func (c *evmClient) SubscribeBlocks(ctx context.Context, in *SubscribeBlocksRequest, opts ...grpc.CallOption) (EVM_SubscribeBlocksClient, error) {
+	stream, err := c.newStream(ctx, 1, in, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &subscribeBlocksClient{stream}, nil
+}
+
+func (x *subscribeBlocksClient) Recv() (*Block, error) {
+	m := new(Block)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// EVM_SubscribeLogsClient is the client side of the SubscribeLogs stream
+type EVM_SubscribeLogsClient interface {
+	Recv() (*Log, error)
+	grpc.ClientStream
+}
+
+type subscribeLogsClient struct {
+	grpc.ClientStream
+}
+
+func (c *evmClient) SubscribeLogs(ctx context.Context, in *LogFilter, opts ...grpc.CallOption) (EVM_SubscribeLogsClient, error) {
+	stream, err := c.newStream(ctx, 2, in, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &subscribeLogsClient{stream}, nil
+}
+
+func (x *subscribeLogsClient) Recv() (*Log, error) {
+	m := new(Log)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
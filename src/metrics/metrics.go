@@ -0,0 +1,209 @@
+// Package metrics holds the counters and histograms of the node, written in
+// the Prometheus text format on the /metrics endpoint of the admin dashboard.
+// Metrics are registered once, when their package is initialized. Metrics
+// with the same name and different labels are written as one family.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the buckets of the
+// duration histograms
+var DefaultBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// metric is a series of a family
+type metric interface {
+	write(w io.Writer, name, labels string)
+}
+
+type family struct {
+	name   string
+	help   string
+	typ    string
+	series []series
+}
+
+type series struct {
+	labels string
+	metric metric
+}
+
+var (
+	registryMu sync.Mutex
+	families   []*family
+)
+
+// register adds a metric to its family. labels are name and value pairs.
+func register(name, help, typ string, m metric, labels []string) {
+	if len(labels)%2 != 0 {
+		panic(fmt.Sprintf("metric %s: odd number of label names and values", name))
+	}
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", labels[i], labels[i+1]))
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	for _, f := range families {
+		if f.name == name {
+			f.series = append(f.series, series{labels: strings.Join(pairs, ","), metric: m})
+			return
+		}
+	}
+	families = append(families, &family{
+		name:   name,
+		help:   help,
+		typ:    typ,
+		series: []series{{labels: strings.Join(pairs, ","), metric: m}},
+	})
+}
+
+// WritePrometheus writes the registered metrics in the Prometheus text format
+func WritePrometheus(w io.Writer) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	for _, f := range families {
+		fmt.Fprintf(w, "# HELP %s %s\n", f.name, f.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.typ)
+		for _, s := range f.series {
+			s.metric.write(w, f.name, s.labels)
+		}
+	}
+}
+
+// withLabels returns a series name with its labels and an extra one
+func withLabels(name, labels, extra string) string {
+	switch {
+	case labels == "" && extra == "":
+		return name
+	case labels == "":
+		return name + "{" + extra + "}"
+	case extra == "":
+		return name + "{" + labels + "}"
+	default:
+		return name + "{" + labels + "," + extra + "}"
+	}
+}
+
+// Counter is a monotonic count
+type Counter struct {
+	value uint64
+}
+
+// NewCounter registers a counter. labels are name and value pairs
+// distinguishing it from the other counters of the same name.
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{}
+	register(name, help, "counter", c, labels)
+	return c
+}
+
+// Inc adds 1 to the counter
+func (c *Counter) Inc() {
+	atomic.AddUint64(&c.value, 1)
+}
+
+// Add adds n to the counter
+func (c *Counter) Add(n uint64) {
+	atomic.AddUint64(&c.value, n)
+}
+
+// Value returns the count
+func (c *Counter) Value() uint64 {
+	return atomic.LoadUint64(&c.value)
+}
+
+func (c *Counter) write(w io.Writer, name, labels string) {
+	fmt.Fprintf(w, "%s %d\n", withLabels(name, labels, ""), c.Value())
+}
+
+// Gauge is a value which goes up and down
+type Gauge struct {
+	value int64
+}
+
+// NewGauge registers a gauge. labels are name and value pairs distinguishing
+// it from the other gauges of the same name.
+func NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{}
+	register(name, help, "gauge", g, labels)
+	return g
+}
+
+// Set sets the value of the gauge
+func (g *Gauge) Set(v int64) {
+	atomic.StoreInt64(&g.value, v)
+}
+
+// Value returns the value of the gauge
+func (g *Gauge) Value() int64 {
+	return atomic.LoadInt64(&g.value)
+}
+
+func (g *Gauge) write(w io.Writer, name, labels string) {
+	fmt.Fprintf(w, "%s %d\n", withLabels(name, labels, ""), g.Value())
+}
+
+// Histogram counts observations, e.g. durations, in buckets
+type Histogram struct {
+	sync.Mutex
+	bounds []float64
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram registers a histogram with the given bucket upper bounds, or
+// DefaultBuckets if nil. labels are name and value pairs distinguishing it
+// from the other histograms of the same name.
+func NewHistogram(name, help string, bounds []float64, labels ...string) *Histogram {
+	if bounds == nil {
+		bounds = DefaultBuckets
+	}
+	h := &Histogram{
+		bounds: bounds,
+		counts: make([]uint64, len(bounds)),
+	}
+	register(name, help, "histogram", h, labels)
+	return h
+}
+
+// Observe adds an observation
+func (h *Histogram) Observe(v float64) {
+	h.Lock()
+	defer h.Unlock()
+
+	if i := sort.SearchFloat64s(h.bounds, v); i < len(h.bounds) {
+		h.counts[i]++
+	}
+	h.sum += v
+	h.count++
+}
+
+// Since observes the seconds elapsed since start
+func (h *Histogram) Since(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+func (h *Histogram) write(w io.Writer, name, labels string) {
+	h.Lock()
+	defer h.Unlock()
+
+	cumulative := uint64(0)
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i]
+		le := fmt.Sprintf("le=\"%g\"", bound)
+		fmt.Fprintf(w, "%s %d\n", withLabels(name+"_bucket", labels, le), cumulative)
+	}
+	fmt.Fprintf(w, "%s %d\n", withLabels(name+"_bucket", labels, `le="+Inf"`), h.count)
+	fmt.Fprintf(w, "%s %g\n", withLabels(name+"_sum", labels, ""), h.sum)
+	fmt.Fprintf(w, "%s %d\n", withLabels(name+"_count", labels, ""), h.count)
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// txNamespace groups the HTTP endpoints and JSON-RPC methods which submit
+	// or sign transactions
+	txNamespace = "tx"
+
+	// maximum size of a JSON-RPC request read to find its methods
+	maxRPCRequestSize = 5 * 1024 * 1024
+)
+
+// txMethods are the JSON-RPC methods in the tx namespace, though they belong
+// to the eth namespace
+var txMethods = map[string]bool{
+	"eth_sendTransaction":    true,
+	"eth_sendRawTransaction": true,
+	"eth_sign":               true,
+	"eth_signTransaction":    true,
+	"eth_resend":             true,
+}
+
+// APIKey grants access to protected namespaces. The key is sent as a bearer
+// token (Authorization: Bearer <key>) or as the password of basic auth.
+type APIKey struct {
+	Name       string   `json:"name"`
+	Key        string   `json:"key"`
+	Namespaces []string `json:"namespaces"`
+}
+
+// LoadAPIKeys reads a JSON list of APIKeys from a file
+func LoadAPIKeys(file string) ([]APIKey, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("parsing %s: %s", file, err)
+	}
+
+	names := make(map[string]bool)
+	for i, key := range keys {
+		if key.Name == "" {
+			return nil, fmt.Errorf("API key %d has no name", i)
+		}
+		if names[key.Name] {
+			return nil, fmt.Errorf("duplicate API key %q", key.Name)
+		}
+		names[key.Name] = true
+		if len(key.Key) < 16 {
+			return nil, fmt.Errorf("API key %q is shorter than 16 characters", key.Name)
+		}
+	}
+
+	return keys, nil
+}
+
+// apiAuth checks the API keys of the requests to the protected namespaces
+type apiAuth struct {
+	keys      []APIKey
+	protected map[string]bool
+}
+
+//SetAPIAuth protects the given namespaces: requests to them must carry an API
+//key granting them. The tx namespace covers the HTTP endpoints and JSON-RPC
+//methods submitting or signing transactions, and the admin namespace the
+//admin dashboard. The other endpoints stay public. IPC is not checked.
+func (m *Service) SetAPIAuth(keys []APIKey, namespaces []string) {
+	auth := &apiAuth{
+		keys:      keys,
+		protected: make(map[string]bool),
+	}
+	for _, ns := range namespaces {
+		auth.protected[ns] = true
+	}
+	m.apiAuth = auth
+
+	m.logger.WithFields(logrus.Fields{
+		"namespaces": strings.Join(namespaces, ","),
+		"keys":       len(keys),
+	}).Info("API authentication enabled")
+}
+
+// requestKey returns the API key sent with a request, if any
+func requestKey(r *http.Request) string {
+	if _, pwd, ok := r.BasicAuth(); ok {
+		return pwd
+	}
+	header := r.Header.Get("Authorization")
+	if strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimPrefix(header, "Bearer ")
+	}
+	return ""
+}
+
+// check returns the HTTP status and error of a request to namespaces, or 0 if
+// it is allowed
+func (a *apiAuth) check(r *http.Request, namespaces ...string) (int, error) {
+	required := []string{}
+	for _, ns := range namespaces {
+		if a.protected[ns] {
+			required = append(required, ns)
+		}
+	}
+	if len(required) == 0 {
+		return 0, nil
+	}
+
+	sent := requestKey(r)
+	if sent == "" {
+		return http.StatusUnauthorized, fmt.Errorf("API key required for %s", strings.Join(required, ","))
+	}
+	for _, key := range a.keys {
+		if subtle.ConstantTimeCompare([]byte(sent), []byte(key.Key)) != 1 {
+			continue
+		}
+		for _, ns := range required {
+			if !contains(key.Namespaces, ns) {
+				return http.StatusForbidden, fmt.Errorf("API key %s is not allowed %s", key.Name, ns)
+			}
+		}
+		return 0, nil
+	}
+	return http.StatusUnauthorized, fmt.Errorf("invalid API key")
+}
+
+// reject responds to a request refused by the authentication
+func (m *Service) reject(w http.ResponseWriter, r *http.Request, status int, err error) {
+	m.logger.WithError(err).WithFields(logrus.Fields{
+		"remote": r.RemoteAddr,
+		"path":   r.URL.Path,
+	}).Debug("Rejected unauthorized request")
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="evm"`)
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// requireAuth wraps the handler of an endpoint in a namespace, which requires
+// an API key granting the namespace if it is protected
+func requireAuth(namespace string, fn func(http.ResponseWriter, *http.Request, *Service)) func(http.ResponseWriter, *http.Request, *Service) {
+	return func(w http.ResponseWriter, r *http.Request, m *Service) {
+		if m.apiAuth != nil {
+			if status, err := m.apiAuth.check(r, namespace); err != nil {
+				m.reject(w, r, status, err)
+				return
+			}
+		}
+		fn(w, r, m)
+	}
+}
+
+// rpcNamespaces returns the namespaces of the methods of a JSON-RPC request or
+// batch. Like the JSON-RPC server, it decodes the first JSON value of the body,
+// and it fails if anything else follows, so that no call goes unchecked.
+func rpcNamespaces(body []byte) ([]string, error) {
+	type call struct {
+		Method string `json:"method"`
+	}
+	reader := bytes.NewReader(body)
+	dec := json.NewDecoder(reader)
+	var msg json.RawMessage
+	if err := dec.Decode(&msg); err != nil {
+		return nil, err
+	}
+	rest, err := ioutil.ReadAll(io.MultiReader(dec.Buffered(), reader))
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(rest)) > 0 {
+		return nil, fmt.Errorf("unexpected data after the request")
+	}
+
+	calls := []call{}
+	if trimmed := bytes.TrimSpace(msg); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(msg, &calls); err != nil {
+			return nil, err
+		}
+	} else {
+		var c call
+		if err := json.Unmarshal(msg, &c); err != nil {
+			return nil, err
+		}
+		calls = append(calls, c)
+	}
+
+	res := []string{}
+	for _, c := range calls {
+		if txMethods[c.Method] {
+			res = append(res, txNamespace)
+			continue
+		}
+		res = append(res, strings.SplitN(c.Method, "_", 2)[0])
+	}
+	return res, nil
+}
+
+// rpcAuthHandler checks the API keys of the JSON-RPC requests over HTTP
+// against the namespaces of their methods. The JSON-RPC server runs the body
+// of any request, whatever its method, so all the bodies are checked, and the
+// ones that can't be parsed are rejected.
+func (m *Service) rpcAuthHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxRPCRequestSize+1))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(body) > maxRPCRequestSize {
+			http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if len(bytes.TrimSpace(body)) == 0 {
+			// Nothing to run
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		namespaces, err := rpcNamespaces(body)
+		if err != nil {
+			m.reject(w, r, http.StatusBadRequest, fmt.Errorf("invalid JSON-RPC request: %s", err))
+			return
+		}
+		if status, err := m.apiAuth.check(r, namespaces...); err != nil {
+			m.reject(w, r, status, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// wsAuthHandler checks the API keys of the WebSocket connections. Their
+// requests can't be checked one by one, so the key must grant all the
+// protected namespaces served over WebSocket.
+func (m *Service) wsAuthHandler(next http.Handler, namespaces []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status, err := m.apiAuth.check(r, namespaces...); err != nil {
+			m.reject(w, r, status, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Fantom-foundation/go-evm/src/common"
+)
+
+const (
+	_personalKey = "personal-key-0123456789"
+	_txKey       = "tx-key-0123456789abcdef"
+)
+
+// newTestAuthHandler returns the JSON-RPC handler of a Service protecting the
+// personal and tx namespaces, and a flag telling whether a request got
+// through it
+func newTestAuthHandler(t *testing.T) (http.Handler, *bool) {
+	m := &Service{logger: common.NewTestLogger(t)}
+	m.SetAPIAuth([]APIKey{
+		{Name: "personal", Key: _personalKey, Namespaces: []string{"personal", txNamespace}},
+		{Name: "tx", Key: _txKey, Namespaces: []string{txNamespace}},
+	}, []string{"personal", txNamespace})
+
+	served := new(bool)
+	return m.rpcAuthHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*served = true
+	})), served
+}
+
+func TestRPCAuth(t *testing.T) {
+	const (
+		unlock = `{"jsonrpc":"2.0","id":1,"method":"personal_unlockAccount","params":[]}`
+		send   = `{"jsonrpc":"2.0","id":1,"method":"eth_sendRawTransaction","params":[]}`
+		block  = `{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`
+	)
+
+	cases := []struct {
+		name   string
+		method string
+		body   string
+		key    string
+		status int
+	}{
+		{"public method", http.MethodPost, block, "", http.StatusOK},
+		{"no key", http.MethodPost, unlock, "", http.StatusUnauthorized},
+		{"wrong key", http.MethodPost, unlock, "wrong-key-0123456789", http.StatusUnauthorized},
+		{"key of another namespace", http.MethodPost, unlock, _txKey, http.StatusForbidden},
+		{"granted key", http.MethodPost, unlock, _personalKey, http.StatusOK},
+		{"tx method", http.MethodPost, send, "", http.StatusUnauthorized},
+		{"tx method with key", http.MethodPost, send, _txKey, http.StatusOK},
+		{"batch", http.MethodPost, "[" + block + "," + unlock + "]", "", http.StatusUnauthorized},
+		{"batch with key", http.MethodPost, "[" + block + "," + unlock + "]", _personalKey, http.StatusOK},
+		{"body sent with GET", http.MethodGet, unlock, "", http.StatusUnauthorized},
+		{"body sent with PATCH", http.MethodPatch, unlock, "", http.StatusUnauthorized},
+		{"GET without body", http.MethodGet, "", "", http.StatusOK},
+		{"trailing data", http.MethodPost, unlock + " x", "", http.StatusBadRequest},
+		{"trailing data with key", http.MethodPost, unlock + " x", _personalKey, http.StatusBadRequest},
+		{"second request", http.MethodPost, block + unlock, "", http.StatusBadRequest},
+		{"invalid JSON", http.MethodPost, `{"method":`, "", http.StatusBadRequest},
+	}
+
+	for _, c := range cases {
+		handler, served := newTestAuthHandler(t)
+		r := httptest.NewRequest(c.method, "/", strings.NewReader(c.body))
+		r.Header.Set("Content-Type", "application/json")
+		if c.key != "" {
+			r.Header.Set("Authorization", "Bearer "+c.key)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != c.status {
+			t.Fatalf("%s: status should be %d, not %d (%s)", c.name, c.status, w.Code, w.Body.String())
+		}
+		if *served != (c.status == http.StatusOK) {
+			t.Fatalf("%s: request should be served only when it is allowed", c.name)
+		}
+	}
+}
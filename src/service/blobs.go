@@ -0,0 +1,90 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/gorilla/mux"
+
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+/*
+POST /rawblobtx
+data: JSON JsonBlobTx
+returns: JSON JsonTxRes
+
+Submits a signed transaction with blob data. The last 32 bytes of the data of
+the transaction must be the keccak256 hash of the blob data. The blob data is
+paid for at state.BlobGasPerByte on top of the gas used by the transaction, and
+is kept out of the state: it can be fetched on /blob/{tx_hash} until it is
+pruned.
+*/
+func rawBlobTransactionHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	var req JsonBlobTx
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var t ethTypes.Transaction
+	if err := rlp.DecodeBytes(req.Tx, &t); err != nil {
+		m.logger.WithError(err).Debug("Decoding Transaction")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	m.logger.WithField("hash", t.Hash().Hex()).Debug("Decoded blob tx")
+
+	ready, err := m.state.AddBlobTx(&t, req.Data)
+	if err != nil {
+		writeTxError(w, err, m)
+		return
+	}
+	for _, tx := range ready {
+		if err := m.submitTx(tx); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	writeJSON(w, JsonTxRes{TxHash: t.Hash().Hex()}, m)
+}
+
+/*
+GET /blob/{tx_hash}
+returns: JSON JsonBlob
+
+Returns the blob data of an applied blob transaction, with the hash of the data
+committed to the state. Data is empty, and pruned set, once the data was pruned.
+*/
+func blobHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	param := mux.Vars(r)["tx_hash"]
+	txHash := common.HexToHash(param)
+
+	dataHash, ok := m.state.GetBlobHash(txHash)
+	if !ok {
+		http.Error(w, "unknown blob transaction", http.StatusNotFound)
+		return
+	}
+
+	res := JsonBlob{TxHash: txHash, DataHash: dataHash}
+	data, err := m.state.GetBlob(txHash)
+	if err != nil {
+		res.Pruned = true
+	}
+	res.Data = data
+
+	writeJSON(w, res, m)
+}
+
+// encodeTx encodes a transaction for submission, with its blob data if it is
+// a blob transaction waiting in the TxPool
+func (m *Service) encodeTx(tx *ethTypes.Transaction) ([]byte, error) {
+	if data, ok := m.state.GetPoolBlob(tx.Hash()); ok {
+		return state.EncodeBlobTx(tx, data)
+	}
+	return rlp.EncodeToBytes(tx)
+}
@@ -0,0 +1,295 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Fantom-foundation/go-evm/src/metrics"
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+const (
+	// DefaultCommitSLOWindow is the default number of recent blocks the commit
+	// latency percentiles are computed over
+	DefaultCommitSLOWindow = 1000
+
+	// minimum number of blocks in the window before the SLO is checked
+	commitSLOMinSamples = 10
+)
+
+// sloPercentiles are the tracked percentiles of the commit latency
+var sloPercentiles = []int{50, 95, 99}
+
+// CommitSLO is the objective on the commit latency: the time from the
+// reception of a block from the consensus system to the end of its Commit.
+// A threshold of 0 is not checked.
+type CommitSLO struct {
+	P50 time.Duration
+	P95 time.Duration
+	P99 time.Duration
+
+	// number of recent blocks the percentiles are computed over
+	Window int
+
+	// URL notified of the breaches and recoveries of the thresholds, in
+	// addition to the log and the metrics. Disabled when empty
+	Webhook string
+}
+
+func (slo CommitSLO) threshold(percentile int) time.Duration {
+	switch percentile {
+	case 50:
+		return slo.P50
+	case 95:
+		return slo.P95
+	default:
+		return slo.P99
+	}
+}
+
+// commitLatency keeps the latencies of the recent blocks and the state of the
+// SLO
+type commitLatency struct {
+	sync.RWMutex
+	slo       CommitSLO
+	latencies []time.Duration // most recent last
+	samples   uint64          // all the blocks tracked
+	breached  map[int]bool    // percentiles over their threshold
+	alerts    uint64          // breaches since the start
+}
+
+func newCommitLatency(slo CommitSLO) *commitLatency {
+	if slo.Window <= 0 {
+		slo.Window = DefaultCommitSLOWindow
+	}
+	return &commitLatency{
+		slo:      slo,
+		breached: make(map[int]bool),
+	}
+}
+
+func (l *commitLatency) add(latency time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.latencies = append(l.latencies, latency)
+	if len(l.latencies) > l.slo.Window {
+		l.latencies = l.latencies[len(l.latencies)-l.slo.Window:]
+	}
+	l.samples++
+}
+
+// percentiles returns the tracked percentiles of the latencies of the window,
+// by nearest rank
+func (l *commitLatency) percentiles() map[int]time.Duration {
+	l.RLock()
+	sorted := make([]time.Duration, len(l.latencies))
+	copy(sorted, l.latencies)
+	l.RUnlock()
+
+	res := make(map[int]time.Duration)
+	if len(sorted) == 0 {
+		return res
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	for _, p := range sloPercentiles {
+		rank := (p*len(sorted) + 99) / 100
+		res[p] = sorted[rank-1]
+	}
+	return res
+}
+
+//SetCommitSLO sets the thresholds of the commit latency percentiles. A breach
+//is logged, counted in the metrics and posted to the webhook, once until the
+//percentile is back under its threshold.
+func (m *Service) SetCommitSLO(slo CommitSLO) {
+	m.commitLatency = newCommitLatency(slo)
+	if slo.P50 <= 0 && slo.P95 <= 0 && slo.P99 <= 0 {
+		return
+	}
+	m.logger.WithFields(logrus.Fields{
+		"p50":    slo.P50,
+		"p95":    slo.P95,
+		"p99":    slo.P99,
+		"window": m.commitLatency.slo.Window,
+	}).Info("Commit latency SLO enabled")
+}
+
+// runCommitSLO tracks the latency of the committed blocks and checks the SLO
+func (m *Service) runCommitSLO() {
+	commits := make(chan state.CommitInfo, 16)
+	sub := m.state.SubscribeCommits(commits)
+	defer sub.Unsubscribe()
+
+	client := &http.Client{Timeout: webhookTimeout}
+	for info := range commits {
+		if info.Latency <= 0 {
+			continue
+		}
+		m.commitLatency.add(info.Latency)
+		for _, alert := range m.checkCommitSLO(info.BlockIndex) {
+			if m.commitLatency.slo.Webhook == "" {
+				continue
+			}
+			js, err := json.Marshal(alert)
+			if err != nil {
+				m.logger.WithError(err).Error("Marshaling SLO alert")
+				continue
+			}
+			go m.postSLOAlert(client, m.commitLatency.slo.Webhook, js)
+		}
+	}
+}
+
+// checkCommitSLO compares the percentiles to their thresholds and returns the
+// alerts of the percentiles which crossed them
+func (m *Service) checkCommitSLO(blockIndex int64) []JsonSLOAlert {
+	l := m.commitLatency
+	l.RLock()
+	enough := len(l.latencies) >= commitSLOMinSamples
+	l.RUnlock()
+	if !enough {
+		return nil
+	}
+
+	percentiles := l.percentiles()
+	alerts := []JsonSLOAlert{}
+	for _, p := range sloPercentiles {
+		latency := percentiles[p]
+		threshold := l.slo.threshold(p)
+		if threshold <= 0 {
+			continue
+		}
+
+		l.Lock()
+		breached := latency > threshold
+		changed := breached != l.breached[p]
+		l.breached[p] = breached
+		if changed && breached {
+			l.alerts++
+		}
+		l.Unlock()
+		if !changed {
+			continue
+		}
+
+		alert := JsonSLOAlert{
+			Percentile: fmt.Sprintf("p%d", p),
+			Latency:    hexutil.Uint64(latency / time.Millisecond),
+			Threshold:  hexutil.Uint64(threshold / time.Millisecond),
+			Breached:   breached,
+			BlockIndex: hexutil.Uint64(blockIndex),
+			Time:       time.Now(),
+		}
+		entry := m.logger.WithFields(logrus.Fields{
+			"percentile": alert.Percentile,
+			"latency":    latency,
+			"threshold":  threshold,
+			"block":      blockIndex,
+		})
+		if breached {
+			entry.Warn("Commit latency SLO breached")
+		} else {
+			entry.Info("Commit latency back within SLO")
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts
+}
+
+func (m *Service) postSLOAlert(client *http.Client, url string, js []byte) {
+	resp, err := client.Post(url, "application/json", bytes.NewReader(js))
+	if err != nil {
+		m.logger.WithError(err).WithField("url", url).Warn("Posting SLO alert")
+		return
+	}
+	resp.Body.Close()
+}
+
+// commitLatencyStatus summarizes the commit latency for the dashboard
+func (m *Service) commitLatencyStatus() *JsonCommitLatency {
+	l := m.commitLatency
+	percentiles := l.percentiles()
+
+	l.RLock()
+	defer l.RUnlock()
+	res := &JsonCommitLatency{
+		Samples: hexutil.Uint64(len(l.latencies)),
+		P50:     hexutil.Uint64(percentiles[50] / time.Millisecond),
+		P95:     hexutil.Uint64(percentiles[95] / time.Millisecond),
+		P99:     hexutil.Uint64(percentiles[99] / time.Millisecond),
+		Alerts:  hexutil.Uint64(l.alerts),
+	}
+	for _, p := range sloPercentiles {
+		if l.breached[p] {
+			res.Breached = append(res.Breached, fmt.Sprintf("p%d", p))
+		}
+	}
+	return res
+}
+
+/*
+GET /metrics
+returns: Prometheus text format
+
+Serves the commit latency percentiles, the state of the commit latency SLO, the
+size of the txpool, the counters and histograms of the metrics package (applied
+transactions, commit and call durations, database operations, watched calls)
+and the event metrics (see --eth.event-metrics) as Prometheus metrics. It is
+served on the API address, so that it is available without an admin address,
+and on the admin address; both require an API key granting the admin namespace
+if it is protected.
+*/
+func metricsHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	l := m.commitLatency
+	percentiles := l.percentiles()
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "# HELP evm_commit_latency_seconds Time from the reception of a block to the end of its commit")
+	fmt.Fprintln(&buf, "# TYPE evm_commit_latency_seconds summary")
+	for _, p := range sloPercentiles {
+		if latency, ok := percentiles[p]; ok {
+			fmt.Fprintf(&buf, "evm_commit_latency_seconds{quantile=\"%.2f\"} %g\n", float64(p)/100, latency.Seconds())
+		}
+	}
+
+	l.RLock()
+	fmt.Fprintf(&buf, "evm_commit_latency_seconds_count %d\n", l.samples)
+	fmt.Fprintln(&buf, "# HELP evm_commit_slo_breached Whether a commit latency percentile is over its SLO threshold")
+	fmt.Fprintln(&buf, "# TYPE evm_commit_slo_breached gauge")
+	for _, p := range sloPercentiles {
+		if l.slo.threshold(p) <= 0 {
+			continue
+		}
+		breached := 0
+		if l.breached[p] {
+			breached = 1
+		}
+		fmt.Fprintf(&buf, "evm_commit_slo_breached{quantile=\"%.2f\"} %d\n", float64(p)/100, breached)
+	}
+	fmt.Fprintln(&buf, "# HELP evm_commit_slo_alerts_total Breaches of the commit latency SLO")
+	fmt.Fprintln(&buf, "# TYPE evm_commit_slo_alerts_total counter")
+	fmt.Fprintf(&buf, "evm_commit_slo_alerts_total %d\n", l.alerts)
+	l.RUnlock()
+
+	fmt.Fprintln(&buf, "# HELP evm_txpool_transactions Transactions of the txpool, pending consensus ordering or queued behind a nonce gap")
+	fmt.Fprintln(&buf, "# TYPE evm_txpool_transactions gauge")
+	fmt.Fprintf(&buf, "evm_txpool_transactions{status=\"pending\"} %d\n", m.state.GetPoolSize())
+	fmt.Fprintf(&buf, "evm_txpool_transactions{status=\"queued\"} %d\n", m.state.GetPoolQueued())
+
+	metrics.WritePrometheus(&buf)
+	m.writeEventMetrics(&buf)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		m.logger.WithError(err).Error("Writing metrics")
+	}
+}
@@ -0,0 +1,307 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Fantom-foundation/go-evm/src/service/templates"
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+const (
+	// number of commits and errors shown on the dashboard
+	dashboardCommits = 10
+	dashboardErrors  = 20
+)
+
+// LogEntry is an error logged by any component sharing the Service logger
+type LogEntry struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// errorLog is a logrus hook keeping the most recent errors
+type errorLog struct {
+	sync.RWMutex
+	entries []LogEntry
+}
+
+// Levels implements the logrus.Hook interface
+func (l *errorLog) Levels() []logrus.Level {
+	return []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel}
+}
+
+// Fire implements the logrus.Hook interface
+func (l *errorLog) Fire(entry *logrus.Entry) error {
+	e := LogEntry{
+		Time:    entry.Time,
+		Message: entry.Message,
+	}
+	if err, ok := entry.Data[logrus.ErrorKey]; ok {
+		e.Error = fmt.Sprint(err)
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	l.entries = append(l.entries, e)
+	if len(l.entries) > dashboardErrors {
+		l.entries = l.entries[len(l.entries)-dashboardErrors:]
+	}
+	return nil
+}
+
+// recent returns the recorded errors, most recent first
+func (l *errorLog) recent() []LogEntry {
+	l.RLock()
+	defer l.RUnlock()
+
+	res := make([]LogEntry, len(l.entries))
+	for i, e := range l.entries {
+		res[len(l.entries)-1-i] = e
+	}
+	return res
+}
+
+// DashboardStatus is the data displayed by the operational dashboard
+type DashboardStatus struct {
+	Time             time.Time          `json:"time"`
+	BlockIndex       hexutil.Uint64     `json:"blockIndex"`
+	CommitsPerMinute hexutil.Uint64     `json:"commitsPerMinute"`
+	TxPoolDepth      hexutil.Uint64     `json:"txPoolDepth"`
+	Halted           string             `json:"halted,omitempty"`
+	RecentCommits    []JsonCommitInfo   `json:"recentCommits"`
+	CommitLatency    *JsonCommitLatency `json:"commitLatency"`
+	Consensus        map[string]string  `json:"consensus"`
+	ConsensusError   string             `json:"consensusError,omitempty"`
+	RecentErrors     []LogEntry         `json:"recentErrors"`
+	Watches          []WatchResult      `json:"watches,omitempty"`
+}
+
+func (m *Service) dashboardStatus() *DashboardStatus {
+	now := time.Now()
+	status := &DashboardStatus{
+		Time:             now,
+		BlockIndex:       hexutil.Uint64(m.state.GetBlockIndex()),
+		CommitsPerMinute: hexutil.Uint64(m.state.CommitsSince(now.Add(-time.Minute))),
+		TxPoolDepth:      hexutil.Uint64(m.state.GetPoolSize()),
+		RecentCommits:    []JsonCommitInfo{},
+		CommitLatency:    m.commitLatencyStatus(),
+		RecentErrors:     m.errors.recent(),
+		Watches:          m.watchResults(),
+	}
+	for _, info := range m.state.RecentCommits(dashboardCommits) {
+		status.RecentCommits = append(status.RecentCommits, JsonCommitInfo{
+			Root:       info.Root,
+			ParentRoot: info.ParentRoot,
+			BlockIndex: hexutil.Uint64(info.BlockIndex),
+			Block:      info.Block,
+			Txs:        hexutil.Uint64(info.Txs),
+			Time:       info.Time,
+			Latency:    hexutil.Uint64(info.Latency / time.Millisecond),
+		})
+	}
+	if err := m.state.Halted(); err != nil {
+		status.Halted = err.Error()
+	}
+
+	// The consensus info holds the peer / proxy status
+	if m.getInfo != nil {
+		info, err := m.getInfo()
+		if err != nil {
+			status.ConsensusError = err.Error()
+		}
+		status.Consensus = info
+	}
+
+	return status
+}
+
+// serveAdmin serves the operational dashboard on the admin address
+func (m *Service) serveAdmin() {
+	r := mux.NewRouter()
+	r.HandleFunc("/", m.makeAdminHandler(dashboardHandler)).Methods("GET")
+	r.HandleFunc("/status", m.makeAdminHandler(dashboardStatusHandler)).Methods("GET")
+	r.HandleFunc("/metrics", m.makeAdminHandler(metricsHandler)).Methods("GET")
+	r.HandleFunc("/halt/{height}", m.makeAdminHandler(haltHeightHandler)).Methods("POST")
+	r.HandleFunc("/safe-mode", m.makeAdminHandler(enterSafeModeHandler)).Methods("POST")
+	r.HandleFunc("/safe-mode", m.makeAdminHandler(exitSafeModeHandler)).Methods("DELETE")
+	r.HandleFunc("/slotwatch", m.makeAdminHandler(listSlotWebhooksHandler)).Methods("GET")
+	r.HandleFunc("/slotwatch", m.makeAdminHandler(addSlotWebhookHandler)).Methods("POST")
+	r.HandleFunc("/slotwatch/{id}", m.makeAdminHandler(removeSlotWebhookHandler)).Methods("DELETE")
+	r.HandleFunc("/dlq", m.makeAdminHandler(listDeadLettersHandler)).Methods("GET")
+	r.HandleFunc("/dlq/{id}/retry", m.makeAdminHandler(retryDeadLetterHandler)).Methods("POST")
+	r.HandleFunc("/dlq/{id}", m.makeAdminHandler(dropDeadLetterHandler)).Methods("DELETE")
+	r.HandleFunc("/snapshot", m.makeAdminHandler(createSnapshotHandler)).Methods("POST")
+	if err := http.ListenAndServe(m.adminAddr, r); err != nil {
+		m.logger.WithError(err).Error("Serving admin dashboard")
+	}
+}
+
+// makeAdminHandler doesn't take the Service lock, so that the dashboard remains
+// available while an API request is blocked. It requires an API key if the
+// admin namespace is protected.
+func (m *Service) makeAdminHandler(fn func(http.ResponseWriter, *http.Request, *Service)) http.HandlerFunc {
+	fn = requireAuth("admin", fn)
+	return func(w http.ResponseWriter, r *http.Request) {
+		fn(w, r, m)
+	}
+}
+
+/*
+GET /
+returns: HTML dashboard
+The dashboard refreshes itself every few seconds.
+*/
+func dashboardHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	t, err := template.New("dashboard").Parse(templates.Dashboard)
+	if err != nil {
+		m.logger.WithError(err).Error("Parsing template")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := t.Execute(w, m.dashboardStatus()); err != nil {
+		m.logger.WithError(err).Error("Executing dashboard template")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+/*
+GET /status
+returns: JSON DashboardStatus
+*/
+func dashboardStatusHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	js, err := json.Marshal(m.dashboardStatus())
+	if err != nil {
+		m.logger.WithError(err).Error("Marshaling JSON response")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(js); err != nil {
+		m.logger.WithError(err).Error("Writing JSON response")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+/*
+POST /halt/{height}
+example: /halt/120000
+
+Makes the node halt after committing the block at the given height, to
+coordinate a binary upgrade. 0 cancels a pending halt. A halted node must be
+restarted with --resume.
+*/
+func haltHeightHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	param := mux.Vars(r)["height"]
+	height, err := strconv.ParseInt(param, 10, 64)
+	if err != nil || height < 0 {
+		m.logger.WithField("param", param).Error("Parsing halt height")
+		http.Error(w, fmt.Sprintf("invalid height %q", param), http.StatusBadRequest)
+		return
+	}
+
+	m.state.SetHaltHeight(height)
+	w.WriteHeader(http.StatusOK)
+}
+
+/*
+POST /safe-mode
+data: reason (optional)
+
+Puts the node in safe mode during an incident: it keeps serving reads from the
+last committed root but refuses transactions and blocks until it is cleared
+with DELETE /safe-mode or a restart with --resume. Safe mode survives restarts.
+*/
+func enterSafeModeHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		m.logger.WithError(err).Error("Reading request body")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	reason := strings.TrimSpace(string(body))
+	if reason == "" {
+		reason = "entered by an operator"
+	}
+
+	if err := m.state.EnterSafeMode(reason); err != nil {
+		m.logger.WithError(err).Error("Entering safe mode")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+/*
+DELETE /safe-mode
+
+Clears safe mode so that the node accepts transactions and applies blocks
+again. It fails if the node serves the state of an earlier block because its
+head state root was unreadable.
+*/
+func exitSafeModeHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	if err := m.state.ExitSafeMode(); err != nil {
+		m.logger.WithError(err).Error("Exiting safe mode")
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+/*
+POST /snapshot
+data: JSON JsonSnapshotRequest
+returns: JSON SnapshotManifest
+
+Exports a snapshot of the state of the last committed block to a directory of
+the node host while the node keeps running, and verifies the checksums of the
+written shards. "evm snapshot create --node" calls it.
+*/
+func createSnapshotHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	var req JsonSnapshotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Dir == "" {
+		http.Error(w, "dir is required", http.StatusBadRequest)
+		return
+	}
+	if req.Shards == 0 {
+		req.Shards = state.DefaultSnapshotShards
+	}
+
+	manifest, err := m.state.ExportSnapshot(req.Dir, req.Shards, runtime.NumCPU())
+	if err != nil {
+		m.logger.WithError(err).Error("Exporting snapshot")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := state.VerifySnapshot(req.Dir); err != nil {
+		m.logger.WithError(err).Error("Verifying snapshot")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	m.logger.WithFields(logrus.Fields{
+		"dir":   req.Dir,
+		"block": manifest.Block,
+	}).Info("Created snapshot")
+
+	writeJSON(w, manifest, m)
+}
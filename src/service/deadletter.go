@@ -0,0 +1,133 @@
+package service
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gorilla/mux"
+
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+const (
+	// attempts to submit a transaction to the consensus system before it is
+	// moved to the dead-letter queue
+	submitAttempts = 3
+	// delay before the second attempt, doubled for each following one
+	submitBackoff = 200 * time.Millisecond
+)
+
+// SubmitToConsensus passes a transaction read from the submit channel to the
+// consensus system with submit, retrying failures. A transaction which still
+// fails is moved to the dead-letter queue, where an operator can inspect,
+// retry or drop it. It returns whether the transaction was submitted.
+func (m *Service) SubmitToConsensus(tx []byte, submit func([]byte) error) bool {
+	var err error
+	backoff := submitBackoff
+	for attempt := 1; attempt <= submitAttempts; attempt++ {
+		if err = submit(tx); err == nil {
+			return true
+		}
+		m.logger.WithError(err).WithField("attempt", attempt).Warn("Submitting tx to consensus")
+		if attempt < submitAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+
+	if _, err := m.state.AddDeadLetter(tx, submitAttempts, err); err != nil {
+		m.logger.WithError(err).Error("Adding tx to dead-letter queue")
+	}
+	return false
+}
+
+func jsonDeadLetter(letter *state.DeadLetter) JsonDeadLetter {
+	return JsonDeadLetter{
+		ID:       hexutil.Uint64(letter.ID),
+		Hash:     letter.Hash,
+		Tx:       letter.Tx,
+		Error:    letter.Error,
+		Attempts: hexutil.Uint64(letter.Attempts),
+		Time:     hexutil.Uint64(letter.Time),
+	}
+}
+
+// removeDeadLetter removes the dead letter of the {id} route variable, the hex
+// id listed by /dlq or its decimal value
+func removeDeadLetter(w http.ResponseWriter, r *http.Request, m *Service) (*state.DeadLetter, bool) {
+	param := mux.Vars(r)["id"]
+	var id uint64
+	var err error
+	if strings.HasPrefix(param, "0x") {
+		id, err = hexutil.DecodeUint64(param)
+	} else {
+		id, err = strconv.ParseUint(param, 10, 64)
+	}
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return nil, false
+	}
+	letter, err := m.state.RemoveDeadLetter(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	return letter, true
+}
+
+/*
+GET /dlq
+returns: JSON []JsonDeadLetter
+
+Lists the transactions which could not be submitted to the consensus system,
+oldest first.
+*/
+func listDeadLettersHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	letters, err := m.state.GetDeadLetters()
+	if err != nil {
+		m.logger.WithError(err).Error("Reading dead-letter queue")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res := []JsonDeadLetter{}
+	for _, letter := range letters {
+		res = append(res, jsonDeadLetter(letter))
+	}
+	writeJSON(w, res, m)
+}
+
+/*
+POST /dlq/{id}/retry
+returns: JSON JsonTxRes
+
+Removes a transaction from the dead-letter queue and submits it again. If it
+fails again, it is queued back under a new id.
+*/
+func retryDeadLetterHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	letter, ok := removeDeadLetter(w, r, m)
+	if !ok {
+		return
+	}
+	m.logger.WithField("id", letter.ID).Info("Retrying dead letter")
+	m.submitCh <- letter.Tx
+
+	writeJSON(w, JsonTxRes{TxHash: letter.Hash.Hex()}, m)
+}
+
+/*
+DELETE /dlq/{id}
+
+Drops a transaction from the dead-letter queue.
+*/
+func dropDeadLetterHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	letter, ok := removeDeadLetter(w, r, m)
+	if !ok {
+		return
+	}
+	m.logger.WithField("id", letter.ID).Info("Dropped dead letter")
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/sirupsen/logrus"
+)
+
+// DevAccount is a pre-funded account of a development node, with its private
+// key so that wallets and test suites can import it
+type DevAccount struct {
+	Address    common.Address `json:"address"`
+	PrivateKey hexutil.Bytes  `json:"privateKey"`
+}
+
+//SetDevAccounts enables the dev JSON-RPC namespace of a development node,
+//which lists the given pre-funded accounts and funds other accounts from the
+//first one. Their keys must be in the keystore, unlocked with the password
+//file. It must be called before Run.
+func (m *Service) SetDevAccounts(accounts []DevAccount) error {
+	if len(accounts) == 0 {
+		return fmt.Errorf("no dev account")
+	}
+	m.devAccounts = accounts
+	m.rpcServer.addModule("dev")
+
+	m.logger.WithField("accounts", len(accounts)).Warn("Development mode: the dev namespace is enabled")
+
+	return m.rpcServer.Register(func(context *RpcServiceContext) (RpcService, error) {
+		return &devService{backend: m}, nil
+	})
+}
+
+type devService struct {
+	backend *Service
+}
+
+func (s *devService) Start() error {
+	return nil
+}
+
+func (s *devService) Stop() error {
+	return nil
+}
+
+func (s *devService) APIs() []rpc.API {
+	return []rpc.API{
+		{
+			Namespace: "dev",
+			Version:   "1.0",
+			Service:   NewPrivateDevAPI(s.backend),
+		},
+	}
+}
+
+// PrivateDevAPI offers the RPC methods of a development node
+type PrivateDevAPI struct {
+	b *Service
+}
+
+// NewPrivateDevAPI creates a new dev API instance
+func NewPrivateDevAPI(b *Service) *PrivateDevAPI {
+	return &PrivateDevAPI{b}
+}
+
+// Accounts returns the pre-funded accounts with their private keys
+func (s *PrivateDevAPI) Accounts() []DevAccount {
+	return s.b.devAccounts
+}
+
+// Fund transfers value (1 ether if nil) from the first pre-funded account to
+// the given address, and returns the hash of the transaction
+func (s *PrivateDevAPI) Fund(ctx context.Context, to common.Address, value *hexutil.Big) (common.Hash, error) {
+	if value == nil {
+		value = (*hexutil.Big)(big.NewInt(params.Ether))
+	}
+	args := SendTxArgs{
+		From:  s.b.devAccounts[0].Address,
+		To:    &to,
+		Value: value,
+	}
+	hash, err := NewPublicTransactionPoolAPI(s.b, s.b.nonceLock).SendTransaction(ctx, args)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	s.b.logger.WithFields(logrus.Fields{
+		"to":    to.Hex(),
+		"value": value.ToInt(),
+	}).Debug("Funded account")
+	return hash, nil
+}
@@ -0,0 +1,361 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+const (
+	eventCounter = "counter"
+	eventGauge   = "gauge"
+)
+
+var metricNameRegexp = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// EventMetric maps the logs of a contract event to a Prometheus metric. A
+// counter is incremented by Field of every log, or by 1 if Field is empty. A
+// gauge is set to Field of the last log. Labels are fields of the event whose
+// values label the series.
+type EventMetric struct {
+	Name string `json:"name"`
+	Help string `json:"help"`
+	Type string `json:"type"`
+
+	// emitting contract; any contract if nil
+	Address *common.Address `json:"address"`
+
+	// ABI of the event, as in the ABI of the contract
+	Event json.RawMessage `json:"event"`
+
+	Field    string   `json:"field"`
+	Labels   []string `json:"labels"`
+	Decimals int      `json:"decimals"`
+
+	event abi.Event
+}
+
+// LoadEventMetrics reads a JSON list of EventMetrics from a file
+func LoadEventMetrics(file string) ([]EventMetric, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var metrics []EventMetric
+	if err := json.Unmarshal(data, &metrics); err != nil {
+		return nil, fmt.Errorf("parsing %s: %s", file, err)
+	}
+
+	names := make(map[string]bool)
+	for i := range metrics {
+		metric := &metrics[i]
+		if !metricNameRegexp.MatchString(metric.Name) {
+			return nil, fmt.Errorf("event metric %d has an invalid name %q", i, metric.Name)
+		}
+		if names[metric.Name] {
+			return nil, fmt.Errorf("duplicate event metric %q", metric.Name)
+		}
+		names[metric.Name] = true
+		if err := metric.parse(); err != nil {
+			return nil, fmt.Errorf("event metric %q: %s", metric.Name, err)
+		}
+	}
+
+	return metrics, nil
+}
+
+// parse checks the type and fields of the metric against the ABI of its event
+func (metric *EventMetric) parse() error {
+	if metric.Type != eventCounter && metric.Type != eventGauge {
+		return fmt.Errorf("unknown type %q", metric.Type)
+	}
+
+	// The ABI package only parses whole contract ABIs
+	contract, err := abi.JSON(bytes.NewReader(append(append([]byte("["), metric.Event...), ']')))
+	if err != nil {
+		return fmt.Errorf("parsing event ABI: %s", err)
+	}
+	if len(contract.Events) != 1 {
+		return fmt.Errorf("event ABI must describe a single event")
+	}
+	for _, event := range contract.Events {
+		metric.event = event
+	}
+
+	if metric.Field == "" {
+		if metric.Type == eventGauge {
+			return fmt.Errorf("a gauge needs a field")
+		}
+	} else if _, err := metric.input(metric.Field); err != nil {
+		return err
+	}
+	for _, label := range metric.Labels {
+		if _, err := metric.input(label); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (metric *EventMetric) input(name string) (abi.Argument, error) {
+	for _, input := range metric.event.Inputs {
+		if input.Name == name {
+			return input, nil
+		}
+	}
+	return abi.Argument{}, fmt.Errorf("event %s has no field %q", metric.event.Name, name)
+}
+
+// decode returns the fields of a log of the event by name
+func (metric *EventMetric) decode(log *ethTypes.Log) (map[string]interface{}, error) {
+	if len(log.Topics) == 0 || log.Topics[0] != metric.event.Id() {
+		return nil, nil
+	}
+	if metric.Address != nil && log.Address != *metric.Address {
+		return nil, nil
+	}
+
+	fields := make(map[string]interface{})
+	values, err := metric.event.Inputs.UnpackValues(log.Data)
+	if err != nil {
+		return nil, err
+	}
+	for i, input := range metric.event.Inputs.NonIndexed() {
+		fields[input.Name] = values[i]
+	}
+
+	topics := log.Topics[1:]
+	for _, input := range metric.event.Inputs {
+		if !input.Indexed {
+			continue
+		}
+		if len(topics) == 0 {
+			return nil, fmt.Errorf("missing topic of %s", input.Name)
+		}
+		fields[input.Name] = topicValue(input.Type, topics[0])
+		topics = topics[1:]
+	}
+	return fields, nil
+}
+
+// topicValue decodes an indexed field. Indexed fields of dynamic types are
+// hashes.
+func topicValue(t abi.Type, topic common.Hash) interface{} {
+	switch t.T {
+	case abi.AddressTy:
+		return common.BytesToAddress(topic.Bytes())
+	case abi.UintTy:
+		return new(big.Int).SetBytes(topic.Bytes())
+	case abi.IntTy:
+		return math.S256(new(big.Int).SetBytes(topic.Bytes()))
+	case abi.BoolTy:
+		return topic[common.HashLength-1] == 1
+	default:
+		return topic
+	}
+}
+
+// metricValue converts a numeric field to the value of the metric
+func (metric *EventMetric) metricValue(v interface{}) (float64, error) {
+	var n *big.Int
+	switch v := v.(type) {
+	case *big.Int:
+		n = v
+	case uint8:
+		n = new(big.Int).SetUint64(uint64(v))
+	case uint16:
+		n = new(big.Int).SetUint64(uint64(v))
+	case uint32:
+		n = new(big.Int).SetUint64(uint64(v))
+	case uint64:
+		n = new(big.Int).SetUint64(v)
+	case int8:
+		n = big.NewInt(int64(v))
+	case int16:
+		n = big.NewInt(int64(v))
+	case int32:
+		n = big.NewInt(int64(v))
+	case int64:
+		n = big.NewInt(v)
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("field %s is not numeric", metric.Field)
+	}
+
+	f := new(big.Float).SetInt(n)
+	if metric.Decimals > 0 {
+		scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(metric.Decimals)), nil)
+		f.Quo(f, new(big.Float).SetInt(scale))
+	}
+	res, _ := f.Float64()
+	return res, nil
+}
+
+func labelValue(v interface{}) string {
+	switch v := v.(type) {
+	case common.Address:
+		return v.Hex()
+	case common.Hash:
+		return v.Hex()
+	case []byte:
+		return common.ToHex(v)
+	case [32]byte:
+		return common.Hash(v).Hex()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// eventSeries is the value of a metric for a set of label values
+type eventSeries struct {
+	labels []string
+	value  float64
+}
+
+// eventMetrics evaluates the event metrics on the logs of the committed blocks
+type eventMetrics struct {
+	sync.RWMutex
+	metrics []EventMetric
+	series  []map[string]*eventSeries // by metric, by label values
+	block   int64                     // last evaluated block
+}
+
+//SetEventMetrics makes the Service evaluate the given event metrics on the logs
+//of every committed block once it runs. The metrics are served on /metrics.
+//Counters start from 0 when the node starts.
+func (m *Service) SetEventMetrics(metrics []EventMetric) {
+	series := make([]map[string]*eventSeries, len(metrics))
+	for i := range series {
+		series[i] = make(map[string]*eventSeries)
+	}
+	m.eventMetrics = &eventMetrics{
+		metrics: metrics,
+		series:  series,
+	}
+}
+
+// runEventMetrics evaluates the event metrics at every commit
+func (m *Service) runEventMetrics() {
+	commits := make(chan state.CommitInfo, 16)
+	sub := m.state.SubscribeCommits(commits)
+	defer sub.Unsubscribe()
+
+	for info := range commits {
+		if !info.Block {
+			continue
+		}
+		logs, err := m.state.GetLogs(&state.LogFilter{
+			FromBlock: info.BlockIndex,
+			ToBlock:   info.BlockIndex,
+		}, 0)
+		if err != nil {
+			m.logger.WithError(err).WithField("block", info.BlockIndex).Error("Getting logs for event metrics")
+			continue
+		}
+		m.evaluateEventMetrics(info.BlockIndex, logs)
+	}
+}
+
+func (m *Service) evaluateEventMetrics(blockIndex int64, logs []*ethTypes.Log) {
+	em := m.eventMetrics
+	em.Lock()
+	defer em.Unlock()
+
+	for i := range em.metrics {
+		metric := &em.metrics[i]
+		for _, log := range logs {
+			fields, err := metric.decode(log)
+			if err != nil {
+				m.logger.WithError(err).WithField("metric", metric.Name).Warn("Decoding event")
+				continue
+			}
+			if fields == nil {
+				continue
+			}
+
+			value := 1.0
+			if metric.Field != "" {
+				if value, err = metric.metricValue(fields[metric.Field]); err != nil {
+					m.logger.WithError(err).WithField("metric", metric.Name).Warn("Evaluating event metric")
+					continue
+				}
+			}
+
+			labels := make([]string, len(metric.Labels))
+			for j, label := range metric.Labels {
+				labels[j] = labelValue(fields[label])
+			}
+			key := strings.Join(labels, "\x00")
+			s, ok := em.series[i][key]
+			if !ok {
+				s = &eventSeries{labels: labels}
+				em.series[i][key] = s
+			}
+			if metric.Type == eventCounter {
+				s.value += value
+			} else {
+				s.value = value
+			}
+		}
+	}
+	em.block = blockIndex
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeEventMetrics writes the event metrics in Prometheus text format
+func (m *Service) writeEventMetrics(w io.Writer) {
+	em := m.eventMetrics
+	if em == nil {
+		return
+	}
+
+	em.RLock()
+	defer em.RUnlock()
+	for i, metric := range em.metrics {
+		if metric.Help != "" {
+			fmt.Fprintf(w, "# HELP %s %s\n", metric.Name, metric.Help)
+		}
+		fmt.Fprintf(w, "# TYPE %s %s\n", metric.Name, metric.Type)
+
+		keys := make([]string, 0, len(em.series[i]))
+		for key := range em.series[i] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			s := em.series[i][key]
+			pairs := make([]string, len(s.labels))
+			for j, value := range s.labels {
+				pairs[j] = fmt.Sprintf("%s=\"%s\"", metric.Labels[j], labelEscaper.Replace(value))
+			}
+			if len(pairs) > 0 {
+				fmt.Fprintf(w, "%s{%s} %g\n", metric.Name, strings.Join(pairs, ","), s.value)
+			} else {
+				fmt.Fprintf(w, "%s %g\n", metric.Name, s.value)
+			}
+		}
+	}
+	fmt.Fprintln(w, "# HELP evm_event_metrics_block Last block the event metrics were evaluated on")
+	fmt.Fprintln(w, "# TYPE evm_event_metrics_block gauge")
+	fmt.Fprintf(w, "evm_event_metrics_block %d\n", em.block)
+}
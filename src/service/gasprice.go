@@ -0,0 +1,148 @@
+package service
+
+import (
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+const (
+	// DefaultGasPriceBlocks is the default number of recent blocks the gas
+	// price is suggested from
+	DefaultGasPriceBlocks = 20
+
+	// DefaultGasPricePercentile is the default percentile of the prices of
+	// the recent blocks suggested
+	DefaultGasPricePercentile = 60
+)
+
+// GasPriceOracle configures the gas price suggested by eth_gasPrice and used
+// for the transactions sent without one. The lowest price of the transactions
+// of each of the recent blocks is sampled, and the given percentile of the
+// samples is suggested. Without recent transactions, Default is suggested.
+type GasPriceOracle struct {
+	Blocks     int
+	Percentile int
+	Default    *big.Int
+}
+
+// gasPriceOracle keeps the lowest gas prices of the recent blocks with
+// transactions
+type gasPriceOracle struct {
+	sync.RWMutex
+	config GasPriceOracle
+	prices []*big.Int // most recent last
+}
+
+func newGasPriceOracle(config GasPriceOracle) *gasPriceOracle {
+	if config.Blocks <= 0 {
+		config.Blocks = DefaultGasPriceBlocks
+	}
+	if config.Percentile <= 0 || config.Percentile > 100 {
+		config.Percentile = DefaultGasPricePercentile
+	}
+	if config.Default == nil {
+		config.Default = defaultGasPrice
+	}
+	return &gasPriceOracle{config: config}
+}
+
+func (o *gasPriceOracle) add(price *big.Int) {
+	o.Lock()
+	defer o.Unlock()
+
+	o.prices = append(o.prices, price)
+	if len(o.prices) > o.config.Blocks {
+		o.prices = o.prices[len(o.prices)-o.config.Blocks:]
+	}
+}
+
+// suggest returns the percentile of the prices of the recent blocks, by
+// nearest rank
+func (o *gasPriceOracle) suggest() *big.Int {
+	o.RLock()
+	sorted := make([]*big.Int, len(o.prices))
+	copy(sorted, o.prices)
+	o.RUnlock()
+
+	if len(sorted) == 0 {
+		return new(big.Int).Set(o.config.Default)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Cmp(sorted[j]) < 0 })
+	rank := (o.config.Percentile*len(sorted) + 99) / 100
+	return new(big.Int).Set(sorted[rank-1])
+}
+
+//SetGasPriceOracle sets how the gas price suggested to the clients is
+//computed from the prices of the recent blocks
+func (m *Service) SetGasPriceOracle(config GasPriceOracle) {
+	m.gasPrices = newGasPriceOracle(config)
+	m.logger.WithFields(logrus.Fields{
+		"blocks":     m.gasPrices.config.Blocks,
+		"percentile": m.gasPrices.config.Percentile,
+		"default":    m.gasPrices.config.Default,
+	}).Debug("Gas price oracle set")
+}
+
+// suggestGasPrice returns the gas price suggested to the clients, at least the
+// minimum gas price of the network
+func (m *Service) suggestGasPrice() *big.Int {
+	price := m.gasPrices.suggest()
+	if floor := m.state.MinGasPrice(); floor != nil && price.Cmp(floor) < 0 {
+		return floor
+	}
+	return price
+}
+
+// blockGasPrice returns the lowest gas price of the transactions of a block,
+// or nil if it has none
+func (m *Service) blockGasPrice(number int64) *big.Int {
+	block, err := m.state.GetBlockByNumber(number)
+	if err != nil {
+		return nil
+	}
+	var lowest *big.Int
+	for _, hash := range block.Transactions {
+		tx, err := m.state.GetTransaction(hash)
+		if err != nil {
+			continue
+		}
+		if lowest == nil || tx.GasPrice().Cmp(lowest) < 0 {
+			lowest = tx.GasPrice()
+		}
+	}
+	return lowest
+}
+
+// runGasPriceOracle samples the recent blocks, then every committed block
+func (m *Service) runGasPriceOracle() {
+	commits := make(chan state.CommitInfo, 16)
+	sub := m.state.SubscribeCommits(commits)
+	defer sub.Unsubscribe()
+
+	// Blocks without transactions are not sampled, so look further back for
+	// the initial samples
+	head := m.state.GetBlockIndex()
+	first := head - int64(4*m.gasPrices.config.Blocks)
+	if first < 0 {
+		first = 0
+	}
+	for number := first; number <= head; number++ {
+		if price := m.blockGasPrice(number); price != nil {
+			m.gasPrices.add(price)
+		}
+	}
+
+	for info := range commits {
+		if !info.Block || info.Txs == 0 || info.BlockIndex <= head {
+			continue
+		}
+		if price := m.blockGasPrice(info.BlockIndex); price != nil {
+			m.gasPrices.add(price)
+		}
+	}
+}
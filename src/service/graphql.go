@@ -0,0 +1,623 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethState "github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	graphql "github.com/graph-gophers/graphql-go"
+
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+// maxGraphQLBlocks is the maximum number of blocks returned by a blocks query
+const maxGraphQLBlocks = 1000
+
+// graphqlSchema is the part of the schema of geth's GraphQL endpoint this node
+// can serve. Accounts are read at the state of a block.
+const graphqlSchema = `
+	# Bytes32 is a 32 byte binary string, represented as 0x-prefixed hexadecimal.
+	scalar Bytes32
+	# Address is a 20 byte Ethereum address, represented as 0x-prefixed hexadecimal.
+	scalar Address
+	# Bytes is an arbitrary length binary string, represented as 0x-prefixed hexadecimal.
+	scalar Bytes
+	# BigInt is a large integer, represented as 0x-prefixed hexadecimal.
+	scalar BigInt
+	# Long is a 64 bit unsigned integer.
+	scalar Long
+
+	schema {
+		query: Query
+	}
+
+	# Account is an Ethereum account at a particular block.
+	type Account {
+		address: Address!
+		balance: BigInt!
+		transactionCount: Long!
+		code: Bytes!
+		storage(slot: Bytes32!): Bytes32!
+	}
+
+	# Log is an Ethereum event log.
+	type Log {
+		index: Int!
+		account: Account!
+		topics: [Bytes32!]!
+		data: Bytes!
+		transaction: Transaction!
+	}
+
+	# Transaction is an Ethereum transaction.
+	type Transaction {
+		hash: Bytes32!
+		nonce: Long!
+		index: Int
+		from: Account!
+		to: Account
+		value: BigInt!
+		gasPrice: BigInt!
+		gas: Long!
+		inputData: Bytes!
+		block: Block
+		status: Long
+		gasUsed: Long
+		cumulativeGasUsed: Long
+		createdContract: Account
+		logs: [Log!]
+	}
+
+	# BlockFilterCriteria encapsulates log filter criteria for a filter applied
+	# to a single block.
+	input BlockFilterCriteria {
+		addresses: [Address!]
+		topics: [[Bytes32!]!]
+	}
+
+	# Block is an Ethereum block.
+	type Block {
+		number: Long!
+		hash: Bytes32!
+		parent: Block
+		timestamp: Long!
+		stateRoot: Bytes32!
+		transactionsRoot: Bytes32!
+		receiptsRoot: Bytes32!
+		gasUsed: Long!
+		gasLimit: Long!
+		logsBloom: Bytes!
+		transactionCount: Int
+		transactions: [Transaction!]
+		transactionAt(index: Int!): Transaction
+		logs(filter: BlockFilterCriteria!): [Log!]!
+		account(address: Address!): Account!
+	}
+
+	# FilterCriteria encapsulates log filter criteria for searching log entries.
+	input FilterCriteria {
+		fromBlock: Long
+		toBlock: Long
+		addresses: [Address!]
+		topics: [[Bytes32!]!]
+	}
+
+	type Query {
+		block(number: Long, hash: Bytes32): Block
+		blocks(from: Long!, to: Long): [Block!]!
+		transaction(hash: Bytes32!): Transaction
+		logs(filter: FilterCriteria!): [Log!]!
+	}
+`
+
+// GraphQL scalars
+
+type gqlBytes32 common.Hash
+
+func (gqlBytes32) ImplementsGraphQLType(name string) bool { return name == "Bytes32" }
+
+func (h *gqlBytes32) UnmarshalGraphQL(input interface{}) error {
+	s, ok := input.(string)
+	if !ok {
+		return fmt.Errorf("unexpected type %T for Bytes32", input)
+	}
+	data, err := hexutil.Decode(s)
+	if err != nil || len(data) != common.HashLength {
+		return fmt.Errorf("invalid Bytes32 %q", s)
+	}
+	*h = gqlBytes32(common.BytesToHash(data))
+	return nil
+}
+
+func (h gqlBytes32) MarshalJSON() ([]byte, error) {
+	return json.Marshal(common.Hash(h).Hex())
+}
+
+type gqlAddress common.Address
+
+func (gqlAddress) ImplementsGraphQLType(name string) bool { return name == "Address" }
+
+func (a *gqlAddress) UnmarshalGraphQL(input interface{}) error {
+	s, ok := input.(string)
+	if !ok || !common.IsHexAddress(s) {
+		return fmt.Errorf("invalid Address %v", input)
+	}
+	*a = gqlAddress(common.HexToAddress(s))
+	return nil
+}
+
+func (a gqlAddress) MarshalJSON() ([]byte, error) {
+	return json.Marshal(common.Address(a).Hex())
+}
+
+type gqlBytes []byte
+
+func (gqlBytes) ImplementsGraphQLType(name string) bool { return name == "Bytes" }
+
+func (b *gqlBytes) UnmarshalGraphQL(input interface{}) error {
+	s, ok := input.(string)
+	if !ok {
+		return fmt.Errorf("unexpected type %T for Bytes", input)
+	}
+	data, err := hexutil.Decode(s)
+	if err != nil {
+		return fmt.Errorf("invalid Bytes %q", s)
+	}
+	*b = data
+	return nil
+}
+
+func (b gqlBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hexutil.Encode(b))
+}
+
+type gqlBigInt big.Int
+
+func (gqlBigInt) ImplementsGraphQLType(name string) bool { return name == "BigInt" }
+
+func (b *gqlBigInt) UnmarshalGraphQL(input interface{}) error {
+	s, ok := input.(string)
+	if !ok {
+		return fmt.Errorf("unexpected type %T for BigInt", input)
+	}
+	n, err := hexutil.DecodeBig(s)
+	if err != nil {
+		return fmt.Errorf("invalid BigInt %q", s)
+	}
+	*b = gqlBigInt(*n)
+	return nil
+}
+
+func (b gqlBigInt) MarshalJSON() ([]byte, error) {
+	n := big.Int(b)
+	return json.Marshal(hexutil.EncodeBig(&n))
+}
+
+func newGqlBigInt(n *big.Int) gqlBigInt {
+	if n == nil {
+		return gqlBigInt{}
+	}
+	return gqlBigInt(*n)
+}
+
+type gqlLong int64
+
+func (gqlLong) ImplementsGraphQLType(name string) bool { return name == "Long" }
+
+func (l *gqlLong) UnmarshalGraphQL(input interface{}) error {
+	switch v := input.(type) {
+	case int32:
+		*l = gqlLong(v)
+	case int64:
+		*l = gqlLong(v)
+	case float64:
+		*l = gqlLong(v)
+	case string:
+		n, err := strconv.ParseInt(v, 0, 64)
+		if err != nil {
+			return fmt.Errorf("invalid Long %q", v)
+		}
+		*l = gqlLong(n)
+	default:
+		return fmt.Errorf("unexpected type %T for Long", input)
+	}
+	return nil
+}
+
+// Resolvers
+
+type gqlResolver struct {
+	m *Service
+}
+
+// newGraphQLSchema parses the GraphQL schema with the resolvers of the Service
+func newGraphQLSchema(m *Service) *graphql.Schema {
+	return graphql.MustParseSchema(graphqlSchema, &gqlResolver{m})
+}
+
+func (r *gqlResolver) Block(args struct {
+	Number *gqlLong
+	Hash   *gqlBytes32
+}) (*gqlBlock, error) {
+	var (
+		block *state.Block
+		err   error
+	)
+	switch {
+	case args.Hash != nil:
+		block, err = r.m.state.GetBlockByHash(common.Hash(*args.Hash))
+	case args.Number != nil:
+		block, err = r.m.state.GetBlockByNumber(int64(*args.Number))
+	default:
+		block, err = r.m.state.GetBlockByNumber(r.m.state.GetBlockIndex())
+	}
+	if err != nil {
+		return nil, nil
+	}
+	return &gqlBlock{m: r.m, block: block}, nil
+}
+
+func (r *gqlResolver) Blocks(args struct {
+	From gqlLong
+	To   *gqlLong
+}) ([]*gqlBlock, error) {
+	to := r.m.state.GetBlockIndex()
+	if args.To != nil && int64(*args.To) < to {
+		to = int64(*args.To)
+	}
+	from := int64(args.From)
+	if from < 0 {
+		return nil, fmt.Errorf("invalid block number %d", from)
+	}
+	if to-from >= maxGraphQLBlocks {
+		return nil, fmt.Errorf("query returns more than %d blocks", maxGraphQLBlocks)
+	}
+
+	blocks := []*gqlBlock{}
+	for number := from; number <= to; number++ {
+		block, err := r.m.state.GetBlockByNumber(number)
+		if err != nil {
+			// Blocks committed before Block headers were recorded
+			continue
+		}
+		blocks = append(blocks, &gqlBlock{m: r.m, block: block})
+	}
+	return blocks, nil
+}
+
+func (r *gqlResolver) Transaction(args struct{ Hash gqlBytes32 }) (*gqlTransaction, error) {
+	return r.m.gqlTransaction(common.Hash(args.Hash)), nil
+}
+
+func (r *gqlResolver) Logs(args struct{ Filter gqlFilterCriteria }) ([]*gqlLog, error) {
+	head := r.m.state.GetBlockIndex()
+	from, to := head, head
+	if args.Filter.FromBlock != nil {
+		from = int64(*args.Filter.FromBlock)
+	}
+	if args.Filter.ToBlock != nil {
+		to = int64(*args.Filter.ToBlock)
+	}
+	f := gqlLogFilter(from, to, args.Filter.Addresses, args.Filter.Topics)
+	return r.m.gqlLogs(f)
+}
+
+type gqlFilterCriteria struct {
+	FromBlock *gqlLong
+	ToBlock   *gqlLong
+	Addresses *[]gqlAddress
+	Topics    *[][]gqlBytes32
+}
+
+type gqlBlockFilterCriteria struct {
+	Addresses *[]gqlAddress
+	Topics    *[][]gqlBytes32
+}
+
+func gqlLogFilter(from, to int64, addresses *[]gqlAddress, topics *[][]gqlBytes32) *state.LogFilter {
+	f := &state.LogFilter{FromBlock: from, ToBlock: to}
+	if addresses != nil {
+		for _, addr := range *addresses {
+			f.Addresses = append(f.Addresses, common.Address(addr))
+		}
+	}
+	if topics != nil {
+		for _, alts := range *topics {
+			hashes := []common.Hash{}
+			for _, topic := range alts {
+				hashes = append(hashes, common.Hash(topic))
+			}
+			f.Topics = append(f.Topics, hashes)
+		}
+	}
+	return f
+}
+
+func (m *Service) gqlLogs(f *state.LogFilter) ([]*gqlLog, error) {
+	logs, err := m.getLogs(f)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*gqlLog, len(logs))
+	for i, log := range logs {
+		res[i] = &gqlLog{m: m, log: log}
+	}
+	return res, nil
+}
+
+// gqlAccount is an account in the state of a block
+type gqlAccount struct {
+	st   *ethState.StateDB
+	addr common.Address
+}
+
+func (a *gqlAccount) Address() gqlAddress       { return gqlAddress(a.addr) }
+func (a *gqlAccount) Balance() gqlBigInt        { return newGqlBigInt(a.st.GetBalance(a.addr)) }
+func (a *gqlAccount) TransactionCount() gqlLong { return gqlLong(a.st.GetNonce(a.addr)) }
+func (a *gqlAccount) Code() gqlBytes            { return gqlBytes(a.st.GetCode(a.addr)) }
+func (a *gqlAccount) Storage(args struct{ Slot gqlBytes32 }) gqlBytes32 {
+	return gqlBytes32(a.st.GetState(a.addr, common.Hash(args.Slot)))
+}
+
+type gqlBlock struct {
+	m     *Service
+	block *state.Block
+}
+
+// account returns an account in the state of the block
+func (b *gqlBlock) account(addr common.Address) (*gqlAccount, error) {
+	st, err := b.m.state.StateAt(b.block.StateRoot)
+	if err != nil {
+		return nil, fmt.Errorf("state of block %d is not available", b.block.Number)
+	}
+	return &gqlAccount{st: st, addr: addr}, nil
+}
+
+func (b *gqlBlock) Number() gqlLong  { return gqlLong(b.block.Number) }
+func (b *gqlBlock) Hash() gqlBytes32 { return gqlBytes32(b.block.Hash()) }
+
+func (b *gqlBlock) Parent() *gqlBlock {
+	if b.block.ParentHash == (common.Hash{}) {
+		return nil
+	}
+	parent, err := b.m.state.GetBlockByHash(b.block.ParentHash)
+	if err != nil {
+		return nil
+	}
+	return &gqlBlock{m: b.m, block: parent}
+}
+
+func (b *gqlBlock) Timestamp() gqlLong           { return gqlLong(b.block.Timestamp) }
+func (b *gqlBlock) StateRoot() gqlBytes32        { return gqlBytes32(b.block.StateRoot) }
+func (b *gqlBlock) TransactionsRoot() gqlBytes32 { return gqlBytes32(b.block.TxRoot) }
+func (b *gqlBlock) ReceiptsRoot() gqlBytes32     { return gqlBytes32(b.block.ReceiptRoot) }
+func (b *gqlBlock) GasUsed() gqlLong             { return gqlLong(b.block.GasUsed) }
+func (b *gqlBlock) GasLimit() gqlLong            { return gqlLong(b.m.state.GetGasLimit()) }
+
+func (b *gqlBlock) LogsBloom() (gqlBytes, error) {
+	bloom, err := b.m.state.GetBlockBloom(int64(b.block.Number))
+	if err != nil {
+		return nil, err
+	}
+	return gqlBytes(bloom.Bytes()), nil
+}
+
+func (b *gqlBlock) TransactionCount() *int32 {
+	count := int32(len(b.block.Transactions))
+	return &count
+}
+
+func (b *gqlBlock) Transactions() []*gqlTransaction {
+	txs := []*gqlTransaction{}
+	for _, hash := range b.block.Transactions {
+		if tx := b.m.gqlTransaction(hash); tx != nil {
+			txs = append(txs, tx)
+		}
+	}
+	return txs
+}
+
+func (b *gqlBlock) TransactionAt(args struct{ Index int32 }) *gqlTransaction {
+	if args.Index < 0 || int(args.Index) >= len(b.block.Transactions) {
+		return nil
+	}
+	return b.m.gqlTransaction(b.block.Transactions[args.Index])
+}
+
+func (b *gqlBlock) Logs(args struct{ Filter gqlBlockFilterCriteria }) ([]*gqlLog, error) {
+	number := int64(b.block.Number)
+	return b.m.gqlLogs(gqlLogFilter(number, number, args.Filter.Addresses, args.Filter.Topics))
+}
+
+func (b *gqlBlock) Account(args struct{ Address gqlAddress }) (*gqlAccount, error) {
+	return b.account(common.Address(args.Address))
+}
+
+// gqlTransaction is an applied transaction
+type gqlTransaction struct {
+	m      *Service
+	tx     *ethTypes.Transaction
+	lookup *state.TxLookupEntry
+}
+
+// gqlTransaction returns an applied transaction, or nil if it is unknown
+func (m *Service) gqlTransaction(hash common.Hash) *gqlTransaction {
+	tx, err := m.state.GetTransaction(hash)
+	if err != nil {
+		return nil
+	}
+	lookup, err := m.state.GetTxLookup(hash)
+	if err != nil {
+		return nil
+	}
+	return &gqlTransaction{m: m, tx: tx, lookup: lookup}
+}
+
+func (t *gqlTransaction) block() (*gqlBlock, error) {
+	block, err := t.m.state.GetBlockByNumber(int64(t.lookup.BlockNumber))
+	if err != nil {
+		return nil, fmt.Errorf("block %d not found", t.lookup.BlockNumber)
+	}
+	return &gqlBlock{m: t.m, block: block}, nil
+}
+
+func (t *gqlTransaction) receipt() (*ethTypes.Receipt, error) {
+	return t.m.state.GetReceipt(t.tx.Hash())
+}
+
+func (t *gqlTransaction) Hash() gqlBytes32 { return gqlBytes32(t.tx.Hash()) }
+func (t *gqlTransaction) Nonce() gqlLong   { return gqlLong(t.tx.Nonce()) }
+
+func (t *gqlTransaction) Index() *int32 {
+	index := int32(t.lookup.Index)
+	return &index
+}
+
+func (t *gqlTransaction) From() (*gqlAccount, error) {
+	block, err := t.block()
+	if err != nil {
+		return nil, err
+	}
+	return block.account(t.lookup.From)
+}
+
+func (t *gqlTransaction) To() (*gqlAccount, error) {
+	if t.tx.To() == nil {
+		return nil, nil
+	}
+	block, err := t.block()
+	if err != nil {
+		return nil, err
+	}
+	return block.account(*t.tx.To())
+}
+
+func (t *gqlTransaction) Value() gqlBigInt    { return newGqlBigInt(t.tx.Value()) }
+func (t *gqlTransaction) GasPrice() gqlBigInt { return newGqlBigInt(t.tx.GasPrice()) }
+func (t *gqlTransaction) Gas() gqlLong        { return gqlLong(t.tx.Gas()) }
+func (t *gqlTransaction) InputData() gqlBytes { return gqlBytes(t.tx.Data()) }
+
+func (t *gqlTransaction) Block() (*gqlBlock, error) {
+	return t.block()
+}
+
+func (t *gqlTransaction) Status() (*gqlLong, error) {
+	receipt, err := t.receipt()
+	if err != nil {
+		return nil, err
+	}
+	status := gqlLong(receipt.Status)
+	return &status, nil
+}
+
+func (t *gqlTransaction) GasUsed() (*gqlLong, error) {
+	receipt, err := t.receipt()
+	if err != nil {
+		return nil, err
+	}
+	gas := gqlLong(receipt.GasUsed)
+	return &gas, nil
+}
+
+func (t *gqlTransaction) CumulativeGasUsed() (*gqlLong, error) {
+	receipt, err := t.receipt()
+	if err != nil {
+		return nil, err
+	}
+	gas := gqlLong(receipt.CumulativeGasUsed)
+	return &gas, nil
+}
+
+func (t *gqlTransaction) CreatedContract() (*gqlAccount, error) {
+	if t.tx.To() != nil {
+		return nil, nil
+	}
+	receipt, err := t.receipt()
+	if err != nil {
+		return nil, err
+	}
+	if receipt.Status == ethTypes.ReceiptStatusFailed {
+		return nil, nil
+	}
+	block, err := t.block()
+	if err != nil {
+		return nil, err
+	}
+	return block.account(receipt.ContractAddress)
+}
+
+func (t *gqlTransaction) Logs() ([]*gqlLog, error) {
+	receipt, err := t.receipt()
+	if err != nil {
+		return nil, err
+	}
+	logs := make([]*gqlLog, len(receipt.Logs))
+	for i, log := range receipt.Logs {
+		logs[i] = &gqlLog{m: t.m, log: log}
+	}
+	return logs, nil
+}
+
+type gqlLog struct {
+	m   *Service
+	log *ethTypes.Log
+}
+
+func (l *gqlLog) Index() int32 { return int32(l.log.Index) }
+
+func (l *gqlLog) Account() (*gqlAccount, error) {
+	block, err := l.m.state.GetBlockByNumber(int64(l.log.BlockNumber))
+	if err != nil {
+		return nil, fmt.Errorf("block %d not found", l.log.BlockNumber)
+	}
+	return (&gqlBlock{m: l.m, block: block}).account(l.log.Address)
+}
+
+func (l *gqlLog) Topics() []gqlBytes32 {
+	topics := make([]gqlBytes32, len(l.log.Topics))
+	for i, topic := range l.log.Topics {
+		topics[i] = gqlBytes32(topic)
+	}
+	return topics
+}
+
+func (l *gqlLog) Data() gqlBytes { return gqlBytes(l.log.Data) }
+
+func (l *gqlLog) Transaction() (*gqlTransaction, error) {
+	tx := l.m.gqlTransaction(l.log.TxHash)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %s not found", l.log.TxHash.Hex())
+	}
+	return tx, nil
+}
+
+/*
+POST /graphql
+data: JSON {"query": "...", "operationName": "...", "variables": {...}}
+returns: JSON {"data": {...}, "errors": [...]}
+
+Executes a GraphQL query over the blocks, transactions, logs and accounts. The
+schema is the one of geth's GraphQL endpoint, for the queries this node can
+answer (block, blocks, transaction and logs), so explorers and analytics tools
+can use this node directly. Accounts are read at the state of a block.
+*/
+func graphqlHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	var params struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res := m.graphql.Exec(r.Context(), params.Query, params.OperationName, params.Variables)
+	writeJSON(w, res, m)
+}
@@ -0,0 +1,374 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/Fantom-foundation/go-evm/src/evmpb"
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+//SetGRPCAddr makes the Service serve the gRPC API (see evmpb) on the given
+//address, alongside HTTP. It uses the TLS certificate and the API keys of the
+//HTTP API. Disabled when empty.
+func (m *Service) SetGRPCAddr(addr string) {
+	m.grpcAddr = addr
+}
+
+// serveGRPC serves the gRPC API until it fails
+func (m *Service) serveGRPC() {
+	var opts []grpc.ServerOption
+	if m.tlsCert != "" {
+		creds, err := credentials.NewServerTLSFromFile(m.tlsCert, m.tlsKey)
+		if err != nil {
+			m.logger.WithError(err).Error("Loading gRPC TLS certificate")
+			return
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	lis, err := net.Listen("tcp", m.grpcAddr)
+	if err != nil {
+		m.logger.WithError(err).Error("Listening for gRPC")
+		return
+	}
+	server := grpc.NewServer(opts...)
+	evmpb.RegisterEVMServer(server, &grpcServer{m: m})
+	if err := server.Serve(lis); err != nil {
+		m.logger.WithError(err).Error("Serving gRPC")
+	}
+}
+
+// grpcAuth checks the API key of a gRPC call to a namespace. The key is sent
+// in the authorization metadata, like the HTTP header.
+func (m *Service) grpcAuth(ctx context.Context, namespace string) error {
+	if m.apiAuth == nil {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	r := &http.Request{Header: http.Header{"Authorization": md["authorization"]}}
+	code, err := m.apiAuth.check(r, namespace)
+	switch {
+	case err == nil:
+		return nil
+	case code == http.StatusForbidden:
+		return status.Error(codes.PermissionDenied, err.Error())
+	default:
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+}
+
+// grpcTxError is the gRPC status of a rejected transaction, depending on the
+// class of the error like writeTxError
+func (m *Service) grpcTxError(err error) error {
+	code := codes.Internal
+	switch state.ErrorClass(err) {
+	case state.ErrMalformedTx,
+		state.ErrInvalidSig,
+		state.ErrNonceTooLow,
+		state.ErrNonceTooHigh,
+		state.ErrInsufficientFunds,
+		state.ErrIntrinsicGas,
+		state.ErrMaxInitCodeSize,
+		state.ErrMaxCodeSize,
+		state.ErrUnderpriced,
+		state.ErrReplaceUnderpriced:
+		code = codes.InvalidArgument
+	case state.ErrTxPoolFull, state.ErrGasLimitReached, ErrStopping:
+		code = codes.Unavailable
+	}
+
+	if code == codes.Internal {
+		m.logger.WithError(err).Error("Adding Transaction to TxPool")
+	} else {
+		m.logger.WithError(err).Debug("Transaction rejected")
+	}
+	return status.Error(code, err.Error())
+}
+
+// grpcServer implements the gRPC API over the Service
+type grpcServer struct {
+	m *Service
+}
+
+// submitRawTx decodes a signed transaction and adds it to the TxPool
+func (g *grpcServer) submitRawTx(ctx context.Context, raw []byte) (common.Hash, error) {
+	var t ethTypes.Transaction
+	if err := rlp.Decode(bytes.NewReader(raw), &t); err != nil {
+		return common.Hash{}, status.Errorf(codes.InvalidArgument, "decoding transaction: %s", err)
+	}
+	if err := g.m.addTx(ctx, &t); err != nil {
+		return common.Hash{}, g.m.grpcTxError(err)
+	}
+	return t.Hash(), nil
+}
+
+func (g *grpcServer) SubmitTx(ctx context.Context, in *evmpb.RawTx) (*evmpb.TxHash, error) {
+	if err := g.m.grpcAuth(ctx, txNamespace); err != nil {
+		return nil, err
+	}
+	hash, err := g.submitRawTx(ctx, in.Tx)
+	if err != nil {
+		return nil, err
+	}
+	return &evmpb.TxHash{Hash: hash.Bytes()}, nil
+}
+
+func (g *grpcServer) SubmitTxs(stream evmpb.EVM_SubmitTxsServer) error {
+	if err := g.m.grpcAuth(stream.Context(), txNamespace); err != nil {
+		return err
+	}
+	for {
+		in, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		res := &evmpb.SubmitResult{}
+		hash, err := g.submitRawTx(stream.Context(), in.Tx)
+		if err != nil {
+			res.Error = status.Convert(err).Message()
+		} else {
+			res.Hash = hash.Bytes()
+		}
+		if err := stream.Send(res); err != nil {
+			return err
+		}
+	}
+}
+
+func (g *grpcServer) Call(ctx context.Context, in *evmpb.CallRequest) (*evmpb.CallResult, error) {
+	var to *common.Address
+	if len(in.To) > 0 {
+		addr := common.BytesToAddress(in.To)
+		to = &addr
+	}
+	gas := in.Gas
+	if gas == 0 {
+		gas = defaultCallGas
+	}
+	msg := ethTypes.NewMessage(common.BytesToAddress(in.From), to, 0,
+		new(big.Int).SetBytes(in.Value), gas, new(big.Int).SetBytes(in.GasPrice), in.Data, false)
+
+	var (
+		ret     []byte
+		gasUsed uint64
+		failed  bool
+		err     error
+	)
+	if in.BlockNumber == 0 {
+		ret, gasUsed, failed, err = g.m.state.ExecuteCall(ctx, msg)
+	} else {
+		ret, gasUsed, failed, err = g.m.state.ExecuteCallAt(ctx, msg, int64(in.BlockNumber))
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	res := &evmpb.CallResult{Return: ret, Failed: failed, GasUsed: gasUsed}
+	if failed {
+		res.RevertReason, _ = UnpackRevertReason(ret)
+	}
+	return res, nil
+}
+
+func (g *grpcServer) GetReceipt(ctx context.Context, in *evmpb.TxHash) (*evmpb.Receipt, error) {
+	receipt, err := g.m.getJsonReceipt(common.BytesToHash(in.Hash))
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	res := &evmpb.Receipt{
+		TxHash:            receipt.TransactionHash.Bytes(),
+		TxIndex:           uint64(receipt.TransactionIndex),
+		BlockHash:         receipt.BlockHash.Bytes(),
+		BlockNumber:       uint64(receipt.BlockNumber),
+		From:              receipt.From.Bytes(),
+		GasUsed:           uint64(receipt.GasUsed),
+		CumulativeGasUsed: uint64(receipt.CumulativeGasUsed),
+		Status:            uint64(receipt.Status),
+		LogsBloom:         receipt.LogsBloom.Bytes(),
+		RevertReason:      receipt.RevertReason,
+	}
+	if receipt.To != nil {
+		res.To = receipt.To.Bytes()
+	}
+	if receipt.ContractAddress != (common.Address{}) {
+		res.ContractAddress = receipt.ContractAddress.Bytes()
+	}
+	for _, log := range receipt.Logs {
+		res.Logs = append(res.Logs, pbLog(log))
+	}
+	return res, nil
+}
+
+func (g *grpcServer) GetBlock(ctx context.Context, in *evmpb.BlockRequest) (*evmpb.Block, error) {
+	var (
+		block *state.Block
+		err   error
+	)
+	switch {
+	case in.Latest:
+		block, err = g.m.state.GetBlockByNumber(g.m.state.GetBlockIndex())
+	case len(in.Hash) > 0:
+		block, err = g.m.state.GetBlockByHash(common.BytesToHash(in.Hash))
+	default:
+		block, err = g.m.state.GetBlockByNumber(int64(in.Number))
+	}
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return pbBlock(block), nil
+}
+
+func (g *grpcServer) SubscribeBlocks(in *evmpb.SubscribeBlocksRequest, stream evmpb.EVM_SubscribeBlocksServer) error {
+	// Subscribed before the past blocks are sent, so that none is missed
+	commits := make(chan state.CommitInfo, 16)
+	sub := g.m.state.SubscribeCommits(commits)
+	defer sub.Unsubscribe()
+
+	next := g.m.state.GetBlockIndex() + 1
+	if in.FromNumber > 0 {
+		next = int64(in.FromNumber)
+	}
+	sendUpTo := func(head int64) error {
+		for ; next <= head; next++ {
+			block, err := g.m.state.GetBlockByNumber(next)
+			if err != nil {
+				// Blocks committed before Block headers were recorded
+				continue
+			}
+			if err := stream.Send(pbBlock(block)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := sendUpTo(g.m.state.GetBlockIndex()); err != nil {
+		return err
+	}
+	for {
+		select {
+		case info := <-commits:
+			if !info.Block {
+				continue
+			}
+			if err := sendUpTo(info.BlockIndex); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+func (g *grpcServer) SubscribeLogs(in *evmpb.LogFilter, stream evmpb.EVM_SubscribeLogsServer) error {
+	f := &state.LogFilter{}
+	for _, addr := range in.Addresses {
+		f.Addresses = append(f.Addresses, common.BytesToAddress(addr))
+	}
+	for _, topics := range in.Topics {
+		alts := []common.Hash{}
+		for _, topic := range topics.Alternatives {
+			alts = append(alts, common.BytesToHash(topic))
+		}
+		f.Topics = append(f.Topics, alts)
+	}
+
+	// Subscribed before the past logs are sent, so that none is missed
+	commits := make(chan state.CommitInfo, 16)
+	sub := g.m.state.SubscribeCommits(commits)
+	defer sub.Unsubscribe()
+
+	next := g.m.state.GetBlockIndex() + 1
+	if in.FromBlock > 0 {
+		next = int64(in.FromBlock)
+	}
+	sendUpTo := func(head int64) error {
+		if next > head {
+			return nil
+		}
+		f.FromBlock, f.ToBlock = next, head
+		var sendErr error
+		_, err := g.m.state.FilterLogs(f, func(_ int64, logs []*ethTypes.Log) bool {
+			for _, log := range logs {
+				if sendErr = stream.Send(pbLog(log)); sendErr != nil {
+					return false
+				}
+			}
+			return true
+		})
+		next = head + 1
+		if sendErr != nil {
+			return sendErr
+		}
+		return err
+	}
+
+	if err := sendUpTo(g.m.state.GetBlockIndex()); err != nil {
+		return err
+	}
+	for {
+		select {
+		case info := <-commits:
+			if !info.Block {
+				continue
+			}
+			if err := sendUpTo(info.BlockIndex); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+func pbBlock(block *state.Block) *evmpb.Block {
+	res := &evmpb.Block{
+		Number:           block.Number,
+		Hash:             block.Hash().Bytes(),
+		ParentHash:       block.ParentHash.Bytes(),
+		ConsensusHash:    block.ConsensusHash.Bytes(),
+		Timestamp:        block.Timestamp,
+		StateRoot:        block.StateRoot.Bytes(),
+		TransactionsRoot: block.TxRoot.Bytes(),
+		ReceiptsRoot:     block.ReceiptRoot.Bytes(),
+		GasUsed:          block.GasUsed,
+	}
+	for _, hash := range block.Transactions {
+		res.Transactions = append(res.Transactions, hash.Bytes())
+	}
+	return res
+}
+
+func pbLog(log *ethTypes.Log) *evmpb.Log {
+	res := &evmpb.Log{
+		Address:     log.Address.Bytes(),
+		Data:        log.Data,
+		BlockNumber: log.BlockNumber,
+		TxHash:      log.TxHash.Bytes(),
+		TxIndex:     uint32(log.TxIndex),
+		BlockHash:   log.BlockHash.Bytes(),
+		Index:       uint32(log.Index),
+	}
+	for _, topic := range log.Topics {
+		res.Topics = append(res.Topics, topic.Bytes())
+	}
+	return res
+}
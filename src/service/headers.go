@@ -0,0 +1,95 @@
+package service
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+// maxSignedHeaders bounds the number of headers of a single request
+const maxSignedHeaders = 1000
+
+func (m *Service) signedHeader(block *state.Block) (*JsonSignedHeader, error) {
+	encoding, err := block.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	sigs, err := m.state.GetCommitSignatures(int64(block.Number))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &JsonSignedHeader{
+		JsonHeader: jsonHeader(block),
+		Encoding:   encoding,
+		Signatures: []JsonValidatorSignature{},
+	}
+	for _, sig := range sigs {
+		res.Signatures = append(res.Signatures, JsonValidatorSignature{
+			Validator: sig.Validator,
+			Signature: sig.Signature,
+		})
+	}
+	return res, nil
+}
+
+// signedHeaders returns up to count signed headers from the given number,
+// stopping at the last committed block
+func (m *Service) signedHeaders(from int64, count int) ([]*JsonSignedHeader, error) {
+	if count <= 0 || count > maxSignedHeaders {
+		count = maxSignedHeaders
+	}
+
+	res := []*JsonSignedHeader{}
+	for number := from; number < from+int64(count) && number <= m.state.GetBlockIndex(); number++ {
+		block, err := m.state.GetBlockByNumber(number)
+		if err != nil {
+			// Blocks committed before Block headers were recorded
+			continue
+		}
+		header, err := m.signedHeader(block)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, header)
+	}
+	return res, nil
+}
+
+/*
+GET /headers?from={number}&count={count}
+example: /headers?from=1200&count=100
+returns: JSON []JsonSignedHeader
+
+Returns up to count (at most 1000) commit headers from the given number, with
+their canonical encoding and the signatures of their consensus blocks by the
+validators, when the consensus system provides them. The hash of a header is
+the keccak256 hash of its encoding, and each header holds the hash of its
+parent, so auditors can verify the chain of commits served by any node.
+*/
+func signedHeadersHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	query := r.URL.Query()
+	from, err := strconv.ParseInt(query.Get("from"), 10, 64)
+	if err != nil || from < 0 {
+		m.logger.WithField("param", query.Get("from")).Error("Parsing from parameter")
+		http.Error(w, "invalid from", http.StatusBadRequest)
+		return
+	}
+	count := maxSignedHeaders
+	if param := query.Get("count"); param != "" {
+		if count, err = strconv.Atoi(param); err != nil {
+			m.logger.WithField("param", param).Error("Parsing count parameter")
+			http.Error(w, "invalid count", http.StatusBadRequest)
+			return
+		}
+	}
+
+	headers, err := m.signedHeaders(from, count)
+	if err != nil {
+		m.logger.WithError(err).Error("Getting signed headers")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, headers, m)
+}
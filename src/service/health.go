@@ -0,0 +1,74 @@
+package service
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// JsonReadiness is the result of the readiness checks of the node. Checks
+// holds "ok" or the reason of the failure of each check.
+type JsonReadiness struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]string `json:"checks"`
+}
+
+// readiness runs the readiness checks: the database can be read, the genesis
+// accounts were created, and the node is connected to the consensus system
+func (m *Service) readiness() JsonReadiness {
+	res := JsonReadiness{
+		Ready:  true,
+		Checks: map[string]string{"db": "ok", "state": "ok", "consensus": "ok"},
+	}
+	fail := func(check, reason string) {
+		res.Checks[check] = reason
+		res.Ready = false
+	}
+
+	if err := m.state.CheckDB(); err != nil {
+		fail("db", err.Error())
+	}
+	if atomic.LoadInt32(&m.initialized) == 0 {
+		fail("state", "genesis accounts not created")
+	}
+	if m.stopped() {
+		fail("state", "shutting down")
+	}
+	if !m.syncStatus().Connected {
+		fail("consensus", "not connected")
+	}
+
+	return res
+}
+
+/*
+GET /healthz
+returns: ok
+
+Liveness probe: answers as long as the process serves HTTP, without taking the
+Service lock, so that a restart is only triggered by a hung or dead process.
+*/
+func healthzHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	w.Header().Set("Content-Type", "text/plain")
+	if _, err := w.Write([]byte("ok")); err != nil {
+		m.logger.WithError(err).Error("Writing response")
+	}
+}
+
+/*
+GET /readyz
+returns: JSON JsonReadiness
+
+Readiness probe: the database is readable, the state is initialized and the
+node is connected to the consensus system (e.g. the lachesis proxy). The status
+is 503 Service Unavailable when a check fails, so that traffic is only routed
+to nodes able to serve it.
+*/
+func readyzHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	res := m.readiness()
+	if !res.Ready {
+		m.logger.WithField("checks", res.Checks).Debug("Not ready")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	writeJSON(w, res, m)
+}
@@ -2,11 +2,12 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"html/template"
 	"io/ioutil"
 	"math"
-	"math/big"
 	"net/http"
 	"strconv"
 
@@ -16,6 +17,8 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	ethTypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
 
 	"github.com/Fantom-foundation/go-evm/src/service/templates"
 	"github.com/Fantom-foundation/go-evm/src/state"
@@ -31,7 +34,7 @@ to the /accounts/ endpoint which only returns information about accounts for whi
 the private key is known and managed by the evm Service.
 */
 func accountHandler(w http.ResponseWriter, r *http.Request, m *Service) {
-	param := r.URL.Path[len("/account/"):]
+	param := mux.Vars(r)["address"]
 	m.logger.WithField("param", param).Debug("GET account")
 	address := common.HexToAddress(param)
 	m.logger.WithField("address", address.Hex()).Debug("GET account")
@@ -40,8 +43,8 @@ func accountHandler(w http.ResponseWriter, r *http.Request, m *Service) {
 	nonce := m.state.GetNonce(address)
 	account := JsonAccount{
 		Address: address.Hex(),
-		Balance: balance,
-		Nonce:   nonce,
+		Balance: (*hexutil.Big)(balance),
+		Nonce:   hexutil.Uint64(nonce),
 	}
 
 	js, err := json.Marshal(account)
@@ -60,6 +63,123 @@ func accountHandler(w http.ResponseWriter, r *http.Request, m *Service) {
 	}
 }
 
+/*
+GET /account/{address}/state?start={key_hash}&limit={limit}
+example: /account/0x50bd8a037442af4cdf631495bcaa5443de19685d/state?limit=100
+returns: JSON JsonAccountState
+
+This endpoint returns an account in the state of the last committed block: its
+balance, nonce and code, and up to limit slots of its storage (none by
+default) from the key hash start, in trie order. next, if set, is the start of
+the following page.
+*/
+func accountStateHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	address := common.HexToAddress(mux.Vars(r)["address"])
+	m.logger.WithField("address", address.Hex()).Debug("GET account state")
+
+	var start common.Hash
+	if param := r.URL.Query().Get("start"); param != "" {
+		b, err := hexutil.Decode(param)
+		if err != nil || len(b) != common.HashLength {
+			http.Error(w, fmt.Sprintf("invalid start %q", param), http.StatusBadRequest)
+			return
+		}
+		start = common.BytesToHash(b)
+	}
+	limit := 0
+	if param := r.URL.Query().Get("limit"); param != "" {
+		var err error
+		if limit, err = strconv.Atoi(param); err != nil || limit < 0 {
+			http.Error(w, fmt.Sprintf("invalid limit %q", param), http.StatusBadRequest)
+			return
+		}
+	}
+
+	account, err := GetJsonAccountState(m.state, address, start, limit)
+	if err != nil {
+		m.logger.WithError(err).Error("Getting account state")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, account, m)
+}
+
+// GetJsonAccountState builds the JsonAccountState of an account, with up to
+// limit slots of its storage from the key hash start
+func GetJsonAccountState(st *state.State, address common.Address, start common.Hash, limit int) (*JsonAccountState, error) {
+	account, err := st.GetAccountState(address, start, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := &JsonAccountState{
+		Address:     address,
+		BlockNumber: hexutil.Uint64(account.Block),
+		StateRoot:   account.Root,
+		Exists:      account.Exists,
+		Balance:     (*hexutil.Big)(account.Balance),
+		Nonce:       hexutil.Uint64(account.Nonce),
+		CodeHash:    account.CodeHash,
+		CodeSize:    hexutil.Uint64(len(account.Code)),
+		Next:        account.Next,
+	}
+	for _, slot := range account.Storage {
+		res.Storage = append(res.Storage, JsonStorageSlot{Key: slot.Key, Value: slot.Value})
+	}
+	return res, nil
+}
+
+/*
+GET /account/{address}/transactions?offset={offset}&limit={limit}
+example: /account/0x50bd8a037442af4cdf631495bcaa5443de19685d/transactions?limit=20
+returns: JSON JsonAccountTransactions
+
+This endpoint returns the hashes of the transactions sent or received by an
+account, oldest first. offset and limit are optional.
+*/
+func accountTransactionsHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	address := common.HexToAddress(mux.Vars(r)["address"])
+	m.logger.WithField("address", address.Hex()).Debug("GET account transactions")
+
+	offset, limit := 0, 0
+	var err error
+	if param := r.URL.Query().Get("offset"); param != "" {
+		if offset, err = strconv.Atoi(param); err != nil || offset < 0 {
+			http.Error(w, fmt.Sprintf("invalid offset %q", param), http.StatusBadRequest)
+			return
+		}
+	}
+	if param := r.URL.Query().Get("limit"); param != "" {
+		if limit, err = strconv.Atoi(param); err != nil || limit < 0 {
+			http.Error(w, fmt.Sprintf("invalid limit %q", param), http.StatusBadRequest)
+			return
+		}
+	}
+
+	txs, err := m.state.GetAccountTransactions(address, offset, limit)
+	if err != nil {
+		m.logger.WithError(err).Error("Getting account transactions")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	js, err := json.Marshal(JsonAccountTransactions{
+		Address:      address,
+		Transactions: txs,
+	})
+	if err != nil {
+		m.logger.WithError(err).Error("Marshaling JSON response")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(js); err != nil {
+		m.logger.WithError(err).Error("Writing JSON response")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
 /*
 GET /block/{hash}
 example: /block/0x50bd8a037442af4cdf631495bcaa5443de19685d
@@ -68,7 +188,7 @@ returns: JSON JsonBlock
 This endpoint should be used to fetch information about ANY block.
 */
 func blockByHashHandler(w http.ResponseWriter, r *http.Request, m *Service) {
-	param := r.URL.Path[len("/block/"):]
+	param := mux.Vars(r)["hash"]
 	m.logger.WithField("param", param).Debug("GET account")
 	hash := common.HexToHash(param)
 	m.logger.WithField("hash", hash.Hex()).Debug("GET blockByHashHandler")
@@ -80,23 +200,23 @@ func blockByHashHandler(w http.ResponseWriter, r *http.Request, m *Service) {
 		return
 	}
 
-	blockIndex := block.Index()         //int
-	blockRound := block.RoundReceived() //int
+	blockIndex := block.Index()                            //int
+	blockRound := block.RoundReceived()                    //int
+	blockFrameHash := hexutil.Encode(block.GetFrameHash()) //[]byte
 	//blockStateHash := hexutil.Encode(block.StateHash()) //[]byte
-	//blockFrameHash := hexutil.Encode(block.FrameHash()) //[]byte
 
 	jsBlock := JsonBlock{
 		Hash:        block.BlockHex(),
-		Index:       blockIndex,
-		Round:       blockRound,
-		CreatedTime: block.GetCreatedTime(),
+		Index:       hexutil.Uint64(blockIndex),
+		Round:       hexutil.Uint64(blockRound),
+		CreatedTime: hexutil.Uint64(block.GetCreatedTime()),
+		FrameHash:   blockFrameHash,
 		//StateHash: blockStateHash,
-		//FrameHash: blockFrameHash,
 	}
 
 	for _, txBytes := range block.Transactions() {
-		var t ethTypes.Transaction
-		if err := rlp.Decode(bytes.NewReader(txBytes), &t); err != nil {
+		t, _, err := state.DecodeTx(txBytes)
+		if err != nil {
 			m.logger.WithError(err).Error("Decoding Transaction")
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -104,76 +224,12 @@ func blockByHashHandler(w http.ResponseWriter, r *http.Request, m *Service) {
 		m.logger.WithField("hash", t.Hash().Hex()).Debug("blockByIdHandler.decoded")
 		txHash := t.Hash()
 
-		tx, err := m.state.GetTransaction(txHash)
-		jsonReceipt := JsonReceipt{}
+		jsonReceipt, err := m.getJsonReceipt(txHash)
 		if err != nil {
-			m.logger.WithError(err).Error("m.state.GetTransaction(txHash)")
-
-			txFailed, err := m.state.GetFailedTx(txHash)
-			if err != nil {
-				m.logger.WithError(err).Error("m.state.GetFailedTx(txHash)")
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			tx = txFailed.GetTx()
-
-			signer := ethTypes.NewEIP155Signer(big.NewInt(1))
-			from, err := ethTypes.Sender(signer, tx)
-			if err != nil {
-				m.logger.WithError(err).Error("Getting Tx Sender")
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-
-			jsonReceipt = JsonReceipt{
-				TransactionHash: txHash,
-				From:            from,
-				To:              tx.To(),
-				Value:           tx.Value(),
-				Gas:             new(big.Int).SetUint64(tx.Gas()),
-				GasPrice:        tx.GasPrice(),
-				Error:           txFailed.GetError(),
-				Failed:          true,
-			}
-
-		} else {
-
-			signer := ethTypes.NewEIP155Signer(big.NewInt(1))
-			from, err := ethTypes.Sender(signer, tx)
-			if err != nil {
-				m.logger.WithError(err).Error("Getting Tx Sender")
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-
-			receipt, err := m.state.GetReceipt(txHash)
-			if err != nil {
-				m.logger.WithError(err).Error("Getting Receipt")
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-
-			jsonReceipt = JsonReceipt{
-				Root:              common.BytesToHash(receipt.PostState),
-				TransactionHash:   txHash,
-				From:              from,
-				To:                tx.To(),
-				Value:             tx.Value(),
-				Gas:               new(big.Int).SetUint64(tx.Gas()),
-				GasPrice:          tx.GasPrice(),
-				GasUsed:           big.NewInt(0).SetUint64(receipt.GasUsed),
-				CumulativeGasUsed: big.NewInt(0).SetUint64(receipt.CumulativeGasUsed),
-				ContractAddress:   receipt.ContractAddress,
-				Logs:              receipt.Logs,
-				LogsBloom:         receipt.Bloom,
-				Failed:            false,
-			}
-
-			if receipt.Logs == nil {
-				jsonReceipt.Logs = []*ethTypes.Log{}
-			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
-		jsBlock.Transactions = append(jsBlock.Transactions, jsonReceipt)
+		jsBlock.Transactions = append(jsBlock.Transactions, *jsonReceipt)
 	}
 
 	js, err := json.Marshal(jsBlock)
@@ -199,7 +255,7 @@ returns: JSON JsonBlock
 This endpoint should be used to fetch information about ANY block.
 */
 func blockByIdHandler(w http.ResponseWriter, r *http.Request, m *Service) {
-	param := r.URL.Path[len("/blockById/"):]
+	param := mux.Vars(r)["id"]
 	m.logger.WithField("param", param).Debug("GET account")
 	id, err := strconv.ParseInt(param, 10, 64)
 	if err != nil {
@@ -216,23 +272,23 @@ func blockByIdHandler(w http.ResponseWriter, r *http.Request, m *Service) {
 		return
 	}
 
-	blockIndex := block.Index()         //int
-	blockRound := block.RoundReceived() //int
+	blockIndex := block.Index()                            //int
+	blockRound := block.RoundReceived()                    //int
+	blockFrameHash := hexutil.Encode(block.GetFrameHash()) //[]byte
 	//blockStateHash := hexutil.Encode(block.StateHash()) //[]byte
-	//blockFrameHash := hexutil.Encode(block.FrameHash()) //[]byte
 
 	jsBlock := JsonBlock{
 		Hash:        block.BlockHex(),
-		Index:       blockIndex,
-		Round:       blockRound,
-		CreatedTime: block.GetCreatedTime(),
+		Index:       hexutil.Uint64(blockIndex),
+		Round:       hexutil.Uint64(blockRound),
+		CreatedTime: hexutil.Uint64(block.GetCreatedTime()),
+		FrameHash:   blockFrameHash,
 		//StateHash: blockStateHash,
-		//FrameHash: blockFrameHash,
 	}
 
 	for _, txBytes := range block.Transactions() {
-		var t ethTypes.Transaction
-		if err := rlp.Decode(bytes.NewReader(txBytes), &t); err != nil {
+		t, _, err := state.DecodeTx(txBytes)
+		if err != nil {
 			m.logger.WithError(err).Error("Decoding Transaction")
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -240,76 +296,12 @@ func blockByIdHandler(w http.ResponseWriter, r *http.Request, m *Service) {
 		m.logger.WithField("hash", t.Hash().Hex()).Debug("blockByIdHandler.decoded")
 		txHash := t.Hash()
 
-		tx, err := m.state.GetTransaction(txHash)
-		jsonReceipt := JsonReceipt{}
+		jsonReceipt, err := m.getJsonReceipt(txHash)
 		if err != nil {
-			m.logger.WithError(err).Error("m.state.GetTransaction(txHash)")
-
-			txFailed, err := m.state.GetFailedTx(txHash)
-			if err != nil {
-				m.logger.WithError(err).Error("m.state.GetFailedTx(txHash)")
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			tx = txFailed.GetTx()
-
-			signer := ethTypes.NewEIP155Signer(big.NewInt(1))
-			from, err := ethTypes.Sender(signer, tx)
-			if err != nil {
-				m.logger.WithError(err).Error("Getting Tx Sender")
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-
-			jsonReceipt = JsonReceipt{
-				TransactionHash: txHash,
-				From:            from,
-				To:              tx.To(),
-				Value:           tx.Value(),
-				Gas:             new(big.Int).SetUint64(tx.Gas()),
-				GasPrice:        tx.GasPrice(),
-				Error:           txFailed.GetError(),
-				Failed:          true,
-			}
-
-		} else {
-
-			signer := ethTypes.NewEIP155Signer(big.NewInt(1))
-			from, err := ethTypes.Sender(signer, tx)
-			if err != nil {
-				m.logger.WithError(err).Error("Getting Tx Sender")
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-
-			receipt, err := m.state.GetReceipt(txHash)
-			if err != nil {
-				m.logger.WithError(err).Error("Getting Receipt")
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-
-			jsonReceipt = JsonReceipt{
-				Root:              common.BytesToHash(receipt.PostState),
-				TransactionHash:   txHash,
-				From:              from,
-				To:                tx.To(),
-				Value:             tx.Value(),
-				Gas:               new(big.Int).SetUint64(tx.Gas()),
-				GasPrice:          tx.GasPrice(),
-				GasUsed:           big.NewInt(0).SetUint64(receipt.GasUsed),
-				CumulativeGasUsed: big.NewInt(0).SetUint64(receipt.CumulativeGasUsed),
-				ContractAddress:   receipt.ContractAddress,
-				Logs:              receipt.Logs,
-				LogsBloom:         receipt.Bloom,
-				Failed:            false,
-			}
-
-			if receipt.Logs == nil {
-				jsonReceipt.Logs = []*ethTypes.Log{}
-			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
-		jsBlock.Transactions = append(jsBlock.Transactions, jsonReceipt)
+		jsBlock.Transactions = append(jsBlock.Transactions, *jsonReceipt)
 	}
 
 	js, err := json.Marshal(jsBlock)
@@ -347,8 +339,8 @@ func accountsHandler(w http.ResponseWriter, _ *http.Request, m *Service) {
 		al.Accounts = append(al.Accounts,
 			JsonAccount{
 				Address: account.Address.Hex(),
-				Balance: balance,
-				Nonce:   nonce,
+				Balance: (*hexutil.Big)(balance),
+				Nonce:   hexutil.Uint64(nonce),
 			})
 	}
 
@@ -403,7 +395,7 @@ func callHandler(w http.ResponseWriter, r *http.Request, m *Service) {
 		return
 	}
 
-	data, err := m.state.Call(*callMessage)
+	data, err := m.state.Call(r.Context(), *callMessage)
 	if err != nil {
 		m.logger.WithError(err).Error("Executing Call")
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -467,6 +459,13 @@ func transactionHandler(w http.ResponseWriter, r *http.Request, m *Service) {
 		}
 	})()
 
+	if txArgs.Nonce == nil {
+		// Like eth_sendTransaction, hold the sender's lock until the
+		// transaction with the assigned nonce is in the pool
+		m.nonceLock.LockAddr(txArgs.From)
+		defer m.nonceLock.UnlockAddr(txArgs.From)
+	}
+
 	tx, err := prepareTransaction(txArgs, m.state, m.keyStore)
 	if err != nil {
 		m.logger.WithError(err).Error("Preparing Transaction")
@@ -474,17 +473,11 @@ func transactionHandler(w http.ResponseWriter, r *http.Request, m *Service) {
 		return
 	}
 
-	data, err := rlp.EncodeToBytes(tx)
-	if err != nil {
-		m.logger.WithError(err).Error("Encoding Transaction")
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := m.addTx(r.Context(), tx); err != nil {
+		writeTxError(w, err, m)
 		return
 	}
 
-	m.logger.Debug("submitting tx")
-	m.submitCh <- data
-	m.logger.Debug("submitted tx")
-
 	res := JsonTxRes{TxHash: tx.Hash().Hex()}
 	js, err := json.Marshal(res)
 	if err != nil {
@@ -545,17 +538,19 @@ func rawTransactionHandler(w http.ResponseWriter, r *http.Request, m *Service) {
 	}
 	m.logger.WithField("raw tx bytes", rawTxBytes).Debug()
 
-	m.logger.Debug("submitting tx")
-	m.submitCh <- rawTxBytes
-	m.logger.Debug("submitted tx")
-
 	var t ethTypes.Transaction
 	if err := rlp.Decode(bytes.NewReader(rawTxBytes), &t); err != nil {
 		m.logger.WithError(err).Error("Decoding Transaction")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	m.logger.WithField("hash", t.Hash().Hex()).Debug("Decoded tx")
 
+	if err := m.addTx(r.Context(), &t); err != nil {
+		writeTxError(w, err, m)
+		return
+	}
+
 	res := JsonTxRes{TxHash: t.Hash().Hex()}
 	js, err := json.Marshal(res)
 	if err != nil {
@@ -585,79 +580,14 @@ information as the address of a newly created contract, how much gas was use and
 the EVM Logs produced by the execution of the transaction.
 */
 func transactionReceiptHandler(w http.ResponseWriter, r *http.Request, m *Service) {
-	param := r.URL.Path[len("/transaction/"):]
+	param := mux.Vars(r)["tx_hash"]
 	txHash := common.HexToHash(param)
 	m.logger.WithField("tx_hash", txHash.Hex()).Debug("GET tx")
 
-	tx, err := m.state.GetTransaction(txHash)
-	jsonReceipt := JsonReceipt{}
+	jsonReceipt, err := m.getJsonReceipt(txHash)
 	if err != nil {
-		m.logger.WithError(err).Error("m.state.GetTransaction(txHash)")
-
-		txFailed, err := m.state.GetFailedTx(txHash)
-		if err != nil {
-			m.logger.WithError(err).Error("m.state.GetFailedTx(txHash)")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		tx = txFailed.GetTx()
-
-		signer := ethTypes.NewEIP155Signer(big.NewInt(1))
-		from, err := ethTypes.Sender(signer, tx)
-		if err != nil {
-			m.logger.WithError(err).Error("Getting Tx Sender")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		jsonReceipt = JsonReceipt{
-			TransactionHash: txHash,
-			From:            from,
-			To:              tx.To(),
-			Value:           tx.Value(),
-			Gas:             new(big.Int).SetUint64(tx.Gas()),
-			GasPrice:        tx.GasPrice(),
-			Error:           txFailed.GetError(),
-			Failed:          true,
-		}
-
-	} else {
-
-		signer := ethTypes.NewEIP155Signer(big.NewInt(1))
-		from, err := ethTypes.Sender(signer, tx)
-		if err != nil {
-			m.logger.WithError(err).Error("Getting Tx Sender")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		receipt, err := m.state.GetReceipt(txHash)
-		if err != nil {
-			m.logger.WithError(err).Error("Getting Receipt")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		jsonReceipt = JsonReceipt{
-			Root:              common.BytesToHash(receipt.PostState),
-			TransactionHash:   txHash,
-			From:              from,
-			To:                tx.To(),
-			Value:             tx.Value(),
-			Gas:               new(big.Int).SetUint64(tx.Gas()),
-			GasPrice:          tx.GasPrice(),
-			GasUsed:           big.NewInt(0).SetUint64(receipt.GasUsed),
-			CumulativeGasUsed: big.NewInt(0).SetUint64(receipt.CumulativeGasUsed),
-			ContractAddress:   receipt.ContractAddress,
-			Logs:              receipt.Logs,
-			LogsBloom:         receipt.Bloom,
-			Failed:            false,
-			Status:            receipt.Status,
-		}
-
-		if receipt.Logs == nil {
-			jsonReceipt.Logs = []*ethTypes.Log{}
-		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
 	js, err := json.Marshal(jsonReceipt)
@@ -684,85 +614,110 @@ This endpoint allows to retrieve the EVM receipt of a specific transactions if i
 exists. When a transaction is applied to the EVM , a receipt is saved to allow
 checking if/how the transaction affected the state. This is where one can see such
 information as the address of a newly created contract, how much gas was use and
-the EVM Logs produced by the execution of the transaction.
+the EVM Logs produced by the execution of the transaction. For blocks committed
+by Lachesis, the consensus metadata of the block (round, frame hash and
+consensus timestamp) tells how final the transaction is.
 */
 func txReceiptHandler(w http.ResponseWriter, r *http.Request, m *Service) {
-	param := r.URL.Path[len("/tx/"):]
+	param := mux.Vars(r)["tx_hash"]
 	txHash := common.HexToHash(param)
 	m.logger.WithField("tx_hash", txHash.Hex()).Debug("GET tx")
 
-	tx, err := m.state.GetTransaction(txHash)
-	jsonReceipt := JsonReceipt{}
+	jsonReceipt, err := m.getJsonReceipt(txHash)
 	if err != nil {
-		m.logger.WithError(err).Error("m.state.GetTransaction(txHash)")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-		txFailed, err := m.state.GetFailedTx(txHash)
-		if err != nil {
-			m.logger.WithError(err).Error("m.state.GetFailedTx(txHash)")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		tx = txFailed.GetTx()
+	js, err := json.Marshal(jsonReceipt)
+	if err != nil {
+		m.logger.WithError(err).Error("Marshaling JSON response")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-		signer := ethTypes.NewEIP155Signer(big.NewInt(1))
-		from, err := ethTypes.Sender(signer, tx)
-		if err != nil {
-			m.logger.WithError(err).Error("Getting Tx Sender")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(js); err != nil {
+		m.logger.WithError(err).Error("Writing JSON response")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
 
-		jsonReceipt = JsonReceipt{
-			TransactionHash: txHash,
-			From:            from,
-			To:              tx.To(),
-			Value:           tx.Value(),
-			Gas:             new(big.Int).SetUint64(tx.Gas()),
-			GasPrice:        tx.GasPrice(),
-			Error:           txFailed.GetError(),
-			Failed:          true,
-		}
+/*
+GET /header/{number}
+example: /header/12
+returns: JSON JsonHeader
 
-	} else {
+This endpoint returns the EVM Block committed at the given index, with the
+consensus metadata of the block if it was committed by Lachesis.
+*/
+func headerByNumberHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	param := mux.Vars(r)["number"]
+	m.logger.WithField("param", param).Debug("GET header")
+	number, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		m.logger.WithError(err).Errorf("Parsing number parameter %s", param)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-		signer := ethTypes.NewEIP155Signer(big.NewInt(1))
-		from, err := ethTypes.Sender(signer, tx)
-		if err != nil {
-			m.logger.WithError(err).Error("Getting Tx Sender")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+	block, err := m.state.GetBlockByNumber(number)
+	if err != nil {
+		m.logger.WithError(err).Error("Getting Block by number")
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
 
-		receipt, err := m.state.GetReceipt(txHash)
-		if err != nil {
-			m.logger.WithError(err).Error("Getting Receipt")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+	writeHeader(w, block, m)
+}
 
-		jsonReceipt = JsonReceipt{
-			Root:              common.BytesToHash(receipt.PostState),
-			TransactionHash:   txHash,
-			From:              from,
-			To:                tx.To(),
-			Value:             tx.Value(),
-			Gas:               new(big.Int).SetUint64(tx.Gas()),
-			GasPrice:          tx.GasPrice(),
-			GasUsed:           big.NewInt(0).SetUint64(receipt.GasUsed),
-			CumulativeGasUsed: big.NewInt(0).SetUint64(receipt.CumulativeGasUsed),
-			ContractAddress:   receipt.ContractAddress,
-			Logs:              receipt.Logs,
-			LogsBloom:         receipt.Bloom,
-			Failed:            false,
-			Status:            receipt.Status,
-		}
+/*
+GET /headerByHash/{hash}
+example: /headerByHash/0x6ee5e3ab1cdcd2dbd8d0b5e35b9bc1bcbd0ac61e8c9a5e8ab4e3b19b6ec5f1e0
+returns: JSON JsonHeader
 
-		if receipt.Logs == nil {
-			jsonReceipt.Logs = []*ethTypes.Log{}
-		}
+This endpoint returns the EVM Block with the given hash.
+*/
+func headerByHashHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	param := mux.Vars(r)["hash"]
+	m.logger.WithField("param", param).Debug("GET headerByHash")
+	hash := common.HexToHash(param)
+
+	block, err := m.state.GetBlockByHash(hash)
+	if err != nil {
+		m.logger.WithError(err).Error("Getting Block by hash")
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
 	}
 
-	js, err := json.Marshal(jsonReceipt)
+	writeHeader(w, block, m)
+}
+
+/*
+GET /genesis/logs
+returns: JSON JsonGenesisLogs
+
+This endpoint returns the logs emitted by the constructors of the genesis
+contracts, run when the database was initialized, in order.
+*/
+func genesisLogsHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	m.logger.Debug("GET genesis logs")
+
+	logs, err := m.state.GetGenesisLogs()
+	if err != nil {
+		m.logger.WithError(err).Error("Getting genesis logs")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, JsonGenesisLogs{Logs: logs}, m)
+}
+
+func writeHeader(w http.ResponseWriter, block *state.Block, m *Service) {
+	header := jsonHeader(block)
+	header.Consensus = jsonConsensus(m.state, block.ConsensusHash)
+
+	js, err := json.Marshal(header)
 	if err != nil {
 		m.logger.WithError(err).Error("Marshaling JSON response")
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -777,6 +732,85 @@ func txReceiptHandler(w http.ResponseWriter, r *http.Request, m *Service) {
 	}
 }
 
+/*
+GET /txpool
+returns: JSON JsonTxPoolContent
+
+This endpoint returns the transactions waiting for consensus ordering: pending
+transactions were accepted by the TxPool and submitted since the last commit,
+queued transactions wait for a nonce gap to be filled. The size is that of the
+payload, in bytes.
+*/
+func txPoolHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	m.logger.Debug("GET txpool")
+
+	pending, queued := m.state.GetPoolContent()
+	content := JsonTxPoolContent{
+		Pending: jsonPoolTxs(pending),
+		Queued:  jsonPoolTxs(queued),
+	}
+
+	js, err := json.Marshal(content)
+	if err != nil {
+		m.logger.WithError(err).Error("Marshaling JSON response")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(js); err != nil {
+		m.logger.WithError(err).Error("Writing JSON response")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func jsonPoolTxs(txs []state.PoolTx) []JsonPoolTx {
+	res := make([]JsonPoolTx, len(txs))
+	for i, tx := range txs {
+		res[i] = JsonPoolTx{
+			Hash:     tx.Hash,
+			From:     tx.From,
+			To:       tx.To,
+			Nonce:    hexutil.Uint64(tx.Nonce),
+			GasPrice: (*hexutil.Big)(tx.GasPrice),
+			Gas:      hexutil.Uint64(tx.Gas),
+			Size:     hexutil.Uint64(tx.Size),
+		}
+	}
+	return res
+}
+
+// jsonConsensus returns the consensus metadata of the block with the given
+// ConsensusHash, or nil if it was not committed by Lachesis
+func jsonConsensus(st *state.State, consensusHash common.Hash) *JsonConsensus {
+	info, err := st.GetConsensusInfo(consensusHash)
+	if err != nil {
+		return nil
+	}
+	return &JsonConsensus{
+		Round:     hexutil.Uint64(info.Round),
+		FrameHash: info.FrameHash,
+		Timestamp: hexutil.Uint64(info.Timestamp),
+	}
+}
+
+func jsonHeader(block *state.Block) *JsonHeader {
+	return &JsonHeader{
+		Number:        hexutil.Uint64(block.Number),
+		Hash:          block.Hash(),
+		ParentHash:    block.ParentHash,
+		ConsensusHash: block.ConsensusHash,
+		Timestamp:     hexutil.Uint64(block.Timestamp),
+		StateRoot:     block.StateRoot,
+		TxRoot:        block.TxRoot,
+		ReceiptRoot:   block.ReceiptRoot,
+		GasUsed:       hexutil.Uint64(block.GasUsed),
+		Transactions:  block.Transactions,
+		Coinbase:      block.Coinbase,
+	}
+}
+
 /*
 GET /info
 returns: JSON (depends on underlying consensus system)
@@ -839,6 +873,163 @@ func htmlInfoHandler(w http.ResponseWriter, r *http.Request, m *Service) {
 }
 
 //------------------------------------------------------------------------------
+
+// addTx adds a transaction to the TxPool and submits the transactions it made
+// executable. Future-nonce transactions are held in the TxPool.
+func (m *Service) addTx(ctx context.Context, tx *ethTypes.Transaction) error {
+	if m.stopped() {
+		return ErrStopping
+	}
+	ready, err := m.state.AddTx(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if len(ready) == 0 {
+		m.logger.WithField("hash", tx.Hash().Hex()).Debug("queued tx")
+	}
+	for _, t := range ready {
+		if err := m.submitTx(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTxError answers a rejected transaction with a status depending on the
+// class of the error: the client's fault, a full pool, or an internal error
+func writeTxError(w http.ResponseWriter, err error, m *Service) {
+	status := http.StatusInternalServerError
+	switch state.ErrorClass(err) {
+	case state.ErrMalformedTx,
+		state.ErrInvalidSig,
+		state.ErrNonceTooLow,
+		state.ErrNonceTooHigh,
+		state.ErrInsufficientFunds,
+		state.ErrIntrinsicGas,
+		state.ErrMaxInitCodeSize,
+		state.ErrMaxCodeSize,
+		state.ErrUnderpriced,
+		state.ErrReplaceUnderpriced:
+		status = http.StatusBadRequest
+	case state.ErrTxPoolFull, state.ErrGasLimitReached, ErrStopping:
+		status = http.StatusServiceUnavailable
+	}
+
+	if status == http.StatusInternalServerError {
+		m.logger.WithError(err).Error("Adding Transaction to TxPool")
+	} else {
+		m.logger.WithError(err).Debug("Transaction rejected")
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// submitTx submits a transaction to the consensus system
+func (m *Service) submitTx(tx *ethTypes.Transaction) error {
+	data, err := m.encodeTx(tx)
+	if err != nil {
+		m.logger.WithError(err).Error("Encoding Transaction")
+		return err
+	}
+
+	m.logger.WithField("hash", tx.Hash().Hex()).Debug("submitting tx")
+	m.submitCh <- data
+	m.logger.Debug("submitted tx")
+
+	return nil
+}
+
+func (m *Service) getJsonReceipt(txHash common.Hash) (*JsonReceipt, error) {
+	return GetJsonReceipt(m.state, txHash, m.logger)
+}
+
+// GetJsonReceipt builds the JsonReceipt of a transaction, whether it was
+// applied successfully or failed
+func GetJsonReceipt(st *state.State, txHash common.Hash, logger *logrus.Logger) (*JsonReceipt, error) {
+	tx, err := st.GetTransaction(txHash)
+	if err != nil {
+		logger.WithError(err).Error("st.GetTransaction(txHash)")
+
+		txFailed, err := st.GetFailedTx(txHash)
+		if err != nil {
+			logger.WithError(err).Error("st.GetFailedTx(txHash)")
+			return nil, err
+		}
+		tx = txFailed.GetTx()
+
+		signer := ethTypes.NewEIP155Signer(st.ChainID())
+		from, err := ethTypes.Sender(signer, tx)
+		if err != nil {
+			logger.WithError(err).Error("Getting Tx Sender")
+			return nil, err
+		}
+
+		return &JsonReceipt{
+			TransactionHash: txHash,
+			From:            from,
+			To:              tx.To(),
+			Value:           (*hexutil.Big)(tx.Value()),
+			Gas:             hexutil.Uint64(tx.Gas()),
+			GasPrice:        (*hexutil.Big)(tx.GasPrice()),
+			Logs:            []*ethTypes.Log{},
+			Error:           txFailed.GetError(),
+			Failed:          true,
+		}, nil
+	}
+
+	receipt, err := st.GetReceipt(txHash)
+	if err != nil {
+		logger.WithError(err).Error("Getting Receipt")
+		return nil, err
+	}
+
+	lookup, err := st.GetTxLookup(txHash)
+	if err != nil {
+		logger.WithError(err).Error("Getting Tx Lookup")
+		return nil, err
+	}
+
+	jsonReceipt := &JsonReceipt{
+		Root:              common.BytesToHash(receipt.PostState),
+		TransactionHash:   txHash,
+		TransactionIndex:  hexutil.Uint64(lookup.Index),
+		BlockHash:         lookup.BlockHash,
+		BlockNumber:       hexutil.Uint64(lookup.BlockNumber),
+		From:              lookup.From,
+		To:                tx.To(),
+		Value:             (*hexutil.Big)(tx.Value()),
+		Gas:               hexutil.Uint64(tx.Gas()),
+		GasPrice:          (*hexutil.Big)(tx.GasPrice()),
+		GasUsed:           hexutil.Uint64(receipt.GasUsed),
+		CumulativeGasUsed: hexutil.Uint64(receipt.CumulativeGasUsed),
+		ContractAddress:   receipt.ContractAddress,
+		Logs:              receipt.Logs,
+		LogsBloom:         receipt.Bloom,
+		Failed:            false,
+		Status:            hexutil.Uint64(receipt.Status),
+	}
+
+	if receipt.Logs == nil {
+		jsonReceipt.Logs = []*ethTypes.Log{}
+	}
+
+	if block, err := st.GetBlockByNumber(int64(lookup.BlockNumber)); err == nil {
+		jsonReceipt.Consensus = jsonConsensus(st, block.ConsensusHash)
+	}
+
+	if receipt.Status == ethTypes.ReceiptStatusFailed {
+		if ret, err := st.GetRevertData(txHash); err == nil {
+			jsonReceipt.RevertData = ret
+			jsonReceipt.RevertReason, _ = UnpackRevertReason(ret)
+		}
+		if gasCap, ok := st.GetGasCap(txHash); ok {
+			jsonReceipt.GasCapped = true
+			jsonReceipt.Error = fmt.Sprintf("gas cap of %d exceeded", gasCap)
+		}
+	}
+
+	return jsonReceipt, nil
+}
+
 func prepareCallMessage(args SendTxArgs, _ *keystore.KeyStore) (*ethTypes.Message, error) {
 	var err error
 	args, err = prepareSendTxArgs(args)
@@ -902,7 +1093,7 @@ func prepareTransaction(args SendTxArgs, state *state.State, ks *keystore.KeySto
 			[]byte(*args.Data))
 	}
 
-	signer := ethTypes.NewEIP155Signer(big.NewInt(1))
+	signer := ethTypes.NewEIP155Signer(state.ChainID())
 
 	account, err := ks.Find(accounts.Account{Address: args.From})
 	if err != nil {
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+// DefaultMaxLogs is the default maximum number of logs returned by a log query
+const DefaultMaxLogs = 10000
+
+// LogLimits are the ceilings of the log queries (eth_getLogs, GraphQL logs and
+// GET /logs). 0 disables a limit.
+type LogLimits struct {
+	// logs returned by eth_getLogs and GraphQL, which fail above it, and by a
+	// page of GET /logs
+	MaxLogs int
+
+	// blocks of the range of eth_getLogs and GraphQL, which fail above it, and
+	// of a page of GET /logs
+	MaxBlockRange int64
+}
+
+//SetLogLimits sets the ceilings of the log queries, so that a single query
+//can't make the node hold an unbounded number of logs in memory
+func (m *Service) SetLogLimits(limits LogLimits) {
+	m.logLimits = limits
+	m.logger.WithFields(logrus.Fields{
+		"max_logs":        limits.MaxLogs,
+		"max_block_range": limits.MaxBlockRange,
+	}).Debug("Log limits set")
+}
+
+// getLogs returns the logs matching a filter, failing if the query exceeds the
+// LogLimits
+func (m *Service) getLogs(f *state.LogFilter) ([]*ethTypes.Log, error) {
+	to := f.ToBlock
+	if head := m.state.GetBlockIndex(); to > head {
+		to = head
+	}
+	if max := m.logLimits.MaxBlockRange; max > 0 && to-f.FromBlock >= max {
+		return nil, fmt.Errorf("query range exceeds %d blocks, use GET /logs to page through it", max)
+	}
+	return m.state.GetLogs(f, m.logLimits.MaxLogs)
+}
+
+// JsonLogPage is a page of the logs matching a filter. Next is the block from
+// which to query the next page, and is null when the range is exhausted.
+type JsonLogPage struct {
+	Logs  []*ethTypes.Log `json:"logs"`
+	Next  *hexutil.Uint64 `json:"next"`
+	Error string          `json:"error,omitempty"`
+}
+
+/*
+GET /logs?fromBlock={number}&toBlock={number}&address={addresses}&topic0={topics}&limit={limit}
+example: /logs?fromBlock=0&toBlock=latest&address=0x50bd...&topic0=0xddf2...
+returns: JSON JsonLogPage
+
+Returns the logs of the committed blocks matching a filter, like eth_getLogs,
+in pages which are streamed while the blocks are read. The blocks default to
+latest, address is a comma separated list of emitting contracts, and topic0 to
+topic3 are comma separated lists of the topics allowed at each position. A page
+stops after the block where it reaches limit logs (at most the maximum number
+of logs of the node), or at the maximum block range of the node: its next field
+is then the fromBlock of the next page. Errors after the response started are
+reported in its error field.
+*/
+func logsHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	f, err := parseLogQuery(r.URL.Query(), m.state.GetBlockIndex())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit := m.logLimits.MaxLogs
+	if param := r.URL.Query().Get("limit"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n <= 0 {
+			http.Error(w, fmt.Sprintf("invalid limit %q", param), http.StatusBadRequest)
+			return
+		}
+		if limit <= 0 || n < limit {
+			limit = n
+		}
+	}
+
+	// Ranges beyond the ceiling are served in several pages
+	end := f.ToBlock
+	if head := m.state.GetBlockIndex(); end > head {
+		end = head
+	}
+	if max := m.logLimits.MaxBlockRange; max > 0 && end-f.FromBlock >= max {
+		f.ToBlock = f.FromBlock + max - 1
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	flusher, _ := w.(http.Flusher)
+	if _, err := w.Write([]byte(`{"logs":[`)); err != nil {
+		m.logger.WithError(err).Error("Writing JSON response")
+		return
+	}
+
+	count := 0
+	var writeErr error
+	next, err := m.state.FilterLogs(f, func(_ int64, logs []*ethTypes.Log) bool {
+		for _, log := range logs {
+			js, err := json.Marshal(log)
+			if err != nil {
+				writeErr = err
+				return false
+			}
+			if count > 0 {
+				js = append([]byte{','}, js...)
+			}
+			if _, err := w.Write(js); err != nil {
+				writeErr = err
+				return false
+			}
+			count++
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return limit <= 0 || count < limit
+	})
+	if writeErr != nil {
+		m.logger.WithError(writeErr).Error("Writing JSON response")
+		return
+	}
+	if next < 0 && err == nil && f.ToBlock < end {
+		next = f.ToBlock + 1
+	}
+
+	trailer := []byte(`],"next":null`)
+	if next >= 0 && err == nil {
+		trailer = []byte(fmt.Sprintf(`],"next":"%s"`, hexutil.EncodeUint64(uint64(next))))
+	}
+	if err != nil {
+		m.logger.WithError(err).Error("Filtering logs")
+		msg, _ := json.Marshal(err.Error())
+		trailer = append(append(trailer, `,"error":`...), msg...)
+	}
+	if _, err := w.Write(append(trailer, '}')); err != nil {
+		m.logger.WithError(err).Error("Writing JSON response")
+	}
+}
+
+// parseLogQuery returns the filter of the parameters of GET /logs
+func parseLogQuery(query url.Values, head int64) (*state.LogFilter, error) {
+	from, err := parseLogBlock(query.Get("fromBlock"), head)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fromBlock: %s", err)
+	}
+	to, err := parseLogBlock(query.Get("toBlock"), head)
+	if err != nil {
+		return nil, fmt.Errorf("invalid toBlock: %s", err)
+	}
+	f := &state.LogFilter{FromBlock: from, ToBlock: to}
+
+	for _, param := range splitList(query.Get("address")) {
+		addr, err := decodeFilterAddress(param)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q: %s", param, err)
+		}
+		f.Addresses = append(f.Addresses, addr)
+	}
+
+	for i := 0; i < 4; i++ {
+		name := fmt.Sprintf("topic%d", i)
+		var alts []common.Hash
+		for _, param := range splitList(query.Get(name)) {
+			topic, err := decodeFilterTopic(param)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %s", name, param, err)
+			}
+			alts = append(alts, topic)
+		}
+		f.Topics = append(f.Topics, alts)
+	}
+	// Trailing positions matching any topic don't require the logs to have
+	// them
+	for len(f.Topics) > 0 && len(f.Topics[len(f.Topics)-1]) == 0 {
+		f.Topics = f.Topics[:len(f.Topics)-1]
+	}
+	return f, nil
+}
+
+// parseLogBlock parses a block number, in decimal or hex, or latest which is
+// also the default
+func parseLogBlock(param string, head int64) (int64, error) {
+	if param == "" || param == "latest" || param == "pending" {
+		return head, nil
+	}
+	n, err := strconv.ParseInt(param, 0, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("negative block %d", n)
+	}
+	return n, nil
+}
+
+// splitList returns the elements of a comma separated list, or nil if empty
+func splitList(param string) []string {
+	if param == "" {
+		return nil
+	}
+	return strings.Split(param, ",")
+}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// headers of a signed inter-node request
+	nodeTimestampHeader = "X-Node-Timestamp"
+	nodeSignatureHeader = "X-Node-Signature"
+
+	// maximum difference between the timestamp of a signed request and the
+	// local time, which bounds the window to replay a captured request
+	nodeRequestMaxSkew = 5 * time.Minute
+
+	// maximum size of the body of an inter-node request, read before its
+	// signature can be checked
+	maxNodeRequestSize = 1024 * 1024
+)
+
+// errNodeRequestTooLarge is returned for an inter-node request whose body
+// exceeds maxNodeRequestSize
+var errNodeRequestTooLarge = errors.New("request too large")
+
+// nodeAuth identifies this node to its peers and the peers allowed to call
+// the inter-node endpoints. Peers are identified by the address of their node
+// key.
+type nodeAuth struct {
+	key   *ecdsa.PrivateKey
+	peers map[common.Address]bool
+}
+
+// LoadNodeKey reads the node key from a file, generating and saving a new one
+// if the file does not exist
+func LoadNodeKey(file string) (*ecdsa.PrivateKey, error) {
+	key, err := crypto.LoadECDSA(file)
+	if err == nil {
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("loading node key %s: %s", file, err)
+	}
+
+	key, err = crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := crypto.SaveECDSA(file, key); err != nil {
+		return nil, fmt.Errorf("saving node key %s: %s", file, err)
+	}
+	return key, nil
+}
+
+//SetNodeAuth enables the inter-node endpoints (/node/...), which only serve
+//requests signed by the node key of one of the given peers
+func (m *Service) SetNodeAuth(key *ecdsa.PrivateKey, peers []common.Address) {
+	auth := &nodeAuth{
+		key:   key,
+		peers: make(map[common.Address]bool),
+	}
+	for _, peer := range peers {
+		auth.peers[peer] = true
+	}
+	m.nodeAuth = auth
+
+	m.logger.WithFields(logrus.Fields{
+		"node":  crypto.PubkeyToAddress(key.PublicKey).Hex(),
+		"peers": len(peers),
+	}).Info("Inter-node endpoints enabled")
+}
+
+// nodeRequestHash is the hash signed by the sender of an inter-node request
+func nodeRequestHash(method, uri string, timestamp int64, body []byte) []byte {
+	return crypto.Keccak256(
+		[]byte(method),
+		[]byte(uri),
+		[]byte(strconv.FormatInt(timestamp, 10)),
+		crypto.Keccak256(body))
+}
+
+// SignNodeRequest signs an inter-node request, whose body is given, with the
+// node key of the sender
+func SignNodeRequest(r *http.Request, body []byte, key *ecdsa.PrivateKey) error {
+	timestamp := time.Now().Unix()
+	sig, err := crypto.Sign(nodeRequestHash(r.Method, r.URL.RequestURI(), timestamp, body), key)
+	if err != nil {
+		return err
+	}
+
+	r.Header.Set(nodeTimestampHeader, strconv.FormatInt(timestamp, 10))
+	r.Header.Set(nodeSignatureHeader, hexutil.Encode(sig))
+	return nil
+}
+
+// nodeResponseHash is the hash signed by a node responding to an inter-node
+// request. It covers the signature of the request, so that a response can't be
+// replayed to another request.
+func nodeResponseHash(requestSig []byte, body []byte) []byte {
+	return crypto.Keccak256(requestSig, crypto.Keccak256(body))
+}
+
+// verifyNodeResponse returns the node which signed the response, whose body is
+// given, to a request with the given signature
+func verifyNodeResponse(resp *http.Response, body []byte, requestSig []byte) (common.Address, error) {
+	sig, err := hexutil.Decode(resp.Header.Get(nodeSignatureHeader))
+	if err != nil {
+		return common.Address{}, fmt.Errorf("missing or invalid %s", nodeSignatureHeader)
+	}
+	pub, err := crypto.SigToPub(nodeResponseHash(requestSig, body), sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// signedResponse buffers the response to an inter-node request, to sign it
+// once it is complete
+type signedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *signedResponse) Header() http.Header {
+	return r.header
+}
+
+func (r *signedResponse) Write(data []byte) (int, error) {
+	return r.body.Write(data)
+}
+
+func (r *signedResponse) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+// verifyNodeRequest returns the peer which signed a request. The body of the
+// request, up to maxNodeRequestSize, is read and restored.
+func verifyNodeRequest(r *http.Request) (common.Address, error) {
+	timestamp, err := strconv.ParseInt(r.Header.Get(nodeTimestampHeader), 10, 64)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("missing or invalid %s", nodeTimestampHeader)
+	}
+	skew := time.Since(time.Unix(timestamp, 0))
+	if skew > nodeRequestMaxSkew || skew < -nodeRequestMaxSkew {
+		return common.Address{}, fmt.Errorf("request timestamp is %s off", skew)
+	}
+	sig, err := hexutil.Decode(r.Header.Get(nodeSignatureHeader))
+	if err != nil {
+		return common.Address{}, fmt.Errorf("missing or invalid %s", nodeSignatureHeader)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxNodeRequestSize+1))
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(body) > maxNodeRequestSize {
+		return common.Address{}, errNodeRequestTooLarge
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	pub, err := crypto.SigToPub(nodeRequestHash(r.Method, r.URL.RequestURI(), timestamp, body), sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// makeNodeHandler is like makeHandler for the inter-node endpoints: requests
+// must be signed by an allowed peer, and the responses are signed by the node
+// key, for the peer to authenticate them (see SyncPeer)
+func (m *Service) makeNodeHandler(fn func(http.ResponseWriter, *http.Request, *Service)) http.HandlerFunc {
+	handler := m.makeHandler(fn)
+	return func(w http.ResponseWriter, r *http.Request) {
+		peer, err := verifyNodeRequest(r)
+		if err == errNodeRequestTooLarge {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			m.logger.WithError(err).WithField("remote", r.RemoteAddr).Debug("Rejected unsigned node request")
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if !m.nodeAuth.peers[peer] {
+			m.logger.WithFields(logrus.Fields{
+				"peer":   peer.Hex(),
+				"remote": r.RemoteAddr,
+			}).Warn("Rejected node request from unknown peer")
+			http.Error(w, fmt.Sprintf("peer %s is not allowed", peer.Hex()), http.StatusForbidden)
+			return
+		}
+
+		res := &signedResponse{header: w.Header()}
+		handler(res, r)
+
+		requestSig, _ := hexutil.Decode(r.Header.Get(nodeSignatureHeader))
+		sig, err := crypto.Sign(nodeResponseHash(requestSig, res.body.Bytes()), m.nodeAuth.key)
+		if err != nil {
+			m.logger.WithError(err).Error("Signing node response")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(nodeSignatureHeader, hexutil.Encode(sig))
+		if res.status != 0 {
+			w.WriteHeader(res.status)
+		}
+		if _, err := w.Write(res.body.Bytes()); err != nil {
+			m.logger.WithError(err).Error("Writing node response")
+		}
+	}
+}
+
+/*
+GET /node/head
+returns: JSON JsonNodeHead
+
+Returns the identity of the node and the header of its last block, for peers to
+check their replication. Requests must be signed by an allowed peer (see
+SignNodeRequest).
+*/
+func nodeHeadHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	res := JsonNodeHead{
+		Node: crypto.PubkeyToAddress(m.nodeAuth.key.PublicKey),
+	}
+	if block, err := m.state.GetBlockByNumber(m.state.GetBlockIndex()); err == nil {
+		res.Header = jsonHeader(block)
+	}
+
+	js, err := json.Marshal(res)
+	if err != nil {
+		m.logger.WithError(err).Error("Marshaling JSON response")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(js); err != nil {
+		m.logger.WithError(err).Error("Writing JSON response")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	bcommon "github.com/Fantom-foundation/go-evm/src/common"
+)
+
+// signNodeRequestAt is like SignNodeRequest with the given timestamp
+func signNodeRequestAt(t *testing.T, r *http.Request, body []byte, key *ecdsa.PrivateKey, timestamp int64) {
+	sig, err := crypto.Sign(nodeRequestHash(r.Method, r.URL.RequestURI(), timestamp, body), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set(nodeTimestampHeader, strconv.FormatInt(timestamp, 10))
+	r.Header.Set(nodeSignatureHeader, hexutil.Encode(sig))
+}
+
+func TestNodeAuth(t *testing.T) {
+	nodeKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &Service{logger: bcommon.NewTestLogger(t)}
+	m.SetNodeAuth(nodeKey, []common.Address{crypto.PubkeyToAddress(peerKey.PublicKey)})
+	var served []byte
+	handler := m.makeNodeHandler(func(w http.ResponseWriter, r *http.Request, m *Service) {
+		served, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte("ok"))
+	})
+
+	body := []byte(`{"block":1}`)
+	now := time.Now().Unix()
+	skew := int64(nodeRequestMaxSkew/time.Second) + 60
+
+	cases := []struct {
+		name string
+		// request returns the request, whose body is body
+		request func() *http.Request
+		status  int
+	}{
+		{"valid", func() *http.Request {
+			r := httptest.NewRequest(http.MethodPost, "/node/test?from=1", bytes.NewReader(body))
+			if err := SignNodeRequest(r, body, peerKey); err != nil {
+				t.Fatal(err)
+			}
+			return r
+		}, http.StatusOK},
+		{"no signature", func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/node/test?from=1", bytes.NewReader(body))
+		}, http.StatusUnauthorized},
+		{"timestamp too old", func() *http.Request {
+			r := httptest.NewRequest(http.MethodPost, "/node/test?from=1", bytes.NewReader(body))
+			signNodeRequestAt(t, r, body, peerKey, now-skew)
+			return r
+		}, http.StatusUnauthorized},
+		{"timestamp in the future", func() *http.Request {
+			r := httptest.NewRequest(http.MethodPost, "/node/test?from=1", bytes.NewReader(body))
+			signNodeRequestAt(t, r, body, peerKey, now+skew)
+			return r
+		}, http.StatusUnauthorized},
+		// a tampered request recovers another signer, which isn't allowed
+		{"tampered URI", func() *http.Request {
+			r := httptest.NewRequest(http.MethodPost, "/node/test?from=1", bytes.NewReader(body))
+			signNodeRequestAt(t, r, body, peerKey, now)
+			r.URL.RawQuery = "from=2"
+			r.RequestURI = r.URL.RequestURI()
+			return r
+		}, http.StatusForbidden},
+		{"tampered body", func() *http.Request {
+			r := httptest.NewRequest(http.MethodPost, "/node/test?from=1", bytes.NewReader([]byte(`{"block":2}`)))
+			signNodeRequestAt(t, r, body, peerKey, now)
+			return r
+		}, http.StatusForbidden},
+		{"peer not allowed", func() *http.Request {
+			r := httptest.NewRequest(http.MethodPost, "/node/test?from=1", bytes.NewReader(body))
+			signNodeRequestAt(t, r, body, otherKey, now)
+			return r
+		}, http.StatusForbidden},
+		{"body too large", func() *http.Request {
+			large := make([]byte, maxNodeRequestSize+1)
+			r := httptest.NewRequest(http.MethodPost, "/node/test?from=1", bytes.NewReader(large))
+			signNodeRequestAt(t, r, large, peerKey, now)
+			return r
+		}, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, c := range cases {
+		served = nil
+		r := c.request()
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != c.status {
+			t.Fatalf("%s: status should be %d, not %d (%s)", c.name, c.status, w.Code, w.Body.String())
+		}
+		if c.status != http.StatusOK {
+			if served != nil {
+				t.Fatalf("%s: request should not be served", c.name)
+			}
+			continue
+		}
+
+		if !bytes.Equal(served, body) {
+			t.Fatalf("%s: handler should read the body %s, not %s", c.name, body, served)
+		}
+		requestSig, _ := hexutil.Decode(r.Header.Get(nodeSignatureHeader))
+		signer, err := verifyNodeResponse(w.Result(), w.Body.Bytes(), requestSig)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if signer != crypto.PubkeyToAddress(nodeKey.PublicKey) {
+			t.Fatalf("%s: response should be signed by the node, not %s", c.name, signer.Hex())
+		}
+	}
+}
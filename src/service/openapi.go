@@ -0,0 +1,377 @@
+package service
+
+import (
+	"encoding"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Fantom-foundation/go-evm/src/version"
+)
+
+// apiVersionPrefix prefixes the paths of the current version of the HTTP API
+const apiVersionPrefix = "/v1"
+
+// apiRoute is an endpoint of the HTTP API, with its description in the
+// OpenAPI document
+type apiRoute struct {
+	method string
+	path   string
+	fn     func(http.ResponseWriter, *http.Request, *Service)
+	// streamed responses and probes don't take the Service lock
+	unlocked bool
+	// namespace of the endpoint, which requires an API key if protected
+	auth string
+
+	name    string
+	summary string
+	query   []string
+	// values of the types of the request body and of the response; a string
+	// is sent as is, and nil is no body
+	request  interface{}
+	response interface{}
+}
+
+// apiRoutes returns the endpoints of the HTTP API
+func (m *Service) apiRoutes() []apiRoute {
+	return []apiRoute{
+		{method: "GET", path: "/account/{address}", fn: accountHandler,
+			name: "getAccount", summary: "Balance and nonce of any account",
+			response: JsonAccount{}},
+		{method: "GET", path: "/account/{address}/state", fn: accountStateHandler,
+			name: "getAccountState", summary: "Balance, nonce, code and a page of the storage of an account",
+			query: []string{"start", "limit"}, response: JsonAccountState{}},
+		{method: "GET", path: "/account/{address}/transactions", fn: accountTransactionsHandler,
+			name: "getAccountTransactions", summary: "Transactions sent or received by an account, oldest first",
+			query: []string{"offset", "limit"}, response: JsonAccountTransactions{}},
+		{method: "GET", path: "/accounts", fn: accountsHandler,
+			name: "getAccounts", summary: "Accounts of the keystore of the node",
+			response: JsonAccountList{}},
+		{method: "GET", path: "/block/{hash}", fn: blockByHashHandler,
+			name: "getBlockByHash", summary: "Block with the receipts of its transactions",
+			response: JsonBlock{}},
+		{method: "GET", path: "/blockById/{id}", fn: blockByIdHandler,
+			name: "getBlockById", summary: "Block with the receipts of its transactions",
+			response: JsonBlock{}},
+		{method: "GET", path: "/genesis/logs", fn: genesisLogsHandler,
+			name: "getGenesisLogs", summary: "Logs emitted by the constructors of the genesis contracts",
+			response: JsonGenesisLogs{}},
+		{method: "GET", path: "/header/{number}", fn: headerByNumberHandler,
+			name: "getHeaderByNumber", summary: "Commit header of a block",
+			response: JsonHeader{}},
+		{method: "GET", path: "/headerByHash/{hash}", fn: headerByHashHandler,
+			name: "getHeaderByHash", summary: "Commit header of a block",
+			response: JsonHeader{}},
+		{method: "GET", path: "/headers", fn: signedHeadersHandler,
+			name: "getSignedHeaders", summary: "Commit headers with their encoding and validator signatures",
+			query: []string{"from", "count"}, response: []JsonSignedHeader{}},
+		{method: "POST", path: "/call", fn: callHandler,
+			name: "call", summary: "Read-only call of contract code",
+			request: SendTxArgs{}, response: JsonCallRes{}},
+		{method: "POST", path: "/tx", fn: transactionHandler, auth: txNamespace,
+			name: "sendTransaction", summary: "Send a transaction signed by a keystore account",
+			request: SendTxArgs{}, response: JsonTxRes{}},
+		{method: "POST", path: "/transactions", fn: transactionHandler, auth: txNamespace,
+			name: "sendTransactionLegacy", summary: "Same as POST /tx",
+			request: SendTxArgs{}, response: JsonTxRes{}},
+		{method: "POST", path: "/rawtx", fn: rawTransactionHandler, auth: txNamespace,
+			name: "sendRawTransaction", summary: "Send a signed transaction, hex encoded",
+			request: "", response: JsonTxRes{}},
+		{method: "POST", path: "/sendRawTransaction", fn: rawTransactionHandler, auth: txNamespace,
+			name: "sendRawTransactionLegacy", summary: "Same as POST /rawtx",
+			request: "", response: JsonTxRes{}},
+		{method: "POST", path: "/rawblobtx", fn: rawBlobTransactionHandler, auth: txNamespace,
+			name: "sendRawBlobTransaction", summary: "Send a signed transaction with its blob data",
+			request: JsonBlobTx{}, response: JsonTxRes{}},
+		{method: "GET", path: "/blob/{tx_hash}", fn: blobHandler,
+			name: "getBlob", summary: "Blob data of a transaction",
+			response: JsonBlob{}},
+		{method: "POST", path: "/tx/preview", fn: txPreviewHandler,
+			name: "previewTransaction", summary: "Simulate a transaction and report its effects and risks",
+			request: SendTxArgs{}, response: JsonTxPreview{}},
+		{method: "GET", path: "/tx/{tx_hash}", fn: txReceiptHandler,
+			name: "getReceipt", summary: "Receipt of a transaction",
+			response: JsonReceipt{}},
+		{method: "GET", path: "/transaction/{tx_hash}", fn: transactionReceiptHandler,
+			name: "getReceiptLegacy", summary: "Same as GET /tx/{tx_hash}",
+			response: JsonReceipt{}},
+		{method: "GET", path: "/trace/{tx_hash}", fn: traceHandler, unlocked: true, auth: "debug",
+			name: "traceTransaction", summary: "Opcode-level trace of an applied transaction",
+			query: []string{"disableStack", "disableMemory", "disableStorage", "limit"}, response: ExecutionResult{}},
+		{method: "GET", path: "/logs", fn: logsHandler, unlocked: true,
+			name: "getLogs", summary: "Page of the logs matching a filter",
+			query: []string{"fromBlock", "toBlock", "address", "topic0", "topic1", "topic2", "topic3", "limit"}, response: JsonLogPage{}},
+		{method: "GET", path: "/txpool", fn: txPoolHandler,
+			name: "getTxPool", summary: "Pending and queued transactions of the txpool",
+			response: JsonTxPoolContent{}},
+		{method: "POST", path: "/graphql", fn: graphqlHandler,
+			name: "graphql", summary: "GraphQL query over blocks, transactions, logs and accounts",
+			request: map[string]interface{}{}, response: map[string]interface{}{}},
+		{method: "GET", path: "/info", fn: infoHandler,
+			name: "getInfo", summary: "Information about the consensus system",
+			response: map[string]string{}},
+		{method: "GET", path: "/healthz", fn: healthzHandler, unlocked: true,
+			name: "getHealthz", summary: "Liveness probe",
+			response: ""},
+		{method: "GET", path: "/readyz", fn: readyzHandler, unlocked: true,
+			name: "getReadyz", summary: "Readiness probe",
+			response: JsonReadiness{}},
+		{method: "GET", path: "/metrics", fn: metricsHandler, unlocked: true, auth: "admin",
+			name: "getMetrics", summary: "Metrics of the node, in the Prometheus text format",
+			response: ""},
+		{method: "GET", path: "/syncing", fn: syncingHandler,
+			name: "getSyncing", summary: "Progress of the node towards the head of the consensus system",
+			response: JsonSyncStatus{}},
+		{method: "GET", path: "/watches", fn: watchesHandler,
+			name: "getWatches", summary: "Latest results of the watched calls",
+			response: []WatchResult{}},
+		{method: "GET", path: "/html/info", fn: htmlInfoHandler,
+			name: "getHtmlInfo", summary: "HTML version of GET /info"},
+		{method: "GET", path: "/openapi.json", fn: openAPIHandler,
+			name: "getOpenAPI", summary: "This document",
+			response: map[string]interface{}{}},
+	}
+}
+
+// routeHandler returns the HTTP handler of an endpoint. Its requests are
+// traced under the method and the path of the route.
+func (m *Service) routeHandler(route apiRoute) http.HandlerFunc {
+	fn := route.fn
+	if route.auth != "" {
+		fn = requireAuth(route.auth, fn)
+	}
+	name := route.method + " " + route.path
+	if route.unlocked {
+		return spanHandler(name, m.makeStreamHandler(fn))
+	}
+	return spanHandler(name, m.makeHandler(fn))
+}
+
+var (
+	pathParamRe = regexp.MustCompile(`\{(\w+)\}`)
+
+	timeType            = reflect.TypeOf(time.Time{})
+	bigIntType          = reflect.TypeOf(big.Int{})
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	emptyInterfaceType  = reflect.TypeOf((*interface{})(nil)).Elem()
+	openAPIErrorContent = map[string]interface{}{
+		"text/plain": map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+	}
+)
+
+// openAPIDoc builds the OpenAPI document of the HTTP API. The schemas of the
+// JSON bodies are derived from their Go types, named structs being components.
+type openAPIDoc struct {
+	schemas map[string]interface{}
+}
+
+// openAPI returns the OpenAPI 3 document of the endpoints of the HTTP API
+func (m *Service) openAPI() map[string]interface{} {
+	d := &openAPIDoc{schemas: make(map[string]interface{})}
+
+	paths := make(map[string]interface{})
+	for _, route := range m.apiRoutes() {
+		op := map[string]interface{}{
+			"operationId": route.name,
+			"summary":     route.summary,
+		}
+
+		params := []interface{}{}
+		for _, match := range pathParamRe.FindAllStringSubmatch(route.path, -1) {
+			params = append(params, map[string]interface{}{
+				"name": match[1], "in": "path", "required": true,
+				"schema": map[string]interface{}{"type": "string"},
+			})
+		}
+		for _, name := range route.query {
+			params = append(params, map[string]interface{}{
+				"name": name, "in": "query",
+				"schema": map[string]interface{}{"type": "string"},
+			})
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+
+		if route.request != nil {
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  d.content(route.request),
+			}
+		}
+
+		ok := map[string]interface{}{"description": "OK"}
+		if route.response != nil {
+			ok["content"] = d.content(route.response)
+		} else {
+			ok["content"] = map[string]interface{}{"text/html": map[string]interface{}{}}
+		}
+		responses := map[string]interface{}{
+			"200":     ok,
+			"default": map[string]interface{}{"description": "Error", "content": openAPIErrorContent},
+		}
+		if route.auth != "" && m.apiAuth != nil && m.apiAuth.protected[route.auth] {
+			op["security"] = []interface{}{
+				map[string]interface{}{"bearerKey": []string{}},
+				map[string]interface{}{"basicKey": []string{}},
+			}
+			responses["401"] = map[string]interface{}{"description": "API key required for " + route.auth, "content": openAPIErrorContent}
+			responses["403"] = map[string]interface{}{"description": "API key not allowed " + route.auth, "content": openAPIErrorContent}
+		}
+		op["responses"] = responses
+
+		ops, found := paths[route.path].(map[string]interface{})
+		if !found {
+			ops = make(map[string]interface{})
+			paths[route.path] = ops
+		}
+		ops[strings.ToLower(route.method)] = op
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.0",
+		"info": map[string]interface{}{
+			"title":       "evm",
+			"description": "HTTP API of the evm node. Quantities are hex encoded.",
+			"version":     version.Version,
+		},
+		"servers": []interface{}{map[string]interface{}{"url": apiVersionPrefix}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": d.schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearerKey": map[string]interface{}{"type": "http", "scheme": "bearer"},
+				"basicKey":  map[string]interface{}{"type": "http", "scheme": "basic"},
+			},
+		},
+	}
+}
+
+// content returns the media type of a body of the type of v
+func (d *openAPIDoc) content(v interface{}) map[string]interface{} {
+	if _, ok := v.(string); ok {
+		return map[string]interface{}{
+			"text/plain": map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+		}
+	}
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": d.schema(reflect.TypeOf(v))},
+	}
+}
+
+// schema returns the JSON schema of the values of a type, as encoded by
+// encoding/json
+func (d *openAPIDoc) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == bigIntType:
+		return map[string]interface{}{"type": "integer"}
+	case t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType):
+		// Addresses, hashes and the hexutil quantities and bytes
+		return map[string]interface{}{"type": "string", "format": "hex"}
+	case t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType):
+		// Ethereum types with their own encoding, such as logs
+		return map[string]interface{}{"type": "object", "description": t.String()}
+	case t == emptyInterfaceType:
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": d.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": d.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		if _, ok := d.schemas[t.Name()]; !ok {
+			// Reserved before the fields, for recursive types
+			d.schemas[t.Name()] = nil
+			d.schemas[t.Name()] = d.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	}
+	return map[string]interface{}{}
+}
+
+// structSchema returns the JSON schema of the values of a struct type. The
+// fields of embedded structs are inlined, like encoding/json does.
+func (d *openAPIDoc) structSchema(t reflect.Type) map[string]interface{} {
+	props := make(map[string]interface{})
+	required := []string{}
+	var addFields func(t reflect.Type)
+	addFields = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts := tag, ""
+			if idx := strings.Index(tag, ","); idx >= 0 {
+				name, opts = tag[:idx], tag[idx+1:]
+			}
+			ft := field.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if field.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+				addFields(ft)
+				continue
+			}
+			if field.PkgPath != "" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			props[name] = d.schema(field.Type)
+			if !strings.Contains(opts, "omitempty") && field.Type.Kind() != reflect.Ptr {
+				required = append(required, name)
+			}
+		}
+	}
+	addFields(t)
+
+	schema := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+/*
+GET /openapi.json
+returns: JSON OpenAPI 3 document
+
+Returns the OpenAPI document describing the endpoints of the HTTP API under
+/v1, with the schemas of their requests and responses, so that client SDKs can
+be generated. The endpoints are also served at the root for the existing
+clients, but new clients should use /v1.
+*/
+func openAPIHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	writeJSON(w, m.openAPI(), m)
+}
@@ -0,0 +1,27 @@
+package service
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+//SetPprofAddr makes the Service serve the net/http/pprof endpoints on the
+//given address, separately from the API and the admin dashboard, for live CPU
+//and memory profiling. They are not authenticated, so the address should not
+//be reachable from outside. Disabled when empty.
+func (m *Service) SetPprofAddr(addr string) {
+	m.pprofAddr = addr
+}
+
+// servePprof serves the profiling endpoints under /debug/pprof/ until it fails
+func (m *Service) servePprof() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	if err := http.ListenAndServe(m.pprofAddr, mux); err != nil {
+		m.logger.WithError(err).Error("Serving pprof")
+	}
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Risk flags of a transaction preview
+const (
+	riskNewContract     = "new_contract"
+	riskLargeApproval   = "large_approval"
+	riskApprovalForAll  = "approval_for_all"
+	riskFails           = "fails"
+	riskEmptyRecipient  = "empty_recipient"
+	riskValueToContract = "value_to_contract"
+)
+
+// largeApproval is the token allowance from which an approval is flagged. It
+// catches the "unlimited" approvals (2^256-1) requested by many dApps.
+var largeApproval = new(big.Int).Lsh(big.NewInt(1), 128)
+
+// knownMethod is a common token method, decoded in previews. All its
+// arguments are static ABI words.
+type knownMethod struct {
+	name string
+	args []string // types: address, uint256 or bool
+}
+
+var knownMethods = map[string]knownMethod{}
+
+// knownEvents are the common token events decoded in previews, by topic
+var knownEvents = map[common.Hash]string{
+	crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)")): "Transfer",
+	crypto.Keccak256Hash([]byte("Approval(address,address,uint256)")): "Approval",
+}
+
+func init() {
+	for sig, args := range map[string][]string{
+		"transfer(address,uint256)":                 {"address", "uint256"},
+		"approve(address,uint256)":                  {"address", "uint256"},
+		"transferFrom(address,address,uint256)":     {"address", "address", "uint256"},
+		"increaseAllowance(address,uint256)":        {"address", "uint256"},
+		"setApprovalForAll(address,bool)":           {"address", "bool"},
+		"safeTransferFrom(address,address,uint256)": {"address", "address", "uint256"},
+	} {
+		selector := hexutil.Encode(crypto.Keccak256([]byte(sig))[:4])
+		knownMethods[selector] = knownMethod{name: sig, args: args}
+	}
+}
+
+// decodeMethod decodes the call data of a known token method
+func decodeMethod(data []byte) *JsonDecodedMethod {
+	if len(data) < 4 {
+		return nil
+	}
+	res := &JsonDecodedMethod{Selector: hexutil.Encode(data[:4])}
+	method, ok := knownMethods[res.Selector]
+	if !ok || len(data) != 4+32*len(method.args) {
+		return res
+	}
+
+	res.Signature = method.name
+	for i, typ := range method.args {
+		word := data[4+32*i : 4+32*(i+1)]
+		var value interface{}
+		switch typ {
+		case "address":
+			value = common.BytesToAddress(word)
+		case "uint256":
+			value = (*hexutil.Big)(new(big.Int).SetBytes(word))
+		case "bool":
+			value = new(big.Int).SetBytes(word).Sign() != 0
+		}
+		res.Args = append(res.Args, JsonMethodArg{Type: typ, Value: value})
+	}
+	return res
+}
+
+// decodeTokenEvents decodes the Transfer and Approval events of a list of logs
+func decodeTokenEvents(logs []*ethTypes.Log) []JsonTokenEvent {
+	events := []JsonTokenEvent{}
+	for _, log := range logs {
+		if len(log.Topics) != 3 || len(log.Data) != 32 {
+			continue
+		}
+		name, ok := knownEvents[log.Topics[0]]
+		if !ok {
+			continue
+		}
+		events = append(events, JsonTokenEvent{
+			Token:  log.Address,
+			Event:  name,
+			From:   common.BytesToAddress(log.Topics[1].Bytes()),
+			To:     common.BytesToAddress(log.Topics[2].Bytes()),
+			Amount: (*hexutil.Big)(new(big.Int).SetBytes(log.Data)),
+		})
+	}
+	return events
+}
+
+// previewRisks flags what an approver should look at twice
+func (m *Service) previewRisks(args SendTxArgs, method *JsonDecodedMethod, failed bool) []string {
+	risks := []string{}
+	if args.To == nil {
+		risks = append(risks, riskNewContract)
+	} else {
+		isContract := len(m.state.GetCode(*args.To)) > 0
+		if !isContract && m.state.GetNonce(*args.To) == 0 && m.state.GetBalance(*args.To).Sign() == 0 {
+			risks = append(risks, riskEmptyRecipient)
+		}
+		if isContract && args.Value != nil && args.Value.ToInt().Sign() > 0 {
+			risks = append(risks, riskValueToContract)
+		}
+	}
+	if method != nil && len(method.Args) > 0 {
+		switch method.Signature {
+		case "approve(address,uint256)", "increaseAllowance(address,uint256)":
+			if method.Args[1].Value.(*hexutil.Big).ToInt().Cmp(largeApproval) >= 0 {
+				risks = append(risks, riskLargeApproval)
+			}
+		case "setApprovalForAll(address,bool)":
+			if method.Args[1].Value.(bool) {
+				risks = append(risks, riskApprovalForAll)
+			}
+		}
+	}
+	if failed {
+		risks = append(risks, riskFails)
+	}
+	return risks
+}
+
+/*
+POST /tx/preview
+data: JSON SendTxArgs
+returns: JSON JsonTxPreview
+
+Previews an unsigned transaction intent for an approval workflow, without
+submitting anything: the decoded method (for common token methods), the
+simulated effects (balance changes, token transfers and approvals, logs), the
+estimated gas and risk flags:
+
+	new_contract       the transaction deploys a contract
+	large_approval     token allowance of 2^128 or more ("unlimited")
+	approval_for_all   approval of all the NFTs of the sender
+	fails              the simulated execution fails
+	empty_recipient    the recipient has no code, nonce nor balance
+	value_to_contract  native tokens sent to a contract
+
+The simulation runs against the state of the block being applied, with the gas
+given or a generous default, and the gas price given or 0.
+*/
+func txPreviewHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	var args SendTxArgs
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	gas := defaultCallGas
+	if args.Gas != nil {
+		gas = uint64(*args.Gas)
+	}
+	gasPrice, value := new(big.Int), new(big.Int)
+	if args.GasPrice != nil {
+		gasPrice = args.GasPrice.ToInt()
+	}
+	if args.Value != nil {
+		value = args.Value.ToInt()
+	}
+	data := []byte{}
+	if args.Data != nil {
+		data = *args.Data
+	} else if args.Input != nil {
+		data = *args.Input
+	}
+
+	msg := ethTypes.NewMessage(args.From, args.To, 0, value, gas, gasPrice, data, false)
+	sim, err := m.state.Simulate(msg)
+	if err != nil {
+		m.logger.WithError(err).Debug("Simulating transaction preview")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The gas used may not be enough as a gas limit (e.g. refunds)
+	estimatedGas := sim.GasUsed
+	if !sim.Failed {
+		if gas, err := m.state.EstimateGas(msg); err == nil {
+			estimatedGas = gas
+		}
+	}
+
+	method := decodeMethod(data)
+	res := JsonTxPreview{
+		Method:          method,
+		Failed:          sim.Failed,
+		Return:          sim.Return,
+		EstimatedGas:    hexutil.Uint64(estimatedGas),
+		ContractAddress: sim.ContractAddress,
+		BalanceChanges:  []JsonBalanceChange{},
+		TokenEvents:     decodeTokenEvents(sim.Logs),
+		Logs:            sim.Logs,
+		Risks:           m.previewRisks(args, method, sim.Failed),
+	}
+	if sim.Failed {
+		if reason, err := UnpackRevertReason(sim.Return); err == nil {
+			res.RevertReason = reason
+		}
+	}
+	for addr, change := range sim.BalanceChanges {
+		res.BalanceChanges = append(res.BalanceChanges, JsonBalanceChange{
+			Address: addr,
+			Change:  (*hexutil.Big)(change),
+		})
+	}
+	sort.Slice(res.BalanceChanges, func(i, j int) bool {
+		return bytes.Compare(res.BalanceChanges[i].Address[:], res.BalanceChanges[j].Address[:]) < 0
+	})
+
+	writeJSON(w, res, m)
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"crypto/subtle"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// clientIdleTimeout is how long the limits of a client are kept after its last
+// request
+const clientIdleTimeout = 10 * time.Minute
+
+// RateLimits are the limits of the requests of a single client to the HTTP
+// service. Clients are identified by their API key when they send a valid one,
+// and by their IP address otherwise. 0 disables a limit.
+type RateLimits struct {
+	// sustained requests per second, and burst above it
+	Rate  float64
+	Burst int
+
+	// requests served at the same time
+	MaxConcurrent int
+}
+
+// clientLimit is the token bucket and the requests in flight of a client.
+// last is when the tokens were last refilled, and seen when the client last
+// started or ended a request, which tells idle clients apart.
+type clientLimit struct {
+	tokens   float64
+	last     time.Time
+	seen     time.Time
+	inFlight int
+}
+
+// rateLimiter applies the RateLimits to the clients
+type rateLimiter struct {
+	sync.Mutex
+	limits  RateLimits
+	clients map[string]*clientLimit
+	swept   time.Time
+}
+
+//SetRateLimits limits the requests of every client to the HTTP API and the
+//JSON-RPC HTTP endpoint, so that a single client can't starve the others
+func (m *Service) SetRateLimits(limits RateLimits) {
+	if limits.Burst <= 0 {
+		limits.Burst = int(math.Ceil(limits.Rate))
+	}
+	m.rateLimiter = &rateLimiter{
+		limits:  limits,
+		clients: make(map[string]*clientLimit),
+		swept:   time.Now(),
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"rate":           limits.Rate,
+		"burst":          limits.Burst,
+		"max_concurrent": limits.MaxConcurrent,
+	}).Info("Rate limits enabled")
+}
+
+// acquire takes a token and a concurrent slot of a client. It returns how long
+// to wait before retrying if the client is over its limits.
+func (l *rateLimiter) acquire(client string, now time.Time) (bool, time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.sweep(now)
+
+	c, ok := l.clients[client]
+	if !ok {
+		c = &clientLimit{tokens: float64(l.limits.Burst), last: now}
+		l.clients[client] = c
+	}
+	c.seen = now
+
+	if l.limits.MaxConcurrent > 0 && c.inFlight >= l.limits.MaxConcurrent {
+		return false, time.Second
+	}
+	if l.limits.Rate > 0 {
+		c.tokens = math.Min(float64(l.limits.Burst), c.tokens+now.Sub(c.last).Seconds()*l.limits.Rate)
+		c.last = now
+		if c.tokens < 1 {
+			wait := time.Duration((1 - c.tokens) / l.limits.Rate * float64(time.Second))
+			return false, wait
+		}
+		c.tokens--
+	}
+	c.inFlight++
+	return true, 0
+}
+
+// release frees the concurrent slot of a client. The tokens keep refilling
+// from their last refill, whatever the duration of the request.
+func (l *rateLimiter) release(client string, now time.Time) {
+	l.Lock()
+	defer l.Unlock()
+
+	if c, ok := l.clients[client]; ok {
+		c.inFlight--
+		c.seen = now
+	}
+}
+
+// sweep forgets the idle clients, at most once per clientIdleTimeout
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < clientIdleTimeout {
+		return
+	}
+	for client, c := range l.clients {
+		if c.inFlight == 0 && now.Sub(c.seen) > clientIdleTimeout {
+			delete(l.clients, client)
+		}
+	}
+	l.swept = now
+}
+
+// clientID identifies the client of a request by its API key, if valid, and
+// by its IP address otherwise. Unknown keys don't give a client new limits.
+func (m *Service) clientID(r *http.Request) string {
+	if m.apiAuth != nil {
+		if sent := requestKey(r); sent != "" {
+			for _, key := range m.apiAuth.keys {
+				if subtle.ConstantTimeCompare([]byte(sent), []byte(key.Key)) == 1 {
+					return "key:" + key.Name
+				}
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// rateLimitHandler applies the rate limits to the requests of an HTTP server.
+// Requests over the limits are refused with 429 Too Many Requests.
+func (m *Service) rateLimitHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := m.clientID(r)
+		ok, wait := m.rateLimiter.acquire(client, time.Now())
+		if !ok {
+			m.logger.WithFields(logrus.Fields{
+				"client": client,
+				"path":   r.URL.Path,
+			}).Debug("Rate limited request")
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+		defer func() { m.rateLimiter.release(client, time.Now()) }()
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestRateLimiter(limits RateLimits, now time.Time) *rateLimiter {
+	return &rateLimiter{
+		limits:  limits,
+		clients: make(map[string]*clientLimit),
+		swept:   now,
+	}
+}
+
+// TestRateLimiterRefill checks that a client gets its burst, is told how long
+// to wait once it is used, and gets the tokens refilled over time, including
+// while its requests are in flight
+func TestRateLimiterRefill(t *testing.T) {
+	now := time.Unix(1546300800, 0)
+	l := newTestRateLimiter(RateLimits{Rate: 1, Burst: 2}, now)
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.acquire("client", now); !ok {
+			t.Fatalf("request %d should be within the burst", i)
+		}
+	}
+	ok, wait := l.acquire("client", now)
+	if ok {
+		t.Fatal("request over the burst should be refused")
+	}
+	if wait != time.Second {
+		t.Fatalf("wait should be 1s, not %s", wait)
+	}
+	if ok, _ := l.acquire("other", now); !ok {
+		t.Fatal("another client should have its own limits")
+	}
+
+	// The requests last 2 seconds, during which the bucket refills
+	now = now.Add(2 * time.Second)
+	l.release("client", now)
+	l.release("client", now)
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.acquire("client", now); !ok {
+			t.Fatalf("request %d should be allowed by the refilled tokens", i)
+		}
+	}
+	if ok, _ := l.acquire("client", now); ok {
+		t.Fatal("the bucket should not refill above the burst")
+	}
+}
+
+// TestRateLimiterConcurrency checks that concurrent requests take exactly the
+// tokens of the burst, and never more slots than MaxConcurrent
+func TestRateLimiterConcurrency(t *testing.T) {
+	now := time.Unix(1546300800, 0)
+	l := newTestRateLimiter(RateLimits{Rate: 1, Burst: 50}, now)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _ := l.acquire("client", now); ok {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+				l.release("client", now)
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 50 {
+		t.Fatalf("50 requests should be allowed, not %d", allowed)
+	}
+
+	l = newTestRateLimiter(RateLimits{MaxConcurrent: 2}, now)
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.acquire("client", now); !ok {
+			t.Fatalf("request %d should get a slot", i)
+		}
+	}
+	if ok, _ := l.acquire("client", now); ok {
+		t.Fatal("a third concurrent request should be refused")
+	}
+	l.release("client", now)
+	if ok, _ := l.acquire("client", now); !ok {
+		t.Fatal("a released slot should be available")
+	}
+}
+
+// TestRateLimiterSweep checks that only the clients idle for longer than
+// clientIdleTimeout are forgotten
+func TestRateLimiterSweep(t *testing.T) {
+	start := time.Unix(1546300800, 0)
+	l := newTestRateLimiter(RateLimits{Rate: 1, Burst: 1}, start)
+
+	l.acquire("idle", start)
+	l.release("idle", start)
+	l.acquire("busy", start)
+	// a long request, ended after the timeout
+	l.acquire("long", start)
+	l.release("long", start.Add(clientIdleTimeout))
+
+	now := start.Add(clientIdleTimeout + time.Second)
+	l.acquire("new", now)
+
+	l.Lock()
+	defer l.Unlock()
+	if _, ok := l.clients["idle"]; ok {
+		t.Fatal("the idle client should be forgotten")
+	}
+	for _, client := range []string{"busy", "long", "new"} {
+		if _, ok := l.clients[client]; !ok {
+			t.Fatalf("client %s should be kept", client)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	// revertSelector is the selector of Error(string), the ABI encoding used by
+	// solidity for revert and require messages
+	revertSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+
+	errNoRevertReason = errors.New("return data is not an Error(string)")
+)
+
+// UnpackRevertReason decodes the message of the data returned by a reverted
+// transaction
+func UnpackRevertReason(data []byte) (string, error) {
+	if len(data) < 4+64 || !bytes.Equal(data[:4], revertSelector) {
+		return "", errNoRevertReason
+	}
+	data = data[4:]
+
+	// The offset and length come from the contract: they are compared with
+	// what is left of the data and never added, which could overflow
+	offset := new(big.Int).SetBytes(data[:32])
+	if !offset.IsUint64() || offset.Uint64() > uint64(len(data)-32) {
+		return "", errNoRevertReason
+	}
+	start := offset.Uint64() + 32
+
+	length := new(big.Int).SetBytes(data[start-32 : start])
+	if !length.IsUint64() || length.Uint64() > uint64(len(data))-start {
+		return "", errNoRevertReason
+	}
+
+	return string(data[start : start+length.Uint64()]), nil
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// revertData returns the Error(string) data with the given offset, length and
+// message bytes
+func revertData(offset, length *big.Int, msg []byte) []byte {
+	data := append([]byte{}, revertSelector...)
+	data = append(data, common.BigToHash(offset).Bytes()...)
+	data = append(data, common.BigToHash(length).Bytes()...)
+	return append(data, common.RightPadBytes(msg, 32)...)
+}
+
+func TestUnpackRevertReason(t *testing.T) {
+	maxUint64 := new(big.Int).SetUint64(^uint64(0))
+	maxUint256 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+	reason, err := UnpackRevertReason(revertData(big.NewInt(32), big.NewInt(6), []byte("reason")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reason != "reason" {
+		t.Fatalf("reason should be \"reason\", not %q", reason)
+	}
+
+	malformed := map[string][]byte{
+		"short":                  revertSelector,
+		"other selector":         append([]byte{0, 0, 0, 0}, revertData(big.NewInt(32), big.NewInt(6), nil)[4:]...),
+		"offset past the end":    revertData(big.NewInt(96), big.NewInt(6), []byte("reason")),
+		"offset 2^64-1":          revertData(maxUint64, big.NewInt(6), []byte("reason")),
+		"offset 2^256-1":         revertData(maxUint256, big.NewInt(6), []byte("reason")),
+		"length past the end":    revertData(big.NewInt(32), big.NewInt(33), []byte("reason")),
+		"length 2^64-1":          revertData(big.NewInt(32), maxUint64, []byte("reason")),
+		"length 2^64-32":         revertData(big.NewInt(32), new(big.Int).Sub(maxUint64, big.NewInt(31)), []byte("reason")),
+		"length 2^256-1":         revertData(big.NewInt(32), maxUint256, []byte("reason")),
+		"offset and length wrap": revertData(new(big.Int).Sub(maxUint64, big.NewInt(31)), maxUint64, []byte("reason")),
+	}
+	for name, data := range malformed {
+		if _, err := UnpackRevertReason(data); err != errNoRevertReason {
+			t.Fatalf("%s: data should be rejected, got %v", name, err)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"reflect"
 	"strings"
 	"sync"
@@ -100,6 +101,36 @@ func (n *RpcServer) Register(constructor RpcServiceConstructor) error {
 	return nil
 }
 
+// setIPCEndpoint sets the IPC endpoint to listen at when the node starts
+// (empty = IPC disabled).
+func (n *RpcServer) setIPCEndpoint(endpoint string) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.ipcEndpoint = endpoint
+}
+
+// setHTTPCors sets the origins allowed to call the HTTP endpoint from a
+// browser when the node starts.
+func (n *RpcServer) setHTTPCors(origins []string) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.config.HTTPCors = origins
+}
+
+// addModule exposes the namespace of a service registered after the node was
+// created on the HTTP and websocket endpoints.
+func (n *RpcServer) addModule(module string) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	conf := *n.config
+	conf.HTTPModules = append(append([]string{}, conf.HTTPModules...), module)
+	conf.WSModules = append(append([]string{}, conf.WSModules...), module)
+	n.config = &conf
+}
+
 // Start create a live P2P node and starts running it.
 func (n *RpcServer) Start() error {
 	n.lock.Lock()
@@ -245,7 +276,23 @@ func (n *RpcServer) startHTTP(endpoint string, apis []rpc.API, modules []string,
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := rpc.StartHTTPEndpoint(endpoint, apis, modules, cors, vhosts, timeouts)
+	var (
+		listener net.Listener
+		handler  *rpc.Server
+		err      error
+	)
+	listener, handler, err = n.startAuthEndpoint(endpoint, apis, modules, false, func(handler *rpc.Server, _ []string) *http.Server {
+		server := rpc.NewHTTPServer(cors, vhosts, timeouts, handler)
+		if n.backend.apiAuth != nil {
+			server.Handler = n.backend.rpcAuthHandler(server.Handler)
+		}
+		if n.backend.rateLimiter != nil {
+			server.Handler = n.backend.rateLimitHandler(server.Handler)
+		}
+		// The methods taking a context get the span of the request
+		server.Handler = spanHandler("JSON-RPC", server.Handler)
+		return server
+	})
 	if err != nil {
 		return err
 	}
@@ -282,7 +329,20 @@ func (n *RpcServer) startWS(endpoint string, apis []rpc.API, modules []string, w
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := rpc.StartWSEndpoint(endpoint, apis, modules, wsOrigins, exposeAll)
+	var (
+		listener net.Listener
+		handler  *rpc.Server
+		err      error
+	)
+	if n.backend.apiAuth == nil {
+		listener, handler, err = rpc.StartWSEndpoint(endpoint, apis, modules, wsOrigins, exposeAll)
+	} else {
+		listener, handler, err = n.startAuthEndpoint(endpoint, apis, modules, exposeAll, func(handler *rpc.Server, namespaces []string) *http.Server {
+			server := rpc.NewWSServer(wsOrigins, handler)
+			server.Handler = n.backend.wsAuthHandler(server.Handler, namespaces)
+			return server
+		})
+	}
 	if err != nil {
 		return err
 	}
@@ -295,6 +355,36 @@ func (n *RpcServer) startWS(endpoint string, apis []rpc.API, modules []string, w
 	return nil
 }
 
+// startAuthEndpoint is like rpc.StartHTTPEndpoint and rpc.StartWSEndpoint, but
+// lets newServer wrap the handler of the server with the API authentication,
+// the rate limits and the tracing. It
+// is given the namespaces served, with the tx namespace if eth is served.
+func (n *RpcServer) startAuthEndpoint(endpoint string, apis []rpc.API, modules []string, exposeAll bool, newServer func(*rpc.Server, []string) *http.Server) (net.Listener, *rpc.Server, error) {
+	whitelist := make(map[string]bool)
+	for _, module := range modules {
+		whitelist[module] = true
+	}
+	handler := rpc.NewServer()
+	namespaces := []string{}
+	for _, api := range apis {
+		if exposeAll || whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
+			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
+				return nil, nil, err
+			}
+			namespaces = append(namespaces, api.Namespace)
+			if api.Namespace == "eth" {
+				namespaces = append(namespaces, txNamespace)
+			}
+		}
+	}
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	go newServer(handler, namespaces).Serve(listener)
+	return listener, handler, nil
+}
+
 // stopWS terminates the websocket RPC endpoint.
 func (n *RpcServer) stopWS() {
 	if n.wsListener != nil {
@@ -459,11 +549,16 @@ func (n *RpcServer) apis() []rpc.API {
 			Version:   "1.0",
 			Service:   NewPublicDebugAPI(n.backend),
 			Public:    true,
-		}, /* {
+		}, {
 			Namespace: "web3",
 			Version:   "1.0",
-			Service:   NewPublicWeb3API(n.backend),
+			Service:   NewPublicWeb3API(),
+			Public:    true,
+		}, {
+			Namespace: "net",
+			Version:   "1.0",
+			Service:   NewPublicNetAPI(n.backend),
 			Public:    true,
-		}, */
+		},
 	}
 }
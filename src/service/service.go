@@ -1,20 +1,24 @@
 package service
 
 import (
+	"crypto/tls"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
-	"math/big"
 	"net/http"
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
+	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/gorilla/mux"
+	graphql "github.com/graph-gophers/graphql-go"
 	"github.com/sirupsen/logrus"
 
 	"github.com/Fantom-foundation/go-evm/src/common"
@@ -34,19 +38,52 @@ type Service struct {
 	genesisFile string
 	keystoreDir string
 	apiAddr     string
+	tlsCert     string
+	tlsKey      string
+	adminAddr   string
+	grpcAddr    string
+	pprofAddr   string
 	keyStore    *keystore.KeyStore
 	am          *accounts.Manager
 	pwdFile     string
 	logger      *logrus.Logger
+	errors      *errorLog
+	watcher     *callWatcher
+	slotHooks   *slotWebhooks
+	nodeAuth    *nodeAuth
+	apiAuth     *apiAuth
+	rateLimiter *rateLimiter
+	corsOrigins []string
+
+	// held by sender while the nonce of a transaction is assigned from the
+	// pool state until the transaction is in the pool
+	nonceLock *AddrLocker
+
+	// pre-funded accounts of a development node, see SetDevAccounts
+	devAccounts []DevAccount
+
+	commitLatency *commitLatency
+	eventMetrics  *eventMetrics
+	gasPrices     *gasPriceOracle
+	logLimits     LogLimits
+	graphql       *graphql.Schema
 
 	rpcConfig *node.Config
 	rpcServer *RpcServer
 
 	//XXX
 	getInfo infoCallback
+
+	getSyncStatus syncCallback
+	startingBlock int64
+
+	// set once the genesis accounts are created, for the readiness probe
+	initialized int32
+	// set once the node shuts down, see Stop
+	stopping int32
 }
 
-func NewService(genesisFile, keystoreDir, apiAddr, pwdFile string,
+func NewService(genesisFile, keystoreDir, apiAddr, adminAddr, pwdFile string,
 	state *state.State,
 	submitCh chan []byte,
 	logger *logrus.Logger) *Service {
@@ -54,7 +91,7 @@ func NewService(genesisFile, keystoreDir, apiAddr, pwdFile string,
 	rpcConfig := &config.DefaultRpcConfig
 	// TODO: replace ChainConfig with custom
 	chainConfig := &params.ChainConfig{
-		ChainID: big.NewInt(666),
+		ChainID: state.ChainID(),
 	}
 
 	s := &Service{
@@ -62,13 +99,27 @@ func NewService(genesisFile, keystoreDir, apiAddr, pwdFile string,
 		genesisFile: genesisFile,
 		keystoreDir: keystoreDir,
 		apiAddr:     apiAddr,
+		adminAddr:   adminAddr,
 		pwdFile:     pwdFile,
 		state:       state,
 		submitCh:    submitCh,
 		logger:      logger,
+		errors:      &errorLog{},
+		slotHooks:   newSlotWebhooks(),
+		corsOrigins: []string{"*"},
+		nonceLock:   new(AddrLocker),
+
+		startingBlock: state.GetBlockIndex(),
+
+		commitLatency: newCommitLatency(CommitSLO{}),
+		gasPrices:     newGasPriceOracle(GasPriceOracle{}),
+		logLimits:     LogLimits{MaxLogs: DefaultMaxLogs},
 		// TODO: no-default rpcConfig required
 		rpcConfig: rpcConfig,
 	}
+	logger.AddHook(s.errors)
+	s.graphql = newGraphQLSchema(s)
+
 	var err error
 	s.rpcServer, err = NewRpcServer(rpcConfig, s)
 	if err != nil {
@@ -90,6 +141,7 @@ func (m *Service) Run() {
 	m.checkErr(m.makeKeyStore())
 	m.checkErr(m.unlockAccounts())
 	m.checkErr(m.createGenesisAccounts())
+	atomic.StoreInt32(&m.initialized, 1)
 
 	m.logger.Info("serving web3-api ...")
 	if err := m.rpcServer.Start(); err != nil {
@@ -101,10 +153,76 @@ func (m *Service) Run() {
 		}
 	})()
 
+	go m.submitPromotedTxs()
+	go m.runSlotWebhooks()
+	go m.runCommitSLO()
+	go m.runGasPriceOracle()
+
+	if m.watcher != nil {
+		go m.runWatches()
+	}
+
+	if m.eventMetrics != nil {
+		go m.runEventMetrics()
+	}
+
+	if m.adminAddr != "" {
+		m.logger.WithField("addr", m.adminAddr).Info("serving admin dashboard ...")
+		go m.serveAdmin()
+	}
+
+	if m.grpcAddr != "" {
+		m.logger.WithField("addr", m.grpcAddr).Info("serving grpc api ...")
+		go m.serveGRPC()
+	}
+
+	if m.pprofAddr != "" {
+		m.logger.WithField("addr", m.pprofAddr).Info("serving pprof ...")
+		go m.servePprof()
+	}
+
 	m.logger.Info("serving api ...")
 	m.serveAPI()
 }
 
+// submitPromotedTxs submits the queued transactions promoted by the State
+func (m *Service) submitPromotedTxs() {
+	for tx := range m.state.PromotedTxs() {
+		if err := m.submitTx(tx); err != nil {
+			m.logger.WithError(err).Error("Submitting promoted tx")
+		}
+	}
+}
+
+//SetIPCPath makes the Service serve the JSON-RPC APIs on a unix domain socket
+//(a named pipe on Windows) at the given path, alongside HTTP, so co-located
+//tools and consoles (geth attach) can connect without a network port. Disabled
+//when empty.
+func (m *Service) SetIPCPath(path string) {
+	rpcConfig := *m.rpcConfig
+	rpcConfig.IPCPath = path
+	m.rpcConfig = &rpcConfig
+	m.rpcServer.setIPCEndpoint(rpcConfig.IPCEndpoint())
+}
+
+//SetCORS sets the origins allowed to call the HTTP API and the JSON-RPC HTTP
+//endpoint from a browser. "*" allows any origin.
+func (m *Service) SetCORS(origins []string) {
+	m.corsOrigins = origins
+	m.rpcServer.setHTTPCors(origins)
+}
+
+//SetTLS makes the Service serve the HTTP API over HTTPS with the given
+//certificate and key files (PEM), which are checked right away
+func (m *Service) SetTLS(certFile, keyFile string) error {
+	if _, err := tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+		return fmt.Errorf("loading TLS certificate: %s", err)
+	}
+	m.tlsCert = certFile
+	m.tlsKey = keyFile
+	return nil
+}
+
 //XXX
 func (m *Service) GetSubmitCh() chan []byte {
 	return m.submitCh
@@ -143,12 +261,18 @@ func (m *Service) unlockAccounts() error {
 		return err
 	}
 
+	locals := []ethcommon.Address{}
 	for _, ac := range m.keyStore.Accounts() {
 		if err := m.keyStore.Unlock(ac, string(pwd)); err != nil {
 			return err
 		}
 		m.logger.WithField("address", ac.Address.Hex()).Debug("Unlocked account")
+		locals = append(locals, ac.Address)
 	}
+
+	// The node's own accounts get priority in the TxPool
+	m.state.AddLocals(locals)
+
 	return nil
 }
 
@@ -156,6 +280,10 @@ func (m *Service) createGenesisAccounts() error {
 	if _, err := os.Stat(m.genesisFile); os.IsNotExist(err) {
 		return nil
 	}
+	// A database initialized by `evm init` holds the genesis accounts already
+	if _, ok := m.state.GetGenesis(); ok {
+		return nil
+	}
 
 	contents, err := ioutil.ReadFile(m.genesisFile)
 	if err != nil {
@@ -178,32 +306,51 @@ func (m *Service) createGenesisAccounts() error {
 
 func (m *Service) serveAPI() {
 	r := mux.NewRouter()
-	r.HandleFunc("/account/{address}", m.makeHandler(accountHandler)).Methods("GET")
-	r.HandleFunc("/accounts", m.makeHandler(accountsHandler)).Methods("GET")
-	r.HandleFunc("/block/{hash}", m.makeHandler(blockByHashHandler)).Methods("GET")
-	r.HandleFunc("/blockById/{id}", m.makeHandler(blockByIdHandler)).Methods("GET")
-	//r.HandleFunc("/blockIndex", m.makeHandler(blockIndexHandler)).Methods("GET")
-	r.HandleFunc("/call", m.makeHandler(callHandler)).Methods("POST")
-	r.HandleFunc("/tx", m.makeHandler(transactionHandler)).Methods("POST")
-	r.HandleFunc("/transactions", m.makeHandler(transactionHandler)).Methods("POST")
-	r.HandleFunc("/rawtx", m.makeHandler(rawTransactionHandler)).Methods("POST")
-	r.HandleFunc("/sendRawTransaction", m.makeHandler(rawTransactionHandler)).Methods("POST")
-	r.HandleFunc("/tx/{tx_hash}", m.makeHandler(txReceiptHandler)).Methods("GET")
-	r.HandleFunc("/transaction/{tx_hash}", m.makeHandler(transactionReceiptHandler)).Methods("GET")
-	r.HandleFunc("/info", m.makeHandler(infoHandler)).Methods("GET")
-	r.HandleFunc("/html/info", m.makeHandler(htmlInfoHandler)).Methods("GET")
-	http.Handle("/", &CORSServer{r})
+	// The endpoints are versioned under /v1, and also served at the root for
+	// the existing clients
+	v1 := r.PathPrefix(apiVersionPrefix).Subrouter()
+	for _, router := range []*mux.Router{v1, r} {
+		for _, route := range m.apiRoutes() {
+			router.HandleFunc(route.path, m.routeHandler(route)).Methods(route.method)
+		}
+		if m.nodeAuth != nil {
+			router.HandleFunc("/node/head", m.makeNodeHandler(nodeHeadHandler)).Methods("GET")
+			m.routeNodeSync(router)
+		}
+	}
+	var handler http.Handler = &CORSServer{r: r, origins: m.corsOrigins}
+	if m.rateLimiter != nil {
+		handler = m.rateLimitHandler(handler)
+	}
+	http.Handle("/", handler)
+	if m.tlsCert != "" {
+		if err := http.ListenAndServeTLS(m.apiAddr, m.tlsCert, m.tlsKey, nil); err != nil {
+			panic(err)
+		}
+		return
+	}
 	if err := http.ListenAndServe(m.apiAddr, nil); err != nil {
 		panic(err)
 	}
 }
 
+// CORSServer adds the CORS headers to the responses to the allowed origins
 type CORSServer struct {
-	r *mux.Router
+	r       *mux.Router
+	origins []string
+}
+
+func (s *CORSServer) allowed(origin string) bool {
+	for _, allowed := range s.origins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
 }
 
 func (s *CORSServer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
-	if origin := req.Header.Get("Origin"); origin != "" {
+	if origin := req.Header.Get("Origin"); origin != "" && s.allowed(origin) {
 		rw.Header().Set("Access-Control-Allow-Origin", origin)
 		rw.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
 		rw.Header().Set("Access-Control-Allow-Headers",
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrStopping is returned for the transactions submitted while the node shuts
+// down
+var ErrStopping = errors.New("node shutting down")
+
+//Stop makes the Service refuse new transactions, and fail its readiness probe
+//so that traffic is routed away, while the node shuts down
+func (m *Service) Stop() {
+	atomic.StoreInt32(&m.stopping, 1)
+	m.logger.Info("Refusing new transactions")
+}
+
+// stopped returns whether the Service was stopped
+func (m *Service) stopped() bool {
+	return atomic.LoadInt32(&m.stopping) == 1
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gorilla/mux"
+
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+// webhookTimeout bounds the delivery of a notification to a webhook
+const webhookTimeout = 5 * time.Second
+
+// slotWebhooks are the URLs notified of the changes of watched storage slots
+type slotWebhooks struct {
+	sync.RWMutex
+	hooks  map[string]JsonSlotWebhook
+	nextID int
+}
+
+func newSlotWebhooks() *slotWebhooks {
+	return &slotWebhooks{hooks: make(map[string]JsonSlotWebhook)}
+}
+
+func (h *slotWebhooks) add(hook JsonSlotWebhook) string {
+	h.Lock()
+	defer h.Unlock()
+
+	h.nextID++
+	hook.ID = strconv.Itoa(h.nextID)
+	h.hooks[hook.ID] = hook
+	return hook.ID
+}
+
+func (h *slotWebhooks) remove(id string) bool {
+	h.Lock()
+	defer h.Unlock()
+
+	_, ok := h.hooks[id]
+	delete(h.hooks, id)
+	return ok
+}
+
+func (h *slotWebhooks) list() []JsonSlotWebhook {
+	h.RLock()
+	defer h.RUnlock()
+
+	res := make([]JsonSlotWebhook, 0, len(h.hooks))
+	for _, hook := range h.hooks {
+		res = append(res, hook)
+	}
+	return res
+}
+
+func watchedSlots(slots []JsonWatchedSlot) []state.WatchedSlot {
+	res := make([]state.WatchedSlot, len(slots))
+	for i, slot := range slots {
+		res[i] = state.WatchedSlot{Address: slot.Address, Slot: slot.Slot}
+	}
+	return res
+}
+
+// slotChanges returns the changes of the given slots made by a commit, or nil
+// if none changed
+func (m *Service) slotChanges(info state.CommitInfo, slots []state.WatchedSlot) (*JsonSlotChanges, error) {
+	changes, err := m.state.GetSlotChanges(info.ParentRoot, info.Root, slots)
+	if err != nil || len(changes) == 0 {
+		return nil, err
+	}
+
+	res := &JsonSlotChanges{
+		BlockNumber: hexutil.Uint64(info.BlockIndex),
+		StateRoot:   info.Root,
+		Changes:     make([]JsonSlotChange, len(changes)),
+	}
+	for i, c := range changes {
+		res.Changes[i] = JsonSlotChange{
+			Address: c.Address,
+			Slot:    c.Slot,
+			Old:     c.Old,
+			New:     c.New,
+		}
+	}
+	return res, nil
+}
+
+// runSlotWebhooks posts the changes of the watched slots to the webhooks after
+// each commit
+func (m *Service) runSlotWebhooks() {
+	commits := make(chan state.CommitInfo, 16)
+	sub := m.state.SubscribeCommits(commits)
+	defer sub.Unsubscribe()
+
+	client := &http.Client{Timeout: webhookTimeout}
+	for info := range commits {
+		for _, hook := range m.slotHooks.list() {
+			changes, err := m.slotChanges(info, watchedSlots(hook.Slots))
+			if err != nil {
+				m.logger.WithError(err).Error("Getting watched slot changes")
+				continue
+			}
+			if changes == nil {
+				continue
+			}
+
+			js, err := json.Marshal(changes)
+			if err != nil {
+				m.logger.WithError(err).Error("Marshaling slot changes")
+				continue
+			}
+			// Slow webhooks must not hold back the commit feed
+			go m.postSlotChanges(client, hook.URL, js)
+		}
+	}
+}
+
+func (m *Service) postSlotChanges(client *http.Client, url string, js []byte) {
+	resp, err := client.Post(url, "application/json", bytes.NewReader(js))
+	if err != nil {
+		m.logger.WithError(err).WithField("url", url).Warn("Posting slot changes")
+		return
+	}
+	resp.Body.Close()
+}
+
+// PublicSlotWatchAPI streams the changes of watched storage slots
+type PublicSlotWatchAPI struct {
+	e *Service
+}
+
+// NewPublicSlotWatchAPI creates a new slot watch API
+func NewPublicSlotWatchAPI(e *Service) *PublicSlotWatchAPI {
+	return &PublicSlotWatchAPI{e}
+}
+
+// SlotChanges subscribes to the changes of the given storage slots. After each
+// commit changing any of them, the old and new values of the changed slots are
+// sent. Subscriptions require a WebSocket or IPC connection.
+func (api *PublicSlotWatchAPI) SlotChanges(ctx context.Context, slots []JsonWatchedSlot) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	if len(slots) == 0 {
+		return nil, fmt.Errorf("no slots to watch")
+	}
+	watched := watchedSlots(slots)
+	sub := notifier.CreateSubscription()
+
+	commits := make(chan state.CommitInfo, 16)
+	commitSub := api.e.state.SubscribeCommits(commits)
+
+	go func() {
+		defer commitSub.Unsubscribe()
+
+		for {
+			select {
+			case info := <-commits:
+				changes, err := api.e.slotChanges(info, watched)
+				if err != nil {
+					api.e.logger.WithError(err).Error("Getting watched slot changes")
+					continue
+				}
+				if changes == nil {
+					continue
+				}
+				if err := notifier.Notify(sub.ID, changes); err != nil {
+					api.e.logger.WithError(err).Debug("Notifying slot changes")
+				}
+			case <-sub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return sub, nil
+}
+
+/*
+POST /slotwatch
+data: JSON JsonSlotWebhook (without id)
+returns: JSON JsonSlotWebhook
+
+Registers a webhook: after each commit changing any of the slots, the URL
+receives a POST with a JSON JsonSlotChanges. Webhooks are not persisted.
+*/
+func addSlotWebhookHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	var hook JsonSlotWebhook
+	if err := json.NewDecoder(r.Body).Decode(&hook); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if hook.URL == "" || len(hook.Slots) == 0 {
+		http.Error(w, "url and slots are required", http.StatusBadRequest)
+		return
+	}
+	hook.ID = m.slotHooks.add(hook)
+	m.logger.WithField("url", hook.URL).Info("Registered slot webhook")
+
+	writeJSON(w, hook, m)
+}
+
+/*
+GET /slotwatch
+returns: JSON []JsonSlotWebhook
+*/
+func listSlotWebhooksHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	writeJSON(w, m.slotHooks.list(), m)
+}
+
+/*
+DELETE /slotwatch/{id}
+*/
+func removeSlotWebhookHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	if !m.slotHooks.remove(mux.Vars(r)["id"]) {
+		http.Error(w, "unknown webhook", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}, m *Service) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		m.logger.WithError(err).Error("Marshaling JSON response")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(js); err != nil {
+		m.logger.WithError(err).Error("Writing JSON response")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
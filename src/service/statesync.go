@@ -0,0 +1,265 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gorilla/mux"
+
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+// routeNodeSync adds the state sync endpoints to the inter-node endpoints
+func (m *Service) routeNodeSync(router *mux.Router) {
+	router.HandleFunc("/node/sync/head", m.makeNodeHandler(syncHeadHandler)).Methods("GET")
+	router.HandleFunc("/node/sync/accounts", m.makeNodeHandler(syncAccountsHandler)).Methods("GET")
+	router.HandleFunc("/node/sync/storage/{address}", m.makeNodeHandler(syncStorageHandler)).Methods("GET")
+	router.HandleFunc("/node/sync/blocks", m.makeNodeHandler(syncBlocksHandler)).Methods("GET")
+}
+
+// syncRangeParams parses the root, start and limit parameters of a range
+// request
+func syncRangeParams(r *http.Request) (common.Hash, common.Hash, int, error) {
+	query := r.URL.Query()
+	root := common.HexToHash(query.Get("root"))
+	if root == (common.Hash{}) {
+		return common.Hash{}, common.Hash{}, 0, fmt.Errorf("missing root")
+	}
+	start := common.HexToHash(query.Get("start"))
+	limit := state.DefaultSyncRangeLimit
+	if param := query.Get("limit"); param != "" {
+		var err error
+		if limit, err = strconv.Atoi(param); err != nil || limit < 1 {
+			return common.Hash{}, common.Hash{}, 0, fmt.Errorf("invalid limit %q", param)
+		}
+	}
+	return root, start, limit, nil
+}
+
+/*
+GET /node/sync/head
+returns: JSON state.SyncHead
+
+Returns the last committed block and its state root, whose state a syncing
+node downloads. Requests must be signed by an allowed peer.
+*/
+func syncHeadHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	head, err := m.state.GetSyncHead()
+	if err != nil {
+		m.logger.WithError(err).Error("Getting sync head")
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, head, m)
+}
+
+/*
+GET /node/sync/accounts?root={root}&start={hash}&limit={limit}
+example: /node/sync/accounts?root=0x4a1f...&start=0x00&limit=256
+returns: JSON state.AccountRange
+
+Returns up to limit (at most 4096) accounts of the account trie at the given
+root, from the start key, with their code and the Merkle proofs of the first
+and last accounts. The root must still be in the database, e.g. the one of the
+last committed block. Requests must be signed by an allowed peer.
+*/
+func syncAccountsHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	root, start, limit, err := syncRangeParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	res, err := m.state.GetAccountRange(root, start, limit)
+	if err != nil {
+		m.logger.WithError(err).Error("Getting account range")
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, res, m)
+}
+
+/*
+GET /node/sync/storage/{address}?root={root}&start={hash}&limit={limit}
+returns: JSON state.StorageRange
+
+Returns up to limit (at most 4096) storage slots of an account of the account
+trie at the given root, from the start key, with the Merkle proofs of the first
+and last slots against the storage root of the account. Requests must be
+signed by an allowed peer.
+*/
+func syncStorageHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	param := mux.Vars(r)["address"]
+	if !common.IsHexAddress(param) {
+		http.Error(w, fmt.Sprintf("invalid address %q", param), http.StatusBadRequest)
+		return
+	}
+	root, start, limit, err := syncRangeParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	res, err := m.state.GetStorageRange(root, common.HexToAddress(param), start, limit)
+	if err != nil {
+		m.logger.WithError(err).Error("Getting storage range")
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, res, m)
+}
+
+/*
+GET /node/sync/blocks?from={number}&count={count}
+example: /node/sync/blocks?from=1200&count=100
+returns: JSON []state.SyncBlock
+
+Returns up to count (at most 100) committed blocks from the given number, with
+their transactions encoded as they were submitted to the consensus system, for
+a node catching up after importing a snapshot to replay them. Requests must be
+signed by an allowed peer.
+*/
+func syncBlocksHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	query := r.URL.Query()
+	from, err := strconv.ParseInt(query.Get("from"), 10, 64)
+	if err != nil || from < 0 {
+		http.Error(w, "invalid from", http.StatusBadRequest)
+		return
+	}
+	count := state.MaxSyncBlocks
+	if param := query.Get("count"); param != "" {
+		if count, err = strconv.Atoi(param); err != nil {
+			http.Error(w, "invalid count", http.StatusBadRequest)
+			return
+		}
+	}
+
+	blocks, err := m.state.GetSyncBlocks(from, count)
+	if err != nil {
+		m.logger.WithError(err).Error("Getting sync blocks")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, blocks, m)
+}
+
+// SyncPeer is a state.StateSource downloading the state of a peer over its
+// inter-node endpoints, with requests signed by the node key. The responses
+// must be signed by the node key of the peer: the state is only proven against
+// the root of the head the peer returns, which is trusted because of it.
+type SyncPeer struct {
+	url    string
+	key    *ecdsa.PrivateKey
+	peer   common.Address
+	client *http.Client
+}
+
+// NewSyncPeer returns the source of the state of the peer whose API is at the
+// given URL, e.g. http://10.0.0.2:8080, and whose node key has the given
+// address
+func NewSyncPeer(peerURL string, key *ecdsa.PrivateKey, peer common.Address) *SyncPeer {
+	if !strings.Contains(peerURL, "://") {
+		peerURL = "http://" + peerURL
+	}
+	return &SyncPeer{
+		url:    strings.TrimRight(peerURL, "/"),
+		key:    key,
+		peer:   peer,
+		client: &http.Client{Timeout: time.Minute},
+	}
+}
+
+// get sends a signed GET request to the peer and decodes the JSON response
+func (p *SyncPeer) get(path string, query url.Values, res interface{}) error {
+	u := p.url + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return err
+	}
+	if err := SignNodeRequest(req, nil, p.key); err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	requestSig, _ := hexutil.Decode(req.Header.Get(nodeSignatureHeader))
+	signer, err := verifyNodeResponse(resp, body, requestSig)
+	if err != nil {
+		return fmt.Errorf("unsigned response: %s", err)
+	}
+	if signer != p.peer {
+		return fmt.Errorf("response signed by %s instead of the peer %s", signer.Hex(), p.peer.Hex())
+	}
+	return json.Unmarshal(body, res)
+}
+
+// rangeQuery is the query of a range request
+func rangeQuery(root common.Hash, start common.Hash, limit int) url.Values {
+	return url.Values{
+		"root":  {root.Hex()},
+		"start": {start.Hex()},
+		"limit": {strconv.Itoa(limit)},
+	}
+}
+
+// Head implements state.StateSource
+func (p *SyncPeer) Head() (*state.SyncHead, error) {
+	head := new(state.SyncHead)
+	if err := p.get("/node/sync/head", nil, head); err != nil {
+		return nil, err
+	}
+	return head, nil
+}
+
+// AccountRange implements state.StateSource
+func (p *SyncPeer) AccountRange(root common.Hash, start common.Hash, limit int) (*state.AccountRange, error) {
+	res := new(state.AccountRange)
+	if err := p.get("/node/sync/accounts", rangeQuery(root, start, limit), res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// StorageRange implements state.StateSource
+func (p *SyncPeer) StorageRange(root common.Hash, account common.Address, start common.Hash, limit int) (*state.StorageRange, error) {
+	res := new(state.StorageRange)
+	if err := p.get("/node/sync/storage/"+account.Hex(), rangeQuery(root, start, limit), res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Blocks returns up to count blocks of the peer from the given number, with
+// their transactions, to replay them
+func (p *SyncPeer) Blocks(from int64, count int) ([]state.SyncBlock, error) {
+	query := url.Values{
+		"from":  {strconv.FormatInt(from, 10)},
+		"count": {strconv.Itoa(count)},
+	}
+	blocks := []state.SyncBlock{}
+	if err := p.get("/node/sync/blocks", query, &blocks); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gorilla/mux"
+
+	bcommon "github.com/Fantom-foundation/go-evm/src/common"
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+// newTestSyncServer returns the inter-node endpoints of a node with a
+// committed block, which allow requests signed by client, and the key of the
+// node
+func newTestSyncServer(t *testing.T, client common.Address) (http.Handler, *ecdsa.PrivateKey, func()) {
+	st, key, cleanup := state.NewTestState(t)
+	blockHash := common.HexToHash("0x01")
+	st.BeginBlock(blockHash, 1546300800, common.Address{})
+	if err := st.ApplyTransaction(context.Background(), state.NewTestTransfer(t, key, 0), 0, blockHash); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	if _, err := st.Commit(context.Background()); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+
+	nodeKey, err := crypto.GenerateKey()
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	m := &Service{state: st, logger: bcommon.NewTestLogger(t)}
+	m.SetNodeAuth(nodeKey, []common.Address{client})
+	router := mux.NewRouter()
+	m.routeNodeSync(router)
+	return router, nodeKey, cleanup
+}
+
+// forgeResponses rewrites the responses of next to the requests whose path
+// starts with prefix, like a man-in-the-middle. The rewritten responses are
+// signed with key if it is set, and keep their signature otherwise.
+func forgeResponses(next http.Handler, prefix string, key *ecdsa.PrivateKey, forge func([]byte) []byte) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		next.ServeHTTP(rec, r)
+		body := rec.Body.Bytes()
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			body = forge(body)
+		}
+
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		if key != nil {
+			requestSig, _ := hexutil.Decode(r.Header.Get(nodeSignatureHeader))
+			sig, _ := crypto.Sign(nodeResponseHash(requestSig, body), key)
+			w.Header().Set(nodeSignatureHeader, hexutil.Encode(sig))
+		}
+		w.WriteHeader(rec.Code)
+		w.Write(body)
+	})
+}
+
+// TestSyncPeer checks that the state of a peer is synced over the inter-node
+// endpoints, and that a forged head or range is rejected
+func TestSyncPeer(t *testing.T) {
+	clientKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, nodeKey, cleanup := newTestSyncServer(t, crypto.PubkeyToAddress(clientKey.PublicKey))
+	defer cleanup()
+	nodeAddr := crypto.PubkeyToAddress(nodeKey.PublicKey)
+
+	otherKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the head points to the state of the genesis instead
+	forgeHead := func(body []byte) []byte {
+		var head state.SyncHead
+		json.Unmarshal(body, &head)
+		head.Root = ethTypes.EmptyRootHash
+		js, _ := json.Marshal(head)
+		return js
+	}
+	// the last account of the range is dropped
+	forgeRange := func(body []byte) []byte {
+		var r state.AccountRange
+		json.Unmarshal(body, &r)
+		r.Accounts = r.Accounts[:len(r.Accounts)-1]
+		js, _ := json.Marshal(r)
+		return js
+	}
+
+	cases := []struct {
+		name    string
+		handler http.Handler
+		peer    common.Address
+		synced  bool
+	}{
+		{"genuine", handler, nodeAddr, true},
+		{"other peer key", handler, crypto.PubkeyToAddress(otherKey.PublicKey), false},
+		{"forged head", forgeResponses(handler, "/node/sync/head", nil, forgeHead), nodeAddr, false},
+		{"forged head signed by another key", forgeResponses(handler, "/node/sync/head", otherKey, forgeHead), nodeAddr, false},
+		{"forged range", forgeResponses(handler, "/node/sync/accounts", nil, forgeRange), nodeAddr, false},
+		{"forged range signed by another key", forgeResponses(handler, "/node/sync/accounts", otherKey, forgeRange), nodeAddr, false},
+	}
+
+	for _, c := range cases {
+		server := httptest.NewServer(c.handler)
+		st, closeState := state.NewEmptyTestState(t)
+
+		head, err := st.SyncState(NewSyncPeer(server.URL, clientKey, c.peer), state.DefaultSyncRangeLimit)
+		if c.synced {
+			if err != nil {
+				t.Fatalf("%s: %v", c.name, err)
+			}
+			if head.Block != 0 {
+				t.Fatalf("%s: synced block should be 0, not %d", c.name, head.Block)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), "signed") {
+			t.Fatalf("%s: the response should be rejected as not signed by the peer, got %v", c.name, err)
+		}
+
+		closeState()
+		server.Close()
+	}
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// SyncStatus is the progress of the node towards the head of the consensus
+// system
+type SyncStatus struct {
+	// whether the node is connected to the consensus system (e.g. to the
+	// lachesis proxy), and whether the consensus system is itself catching up
+	// with its peers
+	Connected  bool
+	CatchingUp bool
+
+	// last committed block when the node started, last committed block, and
+	// last block ordered by the consensus system, as far as it is known
+	StartingBlock int64
+	CurrentBlock  int64
+	HighestBlock  int64
+}
+
+// Syncing returns whether the node is catching up with the consensus system,
+// or is not connected to it
+func (s SyncStatus) Syncing() bool {
+	return !s.Connected || s.CatchingUp || s.CurrentBlock < s.HighestBlock
+}
+
+type syncCallback func() SyncStatus
+
+//SetSyncCallback sets the source of the progress of the node towards the head
+//of the consensus system, provided by the engine. Without it, the node is
+//considered in sync.
+func (m *Service) SetSyncCallback(f syncCallback) {
+	m.getSyncStatus = f
+}
+
+// syncStatus returns the progress of the node, with the starting and current
+// blocks known to the Service
+func (m *Service) syncStatus() SyncStatus {
+	current := m.state.GetBlockIndex()
+	status := SyncStatus{
+		Connected:    true,
+		CurrentBlock: current,
+		HighestBlock: current,
+	}
+	if m.getSyncStatus != nil {
+		status = m.getSyncStatus()
+	}
+	status.StartingBlock = m.startingBlock
+	if status.HighestBlock < status.CurrentBlock {
+		status.HighestBlock = status.CurrentBlock
+	}
+	return status
+}
+
+// JsonSyncStatus is the JSON representation of a SyncStatus
+type JsonSyncStatus struct {
+	Syncing       bool           `json:"syncing"`
+	Connected     bool           `json:"connected"`
+	StartingBlock hexutil.Uint64 `json:"startingBlock"`
+	CurrentBlock  hexutil.Uint64 `json:"currentBlock"`
+	HighestBlock  hexutil.Uint64 `json:"highestBlock"`
+}
+
+/*
+GET /syncing
+returns: JSON JsonSyncStatus
+
+Returns whether the node is catching up with the consensus system, or is not
+connected to it, with the last committed block and the last block ordered by
+the consensus system. The status is 503 Service Unavailable while the node is
+syncing, so that load balancers can use it as a health check.
+*/
+func syncingHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	status := m.syncStatus()
+	js, err := json.Marshal(JsonSyncStatus{
+		Syncing:       status.Syncing(),
+		Connected:     status.Connected,
+		StartingBlock: hexutil.Uint64(status.StartingBlock),
+		CurrentBlock:  hexutil.Uint64(status.CurrentBlock),
+		HighestBlock:  hexutil.Uint64(status.HighestBlock),
+	})
+	if err != nil {
+		m.logger.WithError(err).Error("Marshaling JSON response")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if status.Syncing() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if _, err := w.Write(js); err != nil {
+		m.logger.WithError(err).Error("Writing JSON response")
+	}
+}
@@ -19,3 +19,71 @@ var Index = `
     </div>
 </body>
 </html>`
+
+// Dashboard is the template of the operational dashboard served on the admin
+// address. It is executed with a service.DashboardStatus.
+var Dashboard = `
+<html>
+<head>
+    <title>EVM-LITE Dashboard</title>
+    <meta http-equiv="refresh" content="5">
+    <style>
+        body { font-family: sans-serif; margin: 2em; }
+        table { border-collapse: collapse; margin-bottom: 2em; }
+        td, th { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+        .error { color: #b00; }
+    </style>
+</head>
+<body>
+    <h2>EVM-LITE Dashboard</h2>
+    <p>{{ .Time.Format "2006-01-02 15:04:05 MST" }}</p>
+
+    <h3>State</h3>
+    {{ if .Halted }}<p class="error">{{ .Halted }}</p>{{ end }}
+    <table>
+        <tr><th>Block index</th><td>{{ printf "%d" .BlockIndex }}</td></tr>
+        <tr><th>Commits / minute</th><td>{{ printf "%d" .CommitsPerMinute }}</td></tr>
+        <tr><th>TxPool depth</th><td>{{ printf "%d" .TxPoolDepth }}</td></tr>
+    </table>
+
+    <h3>Recent commits</h3>
+    <table>
+        <tr><th>Time</th><th>Block</th><th>Txs</th><th>Root</th></tr>
+        {{ range .RecentCommits }}
+        <tr><td>{{ .Time.Format "15:04:05" }}</td><td>{{ printf "%d" .BlockIndex }}</td><td>{{ printf "%d" .Txs }}</td><td>{{ .Root.Hex }}</td></tr>
+        {{ else }}
+        <tr><td colspan="4">No commits yet</td></tr>
+        {{ end }}
+    </table>
+
+    <h3>Consensus</h3>
+    {{ if .ConsensusError }}<p class="error">{{ .ConsensusError }}</p>{{ end }}
+    <table>
+        {{ range $key, $value := .Consensus }}
+        <tr><th>{{ $key }}</th><td>{{ $value }}</td></tr>
+        {{ else }}
+        <tr><td>No consensus information</td></tr>
+        {{ end }}
+    </table>
+
+    {{ if .Watches }}
+    <h3>Watched calls</h3>
+    <table>
+        <tr><th>Name</th><th>Block</th><th>Time</th><th>Result</th></tr>
+        {{ range .Watches }}
+        <tr{{ if .Error }} class="error"{{ end }}><td>{{ .Name }}</td><td>{{ printf "%d" .BlockIndex }}</td><td>{{ .Time.Format "15:04:05" }}</td><td>{{ if .Error }}{{ .Error }}{{ else }}{{ .Result }}{{ end }}</td></tr>
+        {{ end }}
+    </table>
+    {{ end }}
+
+    <h3>Recent errors</h3>
+    <table>
+        <tr><th>Time</th><th>Message</th><th>Error</th></tr>
+        {{ range .RecentErrors }}
+        <tr class="error"><td>{{ .Time.Format "15:04:05" }}</td><td>{{ .Message }}</td><td>{{ .Error }}</td></tr>
+        {{ else }}
+        <tr><td colspan="3">No errors</td></tr>
+        {{ end }}
+    </table>
+</body>
+</html>`
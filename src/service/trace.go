@@ -0,0 +1,59 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/gorilla/mux"
+)
+
+// makeStreamHandler is like makeHandler for the endpoints streaming large
+// responses: it doesn't take the Service lock, so that other API requests are
+// not blocked while a response is streamed.
+func (m *Service) makeStreamHandler(fn func(http.ResponseWriter, *http.Request, *Service)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fn(w, r, m)
+	}
+}
+
+/*
+GET /trace/{tx_hash}
+example: /trace/0xe6c2...?disableStorage=true&limit=10000
+returns: JSON ExecutionResult
+
+Re-executes an applied transaction at its historical state and streams its
+opcode-level trace, in the format of debug_traceTransaction, while it executes.
+The disableStack, disableMemory and disableStorage parameters leave these out
+of the steps, and limit caps the number of steps. Requires an API key if the
+debug namespace is protected.
+*/
+func traceHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	param := mux.Vars(r)["tx_hash"]
+	txHash := common.HexToHash(param)
+
+	query := r.URL.Query()
+	config := &vm.LogConfig{
+		DisableStack:   query.Get("disableStack") == "true",
+		DisableMemory:  query.Get("disableMemory") == "true",
+		DisableStorage: query.Get("disableStorage") == "true",
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			http.Error(w, fmt.Sprintf("invalid limit %q", limit), http.StatusBadRequest)
+			return
+		}
+		config.Limit = n
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := m.state.StreamTrace(txHash, config, w); err != nil {
+		m.logger.WithError(err).WithField("tx_hash", param).Error("Tracing transaction")
+		// Only effective if nothing was streamed yet
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
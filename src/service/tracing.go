@@ -0,0 +1,31 @@
+package service
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// otelTracer records the spans of the Service, through the provider set up by
+// the tracing package
+var otelTracer = otel.Tracer("github.com/Fantom-foundation/go-evm/src/service")
+
+// spanHandler starts a span for each request, continuing the trace of the
+// caller if it sent a traceparent header. The handler gets the span in the
+// context of the request, and passes it on to the State.
+func spanHandler(name string, h http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := otelTracer.Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.method", r.Method),
+				attribute.String("http.target", r.URL.Path)))
+		defer span.End()
+
+		h.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
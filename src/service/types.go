@@ -1,16 +1,48 @@
 package service
 
 import (
-	"math/big"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	ethTypes "github.com/ethereum/go-ethereum/core/types"
 )
 
+// All quantities in the JSON types below are hex encoded (hexutil.Big and
+// hexutil.Uint64) rather than JSON numbers. JSON numbers are parsed as doubles
+// by most clients, which silently truncates anything above 2^53 (eg. balances).
+
 type JsonAccount struct {
-	Address string   `json:"address"`
-	Balance *big.Int `json:"balance"`
-	Nonce   uint64   `json:"nonce"`
+	Address string         `json:"address"`
+	Balance *hexutil.Big   `json:"balance"`
+	Nonce   hexutil.Uint64 `json:"nonce"`
+}
+
+type JsonAccountState struct {
+	Address     common.Address    `json:"address"`
+	BlockNumber hexutil.Uint64    `json:"blockNumber"`
+	StateRoot   common.Hash       `json:"stateRoot"`
+	Exists      bool              `json:"exists"`
+	Balance     *hexutil.Big      `json:"balance"`
+	Nonce       hexutil.Uint64    `json:"nonce"`
+	CodeHash    common.Hash       `json:"codeHash"`
+	CodeSize    hexutil.Uint64    `json:"codeSize"`
+	Storage     []JsonStorageSlot `json:"storage,omitempty"`
+	Next        *common.Hash      `json:"next,omitempty"`
+}
+
+type JsonStorageSlot struct {
+	Key   common.Hash `json:"key"`
+	Value common.Hash `json:"value"`
+}
+
+type JsonAccountTransactions struct {
+	Address      common.Address `json:"address"`
+	Transactions []common.Hash  `json:"transactions"`
+}
+
+type JsonGenesisLogs struct {
+	Logs []*ethTypes.Log `json:"logs"`
 }
 
 type JsonAccountList struct {
@@ -41,27 +73,264 @@ type JsonTxRes struct {
 type JsonReceipt struct {
 	Root              common.Hash     `json:"root"`
 	TransactionHash   common.Hash     `json:"transactionHash"`
+	TransactionIndex  hexutil.Uint64  `json:"transactionIndex"`
+	BlockHash         common.Hash     `json:"blockHash"`
+	BlockNumber       hexutil.Uint64  `json:"blockNumber"`
 	From              common.Address  `json:"from"`
 	To                *common.Address `json:"to"`
-	Value             *big.Int        `json:"value"`
-	Gas               *big.Int        `json:"gas"`
-	GasUsed           *big.Int        `json:"gasUsed"`
-	GasPrice          *big.Int        `json:"gasPrice"`
-	CumulativeGasUsed *big.Int        `json:"cumulativeGasUsed"`
+	Value             *hexutil.Big    `json:"value"`
+	Gas               hexutil.Uint64  `json:"gas"`
+	GasUsed           hexutil.Uint64  `json:"gasUsed"`
+	GasPrice          *hexutil.Big    `json:"gasPrice"`
+	CumulativeGasUsed hexutil.Uint64  `json:"cumulativeGasUsed"`
 	ContractAddress   common.Address  `json:"contractAddress"`
 	Logs              []*ethTypes.Log `json:"logs"`
 	LogsBloom         ethTypes.Bloom  `json:"logsBloom"`
 	Error             string          `json:"error"`
 	Failed            bool            `json:"failed"`
-	Status            uint64          `json:"status"`
+	Status            hexutil.Uint64  `json:"status"`
+	RevertData        hexutil.Bytes   `json:"revertData,omitempty"`
+	RevertReason      string          `json:"revertReason,omitempty"`
+	GasCapped         bool            `json:"gasCapped,omitempty"`
+	Consensus         *JsonConsensus  `json:"consensus,omitempty"`
+}
+
+// JsonConsensus is the consensus metadata of a block committed by Lachesis:
+// the round in which it was received, the hash of its frame and its consensus
+// timestamp
+type JsonConsensus struct {
+	Round     hexutil.Uint64 `json:"round"`
+	FrameHash hexutil.Bytes  `json:"frameHash"`
+	Timestamp hexutil.Uint64 `json:"timestamp"`
 }
 
 type JsonBlock struct {
-	Hash         string        `json:"hash"`
-	Index        int64         `json:"index"`
-	Round        int64         `json:"round"`
-	CreatedTime  int64         `json:"createdTime"`
-	StateHash    string        `json:"stateHash"`
-	FrameHash    string        `json:"frameHash"`
-	Transactions []JsonReceipt `json:"transactions"`
+	Hash         string         `json:"hash"`
+	Index        hexutil.Uint64 `json:"index"`
+	Round        hexutil.Uint64 `json:"round"`
+	CreatedTime  hexutil.Uint64 `json:"createdTime"`
+	StateHash    string         `json:"stateHash"`
+	FrameHash    string         `json:"frameHash"`
+	Transactions []JsonReceipt  `json:"transactions"`
+}
+
+type JsonHeader struct {
+	Number        hexutil.Uint64 `json:"number"`
+	Hash          common.Hash    `json:"hash"`
+	ParentHash    common.Hash    `json:"parentHash"`
+	ConsensusHash common.Hash    `json:"consensusHash"`
+	Timestamp     hexutil.Uint64 `json:"timestamp"`
+	StateRoot     common.Hash    `json:"stateRoot"`
+	TxRoot        common.Hash    `json:"transactionsRoot"`
+	ReceiptRoot   common.Hash    `json:"receiptsRoot"`
+	GasUsed       hexutil.Uint64 `json:"gasUsed"`
+	Transactions  []common.Hash  `json:"transactions"`
+	Coinbase      common.Address `json:"coinbase"`
+	Consensus     *JsonConsensus `json:"consensus,omitempty"`
+}
+
+// JsonSignedHeader is a commit header with its canonical encoding, whose
+// keccak256 hash is the hash of the header, and the signatures of its
+// consensus block by the validators
+type JsonSignedHeader struct {
+	*JsonHeader
+	Encoding   hexutil.Bytes            `json:"encoding"`
+	Signatures []JsonValidatorSignature `json:"signatures"`
+}
+
+type JsonValidatorSignature struct {
+	Validator string `json:"validator"`
+	Signature string `json:"signature"`
+}
+
+type JsonPoolTx struct {
+	Hash     common.Hash     `json:"hash"`
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to"`
+	Nonce    hexutil.Uint64  `json:"nonce"`
+	GasPrice *hexutil.Big    `json:"gasPrice"`
+	Gas      hexutil.Uint64  `json:"gas"`
+	Size     hexutil.Uint64  `json:"size"`
+}
+
+type JsonTxPoolContent struct {
+	Pending []JsonPoolTx `json:"pending"`
+	Queued  []JsonPoolTx `json:"queued"`
+}
+
+type JsonStorageProof struct {
+	Key   common.Hash     `json:"key"`
+	Value *hexutil.Big    `json:"value"`
+	Proof []hexutil.Bytes `json:"proof"`
+}
+
+type JsonAccountProof struct {
+	Address      common.Address     `json:"address"`
+	BlockNumber  hexutil.Uint64     `json:"blockNumber"`
+	StateRoot    common.Hash        `json:"stateRoot"`
+	AccountProof []hexutil.Bytes    `json:"accountProof"`
+	Balance      *hexutil.Big       `json:"balance"`
+	CodeHash     common.Hash        `json:"codeHash"`
+	Nonce        hexutil.Uint64     `json:"nonce"`
+	StorageHash  common.Hash        `json:"storageHash"`
+	StorageProof []JsonStorageProof `json:"storageProof"`
+}
+
+type JsonAccountDiff struct {
+	Address  common.Address              `json:"address"`
+	Deleted  bool                        `json:"deleted,omitempty"`
+	Balance  *hexutil.Big                `json:"balance,omitempty"`
+	Nonce    hexutil.Uint64              `json:"nonce"`
+	CodeHash common.Hash                 `json:"codeHash"`
+	Storage  map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// JsonCommit is the payload of a commits subscription. The optional fields
+// are filled depending on the detail requested by the subscriber.
+type JsonCommit struct {
+	Number        hexutil.Uint64    `json:"number"`
+	Hash          common.Hash       `json:"hash"`
+	ParentHash    common.Hash       `json:"parentHash"`
+	ConsensusHash common.Hash       `json:"consensusHash"`
+	Timestamp     hexutil.Uint64    `json:"timestamp"`
+	StateRoot     common.Hash       `json:"stateRoot"`
+	GasUsed       hexutil.Uint64    `json:"gasUsed"`
+	TxCount       hexutil.Uint64    `json:"txCount"`
+	Transactions  []common.Hash     `json:"transactions,omitempty"`
+	Receipts      []*JsonReceipt    `json:"receipts,omitempty"`
+	StateDiff     []JsonAccountDiff `json:"stateDiff,omitempty"`
+}
+
+type JsonTxPoolEvent struct {
+	Type       string         `json:"type"`
+	Hash       common.Hash    `json:"hash"`
+	From       common.Address `json:"from"`
+	Nonce      hexutil.Uint64 `json:"nonce"`
+	ReplacedBy *common.Hash   `json:"replacedBy,omitempty"`
+	Reason     string         `json:"reason,omitempty"`
+}
+
+type JsonWatchedSlot struct {
+	Address common.Address `json:"address"`
+	Slot    common.Hash    `json:"slot"`
+}
+
+type JsonSlotChange struct {
+	Address common.Address `json:"address"`
+	Slot    common.Hash    `json:"slot"`
+	Old     common.Hash    `json:"old"`
+	New     common.Hash    `json:"new"`
+}
+
+type JsonSlotChanges struct {
+	BlockNumber hexutil.Uint64   `json:"blockNumber"`
+	StateRoot   common.Hash      `json:"stateRoot"`
+	Changes     []JsonSlotChange `json:"changes"`
+}
+
+type JsonSlotWebhook struct {
+	ID    string            `json:"id"`
+	URL   string            `json:"url"`
+	Slots []JsonWatchedSlot `json:"slots"`
+}
+
+type JsonBlobTx struct {
+	Tx   hexutil.Bytes `json:"tx"`
+	Data hexutil.Bytes `json:"data"`
+}
+
+type JsonBlob struct {
+	TxHash   common.Hash   `json:"txHash"`
+	DataHash common.Hash   `json:"dataHash"`
+	Data     hexutil.Bytes `json:"data,omitempty"`
+	Pruned   bool          `json:"pruned"`
+}
+
+type JsonNodeHead struct {
+	Node   common.Address `json:"node"`
+	Header *JsonHeader    `json:"header"`
+}
+
+type JsonMethodArg struct {
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+type JsonDecodedMethod struct {
+	Selector  string          `json:"selector"`
+	Signature string          `json:"signature,omitempty"`
+	Args      []JsonMethodArg `json:"args,omitempty"`
+}
+
+type JsonBalanceChange struct {
+	Address common.Address `json:"address"`
+	Change  *hexutil.Big   `json:"change"`
+}
+
+type JsonTokenEvent struct {
+	Token  common.Address `json:"token"`
+	Event  string         `json:"event"`
+	From   common.Address `json:"from"`
+	To     common.Address `json:"to"`
+	Amount *hexutil.Big   `json:"amount"`
+}
+
+type JsonTxPreview struct {
+	Method          *JsonDecodedMethod  `json:"method,omitempty"`
+	Failed          bool                `json:"failed"`
+	Return          hexutil.Bytes       `json:"return,omitempty"`
+	RevertReason    string              `json:"revertReason,omitempty"`
+	EstimatedGas    hexutil.Uint64      `json:"estimatedGas"`
+	ContractAddress *common.Address     `json:"contractAddress,omitempty"`
+	BalanceChanges  []JsonBalanceChange `json:"balanceChanges"`
+	TokenEvents     []JsonTokenEvent    `json:"tokenEvents"`
+	Logs            []*ethTypes.Log     `json:"logs"`
+	Risks           []string            `json:"risks"`
+}
+
+type JsonDeadLetter struct {
+	ID       hexutil.Uint64 `json:"id"`
+	Hash     common.Hash    `json:"hash"`
+	Tx       hexutil.Bytes  `json:"tx"`
+	Error    string         `json:"error"`
+	Attempts hexutil.Uint64 `json:"attempts"`
+	Time     hexutil.Uint64 `json:"time"`
+}
+
+// JsonCommitLatency summarizes the commit latency, in milliseconds
+type JsonCommitLatency struct {
+	Samples  hexutil.Uint64 `json:"samples"`
+	P50      hexutil.Uint64 `json:"p50Ms"`
+	P95      hexutil.Uint64 `json:"p95Ms"`
+	P99      hexutil.Uint64 `json:"p99Ms"`
+	Breached []string       `json:"breached,omitempty"`
+	Alerts   hexutil.Uint64 `json:"alerts"`
+}
+
+// JsonCommitInfo describes a recent commit of the State, with its latency in
+// milliseconds
+type JsonCommitInfo struct {
+	Root       common.Hash    `json:"root"`
+	ParentRoot common.Hash    `json:"parentRoot"`
+	BlockIndex hexutil.Uint64 `json:"blockIndex"`
+	Block      bool           `json:"block"`
+	Txs        hexutil.Uint64 `json:"txs"`
+	Time       time.Time      `json:"time"`
+	Latency    hexutil.Uint64 `json:"latencyMs"`
+}
+
+// JsonSLOAlert reports a commit latency percentile which breached its
+// threshold, or is back within it, with the latencies in milliseconds
+type JsonSLOAlert struct {
+	Percentile string         `json:"percentile"`
+	Latency    hexutil.Uint64 `json:"latencyMs"`
+	Threshold  hexutil.Uint64 `json:"thresholdMs"`
+	Breached   bool           `json:"breached"`
+	BlockIndex hexutil.Uint64 `json:"blockIndex"`
+	Time       time.Time      `json:"time"`
+}
+
+type JsonSnapshotRequest struct {
+	Dir    string `json:"dir"`
+	Shards int    `json:"shards,omitempty"`
 }
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	// 2^53 + 1 is the first integer a double can not represent
+	_aboveDouble = new(big.Int).Add(new(big.Int).Lsh(big.NewInt(1), 53), big.NewInt(1))
+	_huge        = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+)
+
+// findJSONNumbers returns the paths of all the values encoded as JSON numbers
+func findJSONNumbers(path string, v interface{}) []string {
+	res := []string{}
+	switch val := v.(type) {
+	case json.Number:
+		res = append(res, fmt.Sprintf("%s=%s", path, val))
+	case map[string]interface{}:
+		for k, e := range val {
+			res = append(res, findJSONNumbers(path+"."+k, e)...)
+		}
+	case []interface{}:
+		for i, e := range val {
+			res = append(res, findJSONNumbers(fmt.Sprintf("%s[%d]", path, i), e)...)
+		}
+	}
+	return res
+}
+
+func checkNoJSONNumbers(t *testing.T, name string, v interface{}) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+
+	if numbers := findJSONNumbers(name, generic); len(numbers) > 0 {
+		t.Fatalf("%s: quantities should be hex encoded, found JSON numbers %v", name, numbers)
+	}
+}
+
+func TestJsonTypesEncodeQuantitiesAsHex(t *testing.T) {
+	to := common.HexToAddress("0x50bd8a037442af4cdf631495bcaa5443de19685d")
+
+	receipt := JsonReceipt{
+		TransactionHash:   common.HexToHash("0x01"),
+		To:                &to,
+		Value:             (*hexutil.Big)(_huge),
+		Gas:               hexutil.Uint64(_aboveDouble.Uint64()),
+		GasUsed:           hexutil.Uint64(21000),
+		GasPrice:          (*hexutil.Big)(_aboveDouble),
+		CumulativeGasUsed: hexutil.Uint64(_aboveDouble.Uint64()),
+		Logs:              []*ethTypes.Log{},
+		Status:            hexutil.Uint64(ethTypes.ReceiptStatusSuccessful),
+	}
+
+	checkNoJSONNumbers(t, "JsonAccount", JsonAccount{
+		Address: to.Hex(),
+		Balance: (*hexutil.Big)(_huge),
+		Nonce:   hexutil.Uint64(_aboveDouble.Uint64()),
+	})
+	checkNoJSONNumbers(t, "JsonAccountList", JsonAccountList{
+		Accounts: []JsonAccount{{Address: to.Hex(), Balance: (*hexutil.Big)(_aboveDouble)}},
+	})
+	checkNoJSONNumbers(t, "JsonReceipt", receipt)
+	checkNoJSONNumbers(t, "JsonBlock", JsonBlock{
+		Index:        hexutil.Uint64(_aboveDouble.Uint64()),
+		Round:        hexutil.Uint64(3),
+		CreatedTime:  hexutil.Uint64(1546300800),
+		Transactions: []JsonReceipt{receipt},
+	})
+	checkNoJSONNumbers(t, "JsonDeadLetter", JsonDeadLetter{
+		ID:       hexutil.Uint64(7),
+		Hash:     common.HexToHash("0x01"),
+		Attempts: hexutil.Uint64(3),
+		Time:     hexutil.Uint64(1546300800),
+	})
+	checkNoJSONNumbers(t, "JsonCommitLatency", JsonCommitLatency{
+		Samples: hexutil.Uint64(1000),
+		P50:     hexutil.Uint64(120),
+		P95:     hexutil.Uint64(450),
+		P99:     hexutil.Uint64(900),
+		Alerts:  hexutil.Uint64(2),
+	})
+	checkNoJSONNumbers(t, "JsonSLOAlert", JsonSLOAlert{
+		Percentile: "p99",
+		Latency:    hexutil.Uint64(900),
+		Threshold:  hexutil.Uint64(500),
+		Breached:   true,
+		BlockIndex: hexutil.Uint64(_aboveDouble.Uint64()),
+	})
+	checkNoJSONNumbers(t, "JsonAccountState", JsonAccountState{
+		Address:     to,
+		BlockNumber: hexutil.Uint64(_aboveDouble.Uint64()),
+		Exists:      true,
+		Balance:     (*hexutil.Big)(_huge),
+		Nonce:       hexutil.Uint64(1),
+		CodeSize:    hexutil.Uint64(24576),
+	})
+	checkNoJSONNumbers(t, "DashboardStatus", DashboardStatus{
+		BlockIndex:       hexutil.Uint64(_aboveDouble.Uint64()),
+		CommitsPerMinute: hexutil.Uint64(60),
+		TxPoolDepth:      hexutil.Uint64(12),
+		RecentCommits: []JsonCommitInfo{{
+			BlockIndex: hexutil.Uint64(_aboveDouble.Uint64()),
+			Block:      true,
+			Txs:        hexutil.Uint64(3),
+			Latency:    hexutil.Uint64(120),
+		}},
+		CommitLatency: &JsonCommitLatency{Samples: hexutil.Uint64(1000)},
+	})
+	checkNoJSONNumbers(t, "WatchResult", WatchResult{
+		Name:       "oracle",
+		To:         to,
+		Result:     hexutil.Bytes{0x01},
+		BlockIndex: hexutil.Uint64(_aboveDouble.Uint64()),
+	})
+}
+
+func TestJsonAccountBalancePrecision(t *testing.T) {
+	for _, balance := range []*big.Int{big.NewInt(0), _aboveDouble, _huge} {
+		js, err := json.Marshal(JsonAccount{Balance: (*hexutil.Big)(balance)})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var account JsonAccount
+		if err := json.Unmarshal(js, &account); err != nil {
+			t.Fatal(err)
+		}
+
+		if account.Balance.ToInt().Cmp(balance) != 0 {
+			t.Fatalf("balance should be %v, not %v", balance, account.Balance.ToInt())
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/Fantom-foundation/go-evm/src/metrics"
+)
+
+// WatchedCall is a read-only call executed periodically by the node, e.g. a
+// health or oracle check of a system contract. Watched calls are free: they
+// run against a copy of the state and are never submitted.
+type WatchedCall struct {
+	Name string         `json:"name"`
+	From common.Address `json:"from"`
+	To   common.Address `json:"to"`
+	Data hexutil.Bytes  `json:"data"`
+	Gas  hexutil.Uint64 `json:"gas"`
+}
+
+// WatchResult is the latest result of a WatchedCall
+type WatchResult struct {
+	Name       string         `json:"name"`
+	To         common.Address `json:"to"`
+	Result     hexutil.Bytes  `json:"result"`
+	Error      string         `json:"error,omitempty"`
+	BlockIndex hexutil.Uint64 `json:"blockIndex"`
+	Time       time.Time      `json:"time"`
+}
+
+// LoadWatchedCalls reads a JSON list of WatchedCalls from a file
+func LoadWatchedCalls(file string) ([]WatchedCall, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var calls []WatchedCall
+	if err := json.Unmarshal(data, &calls); err != nil {
+		return nil, fmt.Errorf("parsing %s: %s", file, err)
+	}
+
+	names := make(map[string]bool)
+	for i, call := range calls {
+		if call.Name == "" {
+			return nil, fmt.Errorf("watched call %d has no name", i)
+		}
+		if names[call.Name] {
+			return nil, fmt.Errorf("duplicate watched call %q", call.Name)
+		}
+		names[call.Name] = true
+		if call.Gas == 0 {
+			calls[i].Gas = defaultGas
+		}
+	}
+
+	return calls, nil
+}
+
+// watchMetrics are the metrics of a watched call, labeled with its name
+type watchMetrics struct {
+	lastSuccess *metrics.Gauge // Unix time
+	lastBlock   *metrics.Gauge
+	failures    *metrics.Counter
+}
+
+func newWatchMetrics(name string) watchMetrics {
+	return watchMetrics{
+		lastSuccess: metrics.NewGauge("evm_watch_last_success_timestamp_seconds", "Unix time of the last successful execution of the watched call", "name", name),
+		lastBlock:   metrics.NewGauge("evm_watch_last_success_block", "Block index of the last successful execution of the watched call", "name", name),
+		failures:    metrics.NewCounter("evm_watch_failures_total", "Failed executions of the watched call", "name", name),
+	}
+}
+
+// callWatcher executes the watched calls and keeps their latest results
+type callWatcher struct {
+	sync.RWMutex
+	calls    []WatchedCall
+	metrics  []watchMetrics
+	interval time.Duration
+	results  []WatchResult
+}
+
+//SetWatchedCalls makes the Service execute the given calls every interval once
+//it runs. The results are served on /watches and on the dashboard, and the
+//time and block of the last success and the failures of each call on /metrics.
+func (m *Service) SetWatchedCalls(calls []WatchedCall, interval time.Duration) {
+	watcher := &callWatcher{
+		calls:    calls,
+		metrics:  make([]watchMetrics, len(calls)),
+		interval: interval,
+	}
+	for i, call := range calls {
+		watcher.metrics[i] = newWatchMetrics(call.Name)
+	}
+	m.watcher = watcher
+}
+
+// runWatches executes the watched calls every interval
+func (m *Service) runWatches() {
+	ticker := time.NewTicker(m.watcher.interval)
+	defer ticker.Stop()
+
+	for {
+		m.executeWatches()
+		<-ticker.C
+	}
+}
+
+func (m *Service) executeWatches() {
+	results := make([]WatchResult, len(m.watcher.calls))
+	for i, call := range m.watcher.calls {
+		to := call.To
+		msg := ethTypes.NewMessage(call.From, &to, 0, big.NewInt(0), uint64(call.Gas), big.NewInt(0), call.Data, false)
+
+		blockIndex := m.state.GetBlockIndex()
+		res := WatchResult{
+			Name:       call.Name,
+			To:         call.To,
+			BlockIndex: hexutil.Uint64(blockIndex),
+			Time:       time.Now(),
+		}
+		data, err := m.state.Call(context.Background(), msg)
+		if err != nil {
+			m.logger.WithError(err).WithField("name", call.Name).Warn("Executing watched call")
+			res.Error = err.Error()
+			m.watcher.metrics[i].failures.Inc()
+		} else {
+			m.watcher.metrics[i].lastSuccess.Set(res.Time.Unix())
+			m.watcher.metrics[i].lastBlock.Set(blockIndex)
+		}
+		res.Result = data
+		results[i] = res
+	}
+
+	m.watcher.Lock()
+	m.watcher.results = results
+	m.watcher.Unlock()
+}
+
+// watchResults returns the latest results of the watched calls, if any
+func (m *Service) watchResults() []WatchResult {
+	if m.watcher == nil {
+		return nil
+	}
+
+	m.watcher.RLock()
+	defer m.watcher.RUnlock()
+	return m.watcher.results
+}
+
+/*
+GET /watches
+returns: JSON []WatchResult
+
+Returns the latest results of the read-only calls the node executes
+periodically (see --eth.watch-calls).
+*/
+func watchesHandler(w http.ResponseWriter, r *http.Request, m *Service) {
+	results := m.watchResults()
+	if results == nil {
+		results = []WatchResult{}
+	}
+
+	js, err := json.Marshal(results)
+	if err != nil {
+		m.logger.WithError(err).Error("Marshaling JSON response")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(js); err != nil {
+		m.logger.WithError(err).Error("Writing JSON response")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
@@ -26,16 +27,20 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 
 	"github.com/ethereum/go-ethereum/log"
-	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 	//"github.com/syndtr/goleveldb/leveldb"
 	//"github.com/syndtr/goleveldb/leveldb/util"
+
+	"github.com/Fantom-foundation/go-evm/src/state"
 )
 
 var (
 	defaultGasPrice = new(big.Int).Mul(big.NewInt(1), big.NewInt(params.GWei))
+
+	// gas of the calls which don't set one
+	defaultCallGas = uint64(50000000)
 )
 
 // PublicEthereumAPI provides an API to access Ethereum related information.
@@ -49,9 +54,10 @@ func NewPublicEthereumAPI(b *Service) *PublicEthereumAPI {
 	return &PublicEthereumAPI{b}
 }
 
-// GasPrice returns a suggestion for a gas price.
+// GasPrice returns a suggestion for a gas price, from the prices of the
+// transactions of the recent blocks.
 func (s *PublicEthereumAPI) GasPrice(ctx context.Context) (*hexutil.Big, error) {
-	return nil, ErrNotImplemented
+	return (*hexutil.Big)(s.backend.suggestGasPrice()), nil
 }
 
 // ProtocolVersion returns the current Ethereum protocol version this node supports
@@ -68,23 +74,19 @@ func (s *PublicEthereumAPI) ProtocolVersion() hexutil.Uint {
 // - pulledStates:  number of state entries processed until now
 // - knownStates:   number of known state entries that still need to be pulled
 func (s *PublicEthereumAPI) Syncing() (interface{}, error) {
-	/*
-		progress := s.backend.Downloader().Progress()
+	progress := s.backend.syncStatus()
 
-		// Return not syncing if the synchronisation already completed
-		if progress.CurrentBlock >= progress.HighestBlock {
-			return false, nil
-		}
-		// Otherwise gather the block sync stats
-		return map[string]interface{}{
-			"startingBlock": hexutil.Uint64(progress.StartingBlock),
-			"currentBlock":  hexutil.Uint64(progress.CurrentBlock),
-			"highestBlock":  hexutil.Uint64(progress.HighestBlock),
-			"pulledStates":  hexutil.Uint64(progress.PulledStates),
-			"knownStates":   hexutil.Uint64(progress.KnownStates),
-		}, nil
-	*/
-	return nil, ErrNotImplemented
+	// Return not syncing if the node caught up with the consensus system
+	if !progress.Syncing() {
+		return false, nil
+	}
+	// Otherwise gather the block sync stats
+	return map[string]interface{}{
+		"startingBlock": hexutil.Uint64(progress.StartingBlock),
+		"currentBlock":  hexutil.Uint64(progress.CurrentBlock),
+		"highestBlock":  hexutil.Uint64(progress.HighestBlock),
+		"connected":     progress.Connected,
+	}, nil
 }
 
 // PublicTxPoolAPI offers and API for the transaction pool. It only operates on data that is non confidential.
@@ -128,53 +130,53 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 }
 
 // Status returns the number of pending and queued transaction in the pool.
+// Pending transactions were accepted since the last commit and submitted to
+// the consensus system; queued ones wait for a nonce gap to be filled.
 func (s *PublicTxPoolAPI) Status() map[string]hexutil.Uint {
-	/*
-		pending, queue := s.backend.Stats()
-		return map[string]hexutil.Uint{
-			"pending": hexutil.Uint(pending),
-			"queued":  hexutil.Uint(queue),
-		}
-	*/
-	return nil
+	pending, queued := s.backend.state.GetPoolContent()
+	return map[string]hexutil.Uint{
+		"pending": hexutil.Uint(len(pending)),
+		"queued":  hexutil.Uint(len(queued)),
+	}
 }
 
 // Inspect retrieves the content of the transaction pool and flattens it into an
-// easily inspectable list.
+// easily inspectable list. Queued transactions also show the nonce their
+// sender is expected to use next, which they wait for.
 func (s *PublicTxPoolAPI) Inspect() map[string]map[string]map[string]string {
-	/*
-		content := map[string]map[string]map[string]string{
-			"pending": make(map[string]map[string]string),
-			"queued":  make(map[string]map[string]string),
-		}
-		pending, queue := s.backend.TxPoolContent()
+	content := map[string]map[string]map[string]string{
+		"pending": make(map[string]map[string]string),
+		"queued":  make(map[string]map[string]string),
+	}
+	pending, queued := s.backend.state.GetPoolContent()
 
-		// Define a formatter to flatten a transaction into a string
-		var format = func(tx *types.Transaction) string {
-			if to := tx.To(); to != nil {
-				return fmt.Sprintf("%s: %v wei + %v gas × %v wei", tx.To().Hex(), tx.Value(), tx.Gas(), tx.GasPrice())
-			}
-			return fmt.Sprintf("contract creation: %v wei + %v gas × %v wei", tx.Value(), tx.Gas(), tx.GasPrice())
+	// Define a formatter to flatten a transaction into a string
+	var format = func(tx state.PoolTx) string {
+		if to := tx.To; to != nil {
+			return fmt.Sprintf("%s: %v wei + %v gas × %v wei", to.Hex(), tx.Value, tx.Gas, tx.GasPrice)
 		}
-		// Flatten the pending transactions
-		for account, txs := range pending {
-			dump := make(map[string]string)
-			for _, tx := range txs {
-				dump[fmt.Sprintf("%d", tx.Nonce())] = format(tx)
-			}
-			content["pending"][account.Hex()] = dump
+		return fmt.Sprintf("contract creation: %v wei + %v gas × %v wei", tx.Value, tx.Gas, tx.GasPrice)
+	}
+	// Flatten the pending transactions
+	for _, tx := range pending {
+		dump, ok := content["pending"][tx.From.Hex()]
+		if !ok {
+			dump = make(map[string]string)
+			content["pending"][tx.From.Hex()] = dump
 		}
-		// Flatten the queued transactions
-		for account, txs := range queue {
-			dump := make(map[string]string)
-			for _, tx := range txs {
-				dump[fmt.Sprintf("%d", tx.Nonce())] = format(tx)
-			}
-			content["queued"][account.Hex()] = dump
+		dump[fmt.Sprintf("%d", tx.Nonce)] = format(tx)
+	}
+	// Flatten the queued transactions
+	for _, tx := range queued {
+		dump, ok := content["queued"][tx.From.Hex()]
+		if !ok {
+			dump = make(map[string]string)
+			content["queued"][tx.From.Hex()] = dump
 		}
-		return content
-	*/
-	return nil
+		next := s.backend.state.GetPoolNonce(tx.From)
+		dump[fmt.Sprintf("%d", tx.Nonce)] = fmt.Sprintf("%s (waiting for nonce %d)", format(tx), next)
+	}
+	return content
 }
 
 // PublicAccountAPI provides an API to access accounts managed by this node.
@@ -290,9 +292,12 @@ func (s *PrivateAccountAPI) DeriveAccount(url string, path string, pin *bool) (a
 }
 
 // NewAccount will create a new account and returns the address for the new account.
+// Like the accounts of the keystore unlocked at startup, its transactions get
+// priority in the txpool.
 func (s *PrivateAccountAPI) NewAccount(password string) (common.Address, error) {
 	acc, err := fetchKeystore(s.am).NewAccount(password)
 	if err == nil {
+		s.backend.state.AddLocals([]common.Address{acc.Address})
 		return acc.Address, nil
 	}
 	return common.Address{}, err
@@ -311,6 +316,9 @@ func (s *PrivateAccountAPI) ImportRawKey(privkey string, password string) (commo
 		return common.Address{}, err
 	}
 	acc, err := fetchKeystore(s.am).ImportECDSA(key, password)
+	if err == nil {
+		s.backend.state.AddLocals([]common.Address{acc.Address})
+	}
 	return acc.Address, err
 }
 
@@ -493,6 +501,12 @@ func (s *PublicBlockChainAPI) BlockNumber() hexutil.Uint64 {
 // given block number. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta
 // block numbers are also allowed.
 func (s *PublicBlockChainAPI) GetBalance(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (*hexutil.Big, error) {
+	if blockNr == rpc.PendingBlockNumber {
+		return (*hexutil.Big)(s.backend.state.GetPoolBalance(address)), nil
+	}
+	if err := checkLatest(s.backend, blockNr); err != nil {
+		return nil, err
+	}
 	balance := s.backend.state.GetBalance(address)
 	return (*hexutil.Big)(balance), nil
 }
@@ -500,34 +514,28 @@ func (s *PublicBlockChainAPI) GetBalance(ctx context.Context, address common.Add
 // GetBlockByNumber returns the requested block. When blockNr is -1 the chain head is returned. When fullTx is true all
 // transactions in the block are returned in full detail, otherwise only the transaction hash is returned.
 func (s *PublicBlockChainAPI) GetBlockByNumber(ctx context.Context, blockNr rpc.BlockNumber, fullTx bool) (map[string]interface{}, error) {
-	/*
-		block, err := s.backend.BlockByNumber(ctx, blockNr)
-		if block != nil {
-			response, err := s.rpcOutputBlock(block, true, fullTx)
-			if err == nil && blockNr == rpc.PendingBlockNumber {
-				// Pending blocks need to nil out a few fields
-				for _, field := range []string{"hash", "nonce", "miner"} {
-					response[field] = nil
-				}
-			}
-			return response, err
-		}
-		return nil, err
-	*/
-	return nil, ErrNotImplemented
+	// The pending block is not built before consensus orders it, so pending
+	// is the latest block
+	number := int64(blockNr)
+	if blockNr < 0 {
+		number = s.backend.state.GetBlockIndex()
+	}
+	block, err := s.backend.state.GetBlockByNumber(number)
+	if err != nil {
+		// Unknown blocks are returned as null
+		return nil, nil
+	}
+	return s.rpcOutputBlock(block, true, fullTx)
 }
 
 // GetBlockByHash returns the requested block. When fullTx is true all transactions in the block are returned in full
 // detail, otherwise only the transaction hash is returned.
 func (s *PublicBlockChainAPI) GetBlockByHash(ctx context.Context, blockHash common.Hash, fullTx bool) (map[string]interface{}, error) {
-	/*
-		block, err := s.backend.GetBlock(ctx, blockHash)
-		if block != nil {
-			return s.rpcOutputBlock(block, true, fullTx)
-		}
-		return nil, err
-	*/
-	return nil, ErrNotImplemented
+	block, err := s.backend.state.GetBlockByHash(blockHash)
+	if err != nil {
+		return nil, nil
+	}
+	return s.rpcOutputBlock(block, true, fullTx)
 }
 
 // GetUncleByBlockNumberAndIndex returns the uncle block for the given block hash and index. When fullTx is true
@@ -594,30 +602,28 @@ func (s *PublicBlockChainAPI) GetUncleCountByBlockHash(ctx context.Context, bloc
 
 // GetCode returns the code stored at the given address in the state for the given block number.
 func (s *PublicBlockChainAPI) GetCode(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
-	/*
-		state, _, err := s.backend.StateAndHeaderByNumber(ctx, blockNr)
-		if state == nil || err != nil {
-			return nil, err
-		}
-		code := state.GetCode(address)
-		return code, state.Error()
-	*/
-	return nil, ErrNotImplemented
+	if blockNr == rpc.PendingBlockNumber {
+		return s.backend.state.GetPoolCode(address), nil
+	}
+	if err := checkLatest(s.backend, blockNr); err != nil {
+		return nil, err
+	}
+	return s.backend.state.GetCode(address), nil
 }
 
 // GetStorageAt returns the storage from the state at the given address, key and
 // block number. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta block
 // numbers are also allowed.
 func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.Address, key string, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
-	/*
-		state, _, err := s.backend.StateAndHeaderByNumber(ctx, blockNr)
-		if state == nil || err != nil {
-			return nil, err
-		}
-		res := state.GetState(address, common.HexToHash(key))
-		return res[:], state.Error()
-	*/
-	return nil, ErrNotImplemented
+	if blockNr == rpc.PendingBlockNumber {
+		res := s.backend.state.GetPoolStorageAt(address, common.HexToHash(key))
+		return res[:], nil
+	}
+	if err := checkLatest(s.backend, blockNr); err != nil {
+		return nil, err
+	}
+	res := s.backend.state.GetStorageAt(address, common.HexToHash(key))
+	return res[:], nil
 }
 
 // CallArgs represents the arguments for a call.
@@ -631,68 +637,48 @@ type CallArgs struct {
 }
 
 func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, vmCfg vm.Config, timeout time.Duration) ([]byte, uint64, bool, error) {
-	/*
-		defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
+	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
 
-		state, header, err := s.backend.StateAndHeaderByNumber(ctx, blockNr)
-		if state == nil || err != nil {
-			return nil, 0, false, err
-		}
-		// Set sender address or use a default if none specified
-		addr := args.From
-		if addr == (common.Address{}) {
-			if wallets := s.backend.AccountManager().Wallets(); len(wallets) > 0 {
-				if accounts := wallets[0].Accounts(); len(accounts) > 0 {
-					addr = accounts[0].Address
-				}
+	// Calls to earlier blocks execute on their historical state
+	if blockNr >= 0 && int64(blockNr) < s.backend.state.GetBlockIndex() {
+		return s.backend.state.ExecuteCallAt(ctx, s.callMessage(args), int64(blockNr))
+	}
+	if err := checkLatest(s.backend, blockNr); err != nil {
+		return nil, 0, false, err
+	}
+	return s.backend.state.ExecuteCall(ctx, s.callMessage(args))
+}
+
+// callMessage converts call arguments to a message, with defaults for the
+// sender and the gas
+func (s *PublicBlockChainAPI) callMessage(args CallArgs) types.Message {
+	// Set sender address or use a default if none specified
+	addr := args.From
+	if addr == (common.Address{}) {
+		if wallets := s.backend.AccountManager().Wallets(); len(wallets) > 0 {
+			if accounts := wallets[0].Accounts(); len(accounts) > 0 {
+				addr = accounts[0].Address
 			}
 		}
-		// Set default gas & gas price if none were set
-		gas, gasPrice := uint64(args.Gas), args.GasPrice.ToInt()
-		if gas == 0 {
-			gas = math.MaxUint64 / 2
-		}
-		if gasPrice.Sign() == 0 {
-			gasPrice = new(big.Int).SetUint64(defaultGasPrice)
-		}
-
-		// Create new call message
-		msg := types.NewMessage(addr, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, false)
+	}
+	// Set default gas if none was set. Calls are free unless a gas price is
+	// given
+	gas := uint64(args.Gas)
+	if gas == 0 {
+		gas = defaultCallGas
+	}
 
-		// Setup context so it may be cancelled the call has completed
-		// or, in case of unmetered gas, setup a context with a timeout.
-		var cancel context.CancelFunc
-		if timeout > 0 {
-			ctx, cancel = context.WithTimeout(ctx, timeout)
-		} else {
-			ctx, cancel = context.WithCancel(ctx)
-		}
-		// Make sure the context is cancelled when the call has completed
-		// this makes sure resources are cleaned up.
-		defer cancel()
+	return types.NewMessage(addr, args.To, 0, args.Value.ToInt(), gas, args.GasPrice.ToInt(), args.Data, false)
+}
 
-		// Get a new instance of the EVM.
-		evm, vmError, err := s.backend.GetEVM(ctx, msg, state, header, vmCfg)
-		if err != nil {
-			return nil, 0, false, err
-		}
-		// Wait for the context to be done and cancel the evm. Even if the
-		// EVM has finished, cancelling may be done (repeatedly)
-		go func() {
-			<-ctx.Done()
-			evm.Cancel()
-		}()
-
-		// Setup the gas pool (also for unmetered requests)
-		// and apply the message.
-		gp := new(core.GasPool).AddGas(math.MaxUint64)
-		res, gas, failed, err := core.ApplyMessage(evm, msg, gp)
-		if err := vmError(); err != nil {
-			return nil, 0, false, err
-		}
-		return res, gas, failed, err
-	*/
-	return nil, 0, false, ErrNotImplemented
+// checkLatest rejects requests for the state of a block other than the latest.
+// Only the latest (committed) state, and the pending state of the txpool, are
+// served.
+func checkLatest(b *Service, blockNr rpc.BlockNumber) error {
+	if blockNr < 0 || int64(blockNr) == b.state.GetBlockIndex() {
+		return nil
+	}
+	return fmt.Errorf("state of block %d is not available, only the latest state is served", blockNr)
 }
 
 // Call executes the given transaction on the state for the given block number.
@@ -703,55 +689,21 @@ func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr r
 }
 
 // EstimateGas returns an estimate of the amount of gas needed to execute the
-// given transaction against the current pending block.
+// given transaction against the current pending block. The estimate is the
+// minimum gas with which the transaction succeeds, up to the gas given or
+// defaultCallGas.
 func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (hexutil.Uint64, error) {
-	/*
-		// Binary search the gas requirement, as it may be higher than the amount used
-		var (
-			lo  uint64 = params.TxGas - 1
-			hi  uint64
-			cap uint64
-		)
-		if uint64(args.Gas) >= params.TxGas {
-			hi = uint64(args.Gas)
-		} else {
-			// Retrieve the current pending block to act as the gas ceiling
-			block, err := s.backend.BlockByNumber(ctx, rpc.PendingBlockNumber)
-			if err != nil {
-				return 0, err
-			}
-			hi = block.GasLimit()
-		}
-		cap = hi
-
-		// Create a helper to check if a gas allowance results in an executable transaction
-		executable := func(gas uint64) bool {
-			args.Gas = hexutil.Uint64(gas)
-
-			_, _, failed, err := s.doCall(ctx, args, rpc.PendingBlockNumber, vm.Config{}, 0)
-			if err != nil || failed {
-				return false
-			}
-			return true
-		}
-		// Execute the binary search and hone in on an executable gas limit
-		for lo+1 < hi {
-			mid := (hi + lo) / 2
-			if !executable(mid) {
-				lo = mid
-			} else {
-				hi = mid
+	msg := s.callMessage(args)
+	gas, err := s.backend.state.EstimateGas(msg)
+	if err == state.ErrGasEstimation {
+		// Explain the failure with the revert reason, if any
+		if ret, _, failed, cerr := s.backend.state.ExecuteCall(ctx, msg); cerr == nil && failed {
+			if reason, rerr := UnpackRevertReason(ret); rerr == nil {
+				return 0, fmt.Errorf("%s: %s", err, reason)
 			}
 		}
-		// Reject the transaction as invalid if it still fails at the highest allowance
-		if hi == cap {
-			if !executable(hi) {
-				return 0, fmt.Errorf("gas required exceeds allowance or always failing transaction")
-			}
-		}
-		return hexutil.Uint64(hi), nil
-	*/
-	return 0, ErrNotImplemented
+	}
+	return hexutil.Uint64(gas), err
 }
 
 // ExecutionResult groups all structured logs emitted by the EVM
@@ -870,18 +822,79 @@ func RPCMarshalBlock(b *types.Block, inclTx bool, fullTx bool) (map[string]inter
 	return fields, nil
 }
 
-// rpcOutputBlock uses the generalized output filler, then adds the total difficulty field, which requires
-// a `PublicBlockchainAPI`.
-func (s *PublicBlockChainAPI) rpcOutputBlock(b *types.Block, inclTx bool, fullTx bool) (map[string]interface{}, error) {
-	/*
-		fields, err := RPCMarshalBlock(b, inclTx, fullTx)
-		if err != nil {
-			return nil, err
+// rpcMarshalHeader converts a committed Block to the RPC representation of a
+// header. The miner is the beneficiary of the block, zero for the blocks
+// committed before it was recorded. The proof of work and uncle fields are
+// zero.
+func rpcMarshalHeader(b *state.Block, bloom types.Bloom, gasLimit uint64) map[string]interface{} {
+	return map[string]interface{}{
+		"number":           (*hexutil.Big)(new(big.Int).SetUint64(b.Number)),
+		"hash":             b.Hash(),
+		"parentHash":       b.ParentHash,
+		"nonce":            types.BlockNonce{},
+		"mixHash":          common.Hash{},
+		"sha3Uncles":       types.EmptyUncleHash,
+		"logsBloom":        bloom,
+		"stateRoot":        b.StateRoot,
+		"miner":            b.Coinbase,
+		"difficulty":       (*hexutil.Big)(new(big.Int)),
+		"extraData":        hexutil.Bytes{},
+		"gasLimit":         hexutil.Uint64(gasLimit),
+		"gasUsed":          hexutil.Uint64(b.GasUsed),
+		"timestamp":        (*hexutil.Big)(new(big.Int).SetUint64(b.Timestamp)),
+		"transactionsRoot": b.TxRoot,
+		"receiptsRoot":     b.ReceiptRoot,
+	}
+}
+
+// rpcHeader returns the RPC representation of the header of a committed block
+func (m *Service) rpcHeader(number int64) (map[string]interface{}, error) {
+	block, err := m.state.GetBlockByNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	// Blocks committed before the bloom index existed have an empty bloom
+	bloom, _ := m.state.GetBlockBloom(number)
+	return rpcMarshalHeader(block, bloom, m.state.GetGasLimit()), nil
+}
+
+// rpcOutputBlock returns the RPC representation of a committed block, with
+// the hashes of its transactions, or the transactions in full if fullTx is
+// set. Blocks have no uncles and no difficulty.
+func (s *PublicBlockChainAPI) rpcOutputBlock(b *state.Block, inclTx bool, fullTx bool) (map[string]interface{}, error) {
+	// Blocks committed before the bloom index existed have an empty bloom
+	bloom, _ := s.backend.state.GetBlockBloom(int64(b.Number))
+	fields := rpcMarshalHeader(b, bloom, s.backend.state.GetGasLimit())
+
+	data, err := b.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	fields["size"] = hexutil.Uint64(len(data))
+	fields["totalDifficulty"] = (*hexutil.Big)(new(big.Int))
+	fields["uncles"] = []common.Hash{}
+
+	if inclTx {
+		hash := b.Hash()
+		txs := make([]interface{}, len(b.Transactions))
+		for i, txHash := range b.Transactions {
+			if !fullTx {
+				txs[i] = txHash
+				continue
+			}
+			tx, err := s.backend.state.GetTransaction(txHash)
+			if err != nil {
+				return nil, err
+			}
+			res := newRPCTransaction(tx, hash, b.Number, uint64(i))
+			if lookup, err := s.backend.state.GetTxLookup(txHash); err == nil {
+				res.From = lookup.From
+			}
+			txs[i] = res
 		}
-		fields["totalDifficulty"] = (*hexutil.Big)(s.backend.GetTd(b.Hash()))
-		return fields, err
-	*/
-	return nil, ErrNotImplemented
+		fields["transactions"] = txs
+	}
+	return fields, nil
 }
 
 // RPCTransaction represents a transaction that will serialize to the RPC representation of a transaction
@@ -1046,33 +1059,41 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByBlockHashAndIndex(ctx cont
 	return nil
 }
 
-// GetTransactionCount returns the number of transactions the given address has sent for the given block number
+// GetTransactionCount returns the number of transactions the given address has
+// sent for the given block number. The pending count includes the
+// transactions in the txpool, and is the nonce of the next transaction.
 func (s *PublicTransactionPoolAPI) GetTransactionCount(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (*hexutil.Uint64, error) {
-	/*
-		state, _, err := s.backend.StateAndHeaderByNumber(ctx, blockNr)
-		if state == nil || err != nil {
-			return nil, err
-		}
-		nonce := state.GetNonce(address)
-		return (*hexutil.Uint64)(&nonce), state.Error()
-	*/
-	return nil, ErrNotImplemented
+	if blockNr == rpc.PendingBlockNumber {
+		nonce := s.backend.state.GetPoolNonce(address)
+		return (*hexutil.Uint64)(&nonce), nil
+	}
+	if err := checkLatest(s.backend, blockNr); err != nil {
+		return nil, err
+	}
+	nonce := s.backend.state.GetNonce(address)
+	return (*hexutil.Uint64)(&nonce), nil
 }
 
-// GetTransactionByHash returns the transaction for the given hash
+// GetTransactionByHash returns the transaction for the given hash, with the
+// block it was applied in, or without one if it is still in the txpool
 func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, hash common.Hash) *RPCTransaction {
-	/*
-		// Try to return an already finalized transaction
-		if tx, blockHash, blockNumber, index := rawdb.ReadTransaction(s.backend.ChainDb(), hash); tx != nil {
-			return newRPCTransaction(tx, blockHash, blockNumber, index)
-		}
-		// No finalized transaction, try to retrieve it from the pool
-		if tx := s.backend.GetPoolTransaction(hash); tx != nil {
-			return newRPCPendingTransaction(tx)
+	// Try to return an already applied transaction
+	if lookup, err := s.backend.state.GetTxLookup(hash); err == nil {
+		tx, err := s.backend.state.GetTransaction(hash)
+		if err != nil {
+			return nil
 		}
-		// Transaction unknown, return as such
-		return nil
-	*/
+		res := newRPCTransaction(tx, lookup.BlockHash, lookup.BlockNumber, lookup.Index)
+		res.From = lookup.From
+		return res
+	}
+	// No applied transaction, try to retrieve it from the pool
+	if tx, from := s.backend.state.GetPoolTransaction(hash); tx != nil {
+		res := newRPCPendingTransaction(tx)
+		res.From = from
+		return res
+	}
+	// Transaction unknown, return as such
 	return nil
 }
 
@@ -1096,56 +1117,54 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context,
 
 // GetTransactionReceipt returns the transaction receipt for the given transaction hash.
 func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
-	/*
-		tx, blockHash, blockNumber, index := rawdb.ReadTransaction(s.backend.ChainDb(), hash)
-		if tx == nil {
-			return nil, nil
-		}
-		receipts, err := s.backend.GetReceipts(ctx, blockHash)
-		if err != nil {
-			return nil, err
-		}
-		if len(receipts) <= int(index) {
-			return nil, nil
-		}
-		receipt := receipts[index]
+	// Transactions which are not applied yet have no receipt
+	lookup, err := s.backend.state.GetTxLookup(hash)
+	if err != nil {
+		return nil, nil
+	}
+	tx, err := s.backend.state.GetTransaction(hash)
+	if err != nil {
+		return nil, err
+	}
+	receipt, err := s.backend.state.GetReceipt(hash)
+	if err != nil {
+		return nil, err
+	}
 
-		var signer types.Signer = types.FrontierSigner{}
-		if tx.Protected() {
-			signer = types.NewEIP155Signer(tx.ChainId())
-		}
-		from, _ := types.Sender(signer, tx)
+	var signer types.Signer = types.FrontierSigner{}
+	if tx.Protected() {
+		signer = types.NewEIP155Signer(tx.ChainId())
+	}
+	from, _ := types.Sender(signer, tx)
 
-		fields := map[string]interface{}{
-			"blockHash":         blockHash,
-			"blockNumber":       hexutil.Uint64(blockNumber),
-			"transactionHash":   hash,
-			"transactionIndex":  hexutil.Uint64(index),
-			"from":              from,
-			"to":                tx.To(),
-			"gasUsed":           hexutil.Uint64(receipt.GasUsed),
-			"cumulativeGasUsed": hexutil.Uint64(receipt.CumulativeGasUsed),
-			"contractAddress":   nil,
-			"logs":              receipt.Logs,
-			"logsBloom":         receipt.Bloom,
-		}
+	fields := map[string]interface{}{
+		"blockHash":         lookup.BlockHash,
+		"blockNumber":       hexutil.Uint64(lookup.BlockNumber),
+		"transactionHash":   hash,
+		"transactionIndex":  hexutil.Uint64(lookup.Index),
+		"from":              from,
+		"to":                tx.To(),
+		"gasUsed":           hexutil.Uint64(receipt.GasUsed),
+		"cumulativeGasUsed": hexutil.Uint64(receipt.CumulativeGasUsed),
+		"contractAddress":   nil,
+		"logs":              receipt.Logs,
+		"logsBloom":         receipt.Bloom,
+	}
 
-		// Assign receipt status or post state.
-		if len(receipt.PostState) > 0 {
-			fields["root"] = hexutil.Bytes(receipt.PostState)
-		} else {
-			fields["status"] = hexutil.Uint(receipt.Status)
-		}
-		if receipt.Logs == nil {
-			fields["logs"] = [][]*types.Log{}
-		}
-		// If the ContractAddress is 20 0x0 bytes, assume it is not a contract creation
-		if receipt.ContractAddress != (common.Address{}) {
-			fields["contractAddress"] = receipt.ContractAddress
-		}
-		return fields, nil
-	*/
-	return nil, ErrNotImplemented
+	// Assign receipt status or post state.
+	if len(receipt.PostState) > 0 {
+		fields["root"] = hexutil.Bytes(receipt.PostState)
+	} else {
+		fields["status"] = hexutil.Uint(receipt.Status)
+	}
+	if receipt.Logs == nil {
+		fields["logs"] = [][]*types.Log{}
+	}
+	// If the ContractAddress is 20 0x0 bytes, assume it is not a contract creation
+	if receipt.ContractAddress != (common.Address{}) {
+		fields["contractAddress"] = receipt.ContractAddress
+	}
+	return fields, nil
 }
 
 // sign is a helper function that signs a transaction with the private key of the given address.
@@ -1178,19 +1197,27 @@ type SendTxArgs struct {
 }
 
 // setDefaults is a helper function that fills in default values for unspecified tx fields.
+// The gas is estimated, the gas price suggested by the oracle, and the nonce
+// is the next one of the sender in the pool.
 func (args *SendTxArgs) setDefaults(ctx context.Context, b *Service) error {
 	if args.Gas == nil {
-		args.Gas = new(hexutil.Uint64)
-		*(*uint64)(args.Gas) = 90000
+		gas, err := b.state.EstimateGas(args.callMessage())
+		if err != nil {
+			return fmt.Errorf("estimating gas: %s", err)
+		}
+		args.Gas = (*hexutil.Uint64)(&gas)
 	}
 	if args.GasPrice == nil {
-		*args.GasPrice = hexutil.Big(*defaultGasPrice)
+		args.GasPrice = (*hexutil.Big)(b.suggestGasPrice())
 	}
 	if args.Value == nil {
 		args.Value = new(hexutil.Big)
 	}
 	if args.Nonce == nil {
-		*args.Nonce = hexutil.Uint64(b.state.GetPoolNonce(args.From))
+		// The pool nonce counts the transactions of the sender waiting for
+		// consensus ordering
+		nonce := hexutil.Uint64(b.state.GetPoolNonce(args.From))
+		args.Nonce = &nonce
 	}
 	if args.Data != nil && args.Input != nil && !bytes.Equal(*args.Data, *args.Input) {
 		return errors.New(
@@ -1211,6 +1238,26 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b *Service) error {
 	return nil
 }
 
+// callMessage returns the message of the transaction for gas estimation, with
+// the gas given or defaultCallGas
+func (args *SendTxArgs) callMessage() types.Message {
+	var input []byte
+	if args.Data != nil {
+		input = *args.Data
+	} else if args.Input != nil {
+		input = *args.Input
+	}
+	gas := defaultCallGas
+	if args.Gas != nil {
+		gas = uint64(*args.Gas)
+	}
+	value := new(big.Int)
+	if args.Value != nil {
+		value = args.Value.ToInt()
+	}
+	return types.NewMessage(args.From, args.To, 0, value, gas, new(big.Int), input, false)
+}
+
 func (args *SendTxArgs) toTransaction() *types.Transaction {
 	var input []byte
 	if args.Data != nil {
@@ -1226,24 +1273,24 @@ func (args *SendTxArgs) toTransaction() *types.Transaction {
 
 // submitTransaction is a helper function that submits tx to txPool and logs a message.
 func submitTransaction(ctx context.Context, b *Service, tx *types.Transaction) (common.Hash, error) {
+	// The transaction goes through the TxPool like those posted on /rawtx,
+	// which also verifies its signature
+	if err := b.addTx(ctx, tx); err != nil {
+		return common.Hash{}, err
+	}
+
 	if tx.To() == nil {
-		signer := types.MakeSigner(b.ChainConfig(), big.NewInt(b.state.GetBlockIndex()))
-		from, err := types.Sender(signer, tx)
-		if err != nil {
-			return common.Hash{}, err
+		var signer types.Signer = types.HomesteadSigner{}
+		if tx.Protected() {
+			signer = types.NewEIP155Signer(tx.ChainId())
 		}
+		from, _ := types.Sender(signer, tx)
 		addr := crypto.CreateAddress(from, tx.Nonce())
 		log.Info("Submitted contract creation", "fullhash", tx.Hash().Hex(), "contract", addr.Hex())
 	} else {
 		log.Info("Submitted transaction", "fullhash", tx.Hash().Hex(), "recipient", tx.To())
 	}
 
-	raw, err := tx.MarshalJSON()
-	if err != nil {
-		return common.Hash{}, err
-	}
-	b.submitCh <- raw
-
 	return tx.Hash(), nil
 }
 
@@ -1539,28 +1586,28 @@ func (api *PrivateDebugAPI) SetHead(number hexutil.Uint64) {
 	*/
 }
 
-// PublicNetAPI offers network related RPC methods
-type PublicNetAPI struct {
-	net            *p2p.Server
-	networkVersion uint64
+// TraceConfig holds the options of debug_traceTransaction. Only the struct
+// logger is supported, not JavaScript tracers.
+type TraceConfig struct {
+	*vm.LogConfig
+	Tracer *string
 }
 
-// NewPublicNetAPI creates a new net API instance.
-func NewPublicNetAPI(net *p2p.Server, networkVersion uint64) *PublicNetAPI {
-	return &PublicNetAPI{net, networkVersion}
-}
-
-// Listening returns an indication if the node is listening for network connections.
-func (s *PublicNetAPI) Listening() bool {
-	return true // always listening
-}
-
-// PeerCount returns the number of connected peers
-func (s *PublicNetAPI) PeerCount() hexutil.Uint {
-	return hexutil.Uint(s.net.PeerCount())
-}
+// TraceTransaction re-executes an applied transaction at its historical state
+// and returns its opcode-level trace. The trace is serialized while the
+// transaction executes rather than built as an ExecutionResult.
+func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, hash common.Hash, config *TraceConfig) (json.RawMessage, error) {
+	var logConfig *vm.LogConfig
+	if config != nil {
+		if config.Tracer != nil {
+			return nil, fmt.Errorf("JavaScript tracers are not supported")
+		}
+		logConfig = config.LogConfig
+	}
 
-// Version returns the current ethereum protocol version.
-func (s *PublicNetAPI) Version() string {
-	return fmt.Sprintf("%d", s.networkVersion)
+	var buf bytes.Buffer
+	if err := api.backend.state.StreamTrace(hash, logConfig, &buf); err != nil {
+		return nil, err
+	}
+	return json.RawMessage(buf.Bytes()), nil
 }
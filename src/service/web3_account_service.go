@@ -25,7 +25,7 @@ func (s *Web3AccountService) Stop() error {
 }
 
 func (s *Web3AccountService) APIs() []rpc.API {
-	nonceLock := new(AddrLocker)
+	nonceLock := s.backend.nonceLock
 	return []rpc.API{
 		{
 			Namespace: "eth",
@@ -38,20 +38,16 @@ func NewPublicEthereumChainAPI(e *Service) *PublicEthereumChainAPI {
 	return &PublicEthereumChainAPI{e}
 }
 
-// Etherbase is the address that mining rewards will be send to
+// Etherbase is the address credited the transaction fees of the blocks
+// without a proposer
 func (api *PublicEthereumChainAPI) Etherbase() (common.Address, error) {
-	/*
-		return api.e.Etherbase()
-	*/
-	return common.Address{}, ErrNotImplemented
+	return api.e.state.Coinbase(), nil
 }
 
-// Coinbase is the address that mining rewards will be send to (alias for Etherbase)
+// Coinbase is the address credited the transaction fees of the blocks without
+// a proposer (alias for Etherbase)
 func (api *PublicEthereumChainAPI) Coinbase() (common.Address, error) {
-	/*
-		return api.Etherbase()
-	*/
-	return common.Address{}, ErrNotImplemented
+	return api.Etherbase()
 }
 
 // Hashrate returns the POW hashrate
@@ -64,14 +60,7 @@ func (api *PublicEthereumChainAPI) Hashrate() hexutil.Uint64 {
 
 // ChainId is the EIP-155 replay-protection chain id for the current ethereum chain config.
 func (api *PublicEthereumChainAPI) ChainId() hexutil.Uint64 {
-	/*
-		chainID := new(big.Int)
-		if config := api.e.chainConfig; config.IsEIP155(api.e.blockchain.CurrentBlock().Number()) {
-			chainID = config.ChainID
-		}
-		return (hexutil.Uint64)(chainID.Uint64())
-	*/
-	return hexutil.Uint64(0)
+	return (hexutil.Uint64)(api.e.chainConfig.ChainID.Uint64())
 }
 
 // PrivateAdminAPI is the collection of Ethereum full node-related APIs
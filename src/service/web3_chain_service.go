@@ -31,6 +31,31 @@ func (s *Web3ChainService) APIs() []rpc.API {
 			Version:   "1.0",
 			Service:   NewPublicEthereumChainAPI(s.backend),
 			Public:    true,
+		}, {
+			Namespace: "eth",
+			Version:   "1.0",
+			Service:   NewPublicProofAPI(s.backend),
+			Public:    true,
+		}, {
+			Namespace: "eth",
+			Version:   "1.0",
+			Service:   NewPublicCommitAPI(s.backend),
+			Public:    true,
+		}, {
+			Namespace: "eth",
+			Version:   "1.0",
+			Service:   NewPublicTxPoolFeedAPI(s.backend),
+			Public:    true,
+		}, {
+			Namespace: "eth",
+			Version:   "1.0",
+			Service:   NewPublicSlotWatchAPI(s.backend),
+			Public:    true,
+		}, {
+			Namespace: "eth",
+			Version:   "1.0",
+			Service:   NewPublicFilterAPI(s.backend),
+			Public:    true,
 		}, /*{
 			Namespace: "eth",
 			Version:   "1.0",
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+// CommitDetail is the level of detail of the commits sent to a subscriber.
+// Each level includes the previous ones.
+type CommitDetail int
+
+const (
+	CommitHeader CommitDetail = iota
+	CommitTransactions
+	CommitReceipts
+	CommitStateDiff
+)
+
+var commitDetails = map[string]CommitDetail{
+	"header":       CommitHeader,
+	"transactions": CommitTransactions,
+	"receipts":     CommitReceipts,
+	"stateDiff":    CommitStateDiff,
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (d *CommitDetail) UnmarshalText(text []byte) error {
+	detail, ok := commitDetails[string(text)]
+	if !ok {
+		return fmt.Errorf("unknown commit detail %q (header, transactions, receipts or stateDiff)", text)
+	}
+	*d = detail
+	return nil
+}
+
+// CommitOptions are the options of a commits subscription
+type CommitOptions struct {
+	Detail CommitDetail `json:"detail"`
+}
+
+// PublicCommitAPI streams the committed blocks
+type PublicCommitAPI struct {
+	e *Service
+}
+
+// NewPublicCommitAPI creates a new commit API
+func NewPublicCommitAPI(e *Service) *PublicCommitAPI {
+	return &PublicCommitAPI{e}
+}
+
+// Commits subscribes to the committed blocks, with the detail chosen by the
+// subscriber: the header only (default), the hashes of the transactions, their
+// receipts, or the accounts and storage slots changed by the block.
+// Subscriptions require a WebSocket or IPC connection.
+func (api *PublicCommitAPI) Commits(ctx context.Context, options *CommitOptions) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	detail := CommitHeader
+	if options != nil {
+		detail = options.Detail
+	}
+	sub := notifier.CreateSubscription()
+
+	commits := make(chan state.CommitInfo, 16)
+	commitSub := api.e.state.SubscribeCommits(commits)
+
+	go func() {
+		defer commitSub.Unsubscribe()
+
+		for {
+			select {
+			case info := <-commits:
+				if !info.Block {
+					continue
+				}
+				commit, err := api.e.jsonCommit(info, detail)
+				if err != nil {
+					api.e.logger.WithError(err).WithField("block", info.BlockIndex).Error("Preparing commit notification")
+					continue
+				}
+				if err := notifier.Notify(sub.ID, commit); err != nil {
+					api.e.logger.WithError(err).Debug("Notifying commit")
+				}
+			case <-sub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return sub, nil
+}
+
+// GetSignedHeaders returns up to count commit headers from the given number,
+// with their canonical encoding and the validator signatures of their
+// consensus blocks, for auditors to verify the chain of commits.
+func (api *PublicCommitAPI) GetSignedHeaders(from hexutil.Uint64, count hexutil.Uint64) ([]*JsonSignedHeader, error) {
+	return api.e.signedHeaders(int64(from), int(count))
+}
+
+func (m *Service) jsonCommit(info state.CommitInfo, detail CommitDetail) (*JsonCommit, error) {
+	block, err := m.state.GetBlockByNumber(info.BlockIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	commit := &JsonCommit{
+		Number:        hexutil.Uint64(block.Number),
+		Hash:          block.Hash(),
+		ParentHash:    block.ParentHash,
+		ConsensusHash: block.ConsensusHash,
+		Timestamp:     hexutil.Uint64(block.Timestamp),
+		StateRoot:     block.StateRoot,
+		GasUsed:       hexutil.Uint64(block.GasUsed),
+		TxCount:       hexutil.Uint64(len(block.Transactions)),
+	}
+
+	if detail >= CommitTransactions {
+		commit.Transactions = block.Transactions
+	}
+
+	if detail >= CommitReceipts {
+		for _, txHash := range block.Transactions {
+			receipt, err := m.getJsonReceipt(txHash)
+			if err != nil {
+				return nil, err
+			}
+			commit.Receipts = append(commit.Receipts, receipt)
+		}
+	}
+
+	if detail >= CommitStateDiff {
+		diffs, err := m.state.GetStateDiff(info.ParentRoot, info.Root)
+		if err != nil {
+			return nil, err
+		}
+		for _, diff := range diffs {
+			commit.StateDiff = append(commit.StateDiff, JsonAccountDiff{
+				Address:  diff.Address,
+				Deleted:  diff.Deleted,
+				Balance:  (*hexutil.Big)(diff.Balance),
+				Nonce:    hexutil.Uint64(diff.Nonce),
+				CodeHash: diff.CodeHash,
+				Storage:  diff.Storage,
+			})
+		}
+	}
+
+	return commit, nil
+}
@@ -0,0 +1,538 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+// FilterCriteria selects logs by block range, emitting contract and topics. It
+// is the filter object of the eth log subscriptions and filters.
+type FilterCriteria struct {
+	BlockHash *common.Hash
+	FromBlock *rpc.BlockNumber
+	ToBlock   *rpc.BlockNumber
+	Addresses []common.Address
+	// Topics by position. A nil position matches any topic; otherwise the log
+	// topic must be one of the listed ones.
+	Topics [][]common.Hash
+}
+
+// UnmarshalJSON implements json.Unmarshaler. The address may be a single
+// address or a list, and each topic null, a single topic or a list.
+func (c *FilterCriteria) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		BlockHash *common.Hash     `json:"blockHash"`
+		FromBlock *rpc.BlockNumber `json:"fromBlock"`
+		ToBlock   *rpc.BlockNumber `json:"toBlock"`
+		Address   interface{}      `json:"address"`
+		Topics    []interface{}    `json:"topics"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.BlockHash != nil && (raw.FromBlock != nil || raw.ToBlock != nil) {
+		return fmt.Errorf("blockHash can not be combined with fromBlock or toBlock")
+	}
+	c.BlockHash = raw.BlockHash
+	c.FromBlock = raw.FromBlock
+	c.ToBlock = raw.ToBlock
+
+	c.Addresses = nil
+	switch address := raw.Address.(type) {
+	case nil:
+	case string:
+		addr, err := decodeFilterAddress(address)
+		if err != nil {
+			return err
+		}
+		c.Addresses = []common.Address{addr}
+	case []interface{}:
+		for i, a := range address {
+			s, ok := a.(string)
+			if !ok {
+				return fmt.Errorf("invalid address at index %d", i)
+			}
+			addr, err := decodeFilterAddress(s)
+			if err != nil {
+				return fmt.Errorf("address at index %d: %s", i, err)
+			}
+			c.Addresses = append(c.Addresses, addr)
+		}
+	default:
+		return fmt.Errorf("invalid address")
+	}
+
+	c.Topics = make([][]common.Hash, len(raw.Topics))
+	for i, t := range raw.Topics {
+		switch topic := t.(type) {
+		case nil:
+		case string:
+			hash, err := decodeFilterTopic(topic)
+			if err != nil {
+				return fmt.Errorf("topic %d: %s", i, err)
+			}
+			c.Topics[i] = []common.Hash{hash}
+		case []interface{}:
+			for _, alt := range topic {
+				if alt == nil {
+					// null in a list matches any topic
+					c.Topics[i] = nil
+					break
+				}
+				s, ok := alt.(string)
+				if !ok {
+					return fmt.Errorf("invalid topic %d", i)
+				}
+				hash, err := decodeFilterTopic(s)
+				if err != nil {
+					return fmt.Errorf("topic %d: %s", i, err)
+				}
+				c.Topics[i] = append(c.Topics[i], hash)
+			}
+		default:
+			return fmt.Errorf("invalid topic %d", i)
+		}
+	}
+
+	return nil
+}
+
+func decodeFilterAddress(s string) (common.Address, error) {
+	b, err := hexutil.Decode(s)
+	if err == nil && len(b) != common.AddressLength {
+		err = fmt.Errorf("hex has invalid length %d after decoding", len(b))
+	}
+	return common.BytesToAddress(b), err
+}
+
+func decodeFilterTopic(s string) (common.Hash, error) {
+	b, err := hexutil.Decode(s)
+	if err == nil && len(b) != common.HashLength {
+		err = fmt.Errorf("hex has invalid length %d after decoding", len(b))
+	}
+	return common.BytesToHash(b), err
+}
+
+// logFilter resolves the block range of the criteria against the current head
+func (c *FilterCriteria) logFilter(m *Service) (*state.LogFilter, error) {
+	f := &state.LogFilter{
+		Addresses: c.Addresses,
+		Topics:    c.Topics,
+	}
+	if c.BlockHash != nil {
+		block, err := m.state.GetBlockByHash(*c.BlockHash)
+		if err != nil {
+			return nil, fmt.Errorf("unknown block %s", c.BlockHash.Hex())
+		}
+		f.FromBlock = int64(block.Number)
+		f.ToBlock = int64(block.Number)
+		return f, nil
+	}
+
+	head := m.state.GetBlockIndex()
+	f.FromBlock = resolveBlockNumber(c.FromBlock, head)
+	f.ToBlock = resolveBlockNumber(c.ToBlock, head)
+	return f, nil
+}
+
+// resolveBlockNumber returns the number of a block of a filter range. nil, the
+// latest and pending blocks are the head.
+func resolveBlockNumber(n *rpc.BlockNumber, head int64) int64 {
+	if n == nil || *n == rpc.LatestBlockNumber || *n == rpc.PendingBlockNumber {
+		return head
+	}
+	return n.Int64()
+}
+
+// blockLogs returns the logs emitted by the transactions of a committed block
+func (m *Service) blockLogs(number int64) ([]*types.Log, error) {
+	block, err := m.state.GetBlockByNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	logs := []*types.Log{}
+	for _, txHash := range block.Transactions {
+		receipt, err := m.state.GetReceipt(txHash)
+		if err != nil {
+			return nil, fmt.Errorf("receipt %s: %s", txHash.Hex(), err)
+		}
+		logs = append(logs, receipt.Logs...)
+	}
+	return logs, nil
+}
+
+// filterTimeout is how long a filter lives without being polled
+const filterTimeout = 5 * time.Minute
+
+type filterType int
+
+const (
+	logsFilter filterType = iota
+	blocksFilter
+	pendingTxFilter
+)
+
+// filter is an installed filter and the changes since it was last polled
+type filter struct {
+	typ      filterType
+	crit     FilterCriteria
+	match    *state.LogFilter
+	lastPoll time.Time
+
+	hashes []common.Hash
+	logs   []*types.Log
+}
+
+// PublicFilterAPI offers the standard eth log queries, polled filters and
+// eth_subscribe subscriptions
+type PublicFilterAPI struct {
+	e *Service
+
+	filtersMu sync.Mutex
+	filters   map[rpc.ID]*filter
+}
+
+// NewPublicFilterAPI creates a new filter API
+func NewPublicFilterAPI(e *Service) *PublicFilterAPI {
+	api := &PublicFilterAPI{
+		e:       e,
+		filters: make(map[rpc.ID]*filter),
+	}
+	go api.filterLoop()
+	return api
+}
+
+// filterLoop records the changes of the installed filters, and uninstalls the
+// filters which were not polled for filterTimeout
+func (api *PublicFilterAPI) filterLoop() {
+	commits := make(chan state.CommitInfo, 16)
+	commitSub := api.e.state.SubscribeCommits(commits)
+	defer commitSub.Unsubscribe()
+
+	events := make(chan state.TxPoolEvent, 256)
+	eventSub := api.e.state.SubscribeTxPoolEvents(events)
+	defer eventSub.Unsubscribe()
+
+	expiry := time.NewTicker(filterTimeout / 5)
+	defer expiry.Stop()
+
+	for {
+		select {
+		case info := <-commits:
+			if info.Block {
+				api.recordBlock(info.BlockIndex)
+			}
+		case ev := <-events:
+			if ev.Type == state.TxPending || ev.Type == state.TxPromoted {
+				api.recordPendingTx(ev.Hash)
+			}
+		case <-expiry.C:
+			api.expireFilters()
+		case <-commitSub.Err():
+			return
+		case <-eventSub.Err():
+			return
+		}
+	}
+}
+
+// recordBlock records a committed block, and its logs, for the installed
+// filters
+func (api *PublicFilterAPI) recordBlock(number int64) {
+	api.filtersMu.Lock()
+	defer api.filtersMu.Unlock()
+
+	if len(api.filters) == 0 {
+		return
+	}
+	block, err := api.e.state.GetBlockByNumber(number)
+	if err != nil {
+		api.e.logger.WithError(err).Error("Getting committed block")
+		return
+	}
+	logs, err := api.e.blockLogs(number)
+	if err != nil {
+		api.e.logger.WithError(err).Error("Getting committed block logs")
+		return
+	}
+
+	for _, f := range api.filters {
+		switch f.typ {
+		case blocksFilter:
+			f.hashes = append(f.hashes, block.Hash())
+		case logsFilter:
+			if f.crit.ToBlock != nil && *f.crit.ToBlock >= 0 && number > f.crit.ToBlock.Int64() {
+				continue
+			}
+			for _, log := range logs {
+				if f.match.Match(log) {
+					f.logs = append(f.logs, log)
+				}
+			}
+		}
+	}
+}
+
+// recordPendingTx records a transaction which became pending for the installed
+// filters
+func (api *PublicFilterAPI) recordPendingTx(hash common.Hash) {
+	api.filtersMu.Lock()
+	defer api.filtersMu.Unlock()
+
+	for _, f := range api.filters {
+		if f.typ == pendingTxFilter {
+			f.hashes = append(f.hashes, hash)
+		}
+	}
+}
+
+func (api *PublicFilterAPI) expireFilters() {
+	api.filtersMu.Lock()
+	defer api.filtersMu.Unlock()
+
+	for id, f := range api.filters {
+		if time.Since(f.lastPoll) > filterTimeout {
+			delete(api.filters, id)
+			api.e.logger.WithField("id", id).Debug("Filter expired")
+		}
+	}
+}
+
+func (api *PublicFilterAPI) installFilter(f *filter) rpc.ID {
+	api.filtersMu.Lock()
+	defer api.filtersMu.Unlock()
+
+	id := rpc.NewID()
+	f.lastPoll = time.Now()
+	api.filters[id] = f
+	return id
+}
+
+// NewFilter installs a filter of the logs of the next committed blocks
+// matching the criteria, polled with eth_getFilterChanges. Filters which are
+// not polled for 5 minutes are uninstalled.
+func (api *PublicFilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
+	if crit.BlockHash != nil {
+		return "", fmt.Errorf("blockHash is not supported by filters")
+	}
+	return api.installFilter(&filter{
+		typ:  logsFilter,
+		crit: crit,
+		match: &state.LogFilter{
+			Addresses: crit.Addresses,
+			Topics:    crit.Topics,
+		},
+	}), nil
+}
+
+// NewBlockFilter installs a filter of the hashes of the next committed blocks,
+// polled with eth_getFilterChanges
+func (api *PublicFilterAPI) NewBlockFilter() rpc.ID {
+	return api.installFilter(&filter{typ: blocksFilter})
+}
+
+// NewPendingTransactionFilter installs a filter of the hashes of the
+// transactions becoming pending in the TxPool, polled with
+// eth_getFilterChanges
+func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
+	return api.installFilter(&filter{typ: pendingTxFilter})
+}
+
+// GetFilterChanges returns the changes of a filter since it was last polled:
+// logs for a log filter, and hashes otherwise
+func (api *PublicFilterAPI) GetFilterChanges(id rpc.ID) (interface{}, error) {
+	api.filtersMu.Lock()
+	defer api.filtersMu.Unlock()
+
+	f, ok := api.filters[id]
+	if !ok {
+		return nil, fmt.Errorf("filter not found")
+	}
+	f.lastPoll = time.Now()
+
+	if f.typ == logsFilter {
+		logs := f.logs
+		f.logs = nil
+		if logs == nil {
+			logs = []*types.Log{}
+		}
+		return logs, nil
+	}
+	hashes := f.hashes
+	f.hashes = nil
+	if hashes == nil {
+		hashes = []common.Hash{}
+	}
+	return hashes, nil
+}
+
+// GetFilterLogs returns all the logs matching a log filter, like eth_getLogs
+// with the criteria of the filter
+func (api *PublicFilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*types.Log, error) {
+	api.filtersMu.Lock()
+	f, ok := api.filters[id]
+	if ok {
+		f.lastPoll = time.Now()
+	}
+	api.filtersMu.Unlock()
+
+	if !ok || f.typ != logsFilter {
+		return nil, fmt.Errorf("filter not found")
+	}
+	return api.GetLogs(ctx, f.crit)
+}
+
+// UninstallFilter removes a filter. It returns whether the filter existed.
+func (api *PublicFilterAPI) UninstallFilter(id rpc.ID) bool {
+	api.filtersMu.Lock()
+	defer api.filtersMu.Unlock()
+
+	_, ok := api.filters[id]
+	delete(api.filters, id)
+	return ok
+}
+
+// GetLogs returns the logs of the committed blocks matching the criteria. The
+// range defaults to the latest block.
+func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([]*types.Log, error) {
+	f, err := crit.logFilter(api.e)
+	if err != nil {
+		return nil, err
+	}
+	return api.e.getLogs(f)
+}
+
+// NewHeads subscribes to the headers of the committed blocks. Subscriptions
+// require a WebSocket or IPC connection.
+func (api *PublicFilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	sub := notifier.CreateSubscription()
+
+	commits := make(chan state.CommitInfo, 16)
+	commitSub := api.e.state.SubscribeCommits(commits)
+
+	go func() {
+		defer commitSub.Unsubscribe()
+
+		for {
+			select {
+			case info := <-commits:
+				if !info.Block {
+					continue
+				}
+				header, err := api.e.rpcHeader(info.BlockIndex)
+				if err != nil {
+					api.e.logger.WithError(err).Error("Getting committed block header")
+					continue
+				}
+				if err := notifier.Notify(sub.ID, header); err != nil {
+					api.e.logger.WithError(err).Debug("Notifying new head")
+				}
+			case <-sub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return sub, nil
+}
+
+// Logs subscribes to the logs of the committed blocks which match the
+// criteria. The block range of the criteria is ignored. Subscriptions require
+// a WebSocket or IPC connection.
+func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	filter := &state.LogFilter{
+		Addresses: crit.Addresses,
+		Topics:    crit.Topics,
+	}
+	sub := notifier.CreateSubscription()
+
+	commits := make(chan state.CommitInfo, 16)
+	commitSub := api.e.state.SubscribeCommits(commits)
+
+	go func() {
+		defer commitSub.Unsubscribe()
+
+		for {
+			select {
+			case info := <-commits:
+				if !info.Block || info.Txs == 0 {
+					continue
+				}
+				logs, err := api.e.blockLogs(info.BlockIndex)
+				if err != nil {
+					api.e.logger.WithError(err).Error("Getting committed block logs")
+					continue
+				}
+				for _, log := range logs {
+					if !filter.Match(log) {
+						continue
+					}
+					if err := notifier.Notify(sub.ID, log); err != nil {
+						api.e.logger.WithError(err).Debug("Notifying log")
+					}
+				}
+			case <-sub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return sub, nil
+}
+
+// NewPendingTransactions subscribes to the hashes of the transactions becoming
+// pending in the TxPool. Subscriptions require a WebSocket or IPC connection.
+func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	sub := notifier.CreateSubscription()
+
+	events := make(chan state.TxPoolEvent, 256)
+	eventSub := api.e.state.SubscribeTxPoolEvents(events)
+
+	go func() {
+		defer eventSub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				if ev.Type != state.TxPending && ev.Type != state.TxPromoted {
+					continue
+				}
+				if err := notifier.Notify(sub.ID, ev.Hash); err != nil {
+					api.e.logger.WithError(err).Debug("Notifying pending transaction")
+				}
+			case <-sub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return sub, nil
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/Fantom-foundation/go-evm/src/version"
+)
+
+// clientName prefixes the version returned by web3_clientVersion
+const clientName = "evm"
+
+// PublicNetAPI offers network related RPC methods
+type PublicNetAPI struct {
+	b *Service
+}
+
+// NewPublicNetAPI creates a new net API instance.
+func NewPublicNetAPI(b *Service) *PublicNetAPI {
+	return &PublicNetAPI{b}
+}
+
+// Listening returns an indication if the node is listening for network connections.
+func (s *PublicNetAPI) Listening() bool {
+	return true // always listening
+}
+
+// PeerCount returns the number of peers of the consensus, if it reports them
+func (s *PublicNetAPI) PeerCount() hexutil.Uint {
+	if s.b.getInfo == nil {
+		return 0
+	}
+	info, err := s.b.getInfo()
+	if err != nil {
+		return 0
+	}
+	peers, err := strconv.ParseUint(info["num_peers"], 10, 32)
+	if err != nil {
+		return 0
+	}
+	return hexutil.Uint(peers)
+}
+
+// Version returns the network id, which is the chain id, as wallets expect
+// from net_version.
+func (s *PublicNetAPI) Version() string {
+	return fmt.Sprintf("%d", s.b.chainConfig.ChainID)
+}
+
+// PublicWeb3API offers helper utils
+type PublicWeb3API struct{}
+
+// NewPublicWeb3API creates a new Web3 API instance.
+func NewPublicWeb3API() *PublicWeb3API {
+	return &PublicWeb3API{}
+}
+
+// ClientVersion returns the node name, in the format of geth
+// (evm/v0.4.0-1a2b3c4d/linux-amd64/go1.11, with the git commit of the build)
+func (s *PublicWeb3API) ClientVersion() string {
+	return fmt.Sprintf("%s/v%s/%s/%s", clientName, version.Version, version.Platform(), version.GoVersion())
+}
+
+// Sha3 applies the ethereum sha3 implementation on the input.
+// It assumes the input is hex encoded.
+func (s *PublicWeb3API) Sha3(input hexutil.Bytes) hexutil.Bytes {
+	return crypto.Keccak256(input)
+}
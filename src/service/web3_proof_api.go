@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+// PublicProofAPI provides Merkle proofs of accounts and storage slots, e.g. for
+// bridge relayers
+type PublicProofAPI struct {
+	e *Service
+}
+
+// NewPublicProofAPI creates a new proof API
+func NewPublicProofAPI(e *Service) *PublicProofAPI {
+	return &PublicProofAPI{e}
+}
+
+// GetProof returns the proof of an account and of the given storage slots in
+// the last committed state
+func (api *PublicProofAPI) GetProof(address common.Address, keys []common.Hash) (*JsonAccountProof, error) {
+	proof, err := api.e.state.GetProof(address, keys)
+	if err != nil {
+		return nil, err
+	}
+	return jsonAccountProof(proof), nil
+}
+
+// StorageProofs subscribes to the proofs of an account and of the given storage
+// slots. The current proof is sent right away, and an updated one after each
+// commit. Subscriptions require a WebSocket or IPC connection.
+func (api *PublicProofAPI) StorageProofs(ctx context.Context, address common.Address, keys []common.Hash) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	sub := notifier.CreateSubscription()
+
+	commits := make(chan state.CommitInfo, 16)
+	commitSub := api.e.state.SubscribeCommits(commits)
+
+	go func() {
+		defer commitSub.Unsubscribe()
+
+		notify := func() {
+			proof, err := api.e.state.GetProof(address, keys)
+			if err != nil {
+				api.e.logger.WithError(err).Error("Getting subscribed proof")
+				return
+			}
+			if err := notifier.Notify(sub.ID, jsonAccountProof(proof)); err != nil {
+				api.e.logger.WithError(err).Debug("Notifying proof")
+			}
+		}
+
+		notify()
+		for {
+			select {
+			case <-commits:
+				notify()
+			case <-sub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return sub, nil
+}
+
+func jsonAccountProof(proof *state.AccountProof) *JsonAccountProof {
+	res := &JsonAccountProof{
+		Address:      proof.Address,
+		BlockNumber:  hexutil.Uint64(proof.BlockIndex),
+		StateRoot:    proof.Root,
+		AccountProof: jsonProof(proof.AccountProof),
+		Balance:      (*hexutil.Big)(proof.Balance),
+		CodeHash:     proof.CodeHash,
+		Nonce:        hexutil.Uint64(proof.Nonce),
+		StorageHash:  proof.StorageHash,
+		StorageProof: make([]JsonStorageProof, len(proof.StorageProof)),
+	}
+	for i, sp := range proof.StorageProof {
+		res.StorageProof[i] = JsonStorageProof{
+			Key:   sp.Key,
+			Value: (*hexutil.Big)(sp.Value.Big()),
+			Proof: jsonProof(sp.Proof),
+		}
+	}
+	return res
+}
+
+func jsonProof(proof [][]byte) []hexutil.Bytes {
+	res := make([]hexutil.Bytes, len(proof))
+	for i, node := range proof {
+		res[i] = node
+	}
+	return res
+}
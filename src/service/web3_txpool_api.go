@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/Fantom-foundation/go-evm/src/state"
+)
+
+// PublicTxPoolFeedAPI streams the lifecycle events of the TxPool
+type PublicTxPoolFeedAPI struct {
+	e *Service
+}
+
+// NewPublicTxPoolFeedAPI creates a new TxPool feed API
+func NewPublicTxPoolFeedAPI(e *Service) *PublicTxPoolFeedAPI {
+	return &PublicTxPoolFeedAPI{e}
+}
+
+// TxPoolEvents subscribes to the TxPool events: transactions becoming pending,
+// queued, promoted, replaced or dropped. Subscriptions require a WebSocket or
+// IPC connection.
+func (api *PublicTxPoolFeedAPI) TxPoolEvents(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	sub := notifier.CreateSubscription()
+
+	events := make(chan state.TxPoolEvent, 256)
+	eventSub := api.e.state.SubscribeTxPoolEvents(events)
+
+	go func() {
+		defer eventSub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				if err := notifier.Notify(sub.ID, jsonTxPoolEvent(ev)); err != nil {
+					api.e.logger.WithError(err).Debug("Notifying txpool event")
+				}
+			case <-sub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return sub, nil
+}
+
+func jsonTxPoolEvent(ev state.TxPoolEvent) *JsonTxPoolEvent {
+	res := &JsonTxPoolEvent{
+		Type:   ev.Type,
+		Hash:   ev.Hash,
+		From:   ev.From,
+		Nonce:  hexutil.Uint64(ev.Nonce),
+		Reason: ev.Reason,
+	}
+	if ev.Type == state.TxReplaced {
+		res.ReplacedBy = &ev.ReplacedBy
+	}
+	return res
+}
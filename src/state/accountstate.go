@@ -0,0 +1,80 @@
+package state
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// AccountState is an account in the state of the last committed block, with a
+// page of its storage. Next is the key hash to continue the storage from, nil
+// after the last slot.
+type AccountState struct {
+	Block    int64
+	Root     common.Hash
+	Address  common.Address
+	Exists   bool
+	Balance  *big.Int
+	Nonce    uint64
+	CodeHash common.Hash
+	Code     []byte
+	Storage  []StorageSlot
+	Next     *common.Hash
+}
+
+// StorageSlot is a slot of the storage of an account, with its key hash
+type StorageSlot struct {
+	Hash  common.Hash
+	Key   common.Hash
+	Value common.Hash
+}
+
+//GetAccountState returns an account in the state of the last committed block,
+//with up to limit slots of its storage (none if 0) in trie order, from the
+//given key hash
+func (s *State) GetAccountState(addr common.Address, start common.Hash, limit int) (*AccountState, error) {
+	head, err := s.GetSyncHead()
+	if err != nil {
+		return nil, err
+	}
+	view, err := s.StateAt(head.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &AccountState{
+		Block:    head.Block,
+		Root:     head.Root,
+		Address:  addr,
+		Exists:   view.Exist(addr),
+		Balance:  view.GetBalance(addr),
+		Nonce:    view.GetNonce(addr),
+		CodeHash: view.GetCodeHash(addr),
+		Code:     view.GetCode(addr),
+	}
+	if limit <= 0 || !res.Exists {
+		return res, nil
+	}
+
+	storage, err := s.GetStorageRange(head.Root, addr, start, limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, slot := range storage.Slots {
+		_, value, _, err := rlp.Split(slot.Value)
+		if err != nil {
+			return nil, err
+		}
+		res.Storage = append(res.Storage, StorageSlot{
+			Hash:  slot.Hash,
+			Key:   slot.Key,
+			Value: common.BytesToHash(value),
+		})
+	}
+	if storage.More {
+		next := nextSyncKey(storage.Slots[len(storage.Slots)-1].Hash)
+		res.Next = &next
+	}
+	return res, nil
+}
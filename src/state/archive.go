@@ -0,0 +1,60 @@
+package state
+
+import (
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+// archiveDB is a read-only handle on the LevelDB database of an archive
+// data directory. Archives hold historical chain data (blocks, transactions,
+// receipts) that was split off the main database, e.g. one per year.
+type archiveDB struct {
+	path string
+	db   *leveldb.DB
+}
+
+func openArchive(path string, cache int, handles int) (*archiveDB, error) {
+	db, err := leveldb.OpenFile(path, &opt.Options{
+		OpenFilesCacheCapacity: handles,
+		BlockCacheCapacity:     cache / 2 * opt.MiB,
+		ReadOnly:               true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &archiveDB{
+		path: path,
+		db:   db,
+	}, nil
+}
+
+// Get implements the DatabaseReader interface
+func (a *archiveDB) Get(key []byte) ([]byte, error) {
+	return a.db.Get(key, nil)
+}
+
+func (a *archiveDB) Close() error {
+	return a.db.Close()
+}
+
+// federatedReader looks keys up in the main database first and falls back to
+// the archives, in the order they were configured. The error of the main
+// database is returned when no archive has the key either.
+type federatedReader struct {
+	main     DatabaseReader
+	archives []*archiveDB
+}
+
+// Get implements the DatabaseReader interface
+func (f *federatedReader) Get(key []byte) ([]byte, error) {
+	data, err := f.main.Get(key)
+	if err == nil {
+		return data, nil
+	}
+	for _, a := range f.archives {
+		if data, aerr := a.Get(key); aerr == nil {
+			return data, nil
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,95 @@
+package state
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// backpressure tracks the blocks delivered by the consensus engine and not yet
+// committed. An engine which pulls blocks faster than the State commits them
+// pauses once maxLag blocks wait, instead of queuing them in memory.
+type backpressure struct {
+	sync.Mutex
+	maxLag    int64
+	delivered int64         // last block delivered by the engine
+	committed int64         // last block committed
+	resume    chan struct{} // closed on the next block commit, for a paused engine
+}
+
+// lag returns the number of delivered blocks waiting to be committed
+func (b *backpressure) lag() int64 {
+	if b.delivered <= b.committed {
+		return 0
+	}
+	return b.delivered - b.committed
+}
+
+//SetMaxBlockLag sets how many blocks delivered by the consensus engine may wait
+//to be committed before the engine pauses pulling blocks. 0 disables
+//backpressure.
+func (s *State) SetMaxBlockLag(n int64) {
+	s.bp.Lock()
+	defer s.bp.Unlock()
+
+	s.bp.maxLag = n
+}
+
+//BlockDelivered records that the consensus engine received the block with the
+//given index, to be processed
+func (s *State) BlockDelivered(index int64) {
+	s.bp.Lock()
+	defer s.bp.Unlock()
+
+	if index > s.bp.delivered {
+		s.bp.delivered = index
+		blocksDelivered.Set(index)
+	}
+	blockLag.Set(s.bp.lag())
+}
+
+//BlockLag returns the number of blocks delivered by the consensus engine and
+//not yet committed
+func (s *State) BlockLag() int64 {
+	s.bp.Lock()
+	defer s.bp.Unlock()
+
+	return s.bp.lag()
+}
+
+//Backpressure returns nil if the consensus engine may deliver more blocks.
+//Otherwise the State is too far behind: the engine should stop pulling blocks
+//until the returned channel is closed, on the next block commit, then ask
+//again.
+func (s *State) Backpressure() <-chan struct{} {
+	s.bp.Lock()
+	defer s.bp.Unlock()
+
+	lag := s.bp.lag()
+	if s.bp.maxLag <= 0 || lag < s.bp.maxLag {
+		return nil
+	}
+	if s.bp.resume == nil {
+		s.bp.resume = make(chan struct{})
+		blockPauses.Inc()
+		s.logger.WithFields(logrus.Fields{
+			"lag":     lag,
+			"max_lag": s.bp.maxLag,
+		}).Warn("State behind consensus. Pausing block delivery")
+	}
+	return s.bp.resume
+}
+
+// blockCommitted updates the lag after a block commit, and resumes the engine
+// if it was paused
+func (s *State) blockCommitted(index int64) {
+	s.bp.Lock()
+	defer s.bp.Unlock()
+
+	s.bp.committed = index
+	blockLag.Set(s.bp.lag())
+	if s.bp.resume != nil {
+		close(s.bp.resume)
+		s.bp.resume = nil
+	}
+}
@@ -0,0 +1,265 @@
+package state
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	// blobTxType is the first byte of an encoded blob transaction. Plain
+	// transactions are RLP lists, which never start with it.
+	blobTxType = 0xb0
+
+	// MaxBlobSize is the maximum size of the data of a blob transaction
+	MaxBlobSize = 1 << 20
+
+	// BlobGasPerByte is the gas paid for each byte of blob data, on top of the
+	// gas used by the execution of the transaction
+	BlobGasPerByte = 16
+)
+
+var (
+	blobPrefix      = []byte("blob-")
+	blockBlobPrefix = "blobs"
+	blobPrunedKey   = []byte("blobs_pruned")
+
+	// BlobCommitAddress is the account whose storage maps the hash of each
+	// applied blob transaction to the hash of its data. This commits the data
+	// to the state root, while the data itself is kept out of the state.
+	BlobCommitAddress = common.HexToAddress("0x000000000000000000000000000000000000b10b")
+)
+
+// blockBlobsKey lists the blob transactions of a block, for pruning
+func blockBlobsKey(number int64) []byte {
+	return []byte(fmt.Sprintf("%s_%09d", blockBlobPrefix, number))
+}
+
+// blobTx is the envelope of a transaction carrying blob data. The last 32
+// bytes of the data of the transaction must be the keccak256 hash of the blob
+// data, so that the sender signs it and contracts can read it.
+type blobTx struct {
+	Tx   *ethTypes.Transaction
+	Data []byte
+}
+
+// EncodeBlobTx encodes a transaction with its blob data, for submission to
+// the consensus system
+func EncodeBlobTx(tx *ethTypes.Transaction, data []byte) ([]byte, error) {
+	if err := checkBlob(tx, data); err != nil {
+		return nil, err
+	}
+	enc, err := rlp.EncodeToBytes(&blobTx{Tx: tx, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{blobTxType}, enc...), nil
+}
+
+// DecodeTx decodes a transaction submitted to the consensus system, and its
+// blob data if it is a blob transaction
+func DecodeTx(txBytes []byte) (*ethTypes.Transaction, []byte, error) {
+	if len(txBytes) > 0 && txBytes[0] == blobTxType {
+		env := new(blobTx)
+		if err := rlp.DecodeBytes(txBytes[1:], env); err != nil {
+			return nil, nil, &TxApplyError{Class: ErrMalformedTx, Cause: err}
+		}
+		if err := checkBlob(env.Tx, env.Data); err != nil {
+			return nil, nil, err
+		}
+		return env.Tx, env.Data, nil
+	}
+
+	tx := new(ethTypes.Transaction)
+	if err := rlp.DecodeBytes(txBytes, tx); err != nil {
+		return nil, nil, &TxApplyError{Class: ErrMalformedTx, Cause: err}
+	}
+	return tx, nil, nil
+}
+
+// checkBlob verifies that a transaction commits to its blob data
+func checkBlob(tx *ethTypes.Transaction, data []byte) error {
+	var cause error
+	switch {
+	case len(data) == 0:
+		cause = fmt.Errorf("empty blob data")
+	case len(data) > MaxBlobSize:
+		cause = fmt.Errorf("blob data of %d bytes exceeds %d", len(data), MaxBlobSize)
+	case len(tx.Data()) < common.HashLength:
+		cause = fmt.Errorf("transaction data does not end with the blob hash")
+	default:
+		txData := tx.Data()
+		hash := common.BytesToHash(txData[len(txData)-common.HashLength:])
+		if hash != crypto.Keccak256Hash(data) {
+			cause = fmt.Errorf("blob hash %s does not match the blob data", hash.Hex())
+		}
+	}
+	if cause != nil {
+		return &TxApplyError{Hash: tx.Hash(), Class: ErrMalformedTx, Cause: cause}
+	}
+	return nil
+}
+
+func blobGas(data []byte) uint64 {
+	return uint64(len(data)) * BlobGasPerByte
+}
+
+// chargeBlobGas takes the gas of the blob data from the block gas pool and
+// burns its fee, at the gas price of msg: the fee is sent to the zero address,
+// which no key controls, rather than to the beneficiary of the block. The
+// balance of the zero address is part of the supply, so the invariant checker
+// sees the fee moved, not burned.
+func (was *WriteAheadState) chargeBlobGas(msg ethTypes.Message, gas uint64) error {
+	if msg.Gas() < gas {
+		return core.ErrIntrinsicGas
+	}
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(gas), msg.GasPrice())
+	if was.ethState.GetBalance(msg.From()).Cmp(fee) < 0 {
+		return core.ErrInsufficientFunds
+	}
+	if err := was.gp.SubGas(gas); err != nil {
+		return err
+	}
+	was.ethState.SubBalance(msg.From(), fee)
+	was.ethState.AddBalance(common.Address{}, fee)
+	return nil
+}
+
+// commitBlob records the hash of the blob data of an applied transaction in
+// the state, and keeps the data until Commit
+func (was *WriteAheadState) commitBlob(txHash common.Hash, data []byte) {
+	// The commit account must not be empty, or it would be deleted along
+	// with its storage
+	if was.ethState.GetNonce(BlobCommitAddress) == 0 {
+		was.ethState.SetNonce(BlobCommitAddress, 1)
+	}
+	was.ethState.SetState(BlobCommitAddress, txHash, crypto.Keccak256Hash(data))
+	was.blobs[txHash] = data
+}
+
+// writeBlobs writes the blob data of the applied transactions to the blob
+// store, listed by block for pruning
+func (was *WriteAheadState) writeBlobs() error {
+	if len(was.blobs) == 0 {
+		return nil
+	}
+
+	batch := was.db.NewBatch()
+	hashes := []common.Hash{}
+	for _, tx := range was.transactions {
+		data, ok := was.blobs[tx.Hash()]
+		if !ok {
+			continue
+		}
+		if err := batch.Put(append(blobPrefix, tx.Hash().Bytes()...), data); err != nil {
+			return err
+		}
+		hashes = append(hashes, tx.Hash())
+	}
+	list, err := rlp.EncodeToBytes(hashes)
+	if err != nil {
+		return err
+	}
+	if err := batch.Put(blockBlobsKey(was.blockIndex), list); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// pruneBlobs deletes the blob data of the blocks before the given one. The
+// hashes committed to the state are kept.
+func pruneBlobs(db ethdb.Database, before int64) (int, error) {
+	start := int64(0)
+	if data, err := db.Get(blobPrunedKey); err == nil {
+		start = decodeBlockIndex(data)
+	}
+	if start >= before {
+		return 0, nil
+	}
+
+	batch := db.NewBatch()
+	pruned := 0
+	for number := start; number < before; number++ {
+		list, err := db.Get(blockBlobsKey(number))
+		if err != nil {
+			continue
+		}
+		var hashes []common.Hash
+		if err := rlp.DecodeBytes(list, &hashes); err != nil {
+			return pruned, err
+		}
+		for _, hash := range hashes {
+			if err := batch.Delete(append(blobPrefix, hash.Bytes()...)); err != nil {
+				return pruned, err
+			}
+			pruned++
+		}
+		if err := batch.Delete(blockBlobsKey(number)); err != nil {
+			return pruned, err
+		}
+	}
+	if err := batch.Put(blobPrunedKey, encodeBlockIndex(before)); err != nil {
+		return pruned, err
+	}
+	return pruned, batch.Write()
+}
+
+//AddBlobTx is like AddTx for a transaction carrying blob data. The data is
+//held by the TxPool until the transaction is submitted.
+func (s *State) AddBlobTx(tx *ethTypes.Transaction, data []byte) ([]*ethTypes.Transaction, error) {
+	if err := checkBlob(tx, data); err != nil {
+		return nil, err
+	}
+	if tx.Gas() < blobGas(data) {
+		return nil, &TxApplyError{Hash: tx.Hash(), Class: ErrIntrinsicGas, Cause: core.ErrIntrinsicGas}
+	}
+	return s.txPool.AddBlobTx(tx, data)
+}
+
+//GetPoolBlob returns the blob data of a transaction waiting in the TxPool
+func (s *State) GetPoolBlob(txHash common.Hash) ([]byte, bool) {
+	return s.txPool.Blob(txHash)
+}
+
+//SetBlobRetention sets the number of blocks for which the blob data of
+//transactions is kept. Older data is pruned on Commit; the hashes committed to
+//the state are kept. 0 keeps the data forever.
+func (s *State) SetBlobRetention(blocks int64) {
+	s.commitMutex.Lock()
+	defer s.commitMutex.Unlock()
+
+	s.blobRetention = blocks
+}
+
+//GetBlob returns the blob data of an applied transaction, unless it was pruned
+func (s *State) GetBlob(txHash common.Hash) ([]byte, error) {
+	return s.reader.Get(append(blobPrefix, txHash.Bytes()...))
+}
+
+//GetBlobHash returns the hash of the blob data of an applied transaction, as
+//committed to the state, if it is a blob transaction
+func (s *State) GetBlobHash(txHash common.Hash) (common.Hash, bool) {
+	hash := s.ethState.GetState(BlobCommitAddress, txHash)
+	return hash, hash != (common.Hash{})
+}
+
+// pruneBlobs prunes the blob data which is out of the retention window
+func (s *State) pruneBlobs() {
+	if s.blobRetention <= 0 || s.blockIndex < s.blobRetention {
+		return
+	}
+	pruned, err := pruneBlobs(s.db, s.blockIndex-s.blobRetention+1)
+	if err != nil {
+		s.logger.WithError(err).Error("Pruning blob data")
+		return
+	}
+	if pruned > 0 {
+		s.logger.WithField("blobs", pruned).Debug("Pruned blob data")
+	}
+}
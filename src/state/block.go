@@ -0,0 +1,204 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/Fantom-foundation/go-lachesis/src/poset"
+)
+
+var (
+	blockHeaderPrefix = "header"
+	blockNumberPrefix = []byte("blocknumber-")
+)
+
+func blockHeaderKey(index int64) []byte {
+	return []byte(fmt.Sprintf("%s_%09d", blockHeaderPrefix, index))
+}
+
+func blockNumberKey(hash common.Hash) []byte {
+	return append(blockNumberPrefix, hash.Bytes()...)
+}
+
+// Block is the EVM view of a committed consensus block. It is written to the
+// database on every Commit that applied a block, and is chained to its parent
+// by ParentHash.
+type Block struct {
+	Number        uint64
+	ParentHash    common.Hash
+	ConsensusHash common.Hash // hash of the block in the consensus system
+	Timestamp     uint64      // consensus timestamp (unix seconds)
+	StateRoot     common.Hash
+	TxRoot        common.Hash
+	ReceiptRoot   common.Hash
+	GasUsed       uint64
+	Transactions  []common.Hash
+	Coinbase      common.Address // beneficiary credited the fees of the block
+
+	// legacy is set on the headers committed before the beneficiary was
+	// recorded, which are encoded without it so that their hash is unchanged
+	legacy bool
+}
+
+// blockRLP is the encoding of a Block. Rest holds the Coinbase, and is empty
+// for a legacy header.
+type blockRLP struct {
+	Number        uint64
+	ParentHash    common.Hash
+	ConsensusHash common.Hash
+	Timestamp     uint64
+	StateRoot     common.Hash
+	TxRoot        common.Hash
+	ReceiptRoot   common.Hash
+	GasUsed       uint64
+	Transactions  []common.Hash
+	Rest          []rlp.RawValue `rlp:"tail"`
+}
+
+// EncodeRLP implements rlp.Encoder
+func (b *Block) EncodeRLP(w io.Writer) error {
+	enc := blockRLP{
+		Number:        b.Number,
+		ParentHash:    b.ParentHash,
+		ConsensusHash: b.ConsensusHash,
+		Timestamp:     b.Timestamp,
+		StateRoot:     b.StateRoot,
+		TxRoot:        b.TxRoot,
+		ReceiptRoot:   b.ReceiptRoot,
+		GasUsed:       b.GasUsed,
+		Transactions:  b.Transactions,
+	}
+	if !b.legacy {
+		coinbase, err := rlp.EncodeToBytes(b.Coinbase)
+		if err != nil {
+			return err
+		}
+		enc.Rest = []rlp.RawValue{coinbase}
+	}
+	return rlp.Encode(w, &enc)
+}
+
+// DecodeRLP implements rlp.Decoder
+func (b *Block) DecodeRLP(s *rlp.Stream) error {
+	var dec blockRLP
+	if err := s.Decode(&dec); err != nil {
+		return err
+	}
+	*b = Block{
+		Number:        dec.Number,
+		ParentHash:    dec.ParentHash,
+		ConsensusHash: dec.ConsensusHash,
+		Timestamp:     dec.Timestamp,
+		StateRoot:     dec.StateRoot,
+		TxRoot:        dec.TxRoot,
+		ReceiptRoot:   dec.ReceiptRoot,
+		GasUsed:       dec.GasUsed,
+		Transactions:  dec.Transactions,
+		legacy:        len(dec.Rest) == 0,
+	}
+	if len(dec.Rest) > 0 {
+		return rlp.DecodeBytes(dec.Rest[0], &b.Coinbase)
+	}
+	return nil
+}
+
+// MarshalJSON keeps the layout of a legacy header, e.g. for fast sync, so the
+// peer computes the same hash
+func (b *Block) MarshalJSON() ([]byte, error) {
+	type block Block
+	return json.Marshal(&struct {
+		*block
+		Legacy bool `json:",omitempty"`
+	}{(*block)(b), b.legacy})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (b *Block) UnmarshalJSON(data []byte) error {
+	type block Block
+	dec := struct {
+		*block
+		Legacy bool `json:",omitempty"`
+	}{block: (*block)(b)}
+	if err := json.Unmarshal(data, &dec); err != nil {
+		return err
+	}
+	b.legacy = dec.Legacy
+	return nil
+}
+
+// Hash returns the keccak256 hash of the RLP encoding of the block. This is the
+// canonical hash of the commit header: the RLP list of Number, ParentHash,
+// ConsensusHash, Timestamp, StateRoot, TxRoot, ReceiptRoot, GasUsed, the list
+// of the transaction hashes and Coinbase, in this order. The headers committed
+// before the beneficiary was recorded have no Coinbase. Each header commits to
+// its parent by ParentHash, and to the consensus block by ConsensusHash.
+func (b *Block) Hash() common.Hash {
+	data, _ := rlp.EncodeToBytes(b)
+	return crypto.Keccak256Hash(data)
+}
+
+func (b *Block) Marshal() ([]byte, error) {
+	return rlp.EncodeToBytes(b)
+}
+
+func (b *Block) Unmarshal(data []byte) error {
+	return rlp.DecodeBytes(data, b)
+}
+
+// ValidatorSignature is the signature of a consensus block by a validator
+type ValidatorSignature struct {
+	Validator string // public key of the validator, as given by the consensus
+	Signature string
+}
+
+//GetCommitSignatures returns the signatures of the consensus block committed at
+//the given number, sorted by validator. The validators sign the consensus
+//block, whose hash is the ConsensusHash of the Block. It is empty when the
+//consensus system provides no signatures.
+func (s *State) GetCommitSignatures(number int64) ([]ValidatorSignature, error) {
+	block, err := s.GetBlockById(number)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []ValidatorSignature{}
+	for validator, sig := range block.GetSignatures() {
+		res = append(res, ValidatorSignature{Validator: validator, Signature: sig})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Validator < res[j].Validator })
+	return res, nil
+}
+
+// ConsensusInfo is the metadata of a block given by Lachesis, telling how final
+// it is: the round in which the block was received, the hash of the frame it
+// was decided in, and its consensus timestamp (unix seconds)
+type ConsensusInfo struct {
+	Round     int64
+	FrameHash []byte
+	Timestamp int64
+}
+
+//GetConsensusInfo returns the consensus metadata of the block with the given
+//ConsensusHash. Only the blocks committed by Lachesis have one, not those of
+//the other consensus systems.
+func (s *State) GetConsensusInfo(consensusHash common.Hash) (*ConsensusInfo, error) {
+	data, err := s.reader.Get(consensusHash.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("no consensus metadata for block %s", consensusHash.Hex())
+	}
+	block := new(poset.Block)
+	if err := block.ProtoUnmarshal(data); err != nil {
+		return nil, err
+	}
+	return &ConsensusInfo{
+		Round:     block.RoundReceived(),
+		FrameHash: block.GetFrameHash(),
+		Timestamp: block.GetCreatedTime(),
+	}, nil
+}
@@ -0,0 +1,215 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultGasLimit is the gas limit of a block, unless the genesis sets one
+const DefaultGasLimit = 1000000000000000000
+
+var genesisConfigKey = []byte("genesis-config")
+
+// GenesisConfig is the config section of a genesis file: the parameters of the
+// network. Like the fee policy, they are part of the state transition, so they
+// are shared by all the nodes with the genesis rather than set by flags. Unset
+// fields keep the defaults: the chain id of the node, DefaultGasLimit, no hard
+// fork and no minimum gas price.
+type GenesisConfig struct {
+	ChainID  uint64 `json:"chainId,omitempty"`
+	GasLimit uint64 `json:"gasLimit,omitempty"`
+
+	// blocks from which the rules of the hard forks apply
+	HomesteadBlock      *big.Int `json:"homesteadBlock,omitempty"`
+	EIP150Block         *big.Int `json:"eip150Block,omitempty"`
+	EIP155Block         *big.Int `json:"eip155Block,omitempty"`
+	EIP158Block         *big.Int `json:"eip158Block,omitempty"`
+	ByzantiumBlock      *big.Int `json:"byzantiumBlock,omitempty"`
+	ConstantinopleBlock *big.Int `json:"constantinopleBlock,omitempty"`
+
+	// minimum gas price of the transactions accepted by the pool, in wei
+	MinGasPrice *math.HexOrDecimal256 `json:"minGasPrice,omitempty"`
+}
+
+// validate checks the gas limit and that the hard forks are in order
+func (c *GenesisConfig) validate() error {
+	if c.GasLimit != 0 && c.GasLimit < params.TxGas {
+		return fmt.Errorf("gas limit %d below the gas of a transfer (%d)", c.GasLimit, params.TxGas)
+	}
+	if c.MinGasPrice != nil && (*big.Int)(c.MinGasPrice).Sign() < 0 {
+		return fmt.Errorf("negative minimum gas price")
+	}
+
+	var last struct {
+		name  string
+		block *big.Int
+	}
+	for _, fork := range c.forks() {
+		if fork.block == nil {
+			continue
+		}
+		if fork.block.Sign() < 0 {
+			return fmt.Errorf("negative %s block", fork.name)
+		}
+		if last.block != nil && fork.block.Cmp(last.block) < 0 {
+			return fmt.Errorf("%s block %s before %s block %s", fork.name, fork.block, last.name, last.block)
+		}
+		last.name, last.block = fork.name, fork.block
+	}
+	return nil
+}
+
+// forks returns the hard fork blocks, in the order they must activate
+func (c *GenesisConfig) forks() []struct {
+	name  string
+	block *big.Int
+} {
+	return []struct {
+		name  string
+		block *big.Int
+	}{
+		{"homestead", c.HomesteadBlock},
+		{"eip150", c.EIP150Block},
+		{"eip155", c.EIP155Block},
+		{"eip158", c.EIP158Block},
+		{"byzantium", c.ByzantiumBlock},
+		{"constantinople", c.ConstantinopleBlock},
+	}
+}
+
+// ChainConfig returns the chain config of the EVM with the hard forks of the
+// genesis, and the chain id of the genesis, if set, or the given one
+func (c *GenesisConfig) ChainConfig(chainID *big.Int) params.ChainConfig {
+	if c.ChainID != 0 {
+		chainID = new(big.Int).SetUint64(c.ChainID)
+	}
+	return params.ChainConfig{
+		ChainID:             chainID,
+		HomesteadBlock:      c.HomesteadBlock,
+		EIP150Block:         c.EIP150Block,
+		EIP155Block:         c.EIP155Block,
+		EIP158Block:         c.EIP158Block,
+		ByzantiumBlock:      c.ByzantiumBlock,
+		ConstantinopleBlock: c.ConstantinopleBlock,
+	}
+}
+
+// LoadGenesisConfig reads the config section of a genesis file. It returns nil,
+// i.e. the defaults, if the file or the section doesn't exist.
+func LoadGenesisConfig(genesisFile string) (*GenesisConfig, error) {
+	data, err := ioutil.ReadFile(genesisFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var genesis struct {
+		Config *GenesisConfig `json:"config"`
+	}
+	if err := json.Unmarshal(data, &genesis); err != nil {
+		return nil, fmt.Errorf("parsing %s: %s", genesisFile, err)
+	}
+	if genesis.Config == nil {
+		return nil, nil
+	}
+	if err := genesis.Config.validate(); err != nil {
+		return nil, fmt.Errorf("%s: config: %s", genesisFile, err)
+	}
+	return genesis.Config, nil
+}
+
+// putGenesisConfig records the config of the genesis the database is
+// initialized with, for InitState to apply it whenever the database is opened
+func (s *State) putGenesisConfig(c *GenesisConfig) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return s.db.Put(genesisConfigKey, data)
+}
+
+// getGenesisConfig returns the config recorded by InitGenesis, or nil
+func (s *State) getGenesisConfig() (*GenesisConfig, error) {
+	data, err := s.db.Get(genesisConfigKey)
+	if err != nil {
+		return nil, nil
+	}
+	c := new(GenesisConfig)
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("decoding the genesis config: %s", err)
+	}
+	return c, nil
+}
+
+// applyGenesisConfig sets the parameters of the network of the State. The WAS
+// and the TxPool pick them up when they are created by InitState.
+func (s *State) applyGenesisConfig(c *GenesisConfig) {
+	s.chainConfig = c.ChainConfig(s.chainConfig.ChainID)
+	s.signer = ethTypes.NewEIP155Signer(s.chainConfig.ChainID)
+	s.gasLimit = DefaultGasLimit
+	if c.GasLimit != 0 {
+		s.gasLimit = c.GasLimit
+	}
+	s.minGasPrice = nil
+	if c.MinGasPrice != nil {
+		s.minGasPrice = new(big.Int).Set((*big.Int)(c.MinGasPrice))
+	}
+	s.genesisConfig = c
+}
+
+//SetGenesisConfig sets the parameters of the network from the config section
+//of the genesis file. InitState applies the config recorded by InitGenesis; this
+//is for the databases initialized otherwise, e.g. by the Service on the first
+//start. It must be called before the State applies any block: all validators
+//must use the same parameters.
+func (s *State) SetGenesisConfig(c *GenesisConfig) {
+	s.commitMutex.Lock()
+	defer s.commitMutex.Unlock()
+
+	s.applyGenesisConfig(c)
+
+	s.was.signer = s.signer
+	s.was.chainConfig = s.chainConfig
+	s.was.gasLimit = s.gasLimit
+	s.was.gp = new(core.GasPool).AddGas(s.gasLimit)
+	s.txPool.setNetwork(s.chainConfig, s.gasLimit, s.minGasPrice)
+
+	s.logger.WithFields(logrus.Fields{
+		"chain_id":      s.chainConfig.ChainID,
+		"gas_limit":     s.gasLimit,
+		"min_gas_price": s.minGasPrice,
+		"fork":          ForkName(&s.chainConfig, big.NewInt(s.blockIndex+1)),
+	}).Debug("Genesis config set")
+}
+
+//GenesisConfig returns the parameters of the network set by the genesis, or nil
+//if the genesis has no config section
+func (s *State) GenesisConfig() *GenesisConfig {
+	s.commitMutex.Lock()
+	defer s.commitMutex.Unlock()
+
+	return s.genesisConfig
+}
+
+//MinGasPrice returns the minimum gas price of the transactions accepted by the
+//pool, or nil if there is none
+func (s *State) MinGasPrice() *big.Int {
+	s.commitMutex.Lock()
+	defer s.commitMutex.Unlock()
+
+	if s.minGasPrice == nil {
+		return nil
+	}
+	return new(big.Int).Set(s.minGasPrice)
+}
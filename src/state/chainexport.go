@@ -0,0 +1,100 @@
+package state
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ChainRecord is a block of a chain export: its header, its transactions
+// encoded as they were submitted to the consensus system, and their receipts
+type ChainRecord struct {
+	Block    *Block
+	Txs      []hexutil.Bytes
+	Receipts []*ethTypes.ReceiptForStorage
+}
+
+//ExportChain writes the committed blocks from..to to w, as a stream of RLP
+//encoded ChainRecords, and returns the number of blocks written. A negative
+//from is the first committed block, a negative to the last one.
+func (s *State) ExportChain(w io.Writer, from, to int64) (int, error) {
+	if from < 0 {
+		from = 0
+		if _, err := s.GetBlockByNumber(0); err != nil {
+			from = 1
+		}
+	}
+	if head := s.GetBlockIndex(); to < 0 || to > head {
+		to = head
+	}
+
+	written := 0
+	for number := from; number <= to; {
+		blocks, err := s.GetSyncBlocks(number, int(to-number+1))
+		if err != nil {
+			return written, err
+		}
+		if len(blocks) == 0 {
+			return written, fmt.Errorf("block %d not found", number)
+		}
+		for _, b := range blocks {
+			record := ChainRecord{
+				Block:    b.Block,
+				Txs:      b.Txs,
+				Receipts: make([]*ethTypes.ReceiptForStorage, len(b.Block.Transactions)),
+			}
+			for i, hash := range b.Block.Transactions {
+				receipt, err := s.GetReceipt(hash)
+				if err != nil {
+					return written, fmt.Errorf("receipt of transaction %s: %s", hash.Hex(), err)
+				}
+				record.Receipts[i] = (*ethTypes.ReceiptForStorage)(receipt)
+			}
+			if err := rlp.Encode(w, &record); err != nil {
+				return written, err
+			}
+			written++
+		}
+		number += int64(len(blocks))
+	}
+	return written, nil
+}
+
+//ImportChain replays the blocks of a chain export read from r. The database
+//must hold the state the first block was applied to, e.g. the genesis of a
+//fresh node; blocks already applied are skipped. Each replayed block must
+//result in the exported header, so the state, the transactions and the
+//receipts are the same as on the exporting node. progress, if not nil, is
+//called after each block. It returns the number of blocks read.
+func (s *State) ImportChain(r io.Reader, progress func(*Block)) (int, error) {
+	stream := rlp.NewStream(r, 0)
+	read := 0
+	for {
+		var record ChainRecord
+		if err := stream.Decode(&record); err == io.EOF {
+			return read, nil
+		} else if err != nil {
+			return read, fmt.Errorf("reading block %d of the export: %s", read, err)
+		}
+		block := record.Block
+
+		receipts := make(ethTypes.Receipts, len(record.Receipts))
+		for i, receipt := range record.Receipts {
+			receipts[i] = (*ethTypes.Receipt)(receipt)
+		}
+		if ethTypes.DeriveSha(receipts) != block.ReceiptRoot {
+			return read, fmt.Errorf("receipts of block %d do not match its receipt root", block.Number)
+		}
+
+		if _, err := s.ReplayBlock(&SyncBlock{Block: block, Txs: record.Txs}); err != nil {
+			return read, err
+		}
+		read++
+		if progress != nil {
+			progress(block)
+		}
+	}
+}
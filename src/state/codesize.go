@@ -0,0 +1,103 @@
+package state
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethState "github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/sirupsen/logrus"
+)
+
+// Default code size limits, from EIP-170 and EIP-3860
+const (
+	DefaultMaxCodeSize     = params.MaxCodeSize
+	DefaultMaxInitCodeSize = 2 * params.MaxCodeSize
+)
+
+// CodeSizeLimits limits the size of the contracts deployed by transactions.
+// The limits are part of the state transition: all the validators must use the
+// same limits. 0 disables a limit.
+//
+// A contract creation whose init code exceeds MaxInitCodeSize is rejected. One
+// deploying more than MaxCodeSize bytes of code fails and uses all its gas, as
+// in EIP-170. Contracts created by other contracts are not limited.
+type CodeSizeLimits struct {
+	MaxCodeSize     int
+	MaxInitCodeSize int
+}
+
+// DefaultCodeSizeLimits returns the EIP-170 and EIP-3860 limits
+func DefaultCodeSizeLimits() CodeSizeLimits {
+	return CodeSizeLimits{
+		MaxCodeSize:     DefaultMaxCodeSize,
+		MaxInitCodeSize: DefaultMaxInitCodeSize,
+	}
+}
+
+// checkInitCode rejects a contract creation whose init code is too large
+func (l CodeSizeLimits) checkInitCode(tx *ethTypes.Transaction) error {
+	if tx.To() != nil || l.MaxInitCodeSize <= 0 || len(tx.Data()) <= l.MaxInitCodeSize {
+		return nil
+	}
+	return &TxApplyError{
+		Hash:  tx.Hash(),
+		Class: ErrMaxInitCodeSize,
+		Cause: fmt.Errorf("%s: %d bytes, limit %d", ErrMaxInitCodeSize, len(tx.Data()), l.MaxInitCodeSize),
+	}
+}
+
+// codeTooLarge returns the size of the code deployed by a successful contract
+// creation, if it exceeds the limit
+func (l CodeSizeLimits) codeTooLarge(st *ethState.StateDB, msg ethTypes.Message) (int, bool) {
+	if msg.To() != nil || l.MaxCodeSize <= 0 {
+		return 0, false
+	}
+	size := st.GetCodeSize(crypto.CreateAddress(msg.From(), msg.Nonce()))
+	return size, size > l.MaxCodeSize
+}
+
+// codeSizeError is the error of a contract creation deploying too much code
+func (l CodeSizeLimits) codeSizeError(tx *ethTypes.Transaction, size int) error {
+	return &TxApplyError{
+		Hash:  tx.Hash(),
+		Class: ErrMaxCodeSize,
+		Cause: fmt.Errorf("%s: %d bytes, limit %d", ErrMaxCodeSize, size, l.MaxCodeSize),
+	}
+}
+
+// failCreate turns a contract creation which deployed too much code into a
+// failed transaction using all its gas: the execution since snapshot is
+// reverted, and the sender only pays the gas and increments its nonce. gas is
+// the gas used by the execution, already taken from gp.
+func (was *WriteAheadState) failCreate(msg ethTypes.Message, coinbase common.Address, snapshot int, gas uint64) {
+	was.ethState.RevertToSnapshot(snapshot)
+
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(msg.Gas()), msg.GasPrice())
+	was.ethState.SubBalance(msg.From(), fee)
+	was.ethState.AddBalance(coinbase, fee)
+	was.ethState.SetNonce(msg.From(), msg.Nonce()+1)
+
+	// gp had room for the whole gas limit before the execution
+	if err := was.gp.SubGas(msg.Gas() - gas); err != nil {
+		was.logger.WithError(err).Error("Charging gas of oversized contract creation")
+	}
+}
+
+//SetCodeSizeLimits sets the maximum size of the code, and of the init code, of
+//the contracts deployed by transactions
+func (s *State) SetCodeSizeLimits(limits CodeSizeLimits) {
+	s.commitMutex.Lock()
+	defer s.commitMutex.Unlock()
+
+	s.codeLimits = limits
+	s.was.codeLimits = limits
+	s.txPool.SetCodeSizeLimits(limits)
+	s.logger.WithFields(logrus.Fields{
+		"max_code_size":      limits.MaxCodeSize,
+		"max_init_code_size": limits.MaxInitCodeSize,
+	}).Debug("Code size limits set")
+}
@@ -0,0 +1,179 @@
+package state
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// compactRanges is the number of key ranges CompactDB compacts one after the
+// other: one per first byte of the keys
+const compactRanges = 256
+
+// openDB opens the LevelDB database of a stopped node for maintenance
+func openDB(dbFile string, cache int) (*ethdb.LDBDatabase, error) {
+	handles, err := getFdLimit()
+	if err != nil {
+		return nil, err
+	}
+	return ethdb.NewLDBDatabase(dbFile, cache, handles)
+}
+
+// CompactDB compacts the LevelDB database of a node, which must be stopped, to
+// drop the deleted and overwritten entries (tombstones) slowing down reads. The
+// keys are compacted one range at a time; progress, if not nil, is called after
+// each range.
+func CompactDB(dbFile string, cache int, progress func(done, total int)) error {
+	db, err := openDB(dbFile, cache)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for i := 0; i < compactRanges; i++ {
+		r := util.Range{Start: []byte{byte(i)}}
+		if i < compactRanges-1 {
+			r.Limit = []byte{byte(i + 1)}
+		}
+		if err := db.LDB().CompactRange(r); err != nil {
+			return err
+		}
+		if progress != nil {
+			progress(i+1, compactRanges)
+		}
+	}
+	return nil
+}
+
+// DBSize returns the size on disk of a database directory, in bytes
+func DBSize(dbFile string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dbFile, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// DBFamilyStats is the number of entries of a family of keys of the database,
+// and their size (keys and values), in bytes
+type DBFamilyStats struct {
+	Family  string
+	Entries int64
+	Size    int64
+}
+
+// keyFamilies are the families of the prefixed keys, checked in order
+var keyFamilies = []struct {
+	prefix []byte
+	family string
+}{
+	{receiptsPrefix, "receipts"},
+	{errorPrefix, "failed transactions"},
+	{revertPrefix, "revert data"},
+	{gasCappedPrefix, "gas caps"},
+	{appliedPrefix, "applied blocks"},
+	{blockNumberPrefix, "block headers"},
+	{[]byte(blockHeaderPrefix + "_"), "block headers"},
+	{[]byte(blockHashPrefix + "_"), "block headers"},
+	{[]byte(blockPrefix + "_"), "consensus blocks"},
+	{blobPrefix, "blobs"},
+	{[]byte(blockBlobPrefix), "blobs"},
+	{accountTxPrefix, "indexes"},
+	{topicLogPrefix, "indexes"},
+	{addressLogPrefix, "indexes"},
+	{[]byte(blockBloomPrefix + "_"), "indexes"},
+	{[]byte(indexMarkerPrefix + "_"), "indexes"},
+	{deadLetterPrefix, "dead letters"},
+	{[]byte("secure-key-"), "trie preimages"},
+	{schemaVersionKey, "metadata"},
+	{genesisKey, "metadata"},
+}
+
+// keyFamily returns the family of an entry of the database. The trie nodes,
+// the code and the transactions are all stored under the hash of their
+// content, and are told apart by the shape of the content.
+func keyFamily(key, value []byte) string {
+	if len(key) == common.HashLength+len(txMetaSuffix) && bytes.HasSuffix(key, txMetaSuffix) {
+		return "tx metadata"
+	}
+	if len(key) == common.HashLength {
+		if !bytes.Equal(crypto.Keccak256(value), key) {
+			// Consensus blocks are also stored by their hash
+			return "consensus blocks"
+		}
+		content, _, err := rlp.SplitList(value)
+		if err != nil {
+			return "code"
+		}
+		switch n, err := rlp.CountValues(content); {
+		case err != nil:
+			return "code"
+		case n == 2 || n == 17:
+			return "trie nodes"
+		case n == 9:
+			return "transactions"
+		default:
+			return "code"
+		}
+	}
+	for _, f := range keyFamilies {
+		if bytes.HasPrefix(key, f.prefix) {
+			return f.family
+		}
+	}
+	return "other"
+}
+
+// InspectDB walks the LevelDB database of a node, which must be stopped, and
+// returns the number and size of its entries per family of keys, largest
+// first. progress, if not nil, is called every million entries.
+func InspectDB(dbFile string, cache int, progress func(entries int64)) ([]DBFamilyStats, error) {
+	db, err := openDB(dbFile, cache)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	families := make(map[string]*DBFamilyStats)
+	var entries int64
+	it := db.NewIterator()
+	defer it.Release()
+	for it.Next() {
+		family := keyFamily(it.Key(), it.Value())
+		stats, ok := families[family]
+		if !ok {
+			stats = &DBFamilyStats{Family: family}
+			families[family] = stats
+		}
+		stats.Entries++
+		stats.Size += int64(len(it.Key()) + len(it.Value()))
+
+		entries++
+		if progress != nil && entries%1000000 == 0 {
+			progress(entries)
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+
+	res := make([]DBFamilyStats, 0, len(families))
+	for _, stats := range families {
+		res = append(res, *stats)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Size > res[j].Size })
+	return res, nil
+}
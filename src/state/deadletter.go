@@ -0,0 +1,120 @@
+package state
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	deadLetterPrefix = []byte("dlq-")
+	deadLetterSeqKey = []byte("dlq_seq")
+)
+
+func deadLetterKey(id uint64) []byte {
+	return appendUint64(append([]byte{}, deadLetterPrefix...), id)
+}
+
+// DeadLetter is a transaction which could not be submitted to the consensus
+// system, kept until an operator retries or drops it. It is node-local data,
+// not part of the state.
+type DeadLetter struct {
+	ID       uint64
+	Tx       []byte // as submitted to the consensus system
+	Hash     common.Hash
+	Error    string // error of the last attempt
+	Attempts uint64
+	Time     uint64 // unix time it was added
+}
+
+//AddDeadLetter persists a transaction which failed to reach the consensus
+//system after the given number of attempts
+func (s *State) AddDeadLetter(tx []byte, attempts int, cause error) (*DeadLetter, error) {
+	s.deadLetterMutex.Lock()
+	defer s.deadLetterMutex.Unlock()
+
+	id := uint64(1)
+	if data, err := s.db.Get(deadLetterSeqKey); err == nil {
+		id = uint64(decodeBlockIndex(data)) + 1
+	}
+
+	letter := &DeadLetter{
+		ID:       id,
+		Tx:       tx,
+		Error:    cause.Error(),
+		Attempts: uint64(attempts),
+		Time:     uint64(time.Now().Unix()),
+	}
+	if t, _, err := DecodeTx(tx); err == nil {
+		letter.Hash = t.Hash()
+	}
+	data, err := rlp.EncodeToBytes(letter)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := s.db.NewBatch()
+	if err := batch.Put(deadLetterKey(id), data); err != nil {
+		return nil, err
+	}
+	if err := batch.Put(deadLetterSeqKey, encodeBlockIndex(int64(id))); err != nil {
+		return nil, err
+	}
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"id":       id,
+		"hash":     letter.Hash.Hex(),
+		"attempts": attempts,
+	}).WithError(cause).Error("Transaction moved to the dead-letter queue")
+	return letter, nil
+}
+
+//GetDeadLetters returns the transactions of the dead-letter queue, oldest
+//first
+func (s *State) GetDeadLetters() ([]*DeadLetter, error) {
+	s.deadLetterMutex.Lock()
+	defer s.deadLetterMutex.Unlock()
+
+	ldb, ok := s.ldb()
+	if !ok {
+		return nil, fmt.Errorf("the dead-letter queue requires a LevelDB database")
+	}
+	it := ldb.NewIteratorWithPrefix(deadLetterPrefix)
+	defer it.Release()
+
+	letters := []*DeadLetter{}
+	for it.Next() {
+		letter := new(DeadLetter)
+		if err := rlp.DecodeBytes(it.Value(), letter); err != nil {
+			return nil, err
+		}
+		letters = append(letters, letter)
+	}
+	return letters, it.Error()
+}
+
+//RemoveDeadLetter removes a transaction from the dead-letter queue and returns
+//it, e.g. to retry it
+func (s *State) RemoveDeadLetter(id uint64) (*DeadLetter, error) {
+	s.deadLetterMutex.Lock()
+	defer s.deadLetterMutex.Unlock()
+
+	data, err := s.db.Get(deadLetterKey(id))
+	if err != nil {
+		return nil, fmt.Errorf("no dead letter %d", id)
+	}
+	letter := new(DeadLetter)
+	if err := rlp.DecodeBytes(data, letter); err != nil {
+		return nil, err
+	}
+	if err := s.db.Delete(deadLetterKey(id)); err != nil {
+		return nil, err
+	}
+	return letter, nil
+}
@@ -0,0 +1,151 @@
+package state
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethState "github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// AccountDiff is the new value of an account changed between two state roots.
+// Storage holds the changed slots; cleared slots are zero.
+type AccountDiff struct {
+	Address  common.Address
+	Deleted  bool
+	Balance  *big.Int
+	Nonce    uint64
+	CodeHash common.Hash
+	Storage  map[common.Hash]common.Hash
+}
+
+//GetStateDiff returns the accounts changed between two committed state roots,
+//e.g. the ParentRoot and Root of a CommitInfo. The changes are found by
+//comparing the tries, so both roots must still be in the database.
+func (s *State) GetStateDiff(parentRoot, root common.Hash) ([]AccountDiff, error) {
+	db := s.ethState.Database()
+	oldTrie, err := db.OpenTrie(parentRoot)
+	if err != nil {
+		return nil, err
+	}
+	newTrie, err := db.OpenTrie(root)
+	if err != nil {
+		return nil, err
+	}
+
+	diffs := []AccountDiff{}
+	changed := make(map[common.Address]bool)
+
+	// Created or updated accounts
+	it, _ := trie.NewDifferenceIterator(oldTrie.NodeIterator(nil), newTrie.NodeIterator(nil))
+	for it.Next(true) {
+		if !it.Leaf() {
+			continue
+		}
+		preimage := newTrie.GetKey(it.LeafKey())
+		if preimage == nil {
+			return nil, fmt.Errorf("no preimage for account %x", it.LeafKey())
+		}
+		addr := common.BytesToAddress(preimage)
+
+		var account ethState.Account
+		if err := rlp.DecodeBytes(it.LeafBlob(), &account); err != nil {
+			return nil, err
+		}
+
+		oldStorageRoot := ethTypes.EmptyRootHash
+		if blob, err := oldTrie.TryGet(addr.Bytes()); err == nil && len(blob) > 0 {
+			var old ethState.Account
+			if err := rlp.DecodeBytes(blob, &old); err != nil {
+				return nil, err
+			}
+			oldStorageRoot = old.Root
+		}
+		storage, err := storageDiff(db, common.BytesToHash(it.LeafKey()), oldStorageRoot, account.Root)
+		if err != nil {
+			return nil, fmt.Errorf("storage of %s: %s", addr.Hex(), err)
+		}
+
+		changed[addr] = true
+		diffs = append(diffs, AccountDiff{
+			Address:  addr,
+			Balance:  account.Balance,
+			Nonce:    account.Nonce,
+			CodeHash: common.BytesToHash(account.CodeHash),
+			Storage:  storage,
+		})
+	}
+	if it.Error() != nil {
+		return nil, it.Error()
+	}
+
+	// Deleted accounts
+	it, _ = trie.NewDifferenceIterator(newTrie.NodeIterator(nil), oldTrie.NodeIterator(nil))
+	for it.Next(true) {
+		if !it.Leaf() {
+			continue
+		}
+		addr := common.BytesToAddress(oldTrie.GetKey(it.LeafKey()))
+		if changed[addr] {
+			continue
+		}
+		if blob, err := newTrie.TryGet(addr.Bytes()); err == nil && len(blob) == 0 {
+			diffs = append(diffs, AccountDiff{Address: addr, Deleted: true})
+		}
+	}
+
+	return diffs, it.Error()
+}
+
+// storageDiff returns the slots changed between two storage roots of an
+// account
+func storageDiff(db ethState.Database, addrHash, oldRoot, newRoot common.Hash) (map[common.Hash]common.Hash, error) {
+	changes := make(map[common.Hash]common.Hash)
+	if oldRoot == newRoot {
+		return changes, nil
+	}
+
+	oldTrie, err := db.OpenStorageTrie(addrHash, oldRoot)
+	if err != nil {
+		return nil, err
+	}
+	newTrie, err := db.OpenStorageTrie(addrHash, newRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	it, _ := trie.NewDifferenceIterator(oldTrie.NodeIterator(nil), newTrie.NodeIterator(nil))
+	for it.Next(true) {
+		if !it.Leaf() {
+			continue
+		}
+		_, content, _, err := rlp.Split(it.LeafBlob())
+		if err != nil {
+			return nil, err
+		}
+		changes[common.BytesToHash(newTrie.GetKey(it.LeafKey()))] = common.BytesToHash(content)
+	}
+	if it.Error() != nil {
+		return nil, it.Error()
+	}
+
+	// Slots missing from the new trie were cleared
+	it, _ = trie.NewDifferenceIterator(newTrie.NodeIterator(nil), oldTrie.NodeIterator(nil))
+	for it.Next(true) {
+		if !it.Leaf() {
+			continue
+		}
+		key := common.BytesToHash(oldTrie.GetKey(it.LeafKey()))
+		if _, ok := changes[key]; ok {
+			continue
+		}
+		if blob, err := newTrie.TryGet(key.Bytes()); err == nil && len(blob) == 0 {
+			changes[key] = common.Hash{}
+		}
+	}
+
+	return changes, it.Error()
+}
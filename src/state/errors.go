@@ -0,0 +1,85 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// Classes of the errors returned when a transaction can not be applied, by the
+// TxPool or the WAS. The returned errors are *TxApplyError; use ErrorClass to
+// get their class.
+var (
+	ErrMalformedTx       = errors.New("malformed transaction")
+	ErrInvalidSig        = errors.New("invalid transaction signature")
+	ErrNonceTooLow       = errors.New("nonce too low")
+	ErrNonceTooHigh      = errors.New("nonce too high")
+	ErrInsufficientFunds = errors.New("insufficient funds for gas * price + value")
+	ErrIntrinsicGas      = errors.New("intrinsic gas too low")
+	ErrGasLimitReached   = errors.New("block gas limit reached")
+	ErrMaxInitCodeSize   = errors.New("max init code size exceeded")
+	ErrMaxCodeSize       = errors.New("max code size exceeded")
+	ErrUnderpriced       = errors.New("gas price below the minimum")
+)
+
+// TxApplyError is the error of a transaction which could not be applied
+type TxApplyError struct {
+	Hash  common.Hash
+	Class error // one of the Err* classes above, or the cause if unknown
+	Cause error
+}
+
+func (e *TxApplyError) Error() string {
+	if e.Class == e.Cause {
+		return e.Cause.Error()
+	}
+	return fmt.Sprintf("%s: %s", e.Class, e.Cause)
+}
+
+// ErrorClass returns the class of an error returned by the State, or the error
+// itself if it has no class
+func ErrorClass(err error) error {
+	if e, ok := err.(*TxApplyError); ok {
+		return e.Class
+	}
+	return err
+}
+
+// newTxApplyError classifies the error returned by go-ethereum when applying
+// tx
+func newTxApplyError(tx *ethTypes.Transaction, cause error) *TxApplyError {
+	class := cause
+	switch cause {
+	case core.ErrNonceTooLow:
+		class = ErrNonceTooLow
+	case core.ErrNonceTooHigh:
+		class = ErrNonceTooHigh
+	case core.ErrInsufficientFunds:
+		class = ErrInsufficientFunds
+	case core.ErrIntrinsicGas:
+		class = ErrIntrinsicGas
+	case core.ErrGasLimitReached:
+		class = ErrGasLimitReached
+	case ethTypes.ErrInvalidSig, ethTypes.ErrInvalidChainId:
+		class = ErrInvalidSig
+	default:
+		// The state transition doesn't export this one
+		if cause.Error() == "insufficient balance to pay for gas" {
+			class = ErrInsufficientFunds
+		}
+	}
+
+	e := &TxApplyError{Class: class, Cause: cause}
+	if tx != nil {
+		e.Hash = tx.Hash()
+	}
+	return e
+}
+
+// newSigError wraps the error of the recovery of the sender of tx
+func newSigError(tx *ethTypes.Transaction, cause error) *TxApplyError {
+	return &TxApplyError{Hash: tx.Hash(), Class: ErrInvalidSig, Cause: cause}
+}
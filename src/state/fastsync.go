@@ -0,0 +1,138 @@
+package state
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/sirupsen/logrus"
+)
+
+// MaxSyncBlocks is the maximum number of blocks served at once to a node
+// replaying them
+const MaxSyncBlocks = 100
+
+// SyncBlock is a committed block with its applied transactions, encoded as
+// they were submitted to the consensus system, for a node to replay it
+type SyncBlock struct {
+	Block *Block          `json:"block"`
+	Txs   []hexutil.Bytes `json:"txs"`
+}
+
+// nextBlockNumber returns the number of the block following the last committed
+// one, 0 if none was committed
+func (s *State) nextBlockNumber() int64 {
+	if has, err := s.db.Has(headBlockKey); err != nil || !has {
+		return 0
+	}
+	return s.blockIndex + 1
+}
+
+//NextBlockNumber returns the number of the block the next Commit records, 0
+//if none was committed
+func (s *State) NextBlockNumber() int64 {
+	s.commitMutex.Lock()
+	defer s.commitMutex.Unlock()
+
+	return s.nextBlockNumber()
+}
+
+//GetSyncBlocks returns up to count committed blocks from the given number,
+//with their transactions, stopping at the last committed block or at the
+//first block which isn't recorded
+func (s *State) GetSyncBlocks(from int64, count int) ([]SyncBlock, error) {
+	if count < 1 || count > MaxSyncBlocks {
+		count = MaxSyncBlocks
+	}
+	head := s.GetBlockIndex()
+
+	blocks := []SyncBlock{}
+	for number := from; number <= head && len(blocks) < count; number++ {
+		block, err := s.GetBlockByNumber(number)
+		if err != nil {
+			break
+		}
+		b := SyncBlock{Block: block, Txs: make([]hexutil.Bytes, len(block.Transactions))}
+		for i, hash := range block.Transactions {
+			tx, blob, err := s.storedTx(hash)
+			if err != nil {
+				return nil, fmt.Errorf("block %d: %s", number, err)
+			}
+			if blob != nil {
+				b.Txs[i], err = EncodeBlobTx(tx, blob)
+			} else {
+				b.Txs[i], err = rlp.EncodeToBytes(tx)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks, nil
+}
+
+//ReplayBlock applies a block committed by another node, which must be the
+//next block of the State, e.g. to catch up after importing a snapshot. The
+//block must result in the same header, state root included, as on that node.
+func (s *State) ReplayBlock(b *SyncBlock) (root common.Hash, err error) {
+	s.commitMutex.Lock()
+	defer s.commitMutex.Unlock()
+
+	if s.halted != nil {
+		return common.Hash{}, s.halted
+	}
+	block := b.Block
+	if root, ok := s.AppliedBlock(block.ConsensusHash); ok {
+		return root, nil
+	}
+	if next := s.nextBlockNumber(); int64(block.Number) != next {
+		return common.Hash{}, fmt.Errorf("block %d is not the next block %d", block.Number, next)
+	}
+	if len(b.Txs) != len(block.Transactions) {
+		return common.Hash{}, fmt.Errorf("block %d has %d transactions, %d given",
+			block.Number, len(block.Transactions), len(b.Txs))
+	}
+
+	enterCommitPath()
+	defer s.exitCommitPath()
+
+	ctx := context.Background()
+	s.was.blockIndex = int64(block.Number)
+	s.was.startRecordedBlock(block, s.blockTimestamp(int64(block.Timestamp)))
+	for i, txBytes := range b.Txs {
+		// The transactions of the block were all applied on the other node, so
+		// one which fails here means the states diverge
+		if err := s.applyTransaction(ctx, txBytes, i, block.ConsensusHash); err != nil {
+			err = fmt.Errorf("replayed block %d diverges: transaction %d: %s", block.Number, i, err)
+			s.logger.WithError(err).Error("Replaying block. Halting")
+			s.halted = err
+			return common.Hash{}, err
+		}
+	}
+
+	root, err = s.Commit(ctx)
+	if err != nil {
+		return root, err
+	}
+	replayed, err := s.GetBlockByNumber(int64(block.Number))
+	if err != nil {
+		return root, err
+	}
+	if replayed.Hash() != block.Hash() {
+		err = fmt.Errorf("replayed block %d diverges: root %s, expected %s",
+			block.Number, root.Hex(), block.StateRoot.Hex())
+		s.logger.WithError(err).Error("Replaying block. Halting")
+		s.halted = err
+		return root, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"block": block.Number,
+		"txs":   len(b.Txs),
+		"root":  root.Hex(),
+	}).Debug("Replayed block")
+	return root, nil
+}
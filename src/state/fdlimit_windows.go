@@ -0,0 +1,7 @@
+package state
+
+// getFdLimit returns the number of file handles LevelDB may use. Windows has
+// no per-process limit to read; this is the one of the C runtime.
+func getFdLimit() (int, error) {
+	return 16384, nil
+}
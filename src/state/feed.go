@@ -0,0 +1,31 @@
+package state
+
+import (
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// commitBacklog is the number of commits waiting to be dispatched to the
+// subscribers
+const commitBacklog = 64
+
+//SubscribeCommits registers a channel receiving a CommitInfo after each
+//Commit. Slow subscribers delay the other subscribers but never the State.
+func (s *State) SubscribeCommits(ch chan<- CommitInfo) event.Subscription {
+	return s.commitFeed.Subscribe(ch)
+}
+
+// publishCommit queues a commit for the subscribers without blocking
+func (s *State) publishCommit(info CommitInfo) {
+	select {
+	case s.commits <- info:
+	default:
+		s.logger.WithField("block_index", info.BlockIndex).Warn("Commit feed backlog full. Dropping event")
+	}
+}
+
+// dispatchCommits sends the queued commits to the subscribers, in order
+func (s *State) dispatchCommits() {
+	for info := range s.commits {
+		s.commitFeed.Send(info)
+	}
+}
@@ -0,0 +1,133 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+)
+
+// Fee policy types
+const (
+	// FeeCoinbase leaves the fees to the beneficiary of the block
+	FeeCoinbase = "coinbase"
+	// FeeBurn destroys the fees
+	FeeBurn = "burn"
+	// FeeTreasury sends the fees to a treasury address
+	FeeTreasury = "treasury"
+	// FeeValidators splits the fees equally among a validator set
+	FeeValidators = "validators"
+)
+
+// FeePolicy decides where the transaction fees of a block go. The EVM credits
+// the fees to the beneficiary of the block as transactions are applied; on
+// Commit, the policy moves them from the beneficiary to their destination. The
+// policy is part of the state transition: all the validators must run the same
+// one, which is why it is set in the genesis file.
+type FeePolicy struct {
+	Type string `json:"type"`
+	// receiver of the fees of the treasury policy
+	Treasury common.Address `json:"treasury"`
+	// receivers of the fees of the validators policy. The remainder of the
+	// split goes to the first one.
+	Validators []common.Address `json:"validators"`
+}
+
+// validate checks that the policy has the addresses its type requires
+func (p *FeePolicy) validate() error {
+	switch p.Type {
+	case FeeCoinbase, FeeBurn:
+	case FeeTreasury:
+		if p.Treasury == (common.Address{}) {
+			return fmt.Errorf("treasury fee policy without a treasury address")
+		}
+	case FeeValidators:
+		if len(p.Validators) == 0 {
+			return fmt.Errorf("validators fee policy without validators")
+		}
+	default:
+		return fmt.Errorf("unknown fee policy %q", p.Type)
+	}
+	return nil
+}
+
+// LoadGenesisFeePolicy reads the feePolicy section of a genesis file. It
+// returns nil, i.e. fees go to the block beneficiary, if the file or the
+// section doesn't exist.
+func LoadGenesisFeePolicy(genesisFile string) (*FeePolicy, error) {
+	data, err := ioutil.ReadFile(genesisFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var genesis struct {
+		FeePolicy *FeePolicy `json:"feePolicy"`
+	}
+	if err := json.Unmarshal(data, &genesis); err != nil {
+		return nil, fmt.Errorf("parsing %s: %s", genesisFile, err)
+	}
+	if genesis.FeePolicy == nil {
+		return nil, nil
+	}
+	if err := genesis.FeePolicy.validate(); err != nil {
+		return nil, err
+	}
+
+	return genesis.FeePolicy, nil
+}
+
+// distributeFees moves the fees collected by the beneficiary of the block
+// being applied according to the policy, and returns the amount burned
+func (was *WriteAheadState) distributeFees() *big.Int {
+	burned := new(big.Int)
+	p := was.feePolicy
+	fees := was.blockFees
+	if p == nil || fees.Sign() == 0 {
+		return burned
+	}
+
+	coinbase := was.blockCoinbase
+	switch p.Type {
+	case FeeBurn:
+		was.ethState.SubBalance(coinbase, fees)
+		burned.Set(fees)
+	case FeeTreasury:
+		was.ethState.SubBalance(coinbase, fees)
+		was.ethState.AddBalance(p.Treasury, fees)
+	case FeeValidators:
+		was.ethState.SubBalance(coinbase, fees)
+		share, remainder := new(big.Int).QuoRem(fees, big.NewInt(int64(len(p.Validators))), new(big.Int))
+		for _, validator := range p.Validators {
+			was.ethState.AddBalance(validator, share)
+		}
+		was.ethState.AddBalance(p.Validators[0], remainder)
+	}
+
+	was.logger.WithFields(logrus.Fields{
+		"policy":   p.Type,
+		"coinbase": coinbase.Hex(),
+		"fees":     fees,
+	}).Debug("Distributed fees")
+
+	return burned
+}
+
+//SetFeePolicy sets the policy distributing the transaction fees of each block.
+//nil leaves them to the block beneficiary.
+func (s *State) SetFeePolicy(policy *FeePolicy) {
+	s.commitMutex.Lock()
+	defer s.commitMutex.Unlock()
+
+	s.feePolicy = policy
+	s.was.feePolicy = policy
+	if policy != nil {
+		s.logger.WithField("type", policy.Type).Info("Fee policy set")
+	}
+}
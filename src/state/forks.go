@@ -0,0 +1,37 @@
+package state
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// DefaultChainConfig returns the chain config the EVM runs with, unless the
+// chain id is set with SetChainID. It enables no hard fork.
+func DefaultChainConfig() params.ChainConfig {
+	return params.ChainConfig{ChainID: chainID}
+}
+
+// ForkName returns the name of the latest Ethereum hard fork whose rules a
+// chain config enables at the given block
+func ForkName(config *params.ChainConfig, number *big.Int) string {
+	switch {
+	case config.IsConstantinople(number):
+		return "Constantinople"
+	case config.IsByzantium(number):
+		return "Byzantium"
+	case config.IsEIP158(number):
+		return "Spurious Dragon"
+	case config.IsEIP150(number):
+		return "Tangerine Whistle"
+	case config.IsHomestead(number):
+		return "Homestead"
+	default:
+		return "Frontier"
+	}
+}
+
+//ForkName returns the name of the hard fork whose rules apply to the next block
+func (s *State) ForkName() string {
+	return ForkName(&s.chainConfig, big.NewInt(s.GetBlockIndex()+1))
+}
@@ -0,0 +1,85 @@
+package state
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+)
+
+// gasCappedPrefix records the transactions which failed because they exhausted
+// the gas cap of the contract they called
+var gasCappedPrefix = []byte("gascap-")
+
+// GasCapPolicy caps the gas available to transactions calling specific
+// contracts, as an emergency brake against abusive contracts. The policy is
+// part of the state transition: all the validators must run the same policy,
+// from the same block.
+//
+// A transaction to a capped contract executes with at most the cap, whatever
+// its gas limit. If it runs out of gas, its receipt is failed and it is
+// recorded as gas capped. Calls made by other contracts are not capped.
+type GasCapPolicy struct {
+	// first block the policy applies to
+	FromBlock int64 `json:"fromBlock"`
+	// maximum gas by contract address
+	Caps map[common.Address]uint64 `json:"caps"`
+}
+
+// LoadGasCapPolicy reads a GasCapPolicy from a JSON file
+func LoadGasCapPolicy(file string) (*GasCapPolicy, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := new(GasCapPolicy)
+	if err := json.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("parsing %s: %s", file, err)
+	}
+	for addr, limit := range policy.Caps {
+		if limit == 0 {
+			return nil, fmt.Errorf("gas cap of %s is 0", addr.Hex())
+		}
+	}
+
+	return policy, nil
+}
+
+// gasCap returns the gas cap of a transaction to the given address in the
+// given block, if any
+func (p *GasCapPolicy) gasCap(to *common.Address, blockIndex int64) (uint64, bool) {
+	if p == nil || to == nil || blockIndex < p.FromBlock {
+		return 0, false
+	}
+	limit, ok := p.Caps[*to]
+	return limit, ok
+}
+
+//SetGasCapPolicy sets the policy capping the gas of transactions to specific
+//contracts. nil disables capping.
+func (s *State) SetGasCapPolicy(policy *GasCapPolicy) {
+	s.commitMutex.Lock()
+	defer s.commitMutex.Unlock()
+
+	s.gasCaps = policy
+	s.was.gasCaps = policy
+	if policy != nil {
+		s.logger.WithFields(logrus.Fields{
+			"from_block": policy.FromBlock,
+			"contracts":  len(policy.Caps),
+		}).Info("Gas cap policy set")
+	}
+}
+
+//GetGasCap returns the cap a failed transaction exhausted, if it was gas capped
+func (s *State) GetGasCap(txHash common.Hash) (uint64, bool) {
+	data, err := s.reader.Get(append(gasCappedPrefix, txHash[:]...))
+	if err != nil || len(data) != 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(data), true
+}
@@ -0,0 +1,251 @@
+package state
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	ethState "github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/sirupsen/logrus"
+
+	bcommon "github.com/Fantom-foundation/go-evm/src/common"
+)
+
+var genesisKey = []byte("genesis")
+
+// Genesis is the content of a genesis file: the parameters of the network, the
+// accounts of the initial state and the fee policy
+type Genesis struct {
+	Config    *GenesisConfig     `json:"config,omitempty"`
+	Alloc     bcommon.AccountMap `json:"alloc"`
+	FeePolicy *FeePolicy         `json:"feePolicy,omitempty"`
+}
+
+// GenesisInfo identifies the genesis a database was initialized with: the hash
+// of the genesis and the state root it resulted in
+type GenesisInfo struct {
+	Hash common.Hash
+	Root common.Hash
+}
+
+// ReadGenesis reads and validates a genesis file
+func ReadGenesis(genesisFile string) (*Genesis, error) {
+	data, err := ioutil.ReadFile(genesisFile)
+	if err != nil {
+		return nil, err
+	}
+	genesis := new(Genesis)
+	if err := json.Unmarshal(data, genesis); err != nil {
+		return nil, fmt.Errorf("parsing %s: %s", genesisFile, err)
+	}
+	if err := checkDuplicateAlloc(data); err != nil {
+		return nil, fmt.Errorf("%s: %s", genesisFile, err)
+	}
+	if err := genesis.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %s", genesisFile, err)
+	}
+	return genesis, nil
+}
+
+// checkDuplicateAlloc checks that no account is listed twice in the alloc of
+// a genesis file, which the JSON decoding of the map would silently merge
+func checkDuplicateAlloc(data []byte) error {
+	var raw struct {
+		Alloc json.RawMessage `json:"alloc"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil || len(raw.Alloc) == 0 || string(raw.Alloc) == "null" {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw.Alloc))
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	seen := make(map[common.Address]bool)
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		addr := common.HexToAddress(strings.TrimSpace(key.(string)))
+		if seen[addr] {
+			return fmt.Errorf("alloc: duplicate account %s", addr.Hex())
+		}
+		seen[addr] = true
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks the parameters of the network, the addresses, balances, code,
+// constructors and storage of the accounts and the fee policy. The same address
+// may not be listed twice, and the total of the balances must fit in 256 bits.
+func (g *Genesis) Validate() error {
+	if g.Config != nil {
+		if err := g.Config.validate(); err != nil {
+			return fmt.Errorf("config: %s", err)
+		}
+	}
+	seen := make(map[common.Address]string)
+	total := new(big.Int)
+	for addr, account := range g.Alloc {
+		if !common.IsHexAddress(addr) {
+			return fmt.Errorf("alloc: invalid address %q", addr)
+		}
+		address := common.HexToAddress(addr)
+		if other, ok := seen[address]; ok {
+			return fmt.Errorf("alloc: duplicate account %s (%q and %q)", address.Hex(), other, addr)
+		}
+		seen[address] = addr
+		balance, ok := math.ParseBig256(account.Balance)
+		if !ok {
+			return fmt.Errorf("alloc %s: invalid balance %q", addr, account.Balance)
+		}
+		if total.Add(total, balance).BitLen() > 256 {
+			return fmt.Errorf("alloc: the total of the balances overflows 256 bits")
+		}
+		if _, err := hex.DecodeString(strings.TrimPrefix(account.Code, "0x")); err != nil {
+			return fmt.Errorf("alloc %s: invalid code: %s", addr, err)
+		}
+		if _, err := hex.DecodeString(strings.TrimPrefix(account.Constructor, "0x")); err != nil {
+			return fmt.Errorf("alloc %s: invalid constructor: %s", addr, err)
+		}
+		if _, err := hex.DecodeString(strings.TrimPrefix(account.Args, "0x")); err != nil {
+			return fmt.Errorf("alloc %s: invalid constructor args: %s", addr, err)
+		}
+		if account.Constructor != "" && account.Code != "" {
+			return fmt.Errorf("alloc %s: both code and a constructor", addr)
+		}
+		if account.Args != "" && account.Constructor == "" {
+			return fmt.Errorf("alloc %s: constructor args without a constructor", addr)
+		}
+		for key, value := range account.Storage {
+			if err := checkStorageWord(key); err != nil {
+				return fmt.Errorf("alloc %s: invalid storage key %q: %s", addr, key, err)
+			}
+			if err := checkStorageWord(value); err != nil {
+				return fmt.Errorf("alloc %s: invalid storage value %q: %s", addr, value, err)
+			}
+		}
+	}
+	if g.FeePolicy != nil {
+		if err := g.FeePolicy.validate(); err != nil {
+			return fmt.Errorf("feePolicy: %s", err)
+		}
+	}
+	return nil
+}
+
+// checkStorageWord checks that a storage key or value is hex of at most 32
+// bytes
+func checkStorageWord(word string) error {
+	word = strings.TrimPrefix(word, "0x")
+	if len(word)%2 == 1 {
+		word = "0" + word
+	}
+	b, err := hex.DecodeString(word)
+	if err != nil {
+		return err
+	}
+	if len(b) > common.HashLength {
+		return fmt.Errorf("more than %d bytes", common.HashLength)
+	}
+	return nil
+}
+
+// Hash returns the keccak256 hash of the JSON encoding of the genesis, which
+// doesn't depend on the formatting of the genesis file
+func (g *Genesis) Hash() common.Hash {
+	data, _ := json.Marshal(g)
+	return crypto.Keccak256Hash(data)
+}
+
+// StateRoot returns the root of the initial state of the genesis, as
+// InitGenesis writes it, computed in memory, and the logs emitted by the
+// constructors of its contracts
+func (g *Genesis) StateRoot() (common.Hash, []*ethTypes.Log, error) {
+	statedb, err := ethState.New(common.Hash{}, ethState.NewDatabase(ethdb.NewMemDatabase()))
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	chainConfig := DefaultChainConfig()
+	gasLimit := uint64(DefaultGasLimit)
+	if g.Config != nil {
+		chainConfig = g.Config.ChainConfig(chainConfig.ChainID)
+		if g.Config.GasLimit != 0 {
+			gasLimit = g.Config.GasLimit
+		}
+	}
+	_, logs, err := applyAlloc(statedb, g.Alloc, &chainConfig, gasLimit)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	root, err := statedb.Commit(true)
+	return root, logs, err
+}
+
+//InitGenesis writes the initial state of the genesis to an empty database and
+//records the genesis. Its config, applied at once, is recorded for InitState to
+//apply it whenever the database is opened.
+func (s *State) InitGenesis(genesis *Genesis) (*GenesisInfo, error) {
+	if !s.IsEmpty() {
+		return nil, fmt.Errorf("database already initialized")
+	}
+	if genesis.Config != nil {
+		if err := s.putGenesisConfig(genesis.Config); err != nil {
+			return nil, err
+		}
+		s.SetGenesisConfig(genesis.Config)
+	}
+	if err := s.CreateAccounts(genesis.Alloc); err != nil {
+		return nil, err
+	}
+
+	root, err := s.db.Get(rootKey)
+	if err != nil {
+		return nil, err
+	}
+	info := &GenesisInfo{Hash: genesis.Hash(), Root: common.BytesToHash(root)}
+	data, err := rlp.EncodeToBytes(info)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Put(genesisKey, data); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"hash":     info.Hash.Hex(),
+		"root":     info.Root.Hex(),
+		"accounts": len(genesis.Alloc),
+	}).Info("Initialized genesis")
+	return info, nil
+}
+
+//GetGenesis returns the genesis the database was initialized with, or false if
+//it was not initialized by InitGenesis
+func (s *State) GetGenesis() (*GenesisInfo, bool) {
+	data, err := s.db.Get(genesisKey)
+	if err != nil {
+		return nil, false
+	}
+	info := new(GenesisInfo)
+	if err := rlp.DecodeBytes(data, info); err != nil {
+		return nil, false
+	}
+	return info, true
+}
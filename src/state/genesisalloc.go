@@ -0,0 +1,132 @@
+package state
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core"
+	ethState "github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	bcommon "github.com/Fantom-foundation/go-evm/src/common"
+)
+
+var genesisLogsKey = []byte("genesis-logs")
+
+// applyAlloc writes the accounts of an alloc missing from statedb: balances,
+// nonces, code and storage first, then the constructors of the contracts are
+// run. The accounts are taken in address order, since a constructor may read
+// the other accounts or emit logs. It returns the total of the balances
+// created and the logs emitted by the constructors.
+func applyAlloc(statedb *ethState.StateDB,
+	alloc bcommon.AccountMap,
+	chainConfig *params.ChainConfig,
+	gasLimit uint64) (*big.Int, []*ethTypes.Log, error) {
+
+	addrs := make([]string, 0, len(alloc))
+	for addr := range alloc {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return common.HexToAddress(addrs[i]).Hex() < common.HexToAddress(addrs[j]).Hex()
+	})
+
+	minted := new(big.Int)
+	constructors := []string{}
+	for _, addr := range addrs {
+		address := common.HexToAddress(addr)
+		if statedb.Exist(address) {
+			continue
+		}
+		account := alloc[addr]
+		balance := math.MustParseBig256(account.Balance)
+		statedb.AddBalance(address, balance)
+		minted.Add(minted, balance)
+		statedb.SetNonce(address, account.Nonce)
+		statedb.SetCode(address, common.FromHex(account.Code))
+		for key, value := range account.Storage {
+			statedb.SetState(address, common.HexToHash(key), common.HexToHash(value))
+		}
+		if account.Constructor != "" {
+			constructors = append(constructors, addr)
+		}
+	}
+
+	statedb.Prepare(common.Hash{}, common.Hash{}, 0)
+	for _, addr := range constructors {
+		account := alloc[addr]
+		initCode := append(common.FromHex(account.Constructor), common.FromHex(account.Args)...)
+		if err := deployGenesisContract(statedb, chainConfig, gasLimit, common.HexToAddress(addr), initCode); err != nil {
+			return nil, nil, fmt.Errorf("alloc %s: constructor: %s", addr, err)
+		}
+	}
+	return minted, statedb.GetLogs(common.Hash{}), nil
+}
+
+// deployGenesisContract runs the constructor of a genesis contract at its
+// address, like a contract creation at block 0 by the zero address, and sets
+// the code it returns
+func deployGenesisContract(statedb *ethState.StateDB,
+	chainConfig *params.ChainConfig,
+	gasLimit uint64,
+	address common.Address,
+	initCode []byte) error {
+
+	context := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		GetHash:     func(uint64) common.Hash { return common.Hash{} },
+		GasLimit:    gasLimit,
+		GasPrice:    new(big.Int),
+		BlockNumber: new(big.Int),
+		Time:        new(big.Int),
+		Difficulty:  new(big.Int),
+	}
+	evm := vm.NewEVM(context, statedb, chainConfig, vm.Config{})
+
+	if chainConfig.IsEIP158(context.BlockNumber) && statedb.GetNonce(address) == 0 {
+		statedb.SetNonce(address, 1)
+	}
+	contract := vm.NewContract(vm.AccountRef(common.Address{}), vm.AccountRef(address), new(big.Int), gasLimit)
+	contract.SetCallCode(&address, crypto.Keccak256Hash(initCode), initCode)
+	code, err := evm.Interpreter().Run(contract, nil, false)
+	if err != nil {
+		return err
+	}
+	statedb.SetCode(address, code)
+	return nil
+}
+
+// putGenesisLogs records the logs emitted by the constructors of the genesis
+// contracts
+func (s *State) putGenesisLogs(logs []*ethTypes.Log) error {
+	data, err := rlp.EncodeToBytes(logs)
+	if err != nil {
+		return err
+	}
+	return s.db.Put(genesisLogsKey, data)
+}
+
+//GetGenesisLogs returns the logs emitted by the constructors of the genesis
+//contracts when the database was initialized, in order
+func (s *State) GetGenesisLogs() ([]*ethTypes.Log, error) {
+	data, err := s.db.Get(genesisLogsKey)
+	if err != nil {
+		return []*ethTypes.Log{}, nil
+	}
+	var logs []*ethTypes.Log
+	if err := rlp.DecodeBytes(data, &logs); err != nil {
+		return nil, fmt.Errorf("decoding the genesis logs: %s", err)
+	}
+	for i, log := range logs {
+		log.Index = uint(i)
+	}
+	return logs, nil
+}
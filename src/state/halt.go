@@ -0,0 +1,84 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// haltedKey records the height at which the State halted for an upgrade, so
+// that it stays halted across restarts until it is explicitly resumed
+var haltedKey = []byte("HaltedAt")
+
+// HaltError is returned when the State halted after committing the configured
+// halt height. It refuses to apply anything else until it is resumed.
+type HaltError struct {
+	Height int64
+}
+
+func (e *HaltError) Error() string {
+	return fmt.Sprintf("halted after block %d for upgrade, resume to continue", e.Height)
+}
+
+// SetHaltHeight makes the State halt after committing the block at the given
+// height. 0 disables halting. Heights which were already committed are
+// ignored.
+func (s *State) SetHaltHeight(height int64) {
+	s.commitMutex.Lock()
+	defer s.commitMutex.Unlock()
+
+	if height > 0 && height <= s.blockIndex {
+		s.logger.WithFields(logrus.Fields{
+			"halt_height": height,
+			"block_index": s.blockIndex,
+		}).Warn("Halt height already committed. Ignoring")
+		return
+	}
+
+	s.haltHeight = height
+	if height > 0 {
+		s.logger.WithField("halt_height", height).Info("Halt height set")
+	}
+}
+
+// Halted returns the error which halted the State, if any
+func (s *State) Halted() error {
+	return s.halted
+}
+
+// Resume clears a halt for upgrade so that the State applies blocks again
+func (s *State) Resume() error {
+	s.commitMutex.Lock()
+	defer s.commitMutex.Unlock()
+
+	if _, ok := s.halted.(*HaltError); !ok {
+		return nil
+	}
+	if err := s.db.Delete(haltedKey); err != nil {
+		return err
+	}
+	s.logger.WithField("block_index", s.blockIndex).Info("Resuming")
+	s.halted = nil
+
+	return nil
+}
+
+// halt stops the State after the current block was committed
+func (s *State) halt() error {
+	if err := s.db.Put(haltedKey, encodeBlockIndex(s.blockIndex)); err != nil {
+		return err
+	}
+	s.haltHeight = 0
+	s.halted = &HaltError{Height: s.blockIndex}
+	s.logger.WithField("block_index", s.blockIndex).Warn("Halt height reached. Halting")
+
+	return nil
+}
+
+// loadHalt restores a halt for upgrade recorded in the database
+func (s *State) loadHalt() {
+	data, _ := s.db.Get(haltedKey)
+	if len(data) != 0 {
+		s.halted = &HaltError{Height: decodeBlockIndex(data)}
+	}
+}
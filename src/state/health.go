@@ -0,0 +1,8 @@
+package state
+
+//CheckDB returns an error if the database can't be read, e.g. once it was
+//closed or its files became unreadable
+func (s *State) CheckDB() error {
+	_, err := s.db.Has(headBlockKey)
+	return err
+}
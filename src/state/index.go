@@ -0,0 +1,334 @@
+package state
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	indexMarkerPrefix = "index"
+	accountTxPrefix   = []byte("acctx-")
+	topicLogPrefix    = []byte("topic-")
+	blockBloomPrefix  = "bloom"
+)
+
+// indexMarkerKey records the last block up to which an index is complete,
+// from the first block
+func indexMarkerKey(name string) []byte {
+	return []byte(fmt.Sprintf("%s_%s", indexMarkerPrefix, name))
+}
+
+// accountTxKey indexes a transaction by sender or recipient. Keys of an
+// account sort by block and position.
+func accountTxKey(addr common.Address, number uint64, txIndex uint32) []byte {
+	key := append(append([]byte{}, accountTxPrefix...), addr.Bytes()...)
+	key = appendUint64(key, number)
+	return appendUint32(key, txIndex)
+}
+
+// topicLogKey indexes a log by topic. Keys of a topic sort by block, position
+// of the transaction and position of the log.
+func topicLogKey(topic common.Hash, number uint64, txIndex uint32, logIndex uint32) []byte {
+	key := append(append([]byte{}, topicLogPrefix...), topic.Bytes()...)
+	key = appendUint64(key, number)
+	key = appendUint32(key, txIndex)
+	return appendUint32(key, logIndex)
+}
+
+func blockBloomKey(number uint64) []byte {
+	return []byte(fmt.Sprintf("%s_%09d", blockBloomPrefix, number))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, v)
+	return append(b, enc...)
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	enc := make([]byte, 4)
+	binary.BigEndian.PutUint32(enc, v)
+	return append(b, enc...)
+}
+
+// indexedBlock is a committed Block with its transactions and receipts, in
+// order
+type indexedBlock struct {
+	block    *Block
+	hash     common.Hash
+	txs      []*ethTypes.Transaction
+	receipts []*ethTypes.Receipt
+}
+
+// chainIndex is a secondary index derived from committed blocks. Indexes are
+// maintained on Commit, and can be rebuilt from the stored blocks, txs and
+// receipts (evm reindex), e.g. after an upgrade introducing a new index.
+// Building an index twice for the same block must be harmless.
+type chainIndex struct {
+	name  string
+	build func(batch ethdb.Batch, b *indexedBlock, signer ethTypes.Signer) error
+}
+
+var chainIndexes = []chainIndex{
+	{name: "txlookup", build: indexTxLookups},
+	{name: "accounts", build: indexAccounts},
+	{name: "topics", build: indexTopics},
+	{name: "bloom", build: indexBloom},
+	{name: "logaddresses", build: indexLogAddresses},
+}
+
+// IndexNames returns the names of the indexes
+func IndexNames() []string {
+	names := make([]string, len(chainIndexes))
+	for i, idx := range chainIndexes {
+		names[i] = idx.name
+	}
+	return names
+}
+
+// indexTxLookups records the block, position and sender of each transaction
+func indexTxLookups(batch ethdb.Batch, b *indexedBlock, signer ethTypes.Signer) error {
+	for i, tx := range b.txs {
+		from, _ := ethTypes.Sender(signer, tx)
+		data, err := rlp.EncodeToBytes(TxLookupEntry{
+			BlockHash:   b.hash,
+			BlockNumber: b.block.Number,
+			Index:       uint64(i),
+			From:        from,
+		})
+		if err != nil {
+			return err
+		}
+		if err := batch.Put(txLookupKey(tx.Hash()), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexAccounts records the transactions sent, received or creating a
+// contract, by account
+func indexAccounts(batch ethdb.Batch, b *indexedBlock, signer ethTypes.Signer) error {
+	for i, tx := range b.txs {
+		addrs := []common.Address{}
+		if from, err := ethTypes.Sender(signer, tx); err == nil {
+			addrs = append(addrs, from)
+		}
+		if tx.To() != nil {
+			addrs = append(addrs, *tx.To())
+		} else if i < len(b.receipts) {
+			addrs = append(addrs, b.receipts[i].ContractAddress)
+		}
+
+		for _, addr := range addrs {
+			if err := batch.Put(accountTxKey(addr, b.block.Number, uint32(i)), tx.Hash().Bytes()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// indexTopics records the logs by topic
+func indexTopics(batch ethdb.Batch, b *indexedBlock, _ ethTypes.Signer) error {
+	for i, receipt := range b.receipts {
+		for _, log := range receipt.Logs {
+			for _, topic := range log.Topics {
+				key := topicLogKey(topic, b.block.Number, uint32(i), uint32(log.Index))
+				if err := batch.Put(key, receipt.TxHash.Bytes()); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// indexBloom records the bloom filter of the logs of each block
+func indexBloom(batch ethdb.Batch, b *indexedBlock, _ ethTypes.Signer) error {
+	bloom := ethTypes.CreateBloom(ethTypes.Receipts(b.receipts))
+	return batch.Put(blockBloomKey(b.block.Number), bloom.Bytes())
+}
+
+// writeIndexes builds all the indexes for a newly committed block. The markers
+// of the indexes which are complete up to the previous block are advanced.
+func writeIndexes(db ethdb.Database, b *indexedBlock, signer ethTypes.Signer) error {
+	batch := db.NewBatch()
+	for _, idx := range chainIndexes {
+		if err := idx.build(batch, b, signer); err != nil {
+			return fmt.Errorf("index %s: %s", idx.name, err)
+		}
+
+		complete := b.block.Number == 0
+		if data, err := db.Get(indexMarkerKey(idx.name)); err == nil {
+			complete = decodeBlockIndex(data)+1 == int64(b.block.Number)
+		}
+		if complete {
+			if err := batch.Put(indexMarkerKey(idx.name), encodeBlockIndex(int64(b.block.Number))); err != nil {
+				return err
+			}
+		}
+	}
+	return batch.Write()
+}
+
+// ReindexProgress is called by Reindex after each block
+type ReindexProgress func(index string, number int64, head int64)
+
+// Reindex rebuilds the given indexes (all if names is empty) from the stored
+// blocks, transactions and receipts. Indexes which are already complete are
+// skipped unless force is set. The blocks up to an imported snapshot or synced
+// state are not in the database and are skipped. Any other block which can't
+// be loaded fails the index, whose marker stays at the last indexed block.
+func (s *State) Reindex(names []string, force bool, progress ReindexProgress) error {
+	s.commitMutex.Lock()
+	defer s.commitMutex.Unlock()
+
+	selected := []chainIndex{}
+	for _, idx := range chainIndexes {
+		if len(names) == 0 || contains(names, idx.name) {
+			selected = append(selected, idx)
+		}
+	}
+	for _, name := range names {
+		found := false
+		for _, idx := range chainIndexes {
+			found = found || idx.name == name
+		}
+		if !found {
+			return fmt.Errorf("unknown index %q", name)
+		}
+	}
+
+	head := s.blockIndex
+	imported := int64(-1)
+	if data, err := s.db.Get(importedHeadKey); err == nil {
+		imported = decodeBlockIndex(data)
+	}
+	for _, idx := range selected {
+		start := int64(0)
+		if data, err := s.db.Get(indexMarkerKey(idx.name)); err == nil && !force {
+			start = decodeBlockIndex(data) + 1
+		}
+		if start <= imported {
+			s.logger.WithFields(logrus.Fields{
+				"index":    idx.name,
+				"imported": imported,
+			}).Info("Skipping the blocks up to the imported state")
+			if err := s.db.Put(indexMarkerKey(idx.name), encodeBlockIndex(imported)); err != nil {
+				return err
+			}
+			start = imported + 1
+		}
+		if start > head {
+			s.logger.WithField("index", idx.name).Info("Index complete")
+			continue
+		}
+
+		for number := start; number <= head; number++ {
+			b, err := s.loadIndexedBlock(number)
+			if err != nil {
+				return fmt.Errorf("index %s, block %d: %s", idx.name, number, err)
+			}
+
+			batch := s.db.NewBatch()
+			if err := idx.build(batch, b, s.signer); err != nil {
+				return fmt.Errorf("index %s, block %d: %s", idx.name, number, err)
+			}
+			if err := batch.Put(indexMarkerKey(idx.name), encodeBlockIndex(number)); err != nil {
+				return err
+			}
+			if err := batch.Write(); err != nil {
+				return err
+			}
+
+			if progress != nil {
+				progress(idx.name, number, head)
+			}
+		}
+	}
+
+	return nil
+}
+
+// loadIndexedBlock reads a committed block with its transactions and receipts
+func (s *State) loadIndexedBlock(number int64) (*indexedBlock, error) {
+	data, err := s.db.Get(blockHeaderKey(number))
+	if err != nil {
+		return nil, fmt.Errorf("no block header: %s", err)
+	}
+	block := new(Block)
+	if err := block.Unmarshal(data); err != nil {
+		return nil, err
+	}
+
+	b := &indexedBlock{
+		block: block,
+		hash:  block.Hash(),
+	}
+	for _, txHash := range block.Transactions {
+		tx, err := s.GetTransaction(txHash)
+		if err != nil {
+			return nil, fmt.Errorf("tx %s: %s", txHash.Hex(), err)
+		}
+		receipt, err := s.GetReceipt(txHash)
+		if err != nil {
+			return nil, fmt.Errorf("receipt %s: %s", txHash.Hex(), err)
+		}
+		b.txs = append(b.txs, tx)
+		b.receipts = append(b.receipts, receipt)
+	}
+
+	return b, nil
+}
+
+// GetAccountTransactions returns the hashes of the transactions sent or
+// received by an account, oldest first, skipping the first offset ones and
+// returning at most limit (all if limit is 0).
+func (s *State) GetAccountTransactions(addr common.Address, offset, limit int) ([]common.Hash, error) {
+	ldb, ok := s.ldb()
+	if !ok {
+		return nil, fmt.Errorf("account history requires a LevelDB database")
+	}
+
+	prefix := append(append([]byte{}, accountTxPrefix...), addr.Bytes()...)
+	it := ldb.NewIteratorWithPrefix(prefix)
+	defer it.Release()
+
+	hashes := []common.Hash{}
+	for i := 0; it.Next(); i++ {
+		if i < offset {
+			continue
+		}
+		if limit > 0 && len(hashes) >= limit {
+			break
+		}
+		hashes = append(hashes, common.BytesToHash(it.Value()))
+	}
+
+	return hashes, it.Error()
+}
+
+// GetBlockBloom returns the bloom filter of the logs of a block
+func (s *State) GetBlockBloom(number int64) (ethTypes.Bloom, error) {
+	data, err := s.reader.Get(blockBloomKey(uint64(number)))
+	if err != nil {
+		return ethTypes.Bloom{}, err
+	}
+	return ethTypes.BytesToBloom(data), nil
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
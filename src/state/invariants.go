@@ -0,0 +1,131 @@
+package state
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethState "github.com/ethereum/go-ethereum/core/state"
+)
+
+// InvariantError is returned by Commit, in invariant-checking mode, when the
+// committed state violates one of the invariants. The State refuses to apply
+// anything else once it has returned an InvariantError.
+type InvariantError struct {
+	Root       common.Hash
+	BlockIndex int64
+	Violations []string
+}
+
+func (e *InvariantError) Error() string {
+	return fmt.Sprintf("state invariants violated at block %d, root %s: %s",
+		e.BlockIndex,
+		e.Root.Hex(),
+		strings.Join(e.Violations, "; "))
+}
+
+// invariantChecker verifies after each Commit that the native supply is
+// conserved (accounting for mints and burns), that account nonces never
+// decrease, and that no balance is negative. It walks the entire state on every
+// check so it is a safety net against execution bugs, not something to run on
+// a busy production node.
+type invariantChecker struct {
+	supply *big.Int          // total supply at the last check
+	nonces map[string]uint64 // account nonces at the last check
+	minted *big.Int          // minted since the last check
+	burned *big.Int          // burned since the last check
+}
+
+func newInvariantChecker(st *ethState.StateDB) (*invariantChecker, error) {
+	supply, nonces, _, err := snapshotAccounts(st)
+	if err != nil {
+		return nil, err
+	}
+	return &invariantChecker{
+		supply: supply,
+		nonces: nonces,
+		minted: new(big.Int),
+		burned: new(big.Int),
+	}, nil
+}
+
+// snapshotAccounts returns the total supply, and the nonces and balances of all
+// the accounts of a statedb
+func snapshotAccounts(st *ethState.StateDB) (*big.Int, map[string]uint64, map[string]*big.Int, error) {
+	supply := new(big.Int)
+	nonces := make(map[string]uint64)
+	balances := make(map[string]*big.Int)
+
+	for addr, account := range st.RawDump().Accounts {
+		balance, ok := new(big.Int).SetString(account.Balance, 10)
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("account %s: invalid balance %q", addr, account.Balance)
+		}
+		supply.Add(supply, balance)
+		nonces[addr] = account.Nonce
+		balances[addr] = balance
+	}
+
+	return supply, nonces, balances, nil
+}
+
+// mint records native tokens created outside of transaction execution
+func (c *invariantChecker) mint(amount *big.Int) {
+	c.minted.Add(c.minted, amount)
+}
+
+// burn records native tokens destroyed outside of transaction execution
+func (c *invariantChecker) burn(amount *big.Int) {
+	c.burned.Add(c.burned, amount)
+}
+
+// check verifies the invariants against the freshly committed statedb and
+// makes it the reference for the next check.
+func (c *invariantChecker) check(root common.Hash, blockIndex int64, st *ethState.StateDB) error {
+	supply, nonces, balances, err := snapshotAccounts(st)
+	if err != nil {
+		return err
+	}
+
+	violations := []string{}
+
+	expected := new(big.Int).Add(c.supply, c.minted)
+	expected.Sub(expected, c.burned)
+	if supply.Cmp(expected) != 0 {
+		violations = append(violations,
+			fmt.Sprintf("supply is %v, expected %v (previous %v, minted %v, burned %v)",
+				supply, expected, c.supply, c.minted, c.burned))
+	}
+
+	rangeInCommit()
+	for addr, nonce := range c.nonces {
+		// Accounts can disappear (selfdestruct); only existing ones are checked
+		if newNonce, ok := nonces[addr]; ok && newNonce < nonce {
+			violations = append(violations,
+				fmt.Sprintf("nonce of %s decreased from %d to %d", addr, nonce, newNonce))
+		}
+	}
+
+	rangeInCommit()
+	for addr, balance := range balances {
+		if balance.Sign() < 0 {
+			violations = append(violations,
+				fmt.Sprintf("balance of %s is negative: %v", addr, balance))
+		}
+	}
+
+	c.supply = supply
+	c.nonces = nonces
+	c.minted = new(big.Int)
+	c.burned = new(big.Int)
+
+	if len(violations) > 0 {
+		return &InvariantError{
+			Root:       root,
+			BlockIndex: blockIndex,
+			Violations: violations,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package state
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+var addressLogPrefix = []byte("logaddr-")
+
+// addressLogKey indexes a log by emitting contract. Keys of a contract sort by
+// block, position of the transaction and position of the log.
+func addressLogKey(addr common.Address, number uint64, txIndex uint32, logIndex uint32) []byte {
+	key := append(append([]byte{}, addressLogPrefix...), addr.Bytes()...)
+	key = appendUint64(key, number)
+	key = appendUint32(key, txIndex)
+	return appendUint32(key, logIndex)
+}
+
+// indexLogAddresses records the logs by emitting contract
+func indexLogAddresses(batch ethdb.Batch, b *indexedBlock, _ ethTypes.Signer) error {
+	for i, receipt := range b.receipts {
+		for _, log := range receipt.Logs {
+			key := addressLogKey(log.Address, b.block.Number, uint32(i), uint32(log.Index))
+			if err := batch.Put(key, receipt.TxHash.Bytes()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// LogFilter selects the logs of a block range by emitting contract and topics
+type LogFilter struct {
+	FromBlock int64
+	ToBlock   int64
+	// emitting contracts; any if empty
+	Addresses []common.Address
+	// topics by position. An empty position matches any topic; otherwise the
+	// topic of the log must be one of the listed ones.
+	Topics [][]common.Hash
+}
+
+// Match returns whether a log is emitted by one of the contracts and matches
+// the topics of the filter. The block range is not checked.
+func (f *LogFilter) Match(log *ethTypes.Log) bool {
+	if len(f.Addresses) > 0 && !containsAddress(f.Addresses, log.Address) {
+		return false
+	}
+	if len(f.Topics) > len(log.Topics) {
+		return false
+	}
+	for i, alts := range f.Topics {
+		if len(alts) > 0 && !containsHash(alts, log.Topics[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchBloom returns whether a block whose logs have the given bloom may hold
+// logs matching the filter
+func (f *LogFilter) matchBloom(bloom ethTypes.Bloom) bool {
+	if len(f.Addresses) > 0 {
+		found := false
+		for _, addr := range f.Addresses {
+			found = found || ethTypes.BloomLookup(bloom, addr)
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, alts := range f.Topics {
+		if len(alts) == 0 {
+			continue
+		}
+		found := false
+		for _, topic := range alts {
+			found = found || ethTypes.BloomLookup(bloom, topic)
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+//GetLogs returns the logs of the committed blocks matching a filter, in order.
+//It fails if there are more than limit logs (no limit if 0).
+func (s *State) GetLogs(f *LogFilter, limit int) ([]*ethTypes.Log, error) {
+	logs := []*ethTypes.Log{}
+	over := false
+	_, err := s.FilterLogs(f, func(_ int64, blockLogs []*ethTypes.Log) bool {
+		logs = append(logs, blockLogs...)
+		over = limit > 0 && len(logs) > limit
+		return !over
+	})
+	if err != nil {
+		return nil, err
+	}
+	if over {
+		return nil, fmt.Errorf("query returned more than %d logs", limit)
+	}
+	return logs, nil
+}
+
+//FilterLogs calls fn with the matching logs of every block of the range of a
+//filter which has some, in order, until fn returns false. It returns the
+//number of the block to resume from when fn stopped before the end of the
+//range, and -1 otherwise. The blocks are found with the log indexes when they
+//cover the range, and the block blooms otherwise.
+func (s *State) FilterLogs(f *LogFilter, fn func(number int64, logs []*ethTypes.Log) bool) (int64, error) {
+	if f.ToBlock > s.blockIndex {
+		f.ToBlock = s.blockIndex
+	}
+	if f.FromBlock < 0 || f.FromBlock > f.ToBlock {
+		return -1, nil
+	}
+
+	numbers, err := s.logBlocks(f)
+	if err != nil {
+		return -1, err
+	}
+
+	for _, number := range numbers {
+		if bloom, err := s.GetBlockBloom(number); err == nil && !f.matchBloom(bloom) {
+			continue
+		}
+		block, err := s.GetBlockByNumber(number)
+		if err != nil {
+			// Blocks committed before Block headers were recorded
+			continue
+		}
+		logs := []*ethTypes.Log{}
+		for _, txHash := range block.Transactions {
+			receipt, err := s.GetReceipt(txHash)
+			if err != nil {
+				return -1, fmt.Errorf("receipt %s: %s", txHash.Hex(), err)
+			}
+			for _, log := range receipt.Logs {
+				if f.Match(log) {
+					logs = append(logs, log)
+				}
+			}
+		}
+		if len(logs) == 0 {
+			continue
+		}
+		if !fn(number, logs) {
+			if number < f.ToBlock {
+				return number + 1, nil
+			}
+			return -1, nil
+		}
+	}
+
+	return -1, nil
+}
+
+// logBlocks returns the numbers of the blocks of the range of a filter which
+// may hold matching logs, in order. Without an index covering the range, this
+// is all the blocks of the range.
+func (s *State) logBlocks(f *LogFilter) ([]int64, error) {
+	var (
+		prefix []byte
+		keys   [][]byte
+	)
+	switch {
+	case len(f.Addresses) > 0 && s.indexedUpTo("logaddresses") >= f.ToBlock:
+		prefix = addressLogPrefix
+		for _, addr := range f.Addresses {
+			keys = append(keys, addr.Bytes())
+		}
+	case len(f.Topics) > 0 && len(f.Topics[0]) > 0 && s.indexedUpTo("topics") >= f.ToBlock:
+		prefix = topicLogPrefix
+		for _, topic := range f.Topics[0] {
+			keys = append(keys, topic.Bytes())
+		}
+	}
+
+	ldb, ok := s.ldb()
+	if prefix == nil || !ok {
+		numbers := []int64{}
+		for number := f.FromBlock; number <= f.ToBlock; number++ {
+			numbers = append(numbers, number)
+		}
+		return numbers, nil
+	}
+
+	found := make(map[int64]bool)
+	for _, key := range keys {
+		keyPrefix := append(append([]byte{}, prefix...), key...)
+		it := ldb.NewIteratorWithPrefix(keyPrefix)
+		start := appendUint64(append([]byte{}, keyPrefix...), uint64(f.FromBlock))
+		for valid := it.Seek(start); valid; valid = it.Next() {
+			number := int64(binary.BigEndian.Uint64(it.Key()[len(keyPrefix):]))
+			if number > f.ToBlock {
+				break
+			}
+			found[number] = true
+		}
+		err := it.Error()
+		it.Release()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	numbers := make([]int64, 0, len(found))
+	for number := range found {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	return numbers, nil
+}
+
+// indexedUpTo returns the last block up to which an index is complete, or -1
+func (s *State) indexedUpTo(name string) int64 {
+	data, err := s.db.Get(indexMarkerKey(name))
+	if err != nil {
+		return -1
+	}
+	return decodeBlockIndex(data)
+}
+
+func containsAddress(list []common.Address, addr common.Address) bool {
+	for _, a := range list {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+func containsHash(list []common.Hash, hash common.Hash) bool {
+	for _, h := range list {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package state
+
+import (
+	"github.com/ethereum/go-ethereum/ethdb"
+
+	"github.com/Fantom-foundation/go-evm/src/metrics"
+)
+
+var (
+	txsApplied = metrics.NewCounter("evm_txs_applied_total", "Transactions of the blocks applied to the state, by outcome", "status", "applied")
+	txsSkipped = metrics.NewCounter("evm_txs_applied_total", "Transactions of the blocks applied to the state, by outcome", "status", "skipped")
+
+	commitDuration = metrics.NewHistogram("evm_commit_duration_seconds", "Duration of the commits of the state to the database", nil)
+	callDuration   = metrics.NewHistogram("evm_call_duration_seconds", "Duration of the read-only calls", nil)
+
+	blocksDelivered = metrics.NewGauge("evm_consensus_block_index", "Index of the last block delivered by the consensus engine")
+	blockLag        = metrics.NewGauge("evm_block_lag", "Blocks delivered by the consensus engine and not yet committed")
+	blockPauses     = metrics.NewCounter("evm_backpressure_pauses_total", "Times the consensus engine paused pulling blocks because the state fell behind")
+
+	dbGets    = metrics.NewCounter("evm_db_operations_total", "Operations on the database", "op", "get")
+	dbHas     = metrics.NewCounter("evm_db_operations_total", "Operations on the database", "op", "has")
+	dbPuts    = metrics.NewCounter("evm_db_operations_total", "Operations on the database", "op", "put")
+	dbDeletes = metrics.NewCounter("evm_db_operations_total", "Operations on the database", "op", "delete")
+	dbBatches = metrics.NewCounter("evm_db_operations_total", "Operations on the database", "op", "batch_write")
+)
+
+// meteredDB counts the operations on the database of the State
+type meteredDB struct {
+	*ethdb.LDBDatabase
+}
+
+func (db *meteredDB) Get(key []byte) ([]byte, error) {
+	dbGets.Inc()
+	return db.LDBDatabase.Get(key)
+}
+
+func (db *meteredDB) Has(key []byte) (bool, error) {
+	dbHas.Inc()
+	return db.LDBDatabase.Has(key)
+}
+
+func (db *meteredDB) Put(key []byte, value []byte) error {
+	dbPuts.Inc()
+	return db.LDBDatabase.Put(key, value)
+}
+
+func (db *meteredDB) Delete(key []byte) error {
+	dbDeletes.Inc()
+	return db.LDBDatabase.Delete(key)
+}
+
+func (db *meteredDB) NewBatch() ethdb.Batch {
+	return &meteredBatch{Batch: db.LDBDatabase.NewBatch()}
+}
+
+// meteredBatch counts the writes of a batch of the database of the State
+type meteredBatch struct {
+	ethdb.Batch
+}
+
+func (b *meteredBatch) Write() error {
+	dbBatches.Inc()
+	return b.Batch.Write()
+}
+
+// ldb returns the LevelDB database of the State, for iterations
+func (s *State) ldb() (*ethdb.LDBDatabase, bool) {
+	switch db := s.db.(type) {
+	case *meteredDB:
+		return db.LDBDatabase, true
+	case *ethdb.LDBDatabase:
+		return db, true
+	}
+	return nil, false
+}
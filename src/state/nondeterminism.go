@@ -0,0 +1,54 @@
+package state
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Sources of non-determinism reported by the nondeterminism build
+const (
+	NonDetMapIteration = "map iteration"
+	NonDetTime         = "time.Now"
+)
+
+// NonDeterminismReport is a non-deterministic source reached from the commit
+// path (ProcessBlock, ApplyTransaction or Commit). The commit path must give
+// the same result on all the validators: a report is either a bug, or a source
+// whose result does not depend on the order or value it yields.
+type NonDeterminismReport struct {
+	Source   string
+	Location string // file:line of the source
+	Count    uint64 // times it was reached
+}
+
+// nowInCommit is time.Now for the commit path. The nondeterminism build reports
+// its use.
+func nowInCommit() time.Time {
+	reportNonDeterminism(NonDetTime, 2)
+	return time.Now()
+}
+
+// rangeInCommit marks the iteration of a map in the commit path. The
+// nondeterminism build reports it.
+func rangeInCommit() {
+	reportNonDeterminism(NonDetMapIteration, 2)
+}
+
+//GetNonDeterminismReports returns the non-deterministic sources reached from the
+//commit path. It is always empty unless the binary is built with the
+//nondeterminism tag.
+func (s *State) GetNonDeterminismReports() []NonDeterminismReport {
+	return nonDeterminismReports()
+}
+
+// exitCommitPath leaves the commit path, logging the sources reached for the
+// first time
+func (s *State) exitCommitPath() {
+	for _, r := range leaveCommitPath() {
+		s.logger.WithFields(logrus.Fields{
+			"source":   r.Source,
+			"location": r.Location,
+		}).Warn("Non-deterministic source reached from the commit path")
+	}
+}
@@ -0,0 +1,11 @@
+// +build !nondeterminism
+
+package state
+
+func enterCommitPath() {}
+
+func leaveCommitPath() []NonDeterminismReport { return nil }
+
+func reportNonDeterminism(source string, skip int) {}
+
+func nonDeterminismReports() []NonDeterminismReport { return nil }
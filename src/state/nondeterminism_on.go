@@ -0,0 +1,80 @@
+// +build nondeterminism
+
+package state
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// nonDetTracker records the non-deterministic sources reached while the
+// commit path is running
+var nonDetTracker = struct {
+	sync.Mutex
+	depth   int
+	reports map[string]*NonDeterminismReport
+	fresh   []NonDeterminismReport
+}{
+	reports: make(map[string]*NonDeterminismReport),
+}
+
+// enterCommitPath starts tracking non-deterministic sources. Calls nest.
+func enterCommitPath() {
+	nonDetTracker.Lock()
+	defer nonDetTracker.Unlock()
+
+	nonDetTracker.depth++
+}
+
+// leaveCommitPath stops tracking when the outermost commit path returns, and
+// returns the sources reached for the first time
+func leaveCommitPath() []NonDeterminismReport {
+	nonDetTracker.Lock()
+	defer nonDetTracker.Unlock()
+
+	nonDetTracker.depth--
+	if nonDetTracker.depth > 0 {
+		return nil
+	}
+	fresh := nonDetTracker.fresh
+	nonDetTracker.fresh = nil
+	return fresh
+}
+
+// reportNonDeterminism records a source reached from the commit path. skip is
+// the number of frames between the source and this function.
+func reportNonDeterminism(source string, skip int) {
+	nonDetTracker.Lock()
+	defer nonDetTracker.Unlock()
+
+	if nonDetTracker.depth == 0 {
+		return
+	}
+	_, file, line, _ := runtime.Caller(skip)
+	location := fmt.Sprintf("%s:%d", file, line)
+
+	key := source + "@" + location
+	r, ok := nonDetTracker.reports[key]
+	if !ok {
+		r = &NonDeterminismReport{Source: source, Location: location}
+		nonDetTracker.reports[key] = r
+		nonDetTracker.fresh = append(nonDetTracker.fresh, *r)
+	}
+	r.Count++
+}
+
+func nonDeterminismReports() []NonDeterminismReport {
+	nonDetTracker.Lock()
+	defer nonDetTracker.Unlock()
+
+	reports := make([]NonDeterminismReport, 0, len(nonDetTracker.reports))
+	for _, r := range nonDetTracker.reports {
+		reports = append(reports, *r)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Location < reports[j].Location
+	})
+	return reports
+}
@@ -0,0 +1,77 @@
+package state
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// StorageProof is the Merkle proof of a storage slot of an account
+type StorageProof struct {
+	Key   common.Hash
+	Value common.Hash
+	Proof [][]byte
+}
+
+// AccountProof is the Merkle proof of an account, and of some of its storage
+// slots, against the state root of a committed block (see EIP-1186)
+type AccountProof struct {
+	Address      common.Address
+	BlockIndex   int64
+	Root         common.Hash
+	Balance      *big.Int
+	Nonce        uint64
+	CodeHash     common.Hash
+	StorageHash  common.Hash
+	AccountProof [][]byte
+	StorageProof []StorageProof
+}
+
+//GetProof returns the proof of an account and of the given storage slots in
+//the last committed state
+func (s *State) GetProof(addr common.Address, keys []common.Hash) (*AccountProof, error) {
+	s.commitMutex.Lock()
+	defer s.commitMutex.Unlock()
+
+	root, err := s.db.Get(rootKey)
+	if err != nil {
+		return nil, err
+	}
+
+	accountProof, err := s.ethState.GetProof(addr)
+	if err != nil {
+		s.logger.WithError(err).Error("Getting account proof")
+		return nil, err
+	}
+
+	proof := &AccountProof{
+		Address:      addr,
+		BlockIndex:   s.blockIndex,
+		Root:         common.BytesToHash(root),
+		Balance:      s.ethState.GetBalance(addr),
+		Nonce:        s.ethState.GetNonce(addr),
+		CodeHash:     s.ethState.GetCodeHash(addr),
+		StorageHash:  ethTypes.EmptyRootHash,
+		AccountProof: accountProof,
+		StorageProof: make([]StorageProof, len(keys)),
+	}
+	if trie := s.ethState.StorageTrie(addr); trie != nil {
+		proof.StorageHash = trie.Hash()
+	}
+
+	for i, key := range keys {
+		storageProof, err := s.ethState.GetStorageProof(addr, key)
+		if err != nil {
+			s.logger.WithError(err).Error("Getting storage proof")
+			return nil, err
+		}
+		proof.StorageProof[i] = StorageProof{
+			Key:   key,
+			Value: s.ethState.GetState(addr, key),
+			Proof: storageProof,
+		}
+	}
+
+	return proof, nil
+}
@@ -0,0 +1,114 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// localStateSource serves the state of a committed block of a State, whose
+// trie must still be in its database
+type localStateSource struct {
+	st   *State
+	head *SyncHead
+}
+
+// Head implements StateSource
+func (l *localStateSource) Head() (*SyncHead, error) {
+	return l.head, nil
+}
+
+// AccountRange implements StateSource
+func (l *localStateSource) AccountRange(root common.Hash, start common.Hash, limit int) (*AccountRange, error) {
+	return l.st.GetAccountRange(root, start, limit)
+}
+
+// StorageRange implements StateSource
+func (l *localStateSource) StorageRange(root common.Hash, account common.Address, start common.Hash, limit int) (*StorageRange, error) {
+	return l.st.GetStorageRange(root, account, start, limit)
+}
+
+//StateSourceAt returns a StateSource serving the state of a committed block,
+//e.g. to copy it into another database with SyncState
+func (s *State) StateSourceAt(number int64) (StateSource, error) {
+	block, err := s.GetBlockByNumber(number)
+	if err != nil {
+		return nil, fmt.Errorf("block %d not found", number)
+	}
+	return &localStateSource{
+		st: s,
+		head: &SyncHead{
+			Block:     number,
+			BlockHash: block.Hash(),
+			Root:      block.StateRoot,
+		},
+	}, nil
+}
+
+//StartReplay prepares an empty State to replay the blocks of source from the
+//given block, and returns the state root it starts from. From block 0, the
+//first consensus block, the State is initialized with the genesis, which must
+//be the one source was initialized with, if recorded. From a later block, the
+//state of the block before is copied from source, whose database must still
+//hold its trie.
+func (s *State) StartReplay(source *State, genesis *Genesis, from int64) (common.Hash, error) {
+	if from < 0 {
+		return common.Hash{}, fmt.Errorf("invalid first block %d", from)
+	}
+	if from > 0 {
+		src, err := source.StateSourceAt(from - 1)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		head, err := s.SyncState(src, MaxSyncRangeLimit)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		return head.Root, nil
+	}
+
+	info, err := s.InitGenesis(genesis)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	// A database initialized otherwise, e.g. by the Service on the first start,
+	// has no recorded genesis: the state root of block 0 is checked instead
+	if recorded, ok := source.GetGenesis(); ok && recorded.Root != info.Root {
+		return common.Hash{}, fmt.Errorf("genesis state root %s differs from the recorded genesis root %s",
+			info.Root.Hex(), recorded.Root.Hex())
+	}
+	return info.Root, nil
+}
+
+//ReplayFrom re-executes the committed blocks from..to of source, e.g. the
+//database of a stopped node, on the State prepared by StartReplay. Each block
+//must result in the header committed in source, state root included: the
+//first divergence is returned, and halts the State. A negative to is the last
+//committed block of source. It returns the number of blocks replayed.
+func (s *State) ReplayFrom(source *State, from, to int64, progress func(*Block)) (int, error) {
+	if head := source.GetBlockIndex(); to < 0 || to > head {
+		to = head
+	}
+
+	replayed := 0
+	for number := from; number <= to; {
+		blocks, err := source.GetSyncBlocks(number, int(to-number+1))
+		if err != nil {
+			return replayed, err
+		}
+		if len(blocks) == 0 {
+			return replayed, fmt.Errorf("block %d not found", number)
+		}
+		for _, b := range blocks {
+			if _, err := s.ReplayBlock(b); err != nil {
+				return replayed, err
+			}
+			replayed++
+			if progress != nil {
+				progress(b.Block)
+			}
+		}
+		number += int64(len(blocks))
+	}
+	return replayed, nil
+}
@@ -0,0 +1,125 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethState "github.com/ethereum/go-ethereum/core/state"
+	"github.com/sirupsen/logrus"
+)
+
+// safeModeKey records the reason the State entered safe mode, so that it stays
+// in safe mode across restarts until an operator clears it
+var safeModeKey = []byte("SafeMode")
+
+// safeModeSearchDepth is the number of blocks searched back for a readable
+// state root when the head root is corrupted
+const safeModeSearchDepth = 128
+
+// SafeModeError is returned while the State is in safe mode: it serves reads
+// from the last good root but refuses transactions and commits until an
+// operator clears it.
+type SafeModeError struct {
+	Reason string
+}
+
+func (e *SafeModeError) Error() string {
+	return fmt.Sprintf("in safe mode (%s), reads only until cleared", e.Reason)
+}
+
+// EnterSafeMode stops the State from accepting transactions and applying
+// blocks. It is recorded in the database, so it survives restarts.
+func (s *State) EnterSafeMode(reason string) error {
+	s.commitMutex.Lock()
+	defer s.commitMutex.Unlock()
+
+	return s.enterSafeMode(reason)
+}
+
+func (s *State) enterSafeMode(reason string) error {
+	if err := s.db.Put(safeModeKey, []byte(reason)); err != nil {
+		return err
+	}
+	s.halted = &SafeModeError{Reason: reason}
+	s.logger.WithFields(logrus.Fields{
+		"reason":      reason,
+		"block_index": s.blockIndex,
+	}).Warn("Entering safe mode")
+
+	return nil
+}
+
+// ExitSafeMode clears safe mode so that the State accepts transactions and
+// applies blocks again. It fails if the head state root was unreadable at
+// startup: the database must be repaired and the node restarted first.
+func (s *State) ExitSafeMode() error {
+	s.commitMutex.Lock()
+	defer s.commitMutex.Unlock()
+
+	if _, ok := s.halted.(*SafeModeError); !ok {
+		return nil
+	}
+	if s.fallbackRoot {
+		return fmt.Errorf("serving the state of an earlier block, repair the database and restart first")
+	}
+	if err := s.db.Delete(safeModeKey); err != nil {
+		return err
+	}
+	s.logger.WithField("block_index", s.blockIndex).Info("Exiting safe mode")
+	s.halted = nil
+	// A halt for upgrade recorded before safe mode still applies
+	s.loadHalt()
+
+	return nil
+}
+
+// InSafeMode returns whether the State is in safe mode
+func (s *State) InSafeMode() bool {
+	_, ok := s.halted.(*SafeModeError)
+	return ok
+}
+
+// checkSafeMode returns the safe mode error, if the State is in safe mode
+func (s *State) checkSafeMode() error {
+	if err, ok := s.halted.(*SafeModeError); ok {
+		return err
+	}
+	return nil
+}
+
+// loadSafeMode restores safe mode recorded in the database. It takes
+// precedence over a halt for upgrade.
+func (s *State) loadSafeMode() {
+	data, _ := s.db.Get(safeModeKey)
+	if len(data) != 0 {
+		s.halted = &SafeModeError{Reason: string(data)}
+	}
+}
+
+// recoverRoot enters safe mode when the head state root can't be opened, and
+// returns the state of the most recent block whose root is still readable
+func (s *State) recoverRoot(root common.Hash, cause error) (*ethState.StateDB, common.Hash, error) {
+	reason := fmt.Sprintf("state root %s unreadable: %s", root.Hex(), cause)
+	if err := s.enterSafeMode(reason); err != nil {
+		return nil, common.Hash{}, err
+	}
+
+	for number := s.blockIndex; number >= 0 && number > s.blockIndex-safeModeSearchDepth; number-- {
+		block, err := s.GetBlockByNumber(number)
+		if err != nil || block.StateRoot == root {
+			continue
+		}
+		st, err := ethState.New(block.StateRoot, ethState.NewDatabase(s.db))
+		if err != nil {
+			continue
+		}
+		s.fallbackRoot = true
+		s.logger.WithFields(logrus.Fields{
+			"block": number,
+			"root":  block.StateRoot.Hex(),
+		}).Warn("Serving the state of the last readable root")
+		return st, block.StateRoot, nil
+	}
+
+	return nil, common.Hash{}, fmt.Errorf("%s, and no readable root in the last %d blocks", reason, safeModeSearchDepth)
+}
@@ -0,0 +1,181 @@
+package state
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/sirupsen/logrus"
+)
+
+// SchemaVersion is the version of the layout of the database written by this
+// node. A database without a recorded version was written before versioning,
+// and is at version 0.
+const SchemaVersion = 1
+
+var schemaVersionKey = []byte("schema-version")
+
+// migration upgrades the layout of the database to version from the version
+// before. progress, if not nil, is called with the number of entries done.
+type migration struct {
+	version     int
+	description string
+	run         func(s *State, progress func(done int64)) error
+}
+
+// migrations are the upgrades of the layout of the database, in order. A
+// change of the layout adds one, and increments SchemaVersion.
+var migrations = []migration{
+	{1, "record the sender of the transaction lookup entries", migrateTxLookupSenders},
+}
+
+// Migration describes an upgrade of the layout of the database
+type Migration struct {
+	Version     int
+	Description string
+}
+
+//GetSchemaVersion returns the version of the layout of the database
+func (s *State) GetSchemaVersion() int {
+	data, err := s.db.Get(schemaVersionKey)
+	if err != nil {
+		return 0
+	}
+	var version uint64
+	if err := rlp.DecodeBytes(data, &version); err != nil {
+		return 0
+	}
+	return int(version)
+}
+
+func (s *State) putSchemaVersion(version int) error {
+	data, err := rlp.EncodeToBytes(uint64(version))
+	if err != nil {
+		return err
+	}
+	return s.db.Put(schemaVersionKey, data)
+}
+
+//PendingMigrations returns the migrations the database needs to reach
+//SchemaVersion. It fails if the database was written by a newer node.
+func (s *State) PendingMigrations() ([]Migration, error) {
+	version := s.GetSchemaVersion()
+	if version > SchemaVersion {
+		return nil, fmt.Errorf("the database has schema version %d, this node supports up to %d", version, SchemaVersion)
+	}
+	pending := []Migration{}
+	for _, m := range migrations {
+		if m.version > version {
+			pending = append(pending, Migration{Version: m.version, Description: m.description})
+		}
+	}
+	return pending, nil
+}
+
+//Migrate applies the pending migrations of the database, in order, recording
+//the version reached after each one, so an interrupted migration resumes from
+//there. The node must be stopped. progress, if not nil, is called during each
+//migration with the number of entries done.
+func (s *State) Migrate(progress func(m Migration, done int64)) error {
+	pending, err := s.PendingMigrations()
+	if err != nil {
+		return err
+	}
+	for _, p := range pending {
+		for _, m := range migrations {
+			if m.version != p.Version {
+				continue
+			}
+			s.logger.WithFields(logrus.Fields{
+				"version":   m.version,
+				"migration": m.description,
+			}).Info("Migrating database")
+
+			var report func(int64)
+			if progress != nil {
+				report = func(done int64) { progress(p, done) }
+			}
+			if err := m.run(s, report); err != nil {
+				return fmt.Errorf("migration %d (%s): %s", m.version, m.description, err)
+			}
+			if err := s.putSchemaVersion(m.version); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// migrateTxLookupSenders rewrites the transaction lookup entries indexed before
+// senders were recorded with the sender recovered from the transaction, so
+// that GetTxLookup no longer recovers it on every read
+func migrateTxLookupSenders(s *State, progress func(done int64)) error {
+	s.commitMutex.Lock()
+	defer s.commitMutex.Unlock()
+
+	batch := s.db.NewBatch()
+	var done int64
+	it := s.db.NewIterator()
+	defer it.Release()
+	for it.Next() {
+		key := it.Key()
+		if len(key) != common.HashLength+len(txMetaSuffix) || !bytes.HasSuffix(key, txMetaSuffix) {
+			continue
+		}
+		var entry TxLookupEntry
+		if err := rlp.DecodeBytes(it.Value(), &entry); err == nil {
+			continue
+		}
+		// Other keys may end like a lookup entry, e.g. prefixed hashes
+		var legacy legacyTxLookupEntry
+		if err := rlp.DecodeBytes(it.Value(), &legacy); err != nil {
+			continue
+		}
+
+		txHash := common.BytesToHash(key[:common.HashLength])
+		tx, err := s.GetTransaction(txHash)
+		if err != nil {
+			return fmt.Errorf("transaction %s: %s", txHash.Hex(), err)
+		}
+		from, err := ethTypes.Sender(s.signer, tx)
+		if err != nil {
+			return fmt.Errorf("sender of %s: %s", txHash.Hex(), err)
+		}
+		data, err := rlp.EncodeToBytes(&TxLookupEntry{
+			BlockHash:   legacy.BlockHash,
+			BlockNumber: legacy.BlockNumber,
+			Index:       legacy.Index,
+			From:        from,
+		})
+		if err != nil {
+			return err
+		}
+		if err := batch.Put(common.CopyBytes(key), data); err != nil {
+			return err
+		}
+
+		done++
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+			if progress != nil {
+				progress(done)
+			}
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	if progress != nil {
+		progress(done)
+	}
+	return nil
+}
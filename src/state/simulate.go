@@ -0,0 +1,122 @@
+package state
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// ErrGasEstimation is returned by EstimateGas when the message fails with all
+// the gas allowed
+var ErrGasEstimation = errors.New("gas required exceeds allowance or always failing transaction")
+
+// Simulation is the outcome of a message executed against a copy of the state
+type Simulation struct {
+	Return  []byte
+	GasUsed uint64
+	Failed  bool
+
+	// address of the contract created by the message, if any
+	ContractAddress *common.Address
+
+	Logs []*ethTypes.Log
+
+	// balance changes of the sender, the recipient and the created contract
+	BalanceChanges map[common.Address]*big.Int
+}
+
+//Simulate executes a message like ExecuteCall, and also returns its effects:
+//the logs it emits, the balances it changes and the contract it creates
+func (s *State) Simulate(msg ethTypes.Message) (*Simulation, error) {
+	s.commitMutex.Lock()
+	defer s.commitMutex.Unlock()
+
+	st := s.was.ethState.Copy()
+	st.Prepare(common.Hash{}, common.Hash{}, 0)
+
+	sim := &Simulation{
+		BalanceChanges: make(map[common.Address]*big.Int),
+	}
+	accounts := []common.Address{msg.From()}
+	if msg.To() != nil {
+		accounts = append(accounts, *msg.To())
+	} else {
+		addr := crypto.CreateAddress(msg.From(), st.GetNonce(msg.From()))
+		sim.ContractAddress = &addr
+		accounts = append(accounts, addr)
+	}
+	before := make([]*big.Int, len(accounts))
+	for i, addr := range accounts {
+		before[i] = st.GetBalance(addr)
+	}
+
+	vmenv := vm.NewEVM(s.was.newContext(msg), st, &s.chainConfig, s.vmConfig)
+	ret, gas, failed, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(s.gasLimit))
+	if err != nil {
+		return nil, err
+	}
+	sim.Return, sim.GasUsed, sim.Failed = ret, gas, failed
+	sim.Logs = st.GetLogs(common.Hash{})
+
+	for i, addr := range accounts {
+		change := new(big.Int).Sub(st.GetBalance(addr), before[i])
+		if change.Sign() != 0 {
+			sim.BalanceChanges[addr] = change
+		}
+	}
+	if failed {
+		sim.ContractAddress = nil
+	}
+
+	return sim, nil
+}
+
+//EstimateGas returns the minimum gas limit with which a message executes
+//successfully, up to the gas limit of the message. It is found by binary
+//search, executing the message against a single copy of the state.
+func (s *State) EstimateGas(msg ethTypes.Message) (uint64, error) {
+	s.commitMutex.Lock()
+	defer s.commitMutex.Unlock()
+
+	st := s.was.ethState.Copy()
+	context := s.was.newContext(msg)
+
+	// executable returns whether the message succeeds with the given gas
+	executable := func(gas uint64) (bool, error) {
+		m := ethTypes.NewMessage(msg.From(), msg.To(), msg.Nonce(), msg.Value(), gas, msg.GasPrice(), msg.Data(), msg.CheckNonce())
+		vmenv := vm.NewEVM(context, st.Copy(), &s.chainConfig, s.vmConfig)
+		_, _, failed, err := core.ApplyMessage(vmenv, m, new(core.GasPool).AddGas(s.gasLimit))
+		if err != nil {
+			return false, err
+		}
+		return !failed, nil
+	}
+
+	hi := msg.Gas()
+	ok, err := executable(hi)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, ErrGasEstimation
+	}
+
+	lo := params.TxGas - 1
+	for lo+1 < hi {
+		mid := lo + (hi-lo)/2
+		// Errors below the allowance (e.g. intrinsic gas too low) mean the
+		// gas is not enough
+		if ok, err := executable(mid); err != nil || !ok {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return hi, nil
+}
@@ -0,0 +1,51 @@
+package state
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	ethState "github.com/ethereum/go-ethereum/core/state"
+)
+
+// WatchedSlot is a storage slot of a contract
+type WatchedSlot struct {
+	Address common.Address
+	Slot    common.Hash
+}
+
+// SlotChange is the change of a storage slot between two state roots
+type SlotChange struct {
+	Address common.Address
+	Slot    common.Hash
+	Old     common.Hash
+	New     common.Hash
+}
+
+//GetSlotChanges returns the slots, among the given ones, whose value differs
+//between two committed state roots, e.g. the ParentRoot and Root of a
+//CommitInfo
+func (s *State) GetSlotChanges(parentRoot, root common.Hash, slots []WatchedSlot) ([]SlotChange, error) {
+	db := s.ethState.Database()
+	oldState, err := ethState.New(parentRoot, db)
+	if err != nil {
+		return nil, err
+	}
+	newState, err := ethState.New(root, db)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []SlotChange{}
+	for _, slot := range slots {
+		oldValue := oldState.GetState(slot.Address, slot.Slot)
+		newValue := newState.GetState(slot.Address, slot.Slot)
+		if oldValue != newValue {
+			changes = append(changes, SlotChange{
+				Address: slot.Address,
+				Slot:    slot.Slot,
+				Old:     oldValue,
+				New:     newValue,
+			})
+		}
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,498 @@
+package state
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethState "github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// SnapshotManifestFile is the file of a snapshot directory listing its
+	// shards
+	SnapshotManifestFile = "manifest.json"
+
+	// DefaultSnapshotShards is the default number of ranges the account trie
+	// is split into, by first nibble of the hashed keys
+	DefaultSnapshotShards = 16
+
+	snapshotVersion = 1
+)
+
+var (
+	emptyCodeHash = crypto.Keccak256(nil)
+
+	// importedHeadKey records the block of the last imported or synced state,
+	// before which the database holds no blocks
+	importedHeadKey = []byte("ImportedBlock")
+)
+
+// SnapshotManifest describes a snapshot of the state at a committed block.
+// Each shard holds the accounts of a range of the account trie.
+type SnapshotManifest struct {
+	Version   int             `json:"version"`
+	Block     int64           `json:"block"`
+	BlockHash common.Hash     `json:"blockHash"`
+	Root      common.Hash     `json:"root"`
+	Shards    []SnapshotShard `json:"shards"`
+}
+
+// SnapshotShard is a file of a snapshot holding the accounts whose hashed key
+// is in [Start, End), with their code and storage
+type SnapshotShard struct {
+	File     string        `json:"file"`
+	Start    hexutil.Bytes `json:"start"`
+	End      hexutil.Bytes `json:"end,omitempty"` // none for the last shard
+	Accounts uint64        `json:"accounts"`
+	Slots    uint64        `json:"slots"`
+	Checksum common.Hash   `json:"checksum"` // sha256 of the file
+}
+
+// snapshotAccount is an entry of a shard file, which is a stream of them in
+// trie order. Account is the account as encoded in the trie.
+type snapshotAccount struct {
+	Address common.Address
+	Account []byte
+	Code    []byte
+	Storage []snapshotSlot
+}
+
+type snapshotSlot struct {
+	Key   common.Hash
+	Value []byte // as encoded in the storage trie
+}
+
+// shardRange returns the range of hashed keys of a shard, split by first byte
+func shardRange(i int, shards int) (hexutil.Bytes, hexutil.Bytes) {
+	start := hexutil.Bytes{byte(i * 256 / shards)}
+	if i == shards-1 {
+		return start, nil
+	}
+	return start, hexutil.Bytes{byte((i + 1) * 256 / shards)}
+}
+
+// runShards calls fn for each shard on parallel workers and returns the first
+// error
+func runShards(shards int, workers int, fn func(int) error) error {
+	jobs := make(chan int, shards)
+	for i := 0; i < shards; i++ {
+		jobs <- i
+	}
+	close(jobs)
+
+	errs := make(chan error, workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			for i := range jobs {
+				if err := fn(i); err != nil {
+					errs <- fmt.Errorf("shard %d: %s", i, err)
+					return
+				}
+			}
+			errs <- nil
+		}()
+	}
+
+	var first error
+	for w := 0; w < workers; w++ {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+//ExportSnapshot writes the state of the last committed block to a directory:
+//one file per range of the account trie, exported by parallel workers, and a
+//manifest. Commits may go on meanwhile; the snapshot is of the root read at
+//the start.
+func (s *State) ExportSnapshot(dir string, shards int, workers int) (*SnapshotManifest, error) {
+	if shards < 1 || shards > 256 {
+		return nil, fmt.Errorf("the number of shards must be between 1 and 256")
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	s.commitMutex.Lock()
+	manifest := &SnapshotManifest{
+		Version: snapshotVersion,
+		Block:   s.blockIndex,
+		Root:    ethTypes.EmptyRootHash,
+		Shards:  make([]SnapshotShard, shards),
+	}
+	if data, err := s.db.Get(rootKey); err == nil {
+		manifest.Root = common.BytesToHash(data)
+	}
+	s.commitMutex.Unlock()
+
+	if data, err := s.reader.Get(blockHashKey(manifest.Block)); err == nil {
+		manifest.BlockHash = common.BytesToHash(data)
+	}
+	for i := range manifest.Shards {
+		start, end := shardRange(i, shards)
+		manifest.Shards[i] = SnapshotShard{
+			File:  fmt.Sprintf("shard-%03d.rlp", i),
+			Start: start,
+			End:   end,
+		}
+	}
+
+	db := ethState.NewDatabase(s.db)
+	err := runShards(shards, workers, func(i int) error {
+		shard := &manifest.Shards[i]
+		if err := exportShard(db, manifest.Root, dir, shard); err != nil {
+			return err
+		}
+		s.logger.WithFields(logrus.Fields{
+			"shard":    i,
+			"accounts": shard.Accounts,
+			"slots":    shard.Slots,
+		}).Info("Exported snapshot shard")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, SnapshotManifestFile), data, 0644); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// exportShard writes the accounts of a range of the account trie to the file
+// of the shard, and records their count and the checksum of the file
+func exportShard(db ethState.Database, root common.Hash, dir string, shard *SnapshotShard) error {
+	tr, err := db.OpenTrie(root)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(filepath.Join(dir, shard.File))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sum := sha256.New()
+	w := bufio.NewWriter(io.MultiWriter(f, sum))
+
+	it := trie.NewIterator(tr.NodeIterator(shard.Start))
+	for it.Next() {
+		if len(shard.End) > 0 && bytes.Compare(it.Key, shard.End) >= 0 {
+			break
+		}
+		entry, err := exportAccount(db, tr, it.Key, it.Value)
+		if err != nil {
+			return err
+		}
+		if err := rlp.Encode(w, entry); err != nil {
+			return err
+		}
+		shard.Accounts++
+		shard.Slots += uint64(len(entry.Storage))
+	}
+	if it.Err != nil {
+		return it.Err
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	copy(shard.Checksum[:], sum.Sum(nil))
+	return f.Sync()
+}
+
+// exportAccount reads the code and storage of an account of the trie
+func exportAccount(db ethState.Database, tr ethState.Trie, key []byte, blob []byte) (*snapshotAccount, error) {
+	preimage := tr.GetKey(key)
+	if preimage == nil {
+		return nil, fmt.Errorf("no preimage for account %x", key)
+	}
+	var account ethState.Account
+	if err := rlp.DecodeBytes(blob, &account); err != nil {
+		return nil, err
+	}
+
+	entry := &snapshotAccount{
+		Address: common.BytesToAddress(preimage),
+		Account: common.CopyBytes(blob),
+	}
+	addrHash := common.BytesToHash(key)
+	if !bytes.Equal(account.CodeHash, emptyCodeHash) {
+		code, err := db.ContractCode(addrHash, common.BytesToHash(account.CodeHash))
+		if err != nil {
+			return nil, fmt.Errorf("code of %s: %s", entry.Address.Hex(), err)
+		}
+		entry.Code = code
+	}
+	if account.Root == ethTypes.EmptyRootHash {
+		return entry, nil
+	}
+
+	st, err := db.OpenStorageTrie(addrHash, account.Root)
+	if err != nil {
+		return nil, fmt.Errorf("storage of %s: %s", entry.Address.Hex(), err)
+	}
+	it := trie.NewIterator(st.NodeIterator(nil))
+	for it.Next() {
+		slot := st.GetKey(it.Key)
+		if slot == nil {
+			return nil, fmt.Errorf("no preimage for slot %x of %s", it.Key, entry.Address.Hex())
+		}
+		entry.Storage = append(entry.Storage, snapshotSlot{
+			Key:   common.BytesToHash(slot),
+			Value: common.CopyBytes(it.Value),
+		})
+	}
+	return entry, it.Err
+}
+
+//ReadSnapshotManifest reads the manifest of a snapshot directory
+func ReadSnapshotManifest(dir string) (*SnapshotManifest, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, SnapshotManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	manifest := new(SnapshotManifest)
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("parsing %s: %s", SnapshotManifestFile, err)
+	}
+	if manifest.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", manifest.Version)
+	}
+	return manifest, nil
+}
+
+//VerifySnapshot checks the shard files of a snapshot directory against the
+//checksums of its manifest
+func VerifySnapshot(dir string) (*SnapshotManifest, error) {
+	manifest, err := ReadSnapshotManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, shard := range manifest.Shards {
+		f, err := os.Open(filepath.Join(dir, shard.File))
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.New()
+		_, err = io.Copy(sum, f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		if common.BytesToHash(sum.Sum(nil)) != shard.Checksum {
+			return nil, fmt.Errorf("checksum of %s does not match the manifest", shard.File)
+		}
+	}
+	return manifest, nil
+}
+
+//ImportSnapshot restores a snapshot written by ExportSnapshot into an empty
+//State, importing the shards on parallel workers. The rebuilt state must match
+//the root of the manifest. The State then resumes from the block of the
+//snapshot; the blocks, transactions and receipts before it are not part of
+//the snapshot.
+func (s *State) ImportSnapshot(dir string, workers int) (*SnapshotManifest, error) {
+	s.commitMutex.Lock()
+	defer s.commitMutex.Unlock()
+
+	if _, err := s.db.Get(rootKey); err == nil {
+		return nil, fmt.Errorf("the database is not empty")
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	manifest, err := ReadSnapshotManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	triedb := trie.NewDatabase(s.db)
+	accounts, err := trie.NewSecure(common.Hash{}, triedb, 0)
+	if err != nil {
+		return nil, err
+	}
+	var accountsMutex sync.Mutex
+	addAccount := func(addr common.Address, blob []byte) error {
+		accountsMutex.Lock()
+		defer accountsMutex.Unlock()
+		return accounts.TryUpdate(addr.Bytes(), blob)
+	}
+
+	err = runShards(len(manifest.Shards), workers, func(i int) error {
+		shard := &manifest.Shards[i]
+		if err := importShard(s.db, triedb, dir, shard, addAccount); err != nil {
+			return err
+		}
+		s.logger.WithFields(logrus.Fields{
+			"shard":    i,
+			"accounts": shard.Accounts,
+			"slots":    shard.Slots,
+		}).Info("Imported snapshot shard")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := accounts.Commit(nil)
+	if err != nil {
+		return nil, err
+	}
+	if root != manifest.Root {
+		return nil, fmt.Errorf("imported state root %s does not match the snapshot root %s", root.Hex(), manifest.Root.Hex())
+	}
+	if err := triedb.Commit(root, false); err != nil {
+		return nil, err
+	}
+
+	if err := s.writeImportedHead(manifest.Block, manifest.BlockHash, root); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// writeImportedHead makes the state of an imported or synced block the head
+// of the State, which resumes from it
+func (s *State) writeImportedHead(block int64, blockHash common.Hash, root common.Hash) error {
+	batch := s.db.NewBatch()
+	if blockHash != (common.Hash{}) {
+		if err := batch.Put(blockHashKey(block), blockHash.Bytes()); err != nil {
+			return err
+		}
+	}
+	if err := batch.Put(headBlockKey, encodeBlockIndex(block)); err != nil {
+		return err
+	}
+	if err := batch.Put(importedHeadKey, encodeBlockIndex(block)); err != nil {
+		return err
+	}
+	if err := batch.Put(rootKey, root.Bytes()); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+
+	if err := s.InitState(); err != nil {
+		return err
+	}
+	s.resetWAS()
+	return nil
+}
+
+// importShard rebuilds the code and storage of the accounts of a shard file
+// and passes the accounts to addAccount. The file must match the manifest.
+func importShard(db ethdb.Database, triedb *trie.Database, dir string, shard *SnapshotShard,
+	addAccount func(common.Address, []byte) error) error {
+
+	f, err := os.Open(filepath.Join(dir, shard.File))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sum := sha256.New()
+	stream := rlp.NewStream(io.TeeReader(bufio.NewReader(f), sum), 0)
+	batch := db.NewBatch()
+	accounts, slots := uint64(0), uint64(0)
+	for {
+		entry := new(snapshotAccount)
+		if err := stream.Decode(entry); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if err := importAccount(batch, triedb, entry); err != nil {
+			return fmt.Errorf("account %s: %s", entry.Address.Hex(), err)
+		}
+		if err := addAccount(entry.Address, entry.Account); err != nil {
+			return err
+		}
+		accounts++
+		slots += uint64(len(entry.Storage))
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+
+	if common.BytesToHash(sum.Sum(nil)) != shard.Checksum {
+		return fmt.Errorf("checksum of %s does not match the manifest", shard.File)
+	}
+	if accounts != shard.Accounts || slots != shard.Slots {
+		return fmt.Errorf("%s holds %d accounts and %d slots, the manifest %d and %d",
+			shard.File, accounts, slots, shard.Accounts, shard.Slots)
+	}
+	return nil
+}
+
+// importAccount writes the code of an account and rebuilds its storage trie
+func importAccount(batch ethdb.Batch, triedb *trie.Database, entry *snapshotAccount) error {
+	var account ethState.Account
+	if err := rlp.DecodeBytes(entry.Account, &account); err != nil {
+		return err
+	}
+
+	if !bytes.Equal(account.CodeHash, emptyCodeHash) {
+		if crypto.Keccak256Hash(entry.Code) != common.BytesToHash(account.CodeHash) {
+			return fmt.Errorf("code does not match the code hash")
+		}
+		if err := batch.Put(account.CodeHash, entry.Code); err != nil {
+			return err
+		}
+	}
+
+	st, err := trie.NewSecure(common.Hash{}, triedb, 0)
+	if err != nil {
+		return err
+	}
+	for _, slot := range entry.Storage {
+		if err := st.TryUpdate(slot.Key.Bytes(), slot.Value); err != nil {
+			return err
+		}
+	}
+	root, err := st.Commit(nil)
+	if err != nil {
+		return err
+	}
+	if root != account.Root {
+		return fmt.Errorf("storage root %s does not match %s", root.Hex(), account.Root.Hex())
+	}
+	if len(entry.Storage) == 0 {
+		return nil
+	}
+	return triedb.Commit(root, false)
+}
@@ -1,38 +1,46 @@
 package state
 
 import (
-	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"math/big"
 	"sync"
-	"syscall"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/core"
 	ethState "github.com/ethereum/go-ethereum/core/state"
 	ethTypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	bcommon "github.com/Fantom-foundation/go-evm/src/common"
 	"github.com/Fantom-foundation/go-lachesis/src/poset"
 )
 
+// DefaultChainID is the EIP-155 chain id of the transactions, unless set with
+// SetChainID
+const DefaultChainID = 1
+
 var (
-	chainID        = big.NewInt(1)
-	gasLimit       = big.NewInt(1000000000000000000)
+	chainID        = big.NewInt(DefaultChainID)
 	txMetaSuffix   = []byte{0x01}
 	receiptsPrefix = []byte("receipts-")
 	errorPrefix    = []byte("errors-")
+	revertPrefix   = []byte("revert-")
+	appliedPrefix  = []byte("applied-")
 	MIPMapLevels   = []uint64{1000000, 500000, 100000, 50000, 1000}
 	headTxKey      = []byte("LastTx")
+	headBlockKey   = []byte("LastBlock")
 	rootKey        = []byte("root")
 )
 
@@ -42,6 +50,7 @@ var (
 	roundPrefix       = "round"
 	topoPrefix        = "topo"
 	blockPrefix       = "block"
+	blockHashPrefix   = "blockhash"
 	framePrefix       = "frame"
 )
 
@@ -49,14 +58,101 @@ func blockKey(index int64) []byte {
 	return []byte(fmt.Sprintf("%s_%09d", blockPrefix, index))
 }
 
+func txLookupKey(txHash common.Hash) []byte {
+	return append(txHash.Bytes(), txMetaSuffix...)
+}
+
+// appliedBlockKey maps the consensus hash of an applied block to the state
+// root it resulted in
+func appliedBlockKey(consensusHash common.Hash) []byte {
+	return append(append([]byte{}, appliedPrefix...), consensusHash.Bytes()...)
+}
+
+func blockHashKey(index int64) []byte {
+	return []byte(fmt.Sprintf("%s_%09d", blockHashPrefix, index))
+}
+
+func encodeBlockIndex(index int64) []byte {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, uint64(index))
+	return enc
+}
+
+func decodeBlockIndex(data []byte) int64 {
+	return int64(binary.BigEndian.Uint64(data))
+}
+
+// getHashFn returns a vm.GetHashFunc which resolves block indexes to the
+// hashes of the Blocks recorded on Commit. It backs the BLOCKHASH opcode.
+func getHashFn(db DatabaseReader) vm.GetHashFunc {
+	return func(index uint64) common.Hash {
+		data, err := db.Get(blockHashKey(int64(index)))
+		if err != nil {
+			return common.Hash{}
+		}
+		return common.BytesToHash(data)
+	}
+}
+
 type State struct {
 	db          ethdb.Database
+	archives    []*archiveDB
+	reader      DatabaseReader // db federated with the archives
 	commitMutex sync.Mutex
 	ethState    *ethState.StateDB
 	was         *WriteAheadState
 	txPool      *TxPool
 	blockIndex  int64
 
+	// optional commit-time invariant checks. halted is set to the violation
+	// that stopped the State from applying anything else
+	invariants *invariantChecker
+	halted     error
+
+	// height after which the State halts for an upgrade. 0 when unset
+	haltHeight int64
+
+	// whether the head state root was unreadable and the State serves the
+	// state of an earlier block, in safe mode
+	fallbackRoot bool
+
+	// policy capping the gas of transactions to specific contracts
+	gasCaps *GasCapPolicy
+
+	// policy distributing the transaction fees of each block
+	feePolicy *FeePolicy
+
+	// maximum size of the contracts deployed by transactions
+	codeLimits CodeSizeLimits
+
+	// parameters of the network set by the config section of the genesis:
+	// the gas limit of a block and the minimum gas price (nil for none)
+	genesisConfig *GenesisConfig
+	gasLimit      uint64
+	minGasPrice   *big.Int
+
+	// number of blocks the blob data of transactions is kept. 0 when unset
+	blobRetention int64
+
+	// guards the sequence of the dead-letter queue
+	deadLetterMutex sync.Mutex
+
+	// when the block being processed was received from the consensus
+	// system, for the commit latency. Zero outside of ProcessBlock
+	blockReceived time.Time
+
+	// blocks delivered by the consensus engine and not yet committed
+	bp backpressure
+
+	history commitHistory
+
+	// commits waiting to be sent to the subscribers of commitFeed
+	commitFeed event.Feed
+	commits    chan CommitInfo
+
+	// queued transactions promoted by a Commit, waiting to be submitted
+	promotedTxs chan *ethTypes.Transaction
+
 	signer      ethTypes.Signer
 	chainConfig params.ChainConfig //vm.env is still tightly coupled with chainConfig
 	vmConfig    vm.Config
@@ -64,62 +160,138 @@ type State struct {
 	logger *logrus.Logger
 }
 
-func NewState(logger *logrus.Logger, dbFile string, dbCache int) (*State, error) {
+func NewState(logger *logrus.Logger, dbFile string, dbCache int, archiveDirs []string) (*State, error) {
 
 	handles, err := getFdLimit()
 	if err != nil {
 		return nil, err
 	}
 
-	db, err := ethdb.NewLDBDatabase(dbFile, dbCache, handles)
+	ldb, err := ethdb.NewLDBDatabase(dbFile, dbCache, handles)
 	if err != nil {
 		return nil, err
 	}
+	db := &meteredDB{ldb}
+
+	// The databases opened are closed if the State can't be created
+	archives := []*archiveDB{}
+	closeDBs := func() {
+		for _, archive := range archives {
+			if err := archive.Close(); err != nil {
+				logger.WithError(err).WithField("archive", archive.path).Error("Closing archive")
+			}
+		}
+		db.Close()
+	}
+	for _, dir := range archiveDirs {
+		archive, err := openArchive(dir, dbCache, handles)
+		if err != nil {
+			closeDBs()
+			return nil, fmt.Errorf("opening archive %s: %s", dir, err)
+		}
+		logger.WithField("archive", dir).Debug("Opened archive")
+		archives = append(archives, archive)
+	}
 
 	s := &State{
 		db:          db,
+		archives:    archives,
+		reader:      &federatedReader{main: db, archives: archives},
 		signer:      ethTypes.NewEIP155Signer(chainID),
-		chainConfig: params.ChainConfig{ChainID: chainID},
+		chainConfig: DefaultChainConfig(),
 		vmConfig:    vm.Config{Tracer: vm.NewStructLogger(nil)},
+		codeLimits:  DefaultCodeSizeLimits(),
+		gasLimit:    DefaultGasLimit,
+		promotedTxs: make(chan *ethTypes.Transaction, DefaultGlobalSlots),
+		commits:     make(chan CommitInfo, commitBacklog),
 		logger:      logger,
 	}
 
 	if err := s.InitState(); err != nil {
+		closeDBs()
 		return nil, err
 	}
 
+	// A new database is written with the current layout
+	if s.IsEmpty() {
+		if err := s.putSchemaVersion(SchemaVersion); err != nil {
+			s.txPool.Close()
+			closeDBs()
+			return nil, err
+		}
+	}
+
 	s.resetWAS()
 
+	go s.dispatchCommits()
+
 	return s, nil
 }
 
-// getFdLimit retrieves the number of file descriptors allowed to be opened by this
-// process.
-func getFdLimit() (int, error) {
-	var limit syscall.Rlimit
-	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil {
-		return 0, err
+// EnableInvariantChecks turns on the verification of supply conservation, nonce
+// monotonicity and balance positivity after every Commit. A violation halts the
+// State: Commit returns an *InvariantError and no further transactions are
+// applied.
+func (s *State) EnableInvariantChecks() error {
+	s.commitMutex.Lock()
+	defer s.commitMutex.Unlock()
+
+	checker, err := newInvariantChecker(s.ethState)
+	if err != nil {
+		return err
+	}
+	s.invariants = checker
+	s.logger.Info("Commit-time invariant checks enabled")
+
+	return nil
+}
+
+// ErrClosed is returned for the blocks and transactions delivered after the
+// State was closed
+var ErrClosed = errors.New("state closed")
+
+//Close waits for the block being processed to be committed, then closes the
+//database and the archives. The blocks delivered afterwards are refused, so
+//that a shutdown never leaves a block half written.
+func (s *State) Close() {
+	s.commitMutex.Lock()
+	defer s.commitMutex.Unlock()
+
+	if s.halted == ErrClosed {
+		return
 	}
-	return int(limit.Cur), nil
+	s.halted = ErrClosed
+
+	s.txPool.Close()
+	for _, archive := range s.archives {
+		if err := archive.Close(); err != nil {
+			s.logger.WithError(err).WithField("archive", archive.path).Error("Closing archive")
+		}
+	}
+	s.db.Close()
+	s.logger.WithField("block_index", s.blockIndex).Info("State closed")
 }
 
 //------------------------------------------------------------------------------
 
-func (s *State) Call(callMsg ethTypes.Message) ([]byte, error) {
+func (s *State) Call(ctx context.Context, callMsg ethTypes.Message) ([]byte, error) {
+	res, _, _, err := s.ExecuteCall(ctx, callMsg)
+	return res, err
+}
+
+//ExecuteCall is like Call but also returns the gas used and whether the
+//execution failed (e.g. reverted)
+func (s *State) ExecuteCall(ctx context.Context, callMsg ethTypes.Message) (res []byte, gas uint64, failed bool, err error) {
+	_, span := otelTracer.Start(ctx, "State.ExecuteCall")
+	defer func() { endSpan(span, err) }()
+
 	s.logger.Debug("Call")
 	s.commitMutex.Lock()
 	defer s.commitMutex.Unlock()
+	defer callDuration.Since(time.Now())
 
-	context := vm.Context{
-		CanTransfer: core.CanTransfer,
-		Transfer:    core.Transfer,
-		GetHash:     func(uint64) common.Hash { return common.Hash{} },
-		// Message information
-		Origin:      callMsg.From(),
-		GasLimit:    callMsg.Gas(),
-		GasPrice:    callMsg.GasPrice(),
-		BlockNumber: big.NewInt(0), //the vm has a dependency on this..
-	}
+	// Calls execute in the context of the block being applied
+	vmContext := s.was.newContext(callMsg)
 
 	s.logger.WithField("From", callMsg.From().Hex()).Debug("Call(callMsg ethTypes.Message)")
 	s.logger.WithField("To", callMsg.To().Hex()).Debug("Call(callMsg ethTypes.Message)")
@@ -128,36 +300,107 @@ func (s *State) Call(callMsg ethTypes.Message) ([]byte, error) {
 	// The EVM should never be reused and is not thread safe.
 	// Call is done on a copy of the state...we don't want any changes to be persisted
 	// Call is a readonly operation
-	vmenv := vm.NewEVM(context, s.was.ethState.Copy(), &s.chainConfig, s.vmConfig)
+	vmenv := vm.NewEVM(vmContext, s.was.ethState.Copy(), &s.chainConfig, s.vmConfig)
 
 	// Apply the transaction to the current state (included in the env)
-	res, gas, failed, err := core.ApplyMessage(vmenv, callMsg, new(core.GasPool).AddGas(gasLimit.Uint64()))
+	res, gas, failed, err = core.ApplyMessage(vmenv, callMsg, new(core.GasPool).AddGas(s.gasLimit))
 	if err != nil {
 		s.logger.WithError(err).Error("Executing Call on WAS")
-		return nil, err
+		return nil, 0, false, err
 	}
 	s.logger.WithField("Failed", failed).Debug("Call(callMsg ethTypes.Message)")
 	s.logger.WithField("Res", res).Debug("Call(callMsg ethTypes.Message)")
 	s.logger.WithField("Gas", gas).Debug("Call(callMsg ethTypes.Message)")
 
-	return res, err
+	return res, gas, failed, err
+}
+
+//ExecuteCallAt is like ExecuteCall, but executes the call on the state
+//committed by the block with the given number, in the context of that block.
+//The state root of the block must still be in the database.
+func (s *State) ExecuteCallAt(ctx context.Context, callMsg ethTypes.Message, number int64) (res []byte, gas uint64, failed bool, err error) {
+	_, span := otelTracer.Start(ctx, "State.ExecuteCallAt", trace.WithAttributes(attribute.Int64("block", number)))
+	defer func() { endSpan(span, err) }()
+	defer callDuration.Since(time.Now())
+
+	block, err := s.GetBlockByNumber(number)
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("block %d not found", number)
+	}
+	st, err := s.StateAt(block.StateRoot)
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("state of block %d is not available: %s", number, err)
+	}
+
+	s.commitMutex.Lock()
+	chainConfig := s.chainConfig
+	s.commitMutex.Unlock()
+
+	vmContext := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		GetHash:     getHashFn(s.reader),
+		Origin:      callMsg.From(),
+		GasLimit:    callMsg.Gas(),
+		GasPrice:    callMsg.GasPrice(),
+		BlockNumber: big.NewInt(number),
+		Time:        new(big.Int).SetUint64(block.Timestamp),
+		Difficulty:  new(big.Int),
+	}
+	vmenv := vm.NewEVM(vmContext, st, &chainConfig, vm.Config{})
+
+	res, gas, failed, err = core.ApplyMessage(vmenv, callMsg, new(core.GasPool).AddGas(s.gasLimit))
+	if err != nil {
+		s.logger.WithError(err).WithField("block", number).Debug("Executing Call")
+		return nil, 0, false, err
+	}
+	return res, gas, failed, nil
 }
 
 func (s *State) GetBlockIndex() int64 {
 	return s.blockIndex
 }
 
-func (s *State) ProcessBlock(block poset.Block) (common.Hash, error) {
+//GetGasLimit returns the gas limit of a block
+func (s *State) GetGasLimit() uint64 {
+	return s.gasLimit
+}
+
+func (s *State) ProcessBlock(block poset.Block) (root common.Hash, err error) {
+	received := time.Now()
+	// Blocks are handed over by the consensus system, so each one starts a trace
+	ctx, span := otelTracer.Start(context.Background(), "State.ProcessBlock",
+		trace.WithAttributes(attribute.Int64("block", block.Index()),
+			attribute.Int("txs", len(block.Transactions()))))
+	defer func() { endSpan(span, err) }()
+
 	s.logger.Debug("Process Block")
 	s.commitMutex.Lock()
 	defer s.commitMutex.Unlock()
 
+	enterCommitPath()
+	defer s.exitCommitPath()
+
 	blockIndex := block.Index()
 	hash, _ := block.BlockHash()
 	blockHash := common.BytesToHash(hash)
 
-	if block.GetCreatedTime() == 0 {
-		block.CreatedTime = time.Now().Unix()
+	// TIMESTAMP is the time of the block given by Lachesis, so that it is the
+	// same on every node
+	block.CreatedTime = s.blockTimestamp(block.GetCreatedTime())
+
+	// A block replayed by the consensus system (e.g. after a reconnection) is
+	// not applied twice: the root it resulted in is returned right away
+	if root, ok := s.AppliedBlock(blockHash); ok {
+		s.logger.WithFields(logrus.Fields{
+			"block_index": blockIndex,
+			"root":        root.Hex(),
+		}).Info("Block already applied. Skipping")
+		return root, nil
+	}
+
+	if s.halted != nil {
+		return common.Hash{}, s.halted
 	}
 
 	blockMarshal, _ := block.ProtoMarshal()
@@ -166,7 +409,11 @@ func (s *State) ProcessBlock(block poset.Block) (common.Hash, error) {
 	s.logger.WithField("blockIndex", blockIndex).Debug("ProcessBlock(block poset.Block)")
 	s.logger.WithField("blockHash", block.BlockHex()).Debug("ProcessBlock(block poset.Block)")
 
-	s.blockIndex = blockIndex
+	s.was.blockIndex = blockIndex
+	// Lachesis blocks have no proposer: the fees go to the default coinbase
+	s.was.startBlock(blockHash, block.GetCreatedTime(), common.Address{})
+	s.blockReceived = received
+	defer func() { s.blockReceived = time.Time{} }()
 
 	if err := s.db.Put(hash, blockMarshal); err != nil {
 		return common.Hash{}, err
@@ -177,12 +424,12 @@ func (s *State) ProcessBlock(block poset.Block) (common.Hash, error) {
 
 	for txIndex, txBytes := range block.Transactions() {
 		// Block is valid, don't exit just because of transactions
-		if err := s.applyTransaction(txBytes, txIndex, blockHash); err != nil {
-			s.logger.WithError(err).Error("s.applyTransaction(txBytes, txIndex, blockHash);")
+		if err := s.applyTransaction(ctx, txBytes, txIndex, blockHash); err != nil {
+			s.logger.WithError(err).WithField("class", ErrorClass(err)).Warn("Skipping transaction")
 		}
 	}
 
-	return s.Commit()
+	return s.Commit(ctx)
 }
 
 //++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
@@ -259,47 +506,21 @@ func (s *State) PrintTransaction(tx *ethTypes.Transaction) string {
 }
 
 //applyTransaction applies a transaction to the WAS
-func (s *State) applyTransaction(txBytes []byte, txIndex int, blockHash common.Hash) error {
+func (s *State) applyTransaction(ctx context.Context, txBytes []byte, txIndex int, blockHash common.Hash) (err error) {
+	ctx, span := otelTracer.Start(ctx, "State.ApplyTransaction", trace.WithAttributes(attribute.Int("index", txIndex)))
+	defer func() { endSpan(span, err) }()
 
-	var t ethTypes.Transaction
-	if err := rlp.Decode(bytes.NewReader(txBytes), &t); err != nil {
-		s.logger.WithError(err).Error("Decoding Transaction")
+	tx, blob, err := DecodeTx(txBytes)
+	if err != nil {
+		txsSkipped.Inc()
 		return err
 	}
+	t := *tx
+	span.SetAttributes(attribute.String("hash", t.Hash().Hex()))
 	s.logger.WithField("hash", t.Hash().Hex()).Debug("Decoded tx")
 	s.logger.WithField("tx", s.PrintTransaction(&t)).Debug("Decoded tx")
 
-	msg, err := t.AsMessage(s.signer)
-	if err != nil {
-		s.logger.WithError(err).Error("Converting Transaction to Message")
-		return err
-	}
-
-	context := vm.Context{
-		CanTransfer: core.CanTransfer,
-		Transfer:    core.Transfer,
-		GetHash:     func(uint64) common.Hash { return blockHash },
-		// Message information
-		Origin:      msg.From(),
-		GasLimit:    msg.Gas(),
-		GasPrice:    msg.GasPrice(),
-		BlockNumber: big.NewInt(0), //the vm has a dependency on this..
-	}
-	s.logger.WithFields(logrus.Fields{
-		"GasLimit": msg.Gas(),
-		"s.was.gp": s.was.gp,
-	}).Debug("state.ApplyTransaction")
-
-	//Prepare the ethState with transaction Hash so that it can be used in emitted
-	//logs
-	s.was.ethState.Prepare(t.Hash(), blockHash, txIndex)
-
-	// The EVM should never be reused and is not thread safe.
-	vmenv := vm.NewEVM(context, s.was.ethState, &s.chainConfig, s.vmConfig)
-
-	// Apply the transaction to the current state (included in the env)
-	_, gas, failed, err := core.ApplyMessage(vmenv, msg, s.was.gp)
-	if err != nil {
+	if err := s.was.applyTransaction(ctx, t, blob, blockHash); err != nil {
 		txError := TxError{
 			Tx:    t,
 			Error: err.Error(),
@@ -309,46 +530,42 @@ func (s *State) applyTransaction(txBytes []byte, txIndex int, blockHash common.H
 		if err := s.db.Put(append(errorPrefix, txHash[:]...), txErrorMarshal); err != nil {
 			s.logger.WithError(err).Error("s.db.Put")
 		}
-		s.logger.WithError(err).Error("Applying transaction to State")
+		txsSkipped.Inc()
 		return err
 	}
 
-	s.was.totalUsedGas.Add(s.was.totalUsedGas, big.NewInt(0).SetUint64(gas))
-
-	// Create a new receipt for the transaction, storing the intermediate root and gas used by the tx
-	// based on the eip phase, we're passing whether the root touch-delete accounts.
-	root := s.was.ethState.IntermediateRoot(true) //this has side effects. It updates StateObjects (SmartContract memory)
-	receipt := ethTypes.NewReceipt(root.Bytes(), failed, bcommon.BigintToUInt64(s.was.totalUsedGas))
-	receipt.TxHash = t.Hash()
-	receipt.GasUsed = gas
-	// if the transaction created a contract, store the creation address in the receipt.
-	if msg.To() == nil {
-		receipt.ContractAddress = crypto.CreateAddress(vmenv.Context.Origin, t.Nonce())
-	}
-	// Set the receipt logs and create a bloom for filtering
-	receipt.Logs = s.was.ethState.GetLogs(t.Hash())
-	//receipt.Logs = s.was.state.Logs()
-	receipt.Bloom = ethTypes.CreateBloom(ethTypes.Receipts{receipt})
-
-	s.was.txIndex++
-	s.was.transactions = append(s.was.transactions, &t)
-	s.was.receipts = append(s.was.receipts, receipt)
-	s.was.allLogs = append(s.was.allLogs, receipt.Logs...)
-
-	s.logger.WithField("hash", t.Hash().Hex()).Debug("Applied tx to WAS")
-
+	txsApplied.Inc()
 	return nil
 }
 
 //Commit persists all pending state changes (in the WAS) to the DB, and resets
 //the WAS and TxPool
-func (s *State) Commit() (common.Hash, error) {
+func (s *State) Commit(ctx context.Context) (root common.Hash, err error) {
+	ctx, span := otelTracer.Start(ctx, "State.Commit")
+	defer func() { endSpan(span, err) }()
+
+	if s.halted != nil {
+		return common.Hash{}, s.halted
+	}
+
+	enterCommitPath()
+	defer s.exitCommitPath()
+	defer commitDuration.Since(time.Now())
+
+	parentRoot, _ := s.db.Get(rootKey)
+
 	//commit all state changes to the database
-	root, err := s.was.Commit()
+	root, err = s.was.Commit(ctx)
 	if err != nil {
 		s.logger.WithError(err).Error("Committing WAS")
 		return root, err
 	}
+	blockCommitted := s.was.blockStarted
+	if blockCommitted {
+		s.blockIndex = s.was.blockIndex
+		s.pruneBlobs()
+		s.blockCommitted(s.blockIndex)
+	}
 
 	// reset the write ahead state for the next block
 	// with the latest eth state
@@ -361,6 +578,28 @@ func (s *State) Commit() (common.Hash, error) {
 	}
 	s.logger.WithField("root", root.Hex()).Debug("Committed")
 
+	if s.invariants != nil {
+		s.invariants.burn(s.was.burned)
+		if err := s.invariants.check(root, s.blockIndex, s.ethState); err != nil {
+			s.logger.WithError(err).Error("State invariants violated. Halting")
+			s.halted = err
+			return root, err
+		}
+	}
+
+	info := CommitInfo{
+		Root:       root,
+		ParentRoot: common.BytesToHash(parentRoot),
+		BlockIndex: s.blockIndex,
+		Block:      blockCommitted,
+		Txs:        len(s.was.transactions),
+		Time:       time.Now(),
+	}
+	if !s.blockReceived.IsZero() {
+		info.Latency = info.Time.Sub(s.blockReceived)
+	}
+	s.history.add(info)
+
 	//Reset WAS
 	if err := s.was.Reset(root); err != nil {
 		s.logger.WithError(err).Error("Resetting WAS")
@@ -369,12 +608,32 @@ func (s *State) Commit() (common.Hash, error) {
 	s.logger.Debug("Reset WAS")
 
 	//Reset TxPool
-	if err := s.txPool.Reset(root); err != nil {
+	_, resetSpan := otelTracer.Start(ctx, "TxPool.Reset")
+	promoted, err := s.txPool.Reset(root)
+	endSpan(resetSpan, err)
+	if err != nil {
 		s.logger.WithError(err).Error("Resetting TxPool")
 		return root, err
 	}
 	s.logger.Debug("Reset TxPool")
 
+	for _, tx := range promoted {
+		select {
+		case s.promotedTxs <- tx:
+		default:
+			s.logger.WithField("hash", tx.Hash().Hex()).Error("Promoted txs backlog full. Dropping tx")
+		}
+	}
+
+	s.publishCommit(info)
+
+	if blockCommitted && s.haltHeight > 0 && s.blockIndex >= s.haltHeight {
+		if err := s.halt(); err != nil {
+			s.logger.WithError(err).Error("Recording halt")
+			return root, err
+		}
+	}
+
 	return root, nil
 }
 
@@ -383,14 +642,27 @@ func (s *State) resetWAS() {
 	s.was = &WriteAheadState{
 		db:           s.db,
 		ethState:     state,
+		blockIndex:   s.was.blockIndex,
+		signer:       s.signer,
+		chainConfig:  s.chainConfig,
+		vmConfig:     s.vmConfig,
 		txIndex:      0,
+		reverts:      make(map[common.Hash][]byte),
+		capped:       make(map[common.Hash]uint64),
+		blobs:        make(map[common.Hash][]byte),
+		gasCaps:      s.gasCaps,
+		codeLimits:   s.codeLimits,
+		feePolicy:    s.feePolicy,
+		coinbase:     s.was.coinbase,
 		totalUsedGas: big.NewInt(0),
-		gp:           new(core.GasPool).AddGas(gasLimit.Uint64()),
+		blockFees:    big.NewInt(0),
+		burned:       big.NewInt(0),
+		gp:           new(core.GasPool).AddGas(s.gasLimit),
 		logger:       s.logger,
-		gasLimit:     gasLimit.Uint64(),
+		gasLimit:     s.gasLimit,
 	}
 	s.logger.WithFields(logrus.Fields{
-		"gasLimit": gasLimit.Uint64(),
+		"gasLimit": s.gasLimit,
 		"s.was.gp": s.was.gp,
 	}).Debug("Reset Write Ahead State")
 }
@@ -438,20 +710,55 @@ func (s *State) InitState() error {
 		s.logger.WithField("root", rootHash.Hex()).Debug("Existing State Root")
 	}
 
-	//use root to initialise the state
-	var err error
+	//get the index of the last committed block. The WAS starts on the next one
+	nextBlockIndex := int64(0)
+	data, _ = s.db.Get(headBlockKey)
+	if len(data) != 0 {
+		s.blockIndex = decodeBlockIndex(data)
+		nextBlockIndex = s.blockIndex + 1
+		s.logger.WithField("block_index", s.blockIndex).Debug("Existing Block Index")
+	}
+	s.blockCommitted(s.blockIndex)
 
-	s.ethState, err = ethState.New(rootHash, ethState.NewDatabase(s.db))
+	s.loadHalt()
+	s.loadSafeMode()
+
+	//apply the parameters of the network recorded with the genesis
+	genesisConfig, err := s.getGenesisConfig()
 	if err != nil {
 		return err
 	}
+	if genesisConfig != nil {
+		s.applyGenesisConfig(genesisConfig)
+	}
 
-	s.was, err = NewWriteAheadState(s.db, rootHash, s.signer, s.chainConfig, s.vmConfig, gasLimit.Uint64(), s.logger)
+	//use root to initialise the state. If the root is corrupted, enter safe mode
+	//and serve the last readable root
+	s.ethState, err = ethState.New(rootHash, ethState.NewDatabase(s.db))
+	if err != nil {
+		s.ethState, rootHash, err = s.recoverRoot(rootHash, err)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.was, err = NewWriteAheadState(s.db, rootHash, nextBlockIndex, s.signer, s.chainConfig, s.vmConfig, s.gasLimit, s.logger)
 	if err != nil {
 		return err
 	}
 
-	s.txPool = NewTxPool(s.ethState.Copy(), s.signer, s.chainConfig, s.vmConfig, gasLimit.Uint64(), s.logger)
+	// The expiry loop of the pool replaced stops, and its TTL carries over
+	var ttl time.Duration
+	if s.txPool != nil {
+		ttl = s.txPool.ttl
+		s.txPool.Close()
+	}
+	s.txPool = NewTxPool(s.ethState.Copy(), s.signer, s.chainConfig, s.vmConfig, s.gasLimit, s.logger)
+	s.txPool.setNetwork(s.chainConfig, s.gasLimit, s.minGasPrice)
+	s.txPool.SetTTL(ttl)
+	if _, err := s.txPool.Reset(rootHash); err != nil {
+		return err
+	}
 
 	return err
 }
@@ -460,41 +767,173 @@ func (s *State) InitState() error {
 //by the Service handlers to check if a transaction is valid before submitting
 //it to the consensus system. This also updates the sender's Nonce in the
 //TxPool's statedb.
-func (s *State) CheckTx(tx *ethTypes.Transaction) error {
+func (s *State) CheckTx(ctx context.Context, tx *ethTypes.Transaction) (err error) {
+	_, span := otelTracer.Start(ctx, "State.CheckTx", trace.WithAttributes(attribute.String("hash", tx.Hash().Hex())))
+	defer func() { endSpan(span, err) }()
+
+	if err := s.checkSafeMode(); err != nil {
+		return err
+	}
 	return s.txPool.CheckTx(tx)
 }
 
+//AddTx is like CheckTx but queues transactions whose nonce is ahead of their
+//sender's instead of rejecting them. It returns the transactions to submit to
+//the consensus system, in order: tx itself, unless it was queued, and the
+//queued transactions it unlocked.
+func (s *State) AddTx(ctx context.Context, tx *ethTypes.Transaction) (ready []*ethTypes.Transaction, err error) {
+	_, span := otelTracer.Start(ctx, "State.AddTx", trace.WithAttributes(attribute.String("hash", tx.Hash().Hex())))
+	defer func() { endSpan(span, err) }()
+
+	if err := s.checkSafeMode(); err != nil {
+		return nil, err
+	}
+	return s.txPool.AddTx(tx)
+}
+
+//SetChainID sets the EIP-155 chain id of the transactions. It is part of the
+//state transition: all validators must use the same chain id.
+func (s *State) SetChainID(id *big.Int) {
+	s.commitMutex.Lock()
+	defer s.commitMutex.Unlock()
+
+	s.signer = ethTypes.NewEIP155Signer(id)
+	s.chainConfig.ChainID = id
+	s.was.signer = s.signer
+	s.was.chainConfig.ChainID = id
+	s.txPool.SetChainID(id)
+	s.logger.WithField("chain_id", id).Debug("Chain id set")
+}
+
+//SetCoinbase sets the beneficiary of the blocks whose consensus system names
+//none, e.g. Lachesis blocks, which is credited the transaction fees. It is part
+//of the state transition: all validators must use the same coinbase.
+func (s *State) SetCoinbase(addr common.Address) {
+	s.commitMutex.Lock()
+	defer s.commitMutex.Unlock()
+
+	s.was.coinbase = addr
+	s.logger.WithField("coinbase", addr.Hex()).Debug("Coinbase set")
+}
+
+//Coinbase returns the beneficiary of the blocks whose consensus system names
+//none
+func (s *State) Coinbase() common.Address {
+	s.commitMutex.Lock()
+	defer s.commitMutex.Unlock()
+
+	return s.was.coinbase
+}
+
+//ChainID returns the EIP-155 chain id of the transactions
+func (s *State) ChainID() *big.Int {
+	return new(big.Int).Set(s.chainConfig.ChainID)
+}
+
+//SetPriceBump sets the minimum gas price increase, in percent, for a
+//transaction to replace a pending one with the same nonce
+func (s *State) SetPriceBump(percent uint64) {
+	s.txPool.SetPriceBump(percent)
+}
+
+//SetTxPoolLimits sets the maximum number of transactions held by the TxPool
+//overall and for a single sender, and the maximum number of pending
+//transactions of a single sender per block. 0 disables a limit.
+func (s *State) SetTxPoolLimits(globalSlots, accountSlots, accountPending int) {
+	s.txPool.SetLimits(globalSlots, accountSlots, accountPending)
+}
+
+//AddLocals marks senders as local, e.g. the operator's own accounts: their
+//transactions are exempt from the TxPool limits and from eviction
+func (s *State) AddLocals(addrs []common.Address) {
+	s.txPool.AddLocals(addrs)
+}
+
+//SetTxTTL sets how long a queued transaction may wait in the TxPool to be
+//submitted to consensus before it is dropped. 0 disables expiry.
+func (s *State) SetTxTTL(ttl time.Duration) {
+	s.txPool.SetTTL(ttl)
+}
+
+//PromotedTxs returns the channel of the queued transactions which became
+//executable on Commit. They must be submitted to the consensus system.
+func (s *State) PromotedTxs() <-chan *ethTypes.Transaction {
+	return s.promotedTxs
+}
+
+//AppliedBlock returns the state root resulting from the block with the given
+//consensus hash, if it was already applied. Consensus systems which replay
+//blocks after a restart use it not to apply them twice.
+func (s *State) AppliedBlock(blockHash common.Hash) (common.Hash, bool) {
+	root, err := s.db.Get(appliedBlockKey(blockHash))
+	if err != nil {
+		return common.Hash{}, false
+	}
+	return common.BytesToHash(root), true
+}
+
+//BeginBlock starts the block with the given consensus hash, timestamp and
+//beneficiary, e.g. its proposer, or the default coinbase if zero. It is meant
+//for consensus systems which hand over the transactions of a block one at a
+//time. Otherwise the block starts with its first transaction, and an empty
+//block is not recorded on Commit.
+func (s *State) BeginBlock(blockHash common.Hash, blockTime int64, coinbase common.Address) {
+	s.was.startBlock(blockHash, s.blockTimestamp(blockTime), coinbase)
+}
+
+// blockTimestamp returns the timestamp of the next block from the time given by
+// the consensus system. Timestamps never go backwards: a block without a time,
+// or older than the last committed block, gets the time of that block.
+func (s *State) blockTimestamp(consensusTime int64) int64 {
+	parent, err := s.GetBlockByNumber(s.blockIndex)
+	if err != nil || consensusTime >= int64(parent.Timestamp) {
+		return consensusTime
+	}
+	s.logger.WithFields(logrus.Fields{
+		"time":   consensusTime,
+		"parent": parent.Timestamp,
+	}).Warn("Block time before its parent's. Using the parent's")
+	return int64(parent.Timestamp)
+}
+
 //ApplyTransaction decodes a transaction and applies it to the WAS. It is meant
 //to be called by the consensus system to apply transactions sequentially.
-func (s *State) ApplyTransaction(txBytes []byte, txIndex int, blockHash common.Hash) error {
-
-	var t ethTypes.Transaction
-	if err := rlp.Decode(bytes.NewReader(txBytes), &t); err != nil {
-		s.logger.WithError(err).Error("Decoding Transaction")
-		return err
+func (s *State) ApplyTransaction(ctx context.Context, txBytes []byte, txIndex int, blockHash common.Hash) error {
+	if s.halted != nil {
+		return s.halted
 	}
-	s.logger.WithField("hash", t.Hash().Hex()).Debug("Decoded tx")
 
-	return s.was.ApplyTransaction(t, txIndex, blockHash)
+	enterCommitPath()
+	defer s.exitCommitPath()
+
+	return s.applyTransaction(ctx, txBytes, txIndex, blockHash)
 }
 
+//CreateAccounts writes the accounts of a genesis alloc missing from the state
+//and commits them. The constructors of the contracts are run, and the logs they
+//emit recorded.
 func (s *State) CreateAccounts(accounts bcommon.AccountMap) error {
 	s.commitMutex.Lock()
 	defer s.commitMutex.Unlock()
 
-	for addr, account := range accounts {
-		address := common.HexToAddress(addr)
-		if !s.Exist(address) {
-			s.was.ethState.AddBalance(address, math.MustParseBig256(account.Balance))
-			s.was.ethState.SetCode(address, common.Hex2Bytes(account.Code))
-			for key, value := range account.Storage {
-				s.was.ethState.SetState(address, common.HexToHash(key), common.HexToHash(value))
-			}
-			s.logger.WithField("address", addr).Debug("Adding account")
+	minted, logs, err := applyAlloc(s.was.ethState, accounts, &s.chainConfig, s.gasLimit)
+	if err != nil {
+		return err
+	}
+	if s.invariants != nil {
+		s.invariants.mint(minted)
+	}
+	if len(logs) > 0 {
+		if err := s.putGenesisLogs(logs); err != nil {
+			return err
 		}
 	}
+	s.logger.WithFields(logrus.Fields{
+		"accounts": len(accounts),
+		"logs":     len(logs),
+	}).Debug("Adding accounts")
 
-	_, err := s.Commit()
+	_, err = s.Commit(context.Background())
 
 	return err
 }
@@ -508,18 +947,80 @@ func (s *State) GetBalance(addr common.Address) *big.Int {
 	return s.ethState.GetBalance(addr)
 }
 
+//GetNonce returns an account's nonce in the committed state
 func (s *State) GetNonce(addr common.Address) uint64 {
-	return s.was.ethState.GetNonce(addr)
+	return s.ethState.GetNonce(addr)
+}
+
+//GetCode returns the code of a contract
+func (s *State) GetCode(addr common.Address) []byte {
+	return s.ethState.GetCode(addr)
+}
+
+//StateAt returns a read-only view of the committed state with the given root,
+//e.g. the StateRoot of a Block. Changes made to it are never committed.
+func (s *State) StateAt(root common.Hash) (*ethState.StateDB, error) {
+	return ethState.New(root, s.ethState.Database())
+}
+
+//GetStorageAt returns the value of a storage slot of a contract
+func (s *State) GetStorageAt(addr common.Address, key common.Hash) common.Hash {
+	return s.ethState.GetState(addr, key)
 }
 
 //GetPoolNonce returns an account's nonce from the txpool's ethState
 func (s *State) GetPoolNonce(addr common.Address) uint64 {
-	return s.txPool.ethState.GetNonce(addr)
+	return s.txPool.GetNonce(addr)
+}
+
+//GetPoolBalance returns an account's balance from the txpool's ethState
+func (s *State) GetPoolBalance(addr common.Address) *big.Int {
+	return s.txPool.GetBalance(addr)
+}
+
+//GetPoolCode returns the code of a contract from the txpool's ethState
+func (s *State) GetPoolCode(addr common.Address) []byte {
+	return s.txPool.GetCode(addr)
+}
+
+//GetPoolStorageAt returns the value of a storage slot of a contract from the
+//txpool's ethState
+func (s *State) GetPoolStorageAt(addr common.Address, key common.Hash) common.Hash {
+	return s.txPool.GetState(addr, key)
+}
+
+//GetPoolSize returns the number of transactions accepted by the TxPool since
+//the last Commit
+func (s *State) GetPoolSize() int {
+	return s.txPool.Pending()
+}
+
+//GetPoolQueued returns the number of future-nonce transactions queued by the
+//TxPool
+func (s *State) GetPoolQueued() int {
+	return s.txPool.Queued()
+}
+
+//GetPoolContent returns the transactions waiting for consensus ordering: those
+//accepted by the TxPool since the last Commit, in submission order, and the
+//future-nonce transactions queued by sender and nonce
+func (s *State) GetPoolContent() (pending []PoolTx, queued []PoolTx) {
+	return s.txPool.Content()
+}
+
+//RecentCommits returns up to n of the latest commits, most recent first
+func (s *State) RecentCommits(n int) []CommitInfo {
+	return s.history.recent(n)
+}
+
+//CommitsSince returns the number of commits made after t
+func (s *State) CommitsSince(t time.Time) int {
+	return s.history.since(t)
 }
 
 func (s *State) GetBlock(hash common.Hash) (*poset.Block, error) {
 	// Retrieve the block itself from the database
-	data, err := s.db.Get(hash.Bytes())
+	data, err := s.reader.Get(hash.Bytes())
 	if err != nil {
 		s.logger.WithError(err).Error("GetBlock")
 		return nil, err
@@ -536,7 +1037,7 @@ func (s *State) GetBlock(hash common.Hash) (*poset.Block, error) {
 func (s *State) GetBlockById(id int64) (*poset.Block, error) {
 	// Retrieve the block itself from the database
 	key := blockKey(id)
-	data, err := s.db.Get(key)
+	data, err := s.reader.Get(key)
 	if err != nil {
 		s.logger.WithError(err).Error("GetBlockById")
 		return nil, err
@@ -550,9 +1051,37 @@ func (s *State) GetBlockById(id int64) (*poset.Block, error) {
 	return newBlock, nil
 }
 
+//GetBlockByNumber returns the Block committed at the given index
+func (s *State) GetBlockByNumber(number int64) (*Block, error) {
+	data, err := s.reader.Get(blockHeaderKey(number))
+	if err != nil {
+		s.logger.WithError(err).Error("GetBlockByNumber")
+		return nil, err
+	}
+	block := new(Block)
+	if err := block.Unmarshal(data); err != nil {
+		s.logger.WithError(err).Error("Decoding Block")
+		return nil, err
+	}
+
+	return block, nil
+}
+
+//GetBlockByHash returns the Block with the given hash (as returned by
+//Block.Hash, not the consensus hash)
+func (s *State) GetBlockByHash(hash common.Hash) (*Block, error) {
+	data, err := s.reader.Get(blockNumberKey(hash))
+	if err != nil {
+		s.logger.WithError(err).Error("GetBlockByHash")
+		return nil, err
+	}
+
+	return s.GetBlockByNumber(decodeBlockIndex(data))
+}
+
 func (s *State) GetTransaction(hash common.Hash) (*ethTypes.Transaction, error) {
 	// Retrieve the transaction itself from the database
-	data, err := s.db.Get(hash.Bytes())
+	data, err := s.reader.Get(hash.Bytes())
 	if err != nil {
 		s.logger.WithError(err).Error("GetTransaction")
 		return nil, err
@@ -567,7 +1096,7 @@ func (s *State) GetTransaction(hash common.Hash) (*ethTypes.Transaction, error)
 }
 
 func (s *State) GetReceipt(txHash common.Hash) (*ethTypes.Receipt, error) {
-	data, err := s.db.Get(append(receiptsPrefix, txHash[:]...))
+	data, err := s.reader.Get(append(receiptsPrefix, txHash[:]...))
 	if err != nil {
 		s.logger.WithError(err).Error("GetReceipt")
 		return nil, err
@@ -581,8 +1110,62 @@ func (s *State) GetReceipt(txHash common.Hash) (*ethTypes.Receipt, error) {
 	return (*ethTypes.Receipt)(&receipt), nil
 }
 
+//GetTxLookup returns the block, position and sender of an applied
+//transaction. The sender of the transactions indexed before senders were
+//recorded is recovered from their signature (evm reindex --index txlookup
+//--force records them).
+func (s *State) GetTxLookup(txHash common.Hash) (*TxLookupEntry, error) {
+	data, err := s.reader.Get(txLookupKey(txHash))
+	if err != nil {
+		s.logger.WithError(err).Debug("GetTxLookup")
+		return nil, err
+	}
+	var entry TxLookupEntry
+	if err := rlp.DecodeBytes(data, &entry); err == nil {
+		return &entry, nil
+	}
+
+	var legacy legacyTxLookupEntry
+	if err := rlp.DecodeBytes(data, &legacy); err != nil {
+		s.logger.WithError(err).Error("Decoding TxLookupEntry")
+		return nil, err
+	}
+	entry = TxLookupEntry{
+		BlockHash:   legacy.BlockHash,
+		BlockNumber: legacy.BlockNumber,
+		Index:       legacy.Index,
+	}
+	tx, err := s.GetTransaction(txHash)
+	if err != nil {
+		return nil, err
+	}
+	if entry.From, err = ethTypes.Sender(s.signer, tx); err != nil {
+		s.logger.WithError(err).Error("Recovering sender")
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+//GetPoolTransaction returns a transaction held by the TxPool, with its sender,
+//or nil if the TxPool doesn't hold it
+func (s *State) GetPoolTransaction(txHash common.Hash) (*ethTypes.Transaction, common.Address) {
+	return s.txPool.Get(txHash)
+}
+
+//GetRevertData returns the data returned by a reverted transaction
+func (s *State) GetRevertData(txHash common.Hash) ([]byte, error) {
+	data, err := s.reader.Get(append(revertPrefix, txHash[:]...))
+	if err != nil {
+		s.logger.WithError(err).Debug("GetRevertData")
+		return nil, err
+	}
+
+	return data, nil
+}
+
 func (s *State) GetFailedTx(txHash common.Hash) (*TxError, error) {
-	data, err := s.db.Get(append(errorPrefix, txHash[:]...))
+	data, err := s.reader.Get(append(errorPrefix, txHash[:]...))
 	if err != nil {
 		s.logger.WithError(err).Error("GetFailedTx")
 		return nil, err
@@ -1,8 +1,14 @@
 package state
 
 import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
 	"encoding/json"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"io/ioutil"
 	"math/big"
 	"os"
@@ -10,12 +16,17 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/common"
+	ethState "github.com/ethereum/go-ethereum/core/state"
 	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/sirupsen/logrus"
 
@@ -44,7 +55,7 @@ func NewTest(dataDir string, logger *logrus.Logger, t *testing.T) *Test {
 	dbFile := filepath.Join(dataDir, "chaindata")
 	cache := 128
 
-	state, err := NewState(logger, dbFile, cache)
+	state, err := NewState(logger, dbFile, cache, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -205,11 +216,11 @@ func (test *Test) deployContract(from accounts.Account, contract *Contract, t *t
 	}
 
 	// Try to commit the transaction
-	err = test.state.ApplyTransaction(data, 0, common.Hash{})
+	err = test.state.ApplyTransaction(context.Background(), data, 0, common.Hash{})
 	if err != nil {
 		t.Fatal(err)
 	}
-	_, err = test.state.Commit()
+	_, err = test.state.Commit(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -264,11 +275,11 @@ func TestTransfer(t *testing.T) {
 	}
 
 	// Try to process the block
-	err = test.state.ApplyTransaction(data, 0, common.Hash{})
+	err = test.state.ApplyTransaction(context.Background(), data, 0, common.Hash{})
 	if err != nil {
 		t.Fatal(err)
 	}
-	_, err = test.state.Commit()
+	_, err = test.state.Commit(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -355,7 +366,7 @@ func callDummyContractTest(test *Test, from accounts.Account, contract *Contract
 		t.Fatal(err)
 	}
 
-	res, err := test.state.Call(callMsg)
+	res, err := test.state.Call(context.Background(), callMsg)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -398,11 +409,11 @@ func callDummyContractTestAsync(test *Test, from accounts.Account, contract *Con
 	}
 
 	// Try to process the block
-	err = test.state.ApplyTransaction(data, 0, common.Hash{})
+	err = test.state.ApplyTransaction(context.Background(), data, 0, common.Hash{})
 	if err != nil {
 		t.Fatal(err)
 	}
-	_, err = test.state.Commit()
+	_, err = test.state.Commit(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -493,3 +504,735 @@ func TestDB(t *testing.T) {
 	callDummyContractTest(test2, from, contract, big.NewInt(110), t)
 
 }
+
+// TestNoFloats guards the state transition against float arithmetic, whose
+// results can differ between platforms. Map iteration and time.Now are
+// reported at runtime by the nondeterminism build.
+func TestNoFloats(t *testing.T) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			ast.Inspect(file, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.Ident:
+					if n.Name == "float32" || n.Name == "float64" {
+						t.Errorf("%s: %s", fset.Position(n.Pos()), n.Name)
+					}
+				case *ast.BasicLit:
+					if n.Kind == token.FLOAT {
+						t.Errorf("%s: float literal %s", fset.Position(n.Pos()), n.Value)
+					}
+				}
+				return true
+			})
+		}
+	}
+}
+
+// TestBlockEncoding checks that a header records its beneficiary, and that a
+// header committed before it was recorded keeps its layout, and so its hash
+func TestBlockEncoding(t *testing.T) {
+	block := &Block{
+		Number:       3,
+		Timestamp:    1546300800,
+		GasUsed:      21000,
+		Transactions: []common.Hash{common.HexToHash("0x01")},
+		Coinbase:     common.HexToAddress("0xc0ffee"),
+	}
+	legacyBlock := *block
+	legacyBlock.Coinbase = common.Address{}
+	legacyBlock.legacy = true
+	legacy, err := rlp.EncodeToBytes(&legacyBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a header of the layout before the beneficiary was recorded
+	old, err := rlp.EncodeToBytes([]interface{}{block.Number, block.ParentHash, block.ConsensusHash,
+		block.Timestamp, block.StateRoot, block.TxRoot, block.ReceiptRoot, block.GasUsed, block.Transactions})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(legacy, old) {
+		t.Fatalf("legacy header should be encoded as %x, not %x", old, legacy)
+	}
+
+	for _, b := range []*Block{block, &legacyBlock} {
+		data, err := b.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := new(Block)
+		if err := decoded.Unmarshal(data); err != nil {
+			t.Fatal(err)
+		}
+		if decoded.Hash() != b.Hash() || decoded.Coinbase != b.Coinbase {
+			t.Fatalf("decoded header should be %+v, not %+v", b, decoded)
+		}
+
+		js, err := json.Marshal(&SyncBlock{Block: b})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var synced SyncBlock
+		if err := json.Unmarshal(js, &synced); err != nil {
+			t.Fatal(err)
+		}
+		if synced.Block.Hash() != b.Hash() {
+			t.Fatalf("synced header should be %+v, not %+v", b, synced.Block)
+		}
+	}
+	if block.Hash() == legacyBlock.Hash() {
+		t.Fatal("the beneficiary should be part of the hash")
+	}
+}
+
+// TestGenesisAllocNonce checks that the nonce of an alloc account is kept when
+// its constructor is run, which only sets the nonce of a contract to 1 when it
+// has none
+func TestGenesisAllocNonce(t *testing.T) {
+	st, cleanup := NewEmptyTestState(t)
+	defer cleanup()
+
+	// returns the code 0x00 (STOP)
+	constructor := "0x6001600c60003960016000f300"
+	withNonce := common.HexToAddress("0x1000000000000000000000000000000000000001")
+	withoutNonce := common.HexToAddress("0x1000000000000000000000000000000000000002")
+	genesis := &Genesis{
+		// a contract created from EIP158 on starts with nonce 1
+		Config: &GenesisConfig{EIP158Block: big.NewInt(0)},
+		Alloc: bcommon.AccountMap{
+			withNonce.Hex():    {Balance: "1000", Nonce: 7, Constructor: constructor},
+			withoutNonce.Hex(): {Balance: "1000", Constructor: constructor},
+		},
+	}
+	if _, err := st.InitGenesis(genesis); err != nil {
+		t.Fatal(err)
+	}
+
+	if nonce := st.GetNonce(withNonce); nonce != 7 {
+		t.Fatalf("nonce should be 7, not %d", nonce)
+	}
+	if nonce := st.GetNonce(withoutNonce); nonce != 1 {
+		t.Fatalf("nonce of the contract without alloc nonce should be 1, not %d", nonce)
+	}
+	for _, addr := range []common.Address{withNonce, withoutNonce} {
+		if code := st.GetCode(addr); !reflect.DeepEqual(code, []byte{0x00}) {
+			t.Fatalf("code of %s should be 0x00, not %x", addr.Hex(), code)
+		}
+	}
+}
+
+// newTestPool returns a TxPool on an in-memory state with the given number of
+// funded accounts, and their keys
+func newTestPool(t *testing.T, accounts int) (*TxPool, []*ecdsa.PrivateKey) {
+	db := ethState.NewDatabase(ethdb.NewMemDatabase())
+	statedb, err := ethState.New(common.Hash{}, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := make([]*ecdsa.PrivateKey, accounts)
+	for i := range keys {
+		if keys[i], err = crypto.GenerateKey(); err != nil {
+			t.Fatal(err)
+		}
+		statedb.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1e18))
+	}
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.TrieDB().Commit(root, false); err != nil {
+		t.Fatal(err)
+	}
+
+	pool := NewTxPool(statedb, ethTypes.NewEIP155Signer(chainID), DefaultChainConfig(), vm.Config{}, DefaultGasLimit, bcommon.NewTestLogger(t))
+	if _, err := pool.Reset(root); err != nil {
+		t.Fatal(err)
+	}
+	return pool, keys
+}
+
+// transferTx returns a transfer of 1 wei signed by key
+func transferTx(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, gasPrice int64) *ethTypes.Transaction {
+	tx := ethTypes.NewTransaction(nonce, common.HexToAddress("0x0102"), big.NewInt(1), 21000, big.NewInt(gasPrice), nil)
+	signed, err := ethTypes.SignTx(tx, ethTypes.NewEIP155Signer(chainID), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// TestTxPoolNonceGap checks that a transaction ahead of the nonce of its
+// sender is queued until the gap is filled
+func TestTxPoolNonceGap(t *testing.T) {
+	pool, keys := newTestPool(t, 1)
+
+	tx1 := transferTx(t, keys[0], 1, 1)
+	ready, err := pool.AddTx(tx1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ready) != 0 || pool.Queued() != 1 || pool.Pending() != 0 {
+		t.Fatalf("tx with a nonce gap should be queued: %d ready, %d queued, %d pending",
+			len(ready), pool.Queued(), pool.Pending())
+	}
+
+	tx0 := transferTx(t, keys[0], 0, 1)
+	ready, err = pool.AddTx(tx0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ready) != 2 || ready[0] != tx0 || ready[1] != tx1 {
+		t.Fatalf("filling the gap should return the tx and the queued one, not %v", ready)
+	}
+	if pool.Queued() != 0 || pool.Pending() != 2 {
+		t.Fatalf("queued should be 0, not %d; pending should be 2, not %d", pool.Queued(), pool.Pending())
+	}
+}
+
+// TestTxPoolReplaceByFee checks that a transaction replaces the one applied
+// with the same nonce only if it bumps the gas price enough
+func TestTxPoolReplaceByFee(t *testing.T) {
+	pool, keys := newTestPool(t, 1)
+
+	old := transferTx(t, keys[0], 0, 100)
+	if _, err := pool.AddTx(old); err != nil {
+		t.Fatal(err)
+	}
+
+	// DefaultPriceBump is 10%
+	if _, err := pool.AddTx(transferTx(t, keys[0], 0, 109)); err != ErrReplaceUnderpriced {
+		t.Fatalf("error should be %v, not %v", ErrReplaceUnderpriced, err)
+	}
+	replacement := transferTx(t, keys[0], 0, 110)
+	ready, err := pool.AddTx(replacement)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ready) != 1 || ready[0] != replacement {
+		t.Fatalf("the replacement should be ready, not %v", ready)
+	}
+
+	if tx, _ := pool.Get(old.Hash()); tx != nil {
+		t.Fatal("the replaced tx should no longer be in the pool")
+	}
+	pending, _ := pool.Content()
+	if len(pending) != 1 || pending[0].Hash != replacement.Hash() {
+		t.Fatalf("pending should be the replacement only, not %v", pending)
+	}
+	if nonce := pool.GetNonce(crypto.PubkeyToAddress(keys[0].PublicKey)); nonce != 1 {
+		t.Fatalf("nonce should be 1, not %d", nonce)
+	}
+}
+
+// TestTxPoolReplaceKeepsSubmitted checks that the transactions a replacement
+// prevents from applying, already submitted to consensus like the replaced
+// one, are kept in flight rather than published as dropped
+func TestTxPoolReplaceKeepsSubmitted(t *testing.T) {
+	pool, keys := newTestPool(t, 1)
+	events := make(chan TxPoolEvent, 16)
+	sub := pool.Subscribe(events)
+	defer sub.Unsubscribe()
+
+	old := transferTx(t, keys[0], 0, 100)
+	next := transferTx(t, keys[0], 1, 100)
+	for _, tx := range []*ethTypes.Transaction{old, next} {
+		if _, err := pool.AddTx(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The replacement spends the whole balance, so next no longer applies
+	value := new(big.Int).Sub(big.NewInt(1e18), big.NewInt(21000*110))
+	replacement, err := ethTypes.SignTx(
+		ethTypes.NewTransaction(0, common.HexToAddress("0x0102"), value, 21000, big.NewInt(110), nil),
+		ethTypes.NewEIP155Signer(chainID), keys[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.AddTx(replacement); err != nil {
+		t.Fatal(err)
+	}
+	pending, _ := pool.Content()
+	if len(pending) != 1 || pending[0].Hash != replacement.Hash() {
+		t.Fatalf("pending should be the replacement only, not %v", pending)
+	}
+
+	// a queued tx marks the end of the events of the replacement
+	marker := transferTx(t, keys[0], 5, 1)
+	if _, err := pool.AddTx(marker); err != nil {
+		t.Fatal(err)
+	}
+	for ev := range events {
+		if ev.Type == TxDropped {
+			t.Fatalf("submitted tx %s should not be published as dropped", ev.Hash.Hex())
+		}
+		if ev.Hash == marker.Hash() {
+			break
+		}
+	}
+
+	// consensus hasn't committed any of them yet
+	if _, err := pool.Reset(pool.root); err != nil {
+		t.Fatal(err)
+	}
+	inflight := make(map[common.Hash]bool)
+	for _, tx := range pool.inflight {
+		inflight[tx.Hash()] = true
+	}
+	for _, tx := range []*ethTypes.Transaction{old, next, replacement} {
+		if !inflight[tx.Hash()] {
+			t.Fatalf("tx %s should be in flight", tx.Hash().Hex())
+		}
+	}
+}
+
+// TestTxPoolLimits checks that a full pool evicts its cheapest queued
+// transaction for a better paying one, and that local senders are exempt
+func TestTxPoolLimits(t *testing.T) {
+	pool, keys := newTestPool(t, 3)
+	pool.SetLimits(2, 0, 0)
+
+	cheap := transferTx(t, keys[0], 5, 1)
+	if _, err := pool.AddTx(cheap); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.AddTx(transferTx(t, keys[1], 5, 2)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := pool.AddTx(transferTx(t, keys[2], 5, 1)); err != ErrTxPoolFull {
+		t.Fatalf("error should be %v, not %v", ErrTxPoolFull, err)
+	}
+	if _, err := pool.AddTx(transferTx(t, keys[2], 5, 3)); err != nil {
+		t.Fatal(err)
+	}
+	if tx, _ := pool.Get(cheap.Hash()); tx != nil {
+		t.Fatal("the cheapest queued tx should be evicted")
+	}
+	if pool.Queued() != 2 {
+		t.Fatalf("queued should be 2, not %d", pool.Queued())
+	}
+
+	pool.AddLocals([]common.Address{crypto.PubkeyToAddress(keys[0].PublicKey)})
+	if _, err := pool.AddTx(transferTx(t, keys[0], 6, 1)); err != nil {
+		t.Fatalf("a local tx should be accepted in a full pool: %v", err)
+	}
+	if pool.Queued() != 3 {
+		t.Fatalf("queued should be 3, not %d", pool.Queued())
+	}
+}
+
+// TestTxPoolAccountLimits checks the number of transactions a sender may have
+// in the pool, and applied between two Resets
+func TestTxPoolAccountLimits(t *testing.T) {
+	pool, keys := newTestPool(t, 2)
+	pool.SetLimits(0, 3, 2)
+
+	for nonce := uint64(0); nonce < 2; nonce++ {
+		ready, err := pool.AddTx(transferTx(t, keys[0], nonce, 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ready) != 1 {
+			t.Fatalf("tx %d should be ready", nonce)
+		}
+	}
+	// Beyond the pending limit, the next transactions wait for a Reset
+	ready, err := pool.AddTx(transferTx(t, keys[0], 2, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ready) != 0 || pool.Pending() != 2 || pool.Queued() != 1 {
+		t.Fatalf("tx beyond the pending limit should be queued: %d ready, %d pending, %d queued",
+			len(ready), pool.Pending(), pool.Queued())
+	}
+	// The sender holds as many transactions as its slots
+	if _, err := pool.AddTx(transferTx(t, keys[0], 3, 1)); err != ErrTxPoolFull {
+		t.Fatalf("error should be %v, not %v", ErrTxPoolFull, err)
+	}
+
+	// The other senders are not limited by it
+	if _, err := pool.AddTx(transferTx(t, keys[1], 0, 1)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestTxPoolExpiry checks that the queued transactions expire, and that the
+// transactions submitted to consensus are kept, across a Reset too
+func TestTxPoolExpiry(t *testing.T) {
+	pool, keys := newTestPool(t, 2)
+	defer pool.Close()
+	pool.SetTTL(time.Hour)
+
+	queued := transferTx(t, keys[0], 1, 1)
+	if _, err := pool.AddTx(queued); err != nil {
+		t.Fatal(err)
+	}
+	applied := transferTx(t, keys[1], 0, 1)
+	if _, err := pool.AddTx(applied); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pool.Expire(time.Now().Add(2 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if pool.Queued() != 0 {
+		t.Fatalf("the queued tx should expire, %d queued", pool.Queued())
+	}
+	if tx, _ := pool.Get(applied.Hash()); tx == nil {
+		t.Fatal("the applied tx should not expire")
+	}
+
+	// The block committed doesn't hold the applied transaction: it is still
+	// in flight
+	if _, err := pool.Reset(pool.root); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pool.added[applied.Hash()]; !ok {
+		t.Fatal("the tx in flight should be kept across a Reset")
+	}
+}
+
+// newFundedState returns a State on a fresh database initialized with a
+// genesis funding the accounts of keys, the genesis, and the function which
+// closes and removes the database
+func newFundedState(t *testing.T, keys ...*ecdsa.PrivateKey) (*State, *Genesis, func()) {
+	st, cleanup := NewEmptyTestState(t)
+	genesis := &Genesis{Alloc: bcommon.AccountMap{}}
+	for _, key := range keys {
+		genesis.Alloc[crypto.PubkeyToAddress(key.PublicKey).Hex()] = bcommon.GenesisAccount{Balance: "1000000000000000000"}
+	}
+	if _, err := st.InitGenesis(genesis); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	return st, genesis, cleanup
+}
+
+// newTestKeys returns n new private keys
+func newTestKeys(t *testing.T, n int) []*ecdsa.PrivateKey {
+	keys := make([]*ecdsa.PrivateKey, n)
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = key
+	}
+	return keys
+}
+
+// applyTestBlock applies txs as the block with the given consensus hash and
+// beneficiary, and commits it
+func applyTestBlock(t *testing.T, st *State, blockHash common.Hash, coinbase common.Address, txs ...*ethTypes.Transaction) common.Hash {
+	st.BeginBlock(blockHash, 1546300800, coinbase)
+	for i, tx := range txs {
+		data, err := rlp.EncodeToBytes(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := st.ApplyTransaction(context.Background(), data, i, blockHash); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root, err := st.Commit(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// TestBlockTimeWithoutBeginBlock checks that a block started by its first
+// transaction, without a time from the consensus system, gets the timestamp of
+// its parent rather than the local time
+func TestBlockTimeWithoutBeginBlock(t *testing.T) {
+	keys := newTestKeys(t, 1)
+	st, _, cleanup := newFundedState(t, keys...)
+	defer cleanup()
+
+	applyTestBlock(t, st, common.HexToHash("0x01"), common.Address{}, transferTx(t, keys[0], 0, 1))
+
+	data, err := rlp.EncodeToBytes(transferTx(t, keys[0], 1, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.ApplyTransaction(context.Background(), data, 0, common.HexToHash("0x02")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Commit(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for number := int64(0); number < 2; number++ {
+		block, err := st.GetBlockByNumber(number)
+		if err != nil {
+			t.Fatalf("block %d: %v", number, err)
+		}
+		if block.Timestamp != 1546300800 {
+			t.Fatalf("block %d: timestamp should be 1546300800, not %d", number, block.Timestamp)
+		}
+	}
+}
+
+// TestInvariantChecks checks that the fees burned are accounted for, and that
+// native tokens created outside of a transaction halt the State
+func TestInvariantChecks(t *testing.T) {
+	keys := newTestKeys(t, 1)
+	st, _, cleanup := newFundedState(t, keys...)
+	defer cleanup()
+
+	if err := st.EnableInvariantChecks(); err != nil {
+		t.Fatal(err)
+	}
+	st.SetFeePolicy(&FeePolicy{Type: FeeBurn})
+	applyTestBlock(t, st, common.HexToHash("0x01"), common.Address{}, transferTx(t, keys[0], 0, 1))
+
+	st.was.ethState.AddBalance(common.HexToAddress("0x0103"), big.NewInt(1))
+	_, err := st.Commit(context.Background())
+	if _, ok := err.(*InvariantError); !ok {
+		t.Fatalf("error should be an InvariantError, not %v", err)
+	}
+	if st.Halted() == nil {
+		t.Fatal("the State should be halted")
+	}
+}
+
+// TestFeePolicies checks where each policy sends the fees of a block
+func TestFeePolicies(t *testing.T) {
+	coinbase := common.HexToAddress("0x0c01")
+	treasury := common.HexToAddress("0x0c02")
+	validators := []common.Address{common.HexToAddress("0x0c03"), common.HexToAddress("0x0c04")}
+	// 21000 gas at a gas price of 3
+	fees := big.NewInt(63000)
+
+	cases := []struct {
+		policy   *FeePolicy
+		balances map[common.Address]int64
+	}{
+		{nil, map[common.Address]int64{coinbase: 63000}},
+		{&FeePolicy{Type: FeeCoinbase}, map[common.Address]int64{coinbase: 63000}},
+		{&FeePolicy{Type: FeeBurn}, map[common.Address]int64{coinbase: 0}},
+		{&FeePolicy{Type: FeeTreasury, Treasury: treasury}, map[common.Address]int64{coinbase: 0, treasury: 63000}},
+		// the remainder of the split goes to the first validator
+		{&FeePolicy{Type: FeeValidators, Validators: validators}, map[common.Address]int64{
+			coinbase: 0, validators[0]: 31500, validators[1]: 31500}},
+	}
+	for _, c := range cases {
+		keys := newTestKeys(t, 1)
+		st, _, cleanup := newFundedState(t, keys...)
+		st.SetFeePolicy(c.policy)
+		applyTestBlock(t, st, common.HexToHash("0x01"), coinbase, transferTx(t, keys[0], 0, 3))
+
+		for addr, balance := range c.balances {
+			if got := st.GetBalance(addr); got.Cmp(big.NewInt(balance)) != 0 {
+				t.Errorf("%v: balance of %s should be %d, not %v", c.policy, addr.Hex(), balance, got)
+			}
+		}
+		sender := st.GetBalance(crypto.PubkeyToAddress(keys[0].PublicKey))
+		if spent := new(big.Int).Sub(big.NewInt(1e18), sender); spent.Cmp(new(big.Int).Add(fees, big.NewInt(1))) != 0 {
+			t.Errorf("%v: sender should pay %v and the wei sent, not %v", c.policy, fees, spent)
+		}
+		cleanup()
+	}
+}
+
+// newTestChain returns a State with two committed blocks of transfers, the
+// second one with its own beneficiary, its genesis, and the function which
+// closes and removes its database
+func newTestChain(t *testing.T) (*State, *Genesis, func()) {
+	keys := newTestKeys(t, 2)
+	st, genesis, cleanup := newFundedState(t, keys...)
+	applyTestBlock(t, st, common.HexToHash("0x01"), common.Address{},
+		transferTx(t, keys[0], 0, 1), transferTx(t, keys[1], 0, 1))
+	applyTestBlock(t, st, common.HexToHash("0x02"), common.HexToAddress("0x0c01"),
+		transferTx(t, keys[0], 1, 2))
+	return st, genesis, cleanup
+}
+
+// TestReindexMissingBlock checks that a block missing from the database fails
+// the index, whose marker stays before it, unless it is part of an imported
+// state
+func TestReindexMissingBlock(t *testing.T) {
+	st, _, cleanup := newTestChain(t)
+	defer cleanup()
+
+	checkMarker := func(expected int64) {
+		data, err := st.db.Get(indexMarkerKey("txlookup"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if marker := decodeBlockIndex(data); marker != expected {
+			t.Fatalf("the index should be complete up to block %d, not %d", expected, marker)
+		}
+	}
+
+	if err := st.db.Delete(blockHeaderKey(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Reindex([]string{"txlookup"}, true, nil); err == nil {
+		t.Fatal("reindexing should fail on the missing block")
+	}
+	checkMarker(0)
+
+	if err := st.db.Put(importedHeadKey, encodeBlockIndex(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Reindex([]string{"txlookup"}, true, nil); err != nil {
+		t.Fatal(err)
+	}
+	checkMarker(1)
+}
+
+// checkSameHead checks that two States committed the same last block
+func checkSameHead(t *testing.T, expected, st *State) {
+	want, err := expected.GetBlockByNumber(expected.GetBlockIndex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := st.GetBlockByNumber(st.GetBlockIndex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Hash() != want.Hash() {
+		t.Fatalf("head should be block %d %s, not block %d %s",
+			want.Number, want.Hash().Hex(), got.Number, got.Hash().Hex())
+	}
+}
+
+// TestReplayBlock checks that the blocks of another node replayed result in
+// the same headers, and that a block which diverges halts the State
+func TestReplayBlock(t *testing.T) {
+	source, genesis, cleanup := newTestChain(t)
+	defer cleanup()
+
+	st, cleanupTarget := NewEmptyTestState(t)
+	defer cleanupTarget()
+	if _, err := st.InitGenesis(genesis); err != nil {
+		t.Fatal(err)
+	}
+
+	blocks, err := source.GetSyncBlocks(0, MaxSyncBlocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 2 {
+		t.Fatalf("there should be 2 blocks, not %d", len(blocks))
+	}
+	if _, err := st.ReplayBlock(&blocks[0]); err != nil {
+		t.Fatal(err)
+	}
+	// A block replayed twice is skipped
+	if _, err := st.ReplayBlock(&blocks[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	diverging := *blocks[1].Block
+	diverging.StateRoot = common.HexToHash("0xbad")
+	if _, err := st.ReplayBlock(&SyncBlock{Block: &diverging, Txs: blocks[1].Txs}); err == nil {
+		t.Fatal("a diverging block should fail")
+	}
+	if st.Halted() == nil {
+		t.Fatal("the State should be halted")
+	}
+}
+
+// TestReplayBlockCoinbase checks that a replayed block credits its recorded
+// beneficiary rather than the coinbase of the node
+func TestReplayBlockCoinbase(t *testing.T) {
+	source, genesis, cleanup := newTestChain(t)
+	defer cleanup()
+
+	st, cleanupTarget := NewEmptyTestState(t)
+	defer cleanupTarget()
+	if _, err := st.InitGenesis(genesis); err != nil {
+		t.Fatal(err)
+	}
+	st.SetCoinbase(common.HexToAddress("0x0c09"))
+
+	blocks, err := source.GetSyncBlocks(0, MaxSyncBlocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range blocks {
+		if _, err := st.ReplayBlock(&blocks[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkSameHead(t, source, st)
+	if balance := st.GetBalance(common.HexToAddress("0x0c09")); balance.Sign() != 0 {
+		t.Fatalf("the coinbase of the node should get nothing, not %v", balance)
+	}
+}
+
+// TestExportImport checks that a chain export imported into a fresh node with
+// the same genesis results in the same chain, and that importing it again
+// skips the blocks
+func TestExportImport(t *testing.T) {
+	source, genesis, cleanup := newTestChain(t)
+	defer cleanup()
+
+	var buf bytes.Buffer
+	written, err := source.ExportChain(&buf, -1, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != 2 {
+		t.Fatalf("2 blocks should be exported, not %d", written)
+	}
+	export := buf.Bytes()
+
+	st, cleanupTarget := NewEmptyTestState(t)
+	defer cleanupTarget()
+	if _, err := st.InitGenesis(genesis); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		read, err := st.ImportChain(bytes.NewReader(export), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if read != 2 {
+			t.Fatalf("2 blocks should be imported, not %d", read)
+		}
+		checkSameHead(t, source, st)
+	}
+}
+
+// TestReplayFrom checks the replay of a chain into a fresh database, from the
+// genesis and from the state of a later block
+func TestReplayFrom(t *testing.T) {
+	source, genesis, cleanup := newTestChain(t)
+	defer cleanup()
+
+	for _, from := range []int64{0, 1} {
+		st, cleanupTarget := NewEmptyTestState(t)
+		if _, err := st.StartReplay(source, genesis, from); err != nil {
+			t.Fatalf("from %d: %v", from, err)
+		}
+		replayed, err := st.ReplayFrom(source, from, -1, nil)
+		if err != nil {
+			t.Fatalf("from %d: %v", from, err)
+		}
+		if expected := int(2 - from); replayed != expected {
+			t.Fatalf("from %d: %d blocks should be replayed, not %d", from, expected, replayed)
+		}
+		checkSameHead(t, source, st)
+		cleanupTarget()
+	}
+
+	// The genesis must be the one of the chain
+	st, cleanupTarget := NewEmptyTestState(t)
+	defer cleanupTarget()
+	other := &Genesis{Alloc: bcommon.AccountMap{common.HexToAddress("0x0c05").Hex(): {Balance: "1"}}}
+	if _, err := st.StartReplay(source, other, 0); err == nil {
+		t.Fatal("replaying from another genesis should fail")
+	}
+}
@@ -0,0 +1,428 @@
+package state
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethState "github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultSyncRangeLimit is the number of accounts or storage slots asked
+	// for in each range by a syncing node
+	DefaultSyncRangeLimit = 256
+
+	// MaxSyncRangeLimit is the maximum number of accounts or storage slots
+	// served in a range
+	MaxSyncRangeLimit = 4096
+)
+
+// SyncHead is the last committed block of a node serving its state
+type SyncHead struct {
+	Block     int64       `json:"block"`
+	BlockHash common.Hash `json:"blockHash"`
+	Root      common.Hash `json:"root"`
+}
+
+// SyncAccount is an account of the account trie, with its code
+type SyncAccount struct {
+	Hash    common.Hash    `json:"hash"` // key in the trie
+	Address common.Address `json:"address"`
+	Account hexutil.Bytes  `json:"account"` // as encoded in the trie
+	Code    hexutil.Bytes  `json:"code,omitempty"`
+}
+
+// SyncSlot is a storage slot of a storage trie
+type SyncSlot struct {
+	Hash  common.Hash   `json:"hash"` // key in the trie
+	Key   common.Hash   `json:"key"`
+	Value hexutil.Bytes `json:"value"` // as encoded in the trie
+}
+
+// AccountRange is a range of consecutive accounts of the account trie at a
+// root, starting at a key, in trie order. Proof holds the trie nodes proving
+// the first and last accounts against the root. More is set if the trie has
+// accounts after the range.
+type AccountRange struct {
+	Accounts []SyncAccount   `json:"accounts"`
+	Proof    []hexutil.Bytes `json:"proof"`
+	More     bool            `json:"more"`
+}
+
+// StorageRange is a range of consecutive slots of the storage trie of an
+// account, like AccountRange. Proof is against the storage root.
+type StorageRange struct {
+	Slots []SyncSlot      `json:"slots"`
+	Proof []hexutil.Bytes `json:"proof"`
+	More  bool            `json:"more"`
+}
+
+// StateSource serves the state of its last committed block to a syncing node,
+// e.g. a peer over the inter-node endpoints
+type StateSource interface {
+	Head() (*SyncHead, error)
+	AccountRange(root common.Hash, start common.Hash, limit int) (*AccountRange, error)
+	StorageRange(root common.Hash, account common.Address, start common.Hash, limit int) (*StorageRange, error)
+}
+
+// proofList collects the nodes of Merkle proofs
+type proofList []hexutil.Bytes
+
+func (l *proofList) Put(key []byte, value []byte) error {
+	*l = append(*l, common.CopyBytes(value))
+	return nil
+}
+
+// verifyProof checks that the proof holds the given value for the key in the
+// trie of the root
+func verifyProof(root common.Hash, key []byte, value []byte, proof []hexutil.Bytes) error {
+	proofDb := ethdb.NewMemDatabase()
+	for _, node := range proof {
+		if err := proofDb.Put(crypto.Keccak256(node), node); err != nil {
+			return err
+		}
+	}
+	proven, _, err := trie.VerifyProof(root, crypto.Keccak256(key), proofDb)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(proven, value) {
+		return fmt.Errorf("proof of %x does not match the value", key)
+	}
+	return nil
+}
+
+// clampSyncLimit bounds the number of entries of a range
+func clampSyncLimit(limit int) int {
+	if limit < 1 {
+		return DefaultSyncRangeLimit
+	}
+	if limit > MaxSyncRangeLimit {
+		return MaxSyncRangeLimit
+	}
+	return limit
+}
+
+// IsEmpty reports whether nothing was ever committed to the database, so that
+// its state can be synced or imported
+func (s *State) IsEmpty() bool {
+	has, err := s.db.Has(rootKey)
+	return err == nil && !has
+}
+
+// GetSyncHead returns the last committed block, whose state is served to
+// syncing nodes
+func (s *State) GetSyncHead() (*SyncHead, error) {
+	s.commitMutex.Lock()
+	head := &SyncHead{Block: s.blockIndex}
+	data, err := s.db.Get(rootKey)
+	s.commitMutex.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("no committed state")
+	}
+	head.Root = common.BytesToHash(data)
+
+	if data, err := s.reader.Get(blockHashKey(head.Block)); err == nil {
+		head.BlockHash = common.BytesToHash(data)
+	}
+	return head, nil
+}
+
+// GetAccountRange returns up to limit accounts of the account trie at the given
+// root, from the start key. The root must still be in the database, e.g. the
+// one of the last committed block.
+func (s *State) GetAccountRange(root common.Hash, start common.Hash, limit int) (*AccountRange, error) {
+	limit = clampSyncLimit(limit)
+	db := ethState.NewDatabase(s.db)
+	tr, err := db.OpenTrie(root)
+	if err != nil {
+		return nil, fmt.Errorf("state %s is not available: %s", root.Hex(), err)
+	}
+
+	res := &AccountRange{Accounts: []SyncAccount{}, Proof: []hexutil.Bytes{}}
+	it := trie.NewIterator(tr.NodeIterator(start.Bytes()))
+	for it.Next() {
+		if len(res.Accounts) == limit {
+			res.More = true
+			break
+		}
+		preimage := tr.GetKey(it.Key)
+		if preimage == nil {
+			return nil, fmt.Errorf("no preimage for account %x", it.Key)
+		}
+		var account ethState.Account
+		if err := rlp.DecodeBytes(it.Value, &account); err != nil {
+			return nil, err
+		}
+		entry := SyncAccount{
+			Hash:    common.BytesToHash(it.Key),
+			Address: common.BytesToAddress(preimage),
+			Account: common.CopyBytes(it.Value),
+		}
+		if !bytes.Equal(account.CodeHash, emptyCodeHash) {
+			code, err := db.ContractCode(entry.Hash, common.BytesToHash(account.CodeHash))
+			if err != nil {
+				return nil, fmt.Errorf("code of %s: %s", entry.Address.Hex(), err)
+			}
+			entry.Code = code
+		}
+		res.Accounts = append(res.Accounts, entry)
+	}
+	if it.Err != nil {
+		return nil, it.Err
+	}
+
+	if n := len(res.Accounts); n > 0 {
+		proof := (*proofList)(&res.Proof)
+		if err := tr.Prove(res.Accounts[0].Address.Bytes(), 0, proof); err != nil {
+			return nil, err
+		}
+		if err := tr.Prove(res.Accounts[n-1].Address.Bytes(), 0, proof); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// GetStorageRange returns up to limit slots of the storage trie of an account
+// in the account trie at the given root, from the start key
+func (s *State) GetStorageRange(root common.Hash, addr common.Address, start common.Hash, limit int) (*StorageRange, error) {
+	limit = clampSyncLimit(limit)
+	db := ethState.NewDatabase(s.db)
+	tr, err := db.OpenTrie(root)
+	if err != nil {
+		return nil, fmt.Errorf("state %s is not available: %s", root.Hex(), err)
+	}
+	blob, err := tr.TryGet(addr.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if blob == nil {
+		return nil, fmt.Errorf("account %s not found", addr.Hex())
+	}
+	var account ethState.Account
+	if err := rlp.DecodeBytes(blob, &account); err != nil {
+		return nil, err
+	}
+
+	res := &StorageRange{Slots: []SyncSlot{}, Proof: []hexutil.Bytes{}}
+	if account.Root == ethTypes.EmptyRootHash {
+		return res, nil
+	}
+	st, err := db.OpenStorageTrie(crypto.Keccak256Hash(addr.Bytes()), account.Root)
+	if err != nil {
+		return nil, fmt.Errorf("storage of %s: %s", addr.Hex(), err)
+	}
+	it := trie.NewIterator(st.NodeIterator(start.Bytes()))
+	for it.Next() {
+		if len(res.Slots) == limit {
+			res.More = true
+			break
+		}
+		slot := st.GetKey(it.Key)
+		if slot == nil {
+			return nil, fmt.Errorf("no preimage for slot %x of %s", it.Key, addr.Hex())
+		}
+		res.Slots = append(res.Slots, SyncSlot{
+			Hash:  common.BytesToHash(it.Key),
+			Key:   common.BytesToHash(slot),
+			Value: common.CopyBytes(it.Value),
+		})
+	}
+	if it.Err != nil {
+		return nil, it.Err
+	}
+
+	if n := len(res.Slots); n > 0 {
+		proof := (*proofList)(&res.Proof)
+		if err := st.Prove(res.Slots[0].Key.Bytes(), 0, proof); err != nil {
+			return nil, err
+		}
+		if err := st.Prove(res.Slots[n-1].Key.Bytes(), 0, proof); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// nextSyncKey returns the key following the last one of a range
+func nextSyncKey(last common.Hash) common.Hash {
+	next := new(big.Int).Add(last.Big(), big.NewInt(1))
+	return common.BigToHash(next)
+}
+
+// checkSyncRange checks that the keys of a range are ordered from start, that
+// each one is the hash of its preimage, and that the boundaries are proven
+// against the root
+func checkSyncRange(root common.Hash, start common.Hash, hashes []common.Hash, keys [][]byte,
+	values [][]byte, proof []hexutil.Bytes) error {
+
+	prev := start
+	for i, hash := range hashes {
+		if crypto.Keccak256Hash(keys[i]) != hash {
+			return fmt.Errorf("key %x does not hash to %s", keys[i], hash.Hex())
+		}
+		if bytes.Compare(hash[:], prev[:]) < 0 || (i > 0 && hash == prev) {
+			return fmt.Errorf("key %s is out of order", hash.Hex())
+		}
+		prev = hash
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+	if err := verifyProof(root, keys[0], values[0], proof); err != nil {
+		return err
+	}
+	last := len(hashes) - 1
+	return verifyProof(root, keys[last], values[last], proof)
+}
+
+// SyncState downloads the state of the last block of a source into an empty
+// State, instead of replaying the history. The accounts and storage are
+// downloaded in ranges whose boundaries are proven against the state root, and
+// the rebuilt state must match the root. Like ImportSnapshot, the State then
+// resumes from the synced block, without the blocks, transactions and receipts
+// before it. The head returned by the source is trusted: a remote source must
+// authenticate it, like service.SyncPeer.
+func (s *State) SyncState(source StateSource, limit int) (*SyncHead, error) {
+	s.commitMutex.Lock()
+	defer s.commitMutex.Unlock()
+
+	if _, err := s.db.Get(rootKey); err == nil {
+		return nil, fmt.Errorf("the database is not empty")
+	}
+	head, err := source.Head()
+	if err != nil {
+		return nil, fmt.Errorf("getting the head of the source: %s", err)
+	}
+	s.logger.WithFields(logrus.Fields{
+		"block": head.Block,
+		"root":  head.Root.Hex(),
+	}).Info("Syncing state")
+
+	triedb := trie.NewDatabase(s.db)
+	accounts, err := trie.NewSecure(common.Hash{}, triedb, 0)
+	if err != nil {
+		return nil, err
+	}
+	batch := s.db.NewBatch()
+	synced, slots := 0, 0
+
+	start := common.Hash{}
+	for {
+		r, err := source.AccountRange(head.Root, start, limit)
+		if err != nil {
+			return nil, fmt.Errorf("getting accounts from %s: %s", start.Hex(), err)
+		}
+		hashes := make([]common.Hash, len(r.Accounts))
+		keys := make([][]byte, len(r.Accounts))
+		values := make([][]byte, len(r.Accounts))
+		for i, acc := range r.Accounts {
+			hashes[i], keys[i], values[i] = acc.Hash, acc.Address.Bytes(), acc.Account
+		}
+		if err := checkSyncRange(head.Root, start, hashes, keys, values, r.Proof); err != nil {
+			return nil, fmt.Errorf("accounts from %s: %s", start.Hex(), err)
+		}
+
+		for _, acc := range r.Accounts {
+			entry, err := s.syncAccount(source, head.Root, acc, limit)
+			if err != nil {
+				return nil, fmt.Errorf("account %s: %s", acc.Address.Hex(), err)
+			}
+			if err := importAccount(batch, triedb, entry); err != nil {
+				return nil, fmt.Errorf("account %s: %s", acc.Address.Hex(), err)
+			}
+			if err := accounts.TryUpdate(acc.Address.Bytes(), acc.Account); err != nil {
+				return nil, err
+			}
+			slots += len(entry.Storage)
+
+			if batch.ValueSize() >= ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					return nil, err
+				}
+				batch.Reset()
+			}
+		}
+		synced += len(r.Accounts)
+		s.logger.WithFields(logrus.Fields{
+			"accounts": synced,
+			"slots":    slots,
+		}).Info("Synced account range")
+
+		if !r.More || len(r.Accounts) == 0 {
+			break
+		}
+		start = nextSyncKey(r.Accounts[len(r.Accounts)-1].Hash)
+	}
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+
+	root, err := accounts.Commit(nil)
+	if err != nil {
+		return nil, err
+	}
+	if root != head.Root {
+		return nil, fmt.Errorf("synced state root %s does not match the source root %s", root.Hex(), head.Root.Hex())
+	}
+	if err := triedb.Commit(root, false); err != nil {
+		return nil, err
+	}
+	if err := s.writeImportedHead(head.Block, head.BlockHash, root); err != nil {
+		return nil, err
+	}
+
+	return head, nil
+}
+
+// syncAccount downloads the storage of an account, verified against its
+// storage root
+func (s *State) syncAccount(source StateSource, root common.Hash, acc SyncAccount, limit int) (*snapshotAccount, error) {
+	var account ethState.Account
+	if err := rlp.DecodeBytes(acc.Account, &account); err != nil {
+		return nil, err
+	}
+	entry := &snapshotAccount{
+		Address: acc.Address,
+		Account: acc.Account,
+		Code:    acc.Code,
+	}
+	if account.Root == ethTypes.EmptyRootHash {
+		return entry, nil
+	}
+
+	start := common.Hash{}
+	for {
+		r, err := source.StorageRange(root, acc.Address, start, limit)
+		if err != nil {
+			return nil, fmt.Errorf("getting storage from %s: %s", start.Hex(), err)
+		}
+		hashes := make([]common.Hash, len(r.Slots))
+		keys := make([][]byte, len(r.Slots))
+		values := make([][]byte, len(r.Slots))
+		for i, slot := range r.Slots {
+			hashes[i], keys[i], values[i] = slot.Hash, slot.Key.Bytes(), slot.Value
+		}
+		if err := checkSyncRange(account.Root, start, hashes, keys, values, r.Proof); err != nil {
+			return nil, fmt.Errorf("storage from %s: %s", start.Hex(), err)
+		}
+		for _, slot := range r.Slots {
+			entry.Storage = append(entry.Storage, snapshotSlot{Key: slot.Key, Value: slot.Value})
+		}
+
+		if !r.More || len(r.Slots) == 0 {
+			return entry, nil
+		}
+		start = nextSyncKey(r.Slots[len(r.Slots)-1].Hash)
+	}
+}
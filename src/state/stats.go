@@ -0,0 +1,69 @@
+package state
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// maxCommitHistory is the number of commits kept in the commit history
+const maxCommitHistory = 100
+
+// CommitInfo describes a Commit of the State
+type CommitInfo struct {
+	Root       common.Hash
+	ParentRoot common.Hash
+	BlockIndex int64
+	Block      bool // whether a Block was recorded
+	Txs        int
+	Time       time.Time
+
+	// time from the reception of the block from the consensus system to the
+	// end of its Commit. 0 for commits not made by ProcessBlock
+	Latency time.Duration
+}
+
+// commitHistory is a bounded history of the most recent commits. It has its
+// own lock so that it can be read while the State is applying a block.
+type commitHistory struct {
+	sync.RWMutex
+	commits []CommitInfo
+}
+
+func (h *commitHistory) add(info CommitInfo) {
+	h.Lock()
+	defer h.Unlock()
+
+	h.commits = append(h.commits, info)
+	if len(h.commits) > maxCommitHistory {
+		h.commits = h.commits[len(h.commits)-maxCommitHistory:]
+	}
+}
+
+// recent returns up to n of the latest commits, most recent first
+func (h *commitHistory) recent(n int) []CommitInfo {
+	h.RLock()
+	defer h.RUnlock()
+
+	if n > len(h.commits) {
+		n = len(h.commits)
+	}
+	res := make([]CommitInfo, n)
+	for i := 0; i < n; i++ {
+		res[i] = h.commits[len(h.commits)-1-i]
+	}
+	return res
+}
+
+// since returns the number of commits made after t
+func (h *commitHistory) since(t time.Time) int {
+	h.RLock()
+	defer h.RUnlock()
+
+	count := 0
+	for i := len(h.commits) - 1; i >= 0 && h.commits[i].Time.After(t); i-- {
+		count++
+	}
+	return count
+}
@@ -0,0 +1,73 @@
+package state
+
+import (
+	"crypto/ecdsa"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	bcommon "github.com/Fantom-foundation/go-evm/src/common"
+)
+
+// These helpers set up the States of the tests of this package and of the
+// packages built on it, e.g. the consensus adapters.
+
+// NewEmptyTestState returns a State on an empty database in a temporary
+// directory, and the function which closes and removes it
+func NewEmptyTestState(t *testing.T) (*State, func()) {
+	dir, err := ioutil.TempDir("", "evm-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := NewState(bcommon.NewTestLogger(t), filepath.Join(dir, "chaindata"), 16, nil)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return st, func() {
+		st.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// NewTestState returns a State in a temporary directory whose genesis funds
+// the account of the returned key with 1 ether, and the function which closes
+// and removes it
+func NewTestState(t *testing.T) (*State, *ecdsa.PrivateKey, func()) {
+	st, cleanup := NewEmptyTestState(t)
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	genesis := &Genesis{Alloc: bcommon.AccountMap{
+		crypto.PubkeyToAddress(key.PublicKey).Hex(): {Balance: "1000000000000000000"},
+	}}
+	if _, err := st.InitGenesis(genesis); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	return st, key, cleanup
+}
+
+// NewTestTransfer returns a transfer of 1 wei from the account of key, signed
+// and encoded as it is submitted to the consensus system
+func NewTestTransfer(t *testing.T, key *ecdsa.PrivateKey, nonce uint64) []byte {
+	tx := ethTypes.NewTransaction(nonce, common.HexToAddress("0x0102"), big.NewInt(1), 21000, big.NewInt(1), nil)
+	signed, err := ethTypes.SignTx(tx, ethTypes.NewEIP155Signer(big.NewInt(DefaultChainID)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := rlp.EncodeToBytes(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -0,0 +1,113 @@
+package state
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// Trace is the opcode-level trace of an applied transaction, re-executed at
+// its historical state
+type Trace struct {
+	Return  []byte
+	GasUsed uint64
+	Failed  bool
+	Logs    []vm.StructLog
+}
+
+//TraceTransaction re-executes an applied transaction with a StructLogger. The
+//transactions before it in its block are replayed on the state root of the
+//parent block first, so the trace is the one of the original execution. The
+//state root must still be in the database.
+func (s *State) TraceTransaction(txHash common.Hash, config *vm.LogConfig) (*Trace, error) {
+	tracer := vm.NewStructLogger(config)
+	receipt, err := s.replayTransaction(txHash, tracer)
+	if err != nil {
+		return nil, err
+	}
+	return &Trace{
+		Return:  tracer.Output(),
+		GasUsed: receipt.GasUsed,
+		Failed:  receipt.Status == ethTypes.ReceiptStatusFailed,
+		Logs:    tracer.StructLogs(),
+	}, nil
+}
+
+// replayTransaction re-executes an applied transaction with a tracer, after
+// the transactions before it in its block, and returns its receipt
+func (s *State) replayTransaction(txHash common.Hash, tracer vm.Tracer) (*ethTypes.Receipt, error) {
+	lookup, err := s.GetTxLookup(txHash)
+	if err != nil {
+		return nil, fmt.Errorf("transaction %s not found", txHash.Hex())
+	}
+	block, err := s.GetBlockByNumber(int64(lookup.BlockNumber))
+	if err != nil {
+		return nil, fmt.Errorf("block %d not found", lookup.BlockNumber)
+	}
+	if lookup.Index >= uint64(len(block.Transactions)) || block.Transactions[lookup.Index] != txHash {
+		return nil, fmt.Errorf("transaction %s is not in block %d", txHash.Hex(), block.Number)
+	}
+
+	parentRoot := common.Hash{}
+	if block.ParentHash != (common.Hash{}) {
+		parent, err := s.GetBlockByHash(block.ParentHash)
+		if err != nil {
+			return nil, fmt.Errorf("parent of block %d not found", block.Number)
+		}
+		parentRoot = parent.StateRoot
+	}
+
+	was, err := NewWriteAheadState(s.db, parentRoot, int64(block.Number), s.signer, s.chainConfig, vm.Config{}, s.gasLimit, s.logger)
+	if err != nil {
+		return nil, fmt.Errorf("state before block %d is not available: %s", block.Number, err)
+	}
+	if err := was.Reset(parentRoot); err != nil {
+		return nil, err
+	}
+	s.commitMutex.Lock()
+	was.gasCaps, was.codeLimits = s.gasCaps, s.codeLimits
+	was.coinbase, was.feePolicy = s.was.coinbase, s.feePolicy
+	s.commitMutex.Unlock()
+	was.startRecordedBlock(block, int64(block.Timestamp))
+
+	for _, hash := range block.Transactions[:lookup.Index] {
+		tx, blob, err := s.storedTx(hash)
+		if err != nil {
+			return nil, err
+		}
+		if err := was.applyTransaction(context.Background(), *tx, blob, block.ConsensusHash); err != nil {
+			return nil, fmt.Errorf("replaying transaction %s: %s", hash.Hex(), err)
+		}
+	}
+
+	tx, blob, err := s.storedTx(txHash)
+	if err != nil {
+		return nil, err
+	}
+	was.vmConfig = vm.Config{Debug: true, Tracer: tracer}
+	if err := was.applyTransaction(context.Background(), *tx, blob, block.ConsensusHash); err != nil {
+		return nil, err
+	}
+
+	return was.receipts[len(was.receipts)-1], nil
+}
+
+// storedTx returns an applied transaction, with its blob data if it is a blob
+// transaction
+func (s *State) storedTx(txHash common.Hash) (*ethTypes.Transaction, []byte, error) {
+	tx, err := s.GetTransaction(txHash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("transaction %s not found", txHash.Hex())
+	}
+	if _, ok := s.GetBlobHash(txHash); !ok {
+		return tx, nil, nil
+	}
+	blob, err := s.GetBlob(txHash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("blob data of transaction %s was pruned", txHash.Hex())
+	}
+	return tx, blob, nil
+}
@@ -0,0 +1,211 @@
+package state
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// traceWriterSize is the size of the buffer of a streamed trace, flushed to
+// the client as it fills
+const traceWriterSize = 64 * 1024
+
+// The buffers of the streamed traces are reused across requests, so that
+// tracing doesn't allocate per step and puts little pressure on the GC
+var (
+	traceStepPool = sync.Pool{
+		New: func() interface{} { return new(bytes.Buffer) },
+	}
+	traceWriterPool = sync.Pool{
+		New: func() interface{} { return bufio.NewWriterSize(nil, traceWriterSize) },
+	}
+)
+
+// streamTracer is a vm.Tracer which writes every step of an execution as a
+// struct log in the format of debug_traceTransaction, instead of keeping the
+// steps like the StructLogger
+type streamTracer struct {
+	cfg vm.LogConfig
+	w   *bufio.Writer
+
+	step    *bytes.Buffer
+	scratch [64]byte // hex encoding of a word
+
+	// storage changed by the execution, by contract
+	storage map[common.Address]map[common.Hash]common.Hash
+
+	steps  int
+	output []byte
+	err    error // first error writing the trace
+}
+
+func newStreamTracer(config *vm.LogConfig, w *bufio.Writer) *streamTracer {
+	t := &streamTracer{
+		w:       w,
+		step:    traceStepPool.Get().(*bytes.Buffer),
+		storage: make(map[common.Address]map[common.Hash]common.Hash),
+	}
+	if config != nil {
+		t.cfg = *config
+	}
+	return t
+}
+
+// release returns the buffers of the tracer to their pool
+func (t *streamTracer) release() {
+	t.step.Reset()
+	traceStepPool.Put(t.step)
+	t.step = nil
+}
+
+func (t *streamTracer) CaptureStart(from common.Address, to common.Address, call bool, input []byte, gas uint64, value *big.Int) error {
+	return nil
+}
+
+func (t *streamTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.err != nil {
+		return t.err
+	}
+	if t.cfg.Limit != 0 && t.steps >= t.cfg.Limit {
+		return vm.ErrTraceLimitReached
+	}
+
+	// Like the StructLogger, record the storage written by SSTORE before the
+	// step is logged
+	if !t.cfg.DisableStorage && op == vm.SSTORE && stack.Len() >= 2 {
+		changed, ok := t.storage[contract.Address()]
+		if !ok {
+			changed = make(map[common.Hash]common.Hash)
+			t.storage[contract.Address()] = changed
+		}
+		changed[common.BigToHash(stack.Back(0))] = common.BigToHash(stack.Back(1))
+	}
+
+	buf := t.step
+	buf.Reset()
+	if t.steps > 0 {
+		buf.WriteByte(',')
+	}
+	buf.WriteString(`{"pc":`)
+	buf.WriteString(strconv.FormatUint(pc, 10))
+	buf.WriteString(`,"op":"`)
+	buf.WriteString(op.String())
+	buf.WriteString(`","gas":`)
+	buf.WriteString(strconv.FormatUint(gas, 10))
+	buf.WriteString(`,"gasCost":`)
+	buf.WriteString(strconv.FormatUint(cost, 10))
+	buf.WriteString(`,"depth":`)
+	buf.WriteString(strconv.Itoa(depth))
+	if err != nil {
+		msg, _ := json.Marshal(err.Error())
+		buf.WriteString(`,"error":`)
+		buf.Write(msg)
+	}
+	if !t.cfg.DisableStack {
+		buf.WriteString(`,"stack":[`)
+		for i, v := range stack.Data() {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			var word [32]byte
+			math.ReadBits(v, word[:])
+			t.writeHex(word[:])
+		}
+		buf.WriteByte(']')
+	}
+	if !t.cfg.DisableMemory {
+		buf.WriteString(`,"memory":[`)
+		data := memory.Data()
+		for i := 0; i+32 <= len(data); i += 32 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			t.writeHex(data[i : i+32])
+		}
+		buf.WriteByte(']')
+	}
+	if !t.cfg.DisableStorage {
+		buf.WriteString(`,"storage":{`)
+		first := true
+		for key, value := range t.storage[contract.Address()] {
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			t.writeHex(key[:])
+			buf.WriteByte(':')
+			t.writeHex(value[:])
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte('}')
+
+	t.steps++
+	_, t.err = t.w.Write(buf.Bytes())
+	return t.err
+}
+
+// writeHex writes a word of at most 32 bytes as a JSON string of hex digits
+func (t *streamTracer) writeHex(b []byte) {
+	n := hex.Encode(t.scratch[:], b)
+	t.step.WriteByte('"')
+	t.step.Write(t.scratch[:n])
+	t.step.WriteByte('"')
+}
+
+func (t *streamTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+func (t *streamTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	t.output = output
+	return nil
+}
+
+//StreamTrace is like TraceTransaction, but writes the trace to w as JSON, in
+//the format of debug_traceTransaction, while the transaction is re-executed.
+//The steps are not kept, and the buffers are pooled, so that large traces
+//don't stall the commits with garbage collection. If the re-execution fails
+//after steps were written, the JSON is left incomplete.
+func (s *State) StreamTrace(txHash common.Hash, config *vm.LogConfig, w io.Writer) error {
+	bw := traceWriterPool.Get().(*bufio.Writer)
+	bw.Reset(w)
+	defer func() {
+		bw.Reset(nil)
+		traceWriterPool.Put(bw)
+	}()
+
+	if _, err := bw.WriteString(`{"structLogs":[`); err != nil {
+		return err
+	}
+	tracer := newStreamTracer(config, bw)
+	defer tracer.release()
+
+	receipt, err := s.replayTransaction(txHash, tracer)
+	if err != nil {
+		return err
+	}
+	if tracer.err != nil {
+		return tracer.err
+	}
+
+	bw.WriteString(`],"gas":`)
+	bw.WriteString(strconv.FormatUint(receipt.GasUsed, 10))
+	bw.WriteString(`,"failed":`)
+	bw.WriteString(strconv.FormatBool(receipt.Status == ethTypes.ReceiptStatusFailed))
+	bw.WriteString(`,"returnValue":"`)
+	bw.WriteString(hex.EncodeToString(tracer.output))
+	bw.WriteString(`"}`)
+	return bw.Flush()
+}
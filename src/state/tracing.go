@@ -0,0 +1,20 @@
+package state
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// otelTracer records the spans of the State and its WAS, through the provider
+// set up by the tracing package
+var otelTracer = otel.Tracer("github.com/Fantom-foundation/go-evm/src/state")
+
+// endSpan ends a span, marking it failed if err is not nil
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -1,18 +1,57 @@
 package state
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"math/big"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	ethState "github.com/ethereum/go-ethereum/core/state"
 	ethTypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	// DefaultGlobalSlots is the default maximum number of transactions, applied
+	// or queued, held by the pool
+	DefaultGlobalSlots = 4096
+	// DefaultAccountSlots is the default maximum number of transactions,
+	// applied or queued, held by the pool for a single sender
+	DefaultAccountSlots = 64
+	// DefaultAccountPending is the default maximum number of transactions of a
+	// single sender applied since the last Reset
+	DefaultAccountPending = 16
+)
+
+var (
+	// ErrTxPoolFull is returned when a transaction doesn't fit in the pool:
+	// its sender has used all its slots, or the pool is full and the
+	// transaction doesn't pay more than the cheapest queued one
+	ErrTxPoolFull = errors.New("txpool is full")
+
+	// ErrReplaceUnderpriced is returned when a transaction replacing another
+	// one with the same nonce doesn't bump the gas price enough
+	ErrReplaceUnderpriced = errors.New("replacement transaction underpriced")
+)
+
+// DefaultPriceBump is the default minimum gas price increase, in percent, for a
+// transaction to replace another one with the same nonce
+const DefaultPriceBump = 10
+
+// TxPool validates transactions against a copy of the state before they are
+// submitted to consensus. Transactions whose nonce is ahead of their sender's
+// nonce are queued, and promoted once the gap is filled.
 type TxPool struct {
+	sync.Mutex
+
 	ethState *ethState.StateDB
 
 	signer       ethTypes.Signer
@@ -21,6 +60,50 @@ type TxPool struct {
 	gasLimit     uint64
 	totalUsedGas uint64
 	gp           *core.GasPool
+	pending      int // transactions accepted since the last Reset
+
+	// root the statedb was last reset to, and transactions applied since then,
+	// also counted by sender
+	root    common.Hash
+	applied []*ethTypes.Transaction
+	senders map[common.Address]int
+
+	priceBump   uint64   // minimum gas price increase (%) of a replacement
+	minGasPrice *big.Int // minimum gas price of the network, nil for none
+
+	codeLimits CodeSizeLimits
+
+	// future-nonce transactions by sender and nonce
+	queue  map[common.Address]map[uint64]*ethTypes.Transaction
+	queued int
+
+	// maximum number of transactions held overall and by sender, and of
+	// transactions applied by sender (0 for no limit)
+	globalSlots    int
+	accountSlots   int
+	accountPending int
+
+	// senders exempt from the limits and from eviction
+	locals map[common.Address]bool
+
+	// maximum time spent in the pool, and when each transaction entered it.
+	// quit stops the expiry loop.
+	ttl      time.Duration
+	added    map[common.Hash]time.Time
+	expiring bool
+	quit     chan struct{}
+	closed   bool
+
+	// transactions applied before the last Reset, and so submitted to
+	// consensus, which it hasn't committed yet
+	inflight []*ethTypes.Transaction
+
+	// blob data of the blob transactions in the pool
+	blobs map[common.Hash][]byte
+
+	// events waiting to be sent to the subscribers of feed
+	feed   event.Feed
+	events chan TxPoolEvent
 
 	logger *logrus.Logger
 }
@@ -32,36 +115,185 @@ func NewTxPool(ethState *ethState.StateDB,
 	gasLimit uint64,
 	logger *logrus.Logger) *TxPool {
 
-	return &TxPool{
-		ethState:    ethState,
-		signer:      signer,
-		chainConfig: chainConfig,
-		vmConfig:    vmConfig,
-		gasLimit:    gasLimit,
-		logger:      logger,
+	p := &TxPool{
+		ethState:       ethState,
+		signer:         signer,
+		chainConfig:    chainConfig,
+		vmConfig:       vmConfig,
+		gasLimit:       gasLimit,
+		priceBump:      DefaultPriceBump,
+		codeLimits:     DefaultCodeSizeLimits(),
+		queue:          make(map[common.Address]map[uint64]*ethTypes.Transaction),
+		globalSlots:    DefaultGlobalSlots,
+		accountSlots:   DefaultAccountSlots,
+		accountPending: DefaultAccountPending,
+		locals:         make(map[common.Address]bool),
+		added:          make(map[common.Hash]time.Time),
+		quit:           make(chan struct{}),
+		blobs:          make(map[common.Hash][]byte),
+		events:         make(chan TxPoolEvent, txPoolBacklog),
+		logger:         logger,
 	}
+	go p.dispatchEvents()
+
+	return p
 }
 
-func (p *TxPool) Reset(root common.Hash) error {
+// Reset resets the pool's statedb to root, drops the queued transactions made
+// stale by the new state and returns those which became executable, in nonce
+// order per sender. The returned transactions have been applied to the pool's
+// statedb and are ready to be submitted.
+func (p *TxPool) Reset(root common.Hash) ([]*ethTypes.Transaction, error) {
+	p.Lock()
+	defer p.Unlock()
 
-	err := p.ethState.Reset(root)
-	if err != nil {
+	inflight := append(p.inflight, p.applied...)
+	if err := p.reset(root); err != nil {
+		return nil, err
+	}
+	p.inflight = p.stillInFlight(inflight)
+
+	promoted := []*ethTypes.Transaction{}
+	for from, txs := range p.queue {
+		nonce := p.ethState.GetNonce(from)
+		for n := range txs {
+			if n < nonce {
+				p.publishDropped(txs[n], "nonce too low")
+				p.dequeue(from, n)
+			}
+		}
+		promoted = append(promoted, p.promote(from)...)
+	}
+	p.pruneAdded()
+
+	return promoted, nil
+}
+
+func (p *TxPool) reset(root common.Hash) error {
+	if err := p.ethState.Reset(root); err != nil {
 		return err
 	}
 
+	p.root = root
+	p.applied = []*ethTypes.Transaction{}
+	p.senders = make(map[common.Address]int)
 	p.totalUsedGas = 0
+	p.pending = 0
 	p.gp = new(core.GasPool).AddGas(p.gasLimit)
 
 	return nil
 }
 
+// CheckTx applies a transaction to the pool's statedb. It fails if the
+// transaction is not executable right now, including when its nonce is ahead
+// of its sender's.
 func (p *TxPool) CheckTx(tx *ethTypes.Transaction) error {
+	p.Lock()
+	defer p.Unlock()
+
+	return p.checkTx(tx)
+}
+
+// AddTx applies a transaction to the pool's statedb, or queues it if its nonce
+// is ahead of its sender's. It returns the transactions ready to be submitted:
+// tx itself and the queued transactions it unlocked, in nonce order. Nothing
+// is returned if tx was queued.
+//
+// A transaction with the same sender and nonce as one applied since the last
+// Reset replaces it if it bumps the gas price by at least priceBump percent.
+// Both are submitted to consensus, which applies whichever comes first.
+//
+// A sender with accountPending transactions applied since the last Reset has
+// its next transactions queued until the following Reset.
+//
+// When the pool is full, tx evicts the cheapest queued transaction if it pays
+// a higher gas price, and is rejected otherwise. Applied transactions are
+// never evicted since they were already submitted.
+//
+// Transactions of local senders are exempt from the limits and never evicted.
+func (p *TxPool) AddTx(tx *ethTypes.Transaction) ([]*ethTypes.Transaction, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	from, err := ethTypes.Sender(p.signer, tx)
+	if err != nil {
+		return nil, newSigError(tx, err)
+	}
+
+	nonce := p.ethState.GetNonce(from)
+	if tx.Nonce() > nonce {
+		return nil, p.enqueue(from, tx)
+	}
+	if tx.Nonce() < nonce {
+		if i := p.findApplied(from, tx.Nonce()); i >= 0 {
+			if err := p.replace(i, tx); err != nil {
+				return nil, err
+			}
+			return []*ethTypes.Transaction{tx}, nil
+		}
+	}
+
+	if p.pendingFull(from) {
+		return nil, p.enqueue(from, tx)
+	}
+
+	evict, err := p.checkRoom(from, tx)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkTx(tx); err != nil {
+		return nil, err
+	}
+	p.touch(tx)
+	p.publishTx(TxPending, from, tx)
+	if evict != nil {
+		evict()
+	}
+
+	return append([]*ethTypes.Transaction{tx}, p.promote(from)...), nil
+}
+
+// AddBlobTx is like AddTx for a transaction carrying blob data. The data is
+// held until the transaction leaves the pool.
+func (p *TxPool) AddBlobTx(tx *ethTypes.Transaction, data []byte) ([]*ethTypes.Transaction, error) {
+	p.Lock()
+	p.blobs[tx.Hash()] = data
+	p.Unlock()
+
+	ready, err := p.AddTx(tx)
+	if err != nil {
+		p.Lock()
+		delete(p.blobs, tx.Hash())
+		p.Unlock()
+	}
+	return ready, err
+}
+
+// Blob returns the blob data of a transaction in the pool
+func (p *TxPool) Blob(hash common.Hash) ([]byte, bool) {
+	p.Lock()
+	defer p.Unlock()
+
+	data, ok := p.blobs[hash]
+	return data, ok
+}
+
+func (p *TxPool) checkTx(tx *ethTypes.Transaction) error {
 
 	msg, err := tx.AsMessage(p.signer)
 	if err != nil {
-		p.logger.WithError(err).Error("Converting Transaction to Message")
+		return newSigError(tx, err)
+	}
+	if err := p.codeLimits.checkInitCode(tx); err != nil {
 		return err
 	}
+	if p.minGasPrice != nil && !p.locals[msg.From()] && tx.GasPrice().Cmp(p.minGasPrice) < 0 {
+		return &TxApplyError{
+			Hash:  tx.Hash(),
+			Class: ErrUnderpriced,
+			Cause: fmt.Errorf("%s: %s, minimum %s", ErrUnderpriced, tx.GasPrice(), p.minGasPrice),
+		}
+	}
 
 	context := vm.Context{
 		CanTransfer: core.CanTransfer,
@@ -72,23 +304,432 @@ func (p *TxPool) CheckTx(tx *ethTypes.Transaction) error {
 		GasLimit:    msg.Gas(),
 		GasPrice:    msg.GasPrice(),
 		BlockNumber: big.NewInt(0), // The vm has a dependency on this.
+		Time:        big.NewInt(time.Now().Unix()),
+		Difficulty:  new(big.Int),
 	}
 
 	// The EVM should never be reused and is not thread safe.
 	vmenv := vm.NewEVM(context, p.ethState, &p.chainConfig, p.vmConfig)
 
 	// Apply the transaction to the current state (included in the env)
-	_, gas, _, err := core.ApplyMessage(vmenv, msg, p.gp)
+	snapshot := p.ethState.Snapshot()
+	_, gas, failed, err := core.ApplyMessage(vmenv, msg, p.gp)
 	if err != nil {
-		p.logger.WithError(err).Error("Applying transaction to TxPool")
-		return err
+		p.logger.WithError(err).WithField("hash", tx.Hash().Hex()).Debug("Applying transaction to TxPool")
+		return newTxApplyError(tx, err)
+	}
+	// Deploying too much code would only burn the gas of the sender
+	if size, tooLarge := p.codeLimits.codeTooLarge(p.ethState, msg); !failed && tooLarge {
+		p.ethState.RevertToSnapshot(snapshot)
+		p.gp.AddGas(gas)
+		return p.codeLimits.codeSizeError(tx, size)
 	}
 
 	p.totalUsedGas += gas
+	p.pending++
+	p.applied = append(p.applied, tx)
+	p.senders[msg.From()]++
+
+	return nil
+}
+
+// findApplied returns the index of the transaction of from with the given
+// nonce among the transactions applied since the last Reset, or -1
+func (p *TxPool) findApplied(from common.Address, nonce uint64) int {
+	for i, tx := range p.applied {
+		if tx.Nonce() != nonce {
+			continue
+		}
+		if sender, err := ethTypes.Sender(p.signer, tx); err == nil && sender == from {
+			return i
+		}
+	}
+	return -1
+}
+
+// replace replaces the i-th applied transaction with tx, and re-applies all
+// the transactions applied since the last Reset. The replaced transaction, and
+// those which no longer apply, were already submitted to consensus, which may
+// still order them: they are kept in flight rather than dropped.
+func (p *TxPool) replace(i int, tx *ethTypes.Transaction) error {
+	old := p.applied[i]
+	if !p.priceBumped(old, tx) {
+		return ErrReplaceUnderpriced
+	}
+
+	txs := make([]*ethTypes.Transaction, len(p.applied))
+	copy(txs, p.applied)
+	txs[i] = tx
+
+	if err := p.reapply(txs, tx); err != nil {
+		// The replacement doesn't apply: restore the original transactions
+		txs[i] = old
+		if rerr := p.reapply(txs, nil); rerr != nil {
+			return rerr
+		}
+		p.keepUnapplied(txs)
+		return err
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"old": old.Hash().Hex(),
+		"new": tx.Hash().Hex(),
+	}).Debug("Replaced tx")
+
+	p.touch(tx)
+	from, _ := ethTypes.Sender(p.signer, tx)
+	p.publishReplaced(from, old, tx)
+	p.publishTx(TxPending, from, tx)
+	p.inflight = append(p.inflight, old)
+	p.keepUnapplied(txs)
+
+	return nil
+}
+
+// keepUnapplied keeps in flight the transactions of txs which reapply didn't
+// apply. They were submitted to consensus, so they are not published as
+// dropped: the next Resets forget them once their nonce is reached.
+func (p *TxPool) keepUnapplied(txs []*ethTypes.Transaction) {
+	applied := make(map[common.Hash]bool, len(p.applied))
+	for _, tx := range p.applied {
+		applied[tx.Hash()] = true
+	}
+	for _, tx := range txs {
+		if !applied[tx.Hash()] {
+			p.inflight = append(p.inflight, tx)
+		}
+	}
+}
+
+// reapply resets the statedb to the last root and applies txs. It fails if
+// required doesn't apply; the other transactions which fail are skipped.
+func (p *TxPool) reapply(txs []*ethTypes.Transaction, required *ethTypes.Transaction) error {
+	if err := p.reset(p.root); err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		if err := p.checkTx(tx); err != nil {
+			if tx == required {
+				return err
+			}
+			p.logger.WithError(err).WithField("hash", tx.Hash().Hex()).Debug("Tx no longer applies to TxPool")
+		}
+	}
+	return nil
+}
+
+// priceBumped reports whether the gas price of tx is at least priceBump
+// percent higher than the gas price of old
+func (p *TxPool) priceBumped(old, tx *ethTypes.Transaction) bool {
+	threshold := new(big.Int).Mul(old.GasPrice(), big.NewInt(int64(100+p.priceBump)))
+	price := new(big.Int).Mul(tx.GasPrice(), big.NewInt(100))
+	return price.Cmp(threshold) >= 0
+}
+
+func (p *TxPool) enqueue(from common.Address, tx *ethTypes.Transaction) error {
+	txs, ok := p.queue[from]
+	if !ok {
+		txs = make(map[uint64]*ethTypes.Transaction)
+		p.queue[from] = txs
+	}
+
+	// A transaction replacing a queued one doesn't take more room
+	if old, ok := txs[tx.Nonce()]; ok {
+		if !p.priceBumped(old, tx) {
+			return ErrReplaceUnderpriced
+		}
+		p.publishReplaced(from, old, tx)
+	} else {
+		evict, err := p.checkRoom(from, tx)
+		if err != nil {
+			if len(txs) == 0 {
+				delete(p.queue, from)
+			}
+			return err
+		}
+		if evict != nil {
+			evict()
+		}
+		p.queued++
+	}
+	txs[tx.Nonce()] = tx
+	p.touch(tx)
+	p.publishTx(TxQueued, from, tx)
+
+	p.logger.WithFields(logrus.Fields{
+		"hash":  tx.Hash().Hex(),
+		"from":  from.Hex(),
+		"nonce": tx.Nonce(),
+	}).Debug("Queued future tx")
 
 	return nil
 }
 
+// checkRoom checks that a new transaction of from fits within the pool limits.
+// If the pool is full, it returns a function evicting the cheapest queued
+// transaction, provided tx pays a higher gas price.
+func (p *TxPool) checkRoom(from common.Address, tx *ethTypes.Transaction) (func(), error) {
+	if p.locals[from] {
+		return nil, nil
+	}
+	if p.accountSlots > 0 && p.senders[from]+len(p.queue[from]) >= p.accountSlots {
+		return nil, ErrTxPoolFull
+	}
+	if p.globalSlots <= 0 || p.pending+p.queued < p.globalSlots {
+		return nil, nil
+	}
+
+	var (
+		cheapest *ethTypes.Transaction
+		owner    common.Address
+	)
+	for addr, txs := range p.queue {
+		if p.locals[addr] {
+			continue
+		}
+		for _, queued := range txs {
+			if cheapest == nil || queued.GasPrice().Cmp(cheapest.GasPrice()) < 0 {
+				cheapest, owner = queued, addr
+			}
+		}
+	}
+	if cheapest == nil || tx.GasPrice().Cmp(cheapest.GasPrice()) <= 0 {
+		return nil, ErrTxPoolFull
+	}
+
+	return func() {
+		p.publishDropped(cheapest, "evicted by a higher gas price")
+		p.dequeue(owner, cheapest.Nonce())
+		p.logger.WithFields(logrus.Fields{
+			"hash":      cheapest.Hash().Hex(),
+			"gas_price": cheapest.GasPrice(),
+		}).Debug("Evicted underpriced tx")
+	}, nil
+}
+
+// pendingFull reports whether from has used all its pending slots until the
+// next Reset
+func (p *TxPool) pendingFull(from common.Address) bool {
+	return !p.locals[from] && p.accountPending > 0 && p.senders[from] >= p.accountPending
+}
+
+func (p *TxPool) dequeue(from common.Address, nonce uint64) {
+	txs := p.queue[from]
+	if _, ok := txs[nonce]; !ok {
+		return
+	}
+	delete(txs, nonce)
+	p.queued--
+	if len(txs) == 0 {
+		delete(p.queue, from)
+	}
+}
+
+// promote applies the queued transactions of from whose nonces follow its
+// current nonce, and returns them. A queued transaction which fails to apply
+// is dropped and stops the promotion, as does reaching the pending limit of
+// from.
+func (p *TxPool) promote(from common.Address) []*ethTypes.Transaction {
+	promoted := []*ethTypes.Transaction{}
+	for {
+		if p.pendingFull(from) {
+			return promoted
+		}
+		nonce := p.ethState.GetNonce(from)
+		tx, ok := p.queue[from][nonce]
+		if !ok {
+			return promoted
+		}
+		p.dequeue(from, nonce)
+
+		if err := p.checkTx(tx); err != nil {
+			p.logger.WithError(err).WithField("hash", tx.Hash().Hex()).Error("Dropping queued tx")
+			p.publishDropped(tx, err.Error())
+			return promoted
+		}
+		p.publishTx(TxPromoted, from, tx)
+		promoted = append(promoted, tx)
+	}
+}
+
+// SetPriceBump sets the minimum gas price increase, in percent, for a
+// transaction to replace another one with the same nonce
+func (p *TxPool) SetPriceBump(percent uint64) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.priceBump = percent
+}
+
+// SetChainID sets the EIP-155 chain id of the transactions
+func (p *TxPool) SetChainID(id *big.Int) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.signer = ethTypes.NewEIP155Signer(id)
+	p.chainConfig.ChainID = id
+}
+
+// setNetwork sets the parameters of the network set by the genesis: the chain
+// config, the gas limit of a block and the minimum gas price (nil for none)
+func (p *TxPool) setNetwork(chainConfig params.ChainConfig, gasLimit uint64, minGasPrice *big.Int) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.signer = ethTypes.NewEIP155Signer(chainConfig.ChainID)
+	p.chainConfig = chainConfig
+	p.gasLimit = gasLimit
+	p.gp = new(core.GasPool)
+	if p.totalUsedGas < gasLimit {
+		p.gp.AddGas(gasLimit - p.totalUsedGas)
+	}
+	p.minGasPrice = minGasPrice
+}
+
+// SetCodeSizeLimits sets the maximum size of the contracts deployed by
+// transactions
+func (p *TxPool) SetCodeSizeLimits(limits CodeSizeLimits) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.codeLimits = limits
+}
+
+// SetLimits sets the maximum number of transactions, applied or queued, held by
+// the pool overall and for a single sender, and the maximum number of
+// transactions of a single sender applied between two Resets. 0 disables a
+// limit.
+func (p *TxPool) SetLimits(globalSlots, accountSlots, accountPending int) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.globalSlots = globalSlots
+	p.accountSlots = accountSlots
+	p.accountPending = accountPending
+}
+
+// AddLocals marks senders as local: their transactions are exempt from the
+// pool limits and from eviction
+func (p *TxPool) AddLocals(addrs []common.Address) {
+	p.Lock()
+	defer p.Unlock()
+
+	for _, addr := range addrs {
+		p.locals[addr] = true
+	}
+}
+
 func (p *TxPool) GetNonce(addr common.Address) uint64 {
+	p.Lock()
+	defer p.Unlock()
+
 	return p.ethState.GetNonce(addr)
 }
+
+func (p *TxPool) GetBalance(addr common.Address) *big.Int {
+	p.Lock()
+	defer p.Unlock()
+
+	return p.ethState.GetBalance(addr)
+}
+
+func (p *TxPool) GetCode(addr common.Address) []byte {
+	p.Lock()
+	defer p.Unlock()
+
+	return p.ethState.GetCode(addr)
+}
+
+func (p *TxPool) GetState(addr common.Address, key common.Hash) common.Hash {
+	p.Lock()
+	defer p.Unlock()
+
+	return p.ethState.GetState(addr, key)
+}
+
+// Get returns a transaction applied since the last Reset or queued, with its
+// sender, or nil if the pool doesn't hold it
+func (p *TxPool) Get(hash common.Hash) (*ethTypes.Transaction, common.Address) {
+	p.Lock()
+	defer p.Unlock()
+
+	for _, tx := range p.applied {
+		if tx.Hash() == hash {
+			from, _ := ethTypes.Sender(p.signer, tx)
+			return tx, from
+		}
+	}
+	for from, txs := range p.queue {
+		for _, tx := range txs {
+			if tx.Hash() == hash {
+				return tx, from
+			}
+		}
+	}
+	return nil, common.Address{}
+}
+
+// Content returns the transactions applied since the last Reset, in order,
+// and the queued transactions, by sender and nonce
+func (p *TxPool) Content() (pending []PoolTx, queued []PoolTx) {
+	p.Lock()
+	defer p.Unlock()
+
+	pending = make([]PoolTx, 0, len(p.applied))
+	for _, tx := range p.applied {
+		from, _ := ethTypes.Sender(p.signer, tx)
+		pending = append(pending, newPoolTx(from, tx))
+	}
+
+	senders := make([]common.Address, 0, len(p.queue))
+	for from := range p.queue {
+		senders = append(senders, from)
+	}
+	sort.Slice(senders, func(i, j int) bool {
+		return bytes.Compare(senders[i].Bytes(), senders[j].Bytes()) < 0
+	})
+
+	queued = make([]PoolTx, 0, p.queued)
+	for _, from := range senders {
+		nonces := make([]uint64, 0, len(p.queue[from]))
+		for nonce := range p.queue[from] {
+			nonces = append(nonces, nonce)
+		}
+		sort.Slice(nonces, func(i, j int) bool { return nonces[i] < nonces[j] })
+		for _, nonce := range nonces {
+			queued = append(queued, newPoolTx(from, p.queue[from][nonce]))
+		}
+	}
+
+	return pending, queued
+}
+
+func newPoolTx(from common.Address, tx *ethTypes.Transaction) PoolTx {
+	return PoolTx{
+		Hash:     tx.Hash(),
+		From:     from,
+		To:       tx.To(),
+		Nonce:    tx.Nonce(),
+		Value:    tx.Value(),
+		GasPrice: tx.GasPrice(),
+		Gas:      tx.Gas(),
+		Size:     len(tx.Data()),
+	}
+}
+
+// Pending returns the number of transactions accepted since the last Reset
+func (p *TxPool) Pending() int {
+	p.Lock()
+	defer p.Unlock()
+
+	return p.pending
+}
+
+// Queued returns the number of future-nonce transactions waiting for a gap to
+// be filled
+func (p *TxPool) Queued() int {
+	p.Lock()
+	defer p.Unlock()
+
+	return p.queued
+}
@@ -0,0 +1,90 @@
+package state
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// TxPool event types
+const (
+	TxPending  = "pending"  // applied to the pool and submitted to consensus
+	TxQueued   = "queued"   // queued until a nonce gap is filled
+	TxPromoted = "promoted" // moved from the queue to pending
+	TxReplaced = "replaced" // replaced by a transaction with a higher gas price
+	TxDropped  = "dropped"  // removed from the pool, see Reason
+)
+
+// txPoolBacklog is the number of events waiting to be dispatched to the
+// subscribers
+const txPoolBacklog = 1024
+
+// TxPoolEvent describes a change of a transaction in the TxPool
+type TxPoolEvent struct {
+	Type  string
+	Hash  common.Hash
+	From  common.Address
+	Nonce uint64
+	// hash of the replacing transaction, for TxReplaced
+	ReplacedBy common.Hash
+	// why the transaction was dropped, for TxDropped
+	Reason string
+}
+
+// Subscribe registers a channel receiving the events of the pool
+func (p *TxPool) Subscribe(ch chan<- TxPoolEvent) event.Subscription {
+	return p.feed.Subscribe(ch)
+}
+
+// publish queues an event for the subscribers without blocking. It is called
+// with the pool locked.
+func (p *TxPool) publish(ev TxPoolEvent) {
+	select {
+	case p.events <- ev:
+	default:
+		p.logger.WithField("hash", ev.Hash.Hex()).Warn("TxPool event backlog full. Dropping event")
+	}
+}
+
+func (p *TxPool) publishTx(typ string, from common.Address, tx *ethTypes.Transaction) {
+	p.publish(TxPoolEvent{
+		Type:  typ,
+		Hash:  tx.Hash(),
+		From:  from,
+		Nonce: tx.Nonce(),
+	})
+}
+
+func (p *TxPool) publishDropped(tx *ethTypes.Transaction, reason string) {
+	from, _ := ethTypes.Sender(p.signer, tx)
+	p.publish(TxPoolEvent{
+		Type:   TxDropped,
+		Hash:   tx.Hash(),
+		From:   from,
+		Nonce:  tx.Nonce(),
+		Reason: reason,
+	})
+}
+
+func (p *TxPool) publishReplaced(from common.Address, old, tx *ethTypes.Transaction) {
+	p.publish(TxPoolEvent{
+		Type:       TxReplaced,
+		Hash:       old.Hash(),
+		From:       from,
+		Nonce:      old.Nonce(),
+		ReplacedBy: tx.Hash(),
+	})
+}
+
+// dispatchEvents sends the queued events to the subscribers, in order
+func (p *TxPool) dispatchEvents() {
+	for ev := range p.events {
+		p.feed.Send(ev)
+	}
+}
+
+//SubscribeTxPoolEvents registers a channel receiving the TxPool events: when
+//transactions enter, leave, or get replaced in the pool
+func (s *State) SubscribeTxPoolEvents(ch chan<- TxPoolEvent) event.Subscription {
+	return s.txPool.Subscribe(ch)
+}
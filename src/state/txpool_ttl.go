@@ -0,0 +1,136 @@
+package state
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// minExpiryPeriod is the minimum interval between two expiry checks
+const minExpiryPeriod = time.Second
+
+// SetTTL sets how long a queued transaction may stay in the pool without being
+// submitted to consensus. Expired transactions are dropped, freeing their slots.
+// 0 disables expiry.
+func (p *TxPool) SetTTL(ttl time.Duration) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.ttl = ttl
+	if ttl > 0 && !p.expiring && !p.closed {
+		p.expiring = true
+		go p.expireLoop()
+	}
+}
+
+// Close stops the expiry loop
+func (p *TxPool) Close() {
+	p.Lock()
+	defer p.Unlock()
+
+	if !p.closed {
+		p.closed = true
+		close(p.quit)
+	}
+}
+
+func (p *TxPool) expireLoop() {
+	for {
+		p.Lock()
+		period := p.ttl / 4
+		p.Unlock()
+		if period < minExpiryPeriod {
+			period = minExpiryPeriod
+		}
+
+		select {
+		case <-p.quit:
+			return
+		case <-time.After(period):
+		}
+		if err := p.Expire(time.Now()); err != nil {
+			p.logger.WithError(err).Error("Expiring TxPool transactions")
+		}
+	}
+}
+
+// Expire drops the queued transactions added to the pool more than ttl before
+// now. The applied transactions were submitted to consensus, which may still
+// order them, so they are left for the next Reset to settle.
+func (p *TxPool) Expire(now time.Time) error {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.ttl <= 0 {
+		return nil
+	}
+
+	dropped := 0
+	for from, txs := range p.queue {
+		for nonce, tx := range txs {
+			added, ok := p.added[tx.Hash()]
+			if ok && now.Sub(added) > p.ttl {
+				p.publishDropped(tx, "expired")
+				p.dequeue(from, nonce)
+				delete(p.added, tx.Hash())
+				delete(p.blobs, tx.Hash())
+				dropped++
+			}
+		}
+	}
+	if dropped > 0 {
+		p.logger.WithField("expired", dropped).Debug("Expired queued txs")
+	}
+	return nil
+}
+
+// touch records when a transaction entered the pool
+func (p *TxPool) touch(tx *ethTypes.Transaction) {
+	if _, ok := p.added[tx.Hash()]; !ok {
+		p.added[tx.Hash()] = time.Now()
+	}
+}
+
+// stillInFlight returns the transactions of txs, submitted to consensus, whose
+// nonce the state reset to hasn't reached yet
+func (p *TxPool) stillInFlight(txs []*ethTypes.Transaction) []*ethTypes.Transaction {
+	res := []*ethTypes.Transaction{}
+	for _, tx := range txs {
+		from, err := ethTypes.Sender(p.signer, tx)
+		if err != nil {
+			continue
+		}
+		if tx.Nonce() >= p.ethState.GetNonce(from) {
+			res = append(res, tx)
+		}
+	}
+	return res
+}
+
+// pruneAdded forgets the transactions, and blob data, which are no longer in
+// the pool nor in flight
+func (p *TxPool) pruneAdded() {
+	live := make(map[common.Hash]bool, len(p.applied)+len(p.inflight)+p.queued)
+	for _, tx := range p.applied {
+		live[tx.Hash()] = true
+	}
+	for _, tx := range p.inflight {
+		live[tx.Hash()] = true
+	}
+	for _, txs := range p.queue {
+		for _, tx := range txs {
+			live[tx.Hash()] = true
+		}
+	}
+	for hash := range p.added {
+		if !live[hash] {
+			delete(p.added, hash)
+		}
+	}
+	for hash := range p.blobs {
+		if !live[hash] {
+			delete(p.blobs, hash)
+		}
+	}
+}
@@ -3,10 +3,39 @@ package state
 import (
 	"bytes"
 	"encoding/json"
+	"math/big"
 
+	"github.com/ethereum/go-ethereum/common"
 	ethTypes "github.com/ethereum/go-ethereum/core/types"
 )
 
+// TxLookupEntry locates an applied transaction in the chain
+type TxLookupEntry struct {
+	BlockHash   common.Hash
+	BlockNumber uint64
+	Index       uint64         // position of the transaction in the block
+	From        common.Address // sender of the transaction
+}
+
+// legacyTxLookupEntry is a TxLookupEntry indexed before senders were recorded
+type legacyTxLookupEntry struct {
+	BlockHash   common.Hash
+	BlockNumber uint64
+	Index       uint64
+}
+
+// PoolTx describes a transaction held by the TxPool
+type PoolTx struct {
+	Hash     common.Hash
+	From     common.Address
+	To       *common.Address
+	Nonce    uint64
+	Value    *big.Int
+	GasPrice *big.Int
+	Gas      uint64
+	Size     int // size of the payload, in bytes
+}
+
 type TxError struct {
 	Tx    ethTypes.Transaction `json:"tx"`
 	Error string               `json:"error"`
@@ -1,7 +1,10 @@
 package state
 
 import (
+	"context"
+	"encoding/binary"
 	"math/big"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
@@ -13,6 +16,8 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // write ahead state, updated with each AppendTx
@@ -25,20 +30,45 @@ type WriteAheadState struct {
 	chainConfig params.ChainConfig // vm.env is still tightly coupled with chainConfig
 	vmConfig    vm.Config
 	gasLimit    uint64
+	gasCaps     *GasCapPolicy
+	codeLimits  CodeSizeLimits
+	feePolicy   *FeePolicy
+
+	// beneficiary of the blocks whose consensus system names none
+	coinbase common.Address
+
+	// index, consensus hash, consensus timestamp and beneficiary of the block
+	// being applied. blockStarted is set as soon as a transaction of the block
+	// is applied
+	blockIndex    int64
+	blockHash     common.Hash
+	blockTime     int64
+	blockCoinbase common.Address
+	blockStarted  bool
+	legacyHeader  bool // the block replayed is recorded without its beneficiary
 
 	txIndex      int
 	transactions []*ethTypes.Transaction
 	receipts     []*ethTypes.Receipt
+	reverts      map[common.Hash][]byte // return data of the reverted transactions
+	capped       map[common.Hash]uint64 // gas cap exhausted by failed transactions
+	blobs        map[common.Hash][]byte // blob data of the blob transactions
 	allLogs      []*ethTypes.Log
 
 	totalUsedGas *big.Int
 	gp           *core.GasPool
 
+	// fees of the block credited to its beneficiary, and the amount burned
+	// by the fee policy on Commit
+	blockFees *big.Int
+	burned    *big.Int
+
 	logger *logrus.Logger
 }
 
 func NewWriteAheadState(db ethdb.Database,
 	root common.Hash,
+	blockIndex int64,
 	signer ethTypes.Signer,
 	chainConfig params.ChainConfig,
 	vmConfig vm.Config,
@@ -53,10 +83,17 @@ func NewWriteAheadState(db ethdb.Database,
 	return &WriteAheadState{
 		db:          db,
 		ethState:    ethState,
+		blockIndex:  blockIndex,
 		signer:      signer,
 		chainConfig: chainConfig,
 		vmConfig:    vmConfig,
 		gasLimit:    gasLimit,
+		codeLimits:  DefaultCodeSizeLimits(),
+		reverts:     make(map[common.Hash][]byte),
+		capped:      make(map[common.Hash]uint64),
+		blobs:       make(map[common.Hash][]byte),
+		blockFees:   new(big.Int),
+		burned:      new(big.Int),
 		logger:      logger,
 	}, nil
 }
@@ -68,53 +105,190 @@ func (was *WriteAheadState) Reset(root common.Hash) error {
 		return err
 	}
 
+	if was.blockStarted {
+		was.blockIndex++
+	}
+	was.blockHash = common.Hash{}
+	was.blockTime = 0
+	was.blockCoinbase = common.Address{}
+	was.blockStarted = false
+	was.legacyHeader = false
+
 	was.txIndex = 0
 	was.transactions = []*ethTypes.Transaction{}
 	was.receipts = []*ethTypes.Receipt{}
+	was.reverts = make(map[common.Hash][]byte)
+	was.capped = make(map[common.Hash]uint64)
+	was.blobs = make(map[common.Hash][]byte)
 	was.allLogs = []*ethTypes.Log{}
 
 	was.totalUsedGas = new(big.Int).SetUint64(0)
 	was.gp = new(core.GasPool).AddGas(was.gasLimit)
+	was.blockFees = new(big.Int)
+	was.burned = new(big.Int)
 
 	was.logger.WithFields(logrus.Fields{
-		"gasLimit": gasLimit,
+		"gasLimit": was.gasLimit,
 		"was.gp":   was.gp,
 	}).Debug("(was *WriteAheadState) Reset(root common.Hash)")
 
 	return nil
 }
 
-func (was *WriteAheadState) ApplyTransaction(tx ethTypes.Transaction, txIndex int, blockHash common.Hash) error {
+// startBlock marks the beginning of the block with the given consensus hash,
+// timestamp and beneficiary, the default one if zero. The block is recorded on
+// the next Commit.
+func (was *WriteAheadState) startBlock(blockHash common.Hash, blockTime int64, coinbase common.Address) {
+	if coinbase == (common.Address{}) {
+		coinbase = was.coinbase
+	}
+	was.blockHash = blockHash
+	was.blockTime = blockTime
+	was.blockCoinbase = coinbase
+	was.blockStarted = true
+}
 
-	msg, err := tx.AsMessage(was.signer)
+// startRecordedBlock marks the beginning of a block committed before, e.g. by
+// another node, which is applied again: the fees go to its recorded
+// beneficiary, even zero. A legacy header records none, so the fees go to the
+// default coinbase, and the block is recorded again without it.
+func (was *WriteAheadState) startRecordedBlock(block *Block, blockTime int64) {
+	was.startBlock(block.ConsensusHash, blockTime, block.Coinbase)
+	if !block.legacy {
+		was.blockCoinbase = block.Coinbase
+	}
+	was.legacyHeader = block.legacy
+}
+
+// parentTime returns the timestamp of the last committed block, 0 before the
+// first one
+func (was *WriteAheadState) parentTime() int64 {
+	if was.blockIndex == 0 {
+		return 0
+	}
+	data, err := was.db.Get(blockHeaderKey(was.blockIndex - 1))
 	if err != nil {
-		was.logger.WithError(err).Error("Converting Transaction to Message")
-		return err
+		return 0
 	}
+	parent := new(Block)
+	if err := parent.Unmarshal(data); err != nil {
+		return 0
+	}
+	return int64(parent.Timestamp)
+}
 
-	context := vm.Context{
+// newContext returns the vm.Context to execute msg in the block being applied.
+// NUMBER, TIMESTAMP and COINBASE are the index, the consensus time and the
+// beneficiary of the block, which is credited the fees of its transactions.
+// Calls made before the next block starts see the current time.
+func (was *WriteAheadState) newContext(msg ethTypes.Message) vm.Context {
+	blockTime, coinbase := was.blockTime, was.blockCoinbase
+	if !was.blockStarted {
+		blockTime, coinbase = time.Now().Unix(), was.coinbase
+	}
+	return vm.Context{
 		CanTransfer: core.CanTransfer,
 		Transfer:    core.Transfer,
-		GetHash:     func(uint64) common.Hash { return blockHash },
+		GetHash:     getHashFn(was.db),
 		Origin:      msg.From(),
+		Coinbase:    coinbase,
 		GasLimit:    msg.Gas(),
 		GasPrice:    msg.GasPrice(),
-		BlockNumber: big.NewInt(0), // The vm has a dependency on this..
+		BlockNumber: big.NewInt(was.blockIndex),
+		Time:        big.NewInt(blockTime),
+		Difficulty:  new(big.Int),
+	}
+}
+
+func (was *WriteAheadState) ApplyTransaction(tx ethTypes.Transaction, blockHash common.Hash) error {
+	return was.applyTransaction(context.Background(), tx, nil, blockHash)
+}
+
+// applyTransaction applies a transaction, with its blob data if it is a blob
+// transaction
+func (was *WriteAheadState) applyTransaction(ctx context.Context, tx ethTypes.Transaction, blob []byte, blockHash common.Hash) (err error) {
+	_, span := otelTracer.Start(ctx, "WAS.ApplyTransaction")
+	defer func() { endSpan(span, err) }()
+
+	// A consensus system which starts no block (see State.BeginBlock) gives no
+	// block time: the block gets the timestamp of its parent, the same on all
+	// the nodes
+	if !was.blockStarted {
+		was.startBlock(blockHash, was.parentTime(), common.Address{})
+	}
+
+	msg, err := tx.AsMessage(was.signer)
+	if err != nil {
+		return newSigError(&tx, err)
+	}
+	if err := was.codeLimits.checkInitCode(&tx); err != nil {
+		return err
+	}
+
+	// Blob data is paid for up front; the execution gets the remaining gas
+	snapshot := was.ethState.Snapshot()
+	dataGas := blobGas(blob)
+	if dataGas > 0 {
+		if err := was.chargeBlobGas(msg, dataGas); err != nil {
+			return newTxApplyError(&tx, err)
+		}
+		msg = ethTypes.NewMessage(msg.From(), msg.To(), msg.Nonce(), msg.Value(), msg.Gas()-dataGas, msg.GasPrice(), msg.Data(), msg.CheckNonce())
 	}
+
+	// Transactions to a capped contract execute with at most the cap
+	gasCap, capped := was.gasCaps.gasCap(msg.To(), was.blockIndex)
+	if capped && msg.Gas() > gasCap {
+		msg = ethTypes.NewMessage(msg.From(), msg.To(), msg.Nonce(), msg.Value(), gasCap, msg.GasPrice(), msg.Data(), msg.CheckNonce())
+	}
+
+	vmContext := was.newContext(msg)
 	was.logger.WithFields(logrus.Fields{
 		"GasLimit": msg.Gas()}).Debug("was.ApplyTransaction")
 
 	//Prepare the ethState with transaction Hash so that it can be used in emitted
-	//logs
-	was.ethState.Prepare(tx.Hash(), blockHash, txIndex)
+	//logs. Transactions are indexed by their position among the applied
+	//transactions of the block, failed ones excluded
+	was.ethState.Prepare(tx.Hash(), blockHash, was.txIndex)
 
-	vmenv := vm.NewEVM(context, was.ethState, &was.chainConfig, was.vmConfig)
+	vmenv := vm.NewEVM(vmContext, was.ethState, &was.chainConfig, was.vmConfig)
 
 	// Apply the transaction to the current state (included in the env)
-	_, gas, failed, err := core.ApplyMessage(vmenv, msg, was.gp)
+	execSnapshot := was.ethState.Snapshot()
+	ret, gas, failed, err := core.ApplyMessage(vmenv, msg, was.gp)
 	if err != nil {
-		was.logger.WithError(err).Error("Applying transaction to WriteAheadState")
-		return err
+		if dataGas > 0 {
+			was.ethState.RevertToSnapshot(snapshot)
+			was.gp.AddGas(dataGas)
+		}
+		return newTxApplyError(&tx, err)
+	}
+	if size, tooLarge := was.codeLimits.codeTooLarge(was.ethState, msg); !failed && tooLarge {
+		was.logger.WithFields(logrus.Fields{
+			"hash":          tx.Hash().Hex(),
+			"code_size":     size,
+			"max_code_size": was.codeLimits.MaxCodeSize,
+		}).Warn("Contract creation exceeds max code size")
+		was.failCreate(msg, vmenv.Context.Coinbase, execSnapshot, gas)
+		ret, gas, failed = nil, msg.Gas(), true
+	}
+	if blob != nil {
+		was.commitBlob(tx.Hash(), blob)
+	}
+	// The execution fee went to the beneficiary; the blob data fee was burned
+	// to the zero address
+	was.blockFees.Add(was.blockFees, new(big.Int).Mul(new(big.Int).SetUint64(gas), msg.GasPrice()))
+	gas += dataGas
+	span.SetAttributes(attribute.Int64("gas_used", int64(gas)), attribute.Bool("failed", failed))
+	if failed && len(ret) > 0 {
+		was.reverts[tx.Hash()] = ret
+	}
+	if failed && capped && gas >= gasCap {
+		was.logger.WithFields(logrus.Fields{
+			"hash":    tx.Hash().Hex(),
+			"to":      msg.To().Hex(),
+			"gas_cap": gasCap,
+		}).Warn("Transaction exhausted gas cap")
+		was.capped[tx.Hash()] = gasCap
 	}
 
 	was.totalUsedGas.Add(was.totalUsedGas, new(big.Int).SetUint64(gas))
@@ -144,17 +318,28 @@ func (was *WriteAheadState) ApplyTransaction(tx ethTypes.Transaction, txIndex in
 	return nil
 }
 
-func (was *WriteAheadState) Commit() (common.Hash, error) {
+func (was *WriteAheadState) Commit(ctx context.Context) (root common.Hash, err error) {
+	ctx, span := otelTracer.Start(ctx, "WAS.Commit", trace.WithAttributes(attribute.Int("txs", len(was.transactions))))
+	defer func() { endSpan(span, err) }()
+
+	if was.blockStarted {
+		was.burned = was.distributeFees()
+	}
+
 	//commit all state changes to the database
-	root, err := was.ethState.Commit(true)
+	_, trieSpan := otelTracer.Start(ctx, "WAS.CommitTrie")
+	root, err = was.ethState.Commit(true)
 	if err != nil {
+		endSpan(trieSpan, err)
 		was.logger.WithError(err).Error("Committing state")
 		return common.Hash{}, err
 	}
 
 	//XXX FORCE DISK WRITE
 	// Apparently Geth does something smarter here... but can't figure it out
-	if err := was.ethState.Database().TrieDB().Commit(root, true); err != nil {
+	err = was.ethState.Database().TrieDB().Commit(root, true)
+	endSpan(trieSpan, err)
+	if err != nil {
 		was.logger.WithError(err).Error("Writing root")
 		return common.Hash{}, err
 	}
@@ -162,6 +347,15 @@ func (was *WriteAheadState) Commit() (common.Hash, error) {
 		was.logger.WithError(err).Error("Writing root")
 		return common.Hash{}, err
 	}
+
+	// The receipts and logs are only written once the block, and so its hash,
+	// is known
+	var block *Block
+	if was.blockStarted {
+		block = was.newBlock(root)
+		was.setBlockContext(block)
+	}
+
 	if err := was.writeHead(); err != nil {
 		was.logger.WithError(err).Error("Writing head")
 		return common.Hash{}, err
@@ -174,6 +368,26 @@ func (was *WriteAheadState) Commit() (common.Hash, error) {
 		was.logger.WithError(err).Error("Writing receipts")
 		return common.Hash{}, err
 	}
+	if err := was.writeBlobs(); err != nil {
+		was.logger.WithError(err).Error("Writing blobs")
+		return common.Hash{}, err
+	}
+	if block != nil {
+		indexed := &indexedBlock{
+			block:    block,
+			hash:     block.Hash(),
+			txs:      was.transactions,
+			receipts: was.receipts,
+		}
+		if err := writeIndexes(was.db, indexed, was.signer); err != nil {
+			was.logger.WithError(err).Error("Writing indexes")
+			return common.Hash{}, err
+		}
+		if err := was.writeBlock(block); err != nil {
+			was.logger.WithError(err).Error("Writing block")
+			return common.Hash{}, err
+		}
+	}
 	return root, nil
 }
 
@@ -189,6 +403,74 @@ func (was *WriteAheadState) writeHead() error {
 	return was.db.Put(headTxKey, head.Hash().Bytes())
 }
 
+// newBlock returns the Block applied since the last Commit
+func (was *WriteAheadState) newBlock(root common.Hash) *Block {
+	block := &Block{
+		Number:        uint64(was.blockIndex),
+		ConsensusHash: was.blockHash,
+		Timestamp:     uint64(was.blockTime),
+		StateRoot:     root,
+		TxRoot:        ethTypes.DeriveSha(ethTypes.Transactions(was.transactions)),
+		ReceiptRoot:   ethTypes.DeriveSha(ethTypes.Receipts(was.receipts)),
+		GasUsed:       was.totalUsedGas.Uint64(),
+		Transactions:  make([]common.Hash, len(was.transactions)),
+		Coinbase:      was.blockCoinbase,
+		legacy:        was.legacyHeader,
+	}
+	for i, tx := range was.transactions {
+		block.Transactions[i] = tx.Hash()
+	}
+	if was.blockIndex > 0 {
+		if data, err := was.db.Get(blockHashKey(was.blockIndex - 1)); err == nil {
+			block.ParentHash = common.BytesToHash(data)
+		}
+	}
+	return block
+}
+
+// setBlockContext sets the block hash and number of the logs of the block's
+// receipts
+func (was *WriteAheadState) setBlockContext(block *Block) {
+	hash := block.Hash()
+	for _, receipt := range was.receipts {
+		for _, log := range receipt.Logs {
+			log.BlockHash = hash
+			log.BlockNumber = block.Number
+		}
+	}
+}
+
+// writeBlock writes a Block, and indexes its hash by number (for BLOCKHASH),
+// its number by hash and its resulting root by consensus hash (to detect
+// replays)
+func (was *WriteAheadState) writeBlock(block *Block) error {
+	data, err := block.Marshal()
+	if err != nil {
+		return err
+	}
+	hash := block.Hash()
+
+	batch := was.db.NewBatch()
+	if err := batch.Put(blockHeaderKey(was.blockIndex), data); err != nil {
+		return err
+	}
+	if err := batch.Put(blockHashKey(was.blockIndex), hash.Bytes()); err != nil {
+		return err
+	}
+	if err := batch.Put(blockNumberKey(hash), encodeBlockIndex(was.blockIndex)); err != nil {
+		return err
+	}
+	if err := batch.Put(headBlockKey, encodeBlockIndex(was.blockIndex)); err != nil {
+		return err
+	}
+	if was.blockHash != (common.Hash{}) {
+		if err := batch.Put(appliedBlockKey(was.blockHash), block.StateRoot.Bytes()); err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}
+
 func (was *WriteAheadState) writeTransactions() error {
 	batch := was.db.NewBatch()
 
@@ -220,5 +502,21 @@ func (was *WriteAheadState) writeReceipts() error {
 		}
 	}
 
+	rangeInCommit()
+	for txHash, ret := range was.reverts {
+		if err := batch.Put(append(revertPrefix, txHash.Bytes()...), ret); err != nil {
+			return err
+		}
+	}
+
+	rangeInCommit()
+	for txHash, gasCap := range was.capped {
+		data := make([]byte, 8)
+		binary.BigEndian.PutUint64(data, gasCap)
+		if err := batch.Put(append(gasCappedPrefix, txHash.Bytes()...), data); err != nil {
+			return err
+		}
+	}
+
 	return batch.Write()
 }
@@ -1,6 +1,7 @@
 package version
 
 import (
+	"runtime"
 	"strings"
 )
 
@@ -12,12 +13,24 @@ var (
 	// The full version string
 	Version = strings.Join([]string{Maj, Min, Fix}, ".")
 
-	// GitCommit is set with --ldflags "-X main.gitCommit=$(git rev-parse HEAD)"
+	// GitCommit is set at build time with
+	// --ldflags "-X github.com/Fantom-foundation/go-evm/src/version.GitCommit=$(git rev-parse HEAD)"
 	GitCommit string
 )
 
 func init() {
-	if GitCommit != "" {
+	if len(GitCommit) >= 8 {
 		Version += "-" + GitCommit[:8]
 	}
 }
+
+// GoVersion returns the version of Go the node was built with
+func GoVersion() string {
+	return runtime.Version()
+}
+
+// Platform returns the OS and architecture the node was built for, e.g.
+// linux-amd64
+func Platform() string {
+	return runtime.GOOS + "-" + runtime.GOARCH
+}